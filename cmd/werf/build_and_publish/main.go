@@ -111,6 +111,8 @@ If one or more IMAGE_NAME parameters specified, werf will build images stages an
 	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
 	common.SetupParallelOptions(&commonCmdData, cmd)
 
+	common.SetupHostCleanupAllowedDiskUsage(&commonCmdData, cmd)
+
 	return cmd
 }
 
@@ -251,5 +253,5 @@ func runBuildAndPublish(imagesToProcess []string) error {
 		return err
 	}
 
-	return nil
+	return common.RunHostCleanupByDiskUsage(ctx, &commonCmdData)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -22,9 +23,12 @@ import (
 	cleanup "github.com/werf/werf/pkg/cleaning"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/deploy/events"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/multicluster"
 	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/host_cleaning"
 	"github.com/werf/werf/pkg/logging"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/util"
@@ -110,13 +114,31 @@ type CmdData struct {
 	LogTerminalWidth *int64
 
 	ThreeWayMergeMode *string
+	AutoRollback      *bool
+	HelmVersion       *string
+	LintRules         *[]string
+
+	Clusters                  *[]string
+	ClustersStrategy          *string
+	ClustersContinueOnFailure *bool
+
+	EventsWebhooks       *[]string
+	EventsFile           *string
+	EventsWebhookRetries *int
 
 	PublishReportPath   *string
 	PublishReportFormat *string
 
+	ExportAdd          *string
+	ExportTo           *string
+	ExportIncludePaths *[]string
+	ExportExcludePaths *[]string
+
 	VirtualMerge           *bool
 	VirtualMergeFromCommit *string
 	VirtualMergeIntoCommit *string
+
+	HostCleanupAllowedDiskUsage *string
 }
 
 const (
@@ -193,6 +215,41 @@ func GetPublishReportFormat(cmdData *CmdData) (build.PublishReportFormat, error)
 	}
 }
 
+func SetupExportOptions(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.ExportAdd = new(string)
+	cmdData.ExportTo = new(string)
+	cmdData.ExportIncludePaths = new([]string)
+	cmdData.ExportExcludePaths = new([]string)
+
+	cmd.Flags().StringVarP(cmdData.ExportAdd, "add", "", os.Getenv("WERF_ADD"), "Absolute path in the artifact or image to export (default $WERF_ADD)")
+	cmd.Flags().StringVarP(cmdData.ExportTo, "to", "", os.Getenv("WERF_TO"), "Host path to export files to (default $WERF_TO)")
+	cmd.Flags().StringArrayVarP(cmdData.ExportIncludePaths, "include-path", "", []string{}, "Export only specified paths relative to the --add path (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(cmdData.ExportExcludePaths, "exclude-path", "", []string{}, "Do not export specified paths relative to the --add path (can be specified multiple times)")
+}
+
+func GetExportImageFilesOptions(cmdData *CmdData) (build.ExportImageFilesOptions, error) {
+	if *cmdData.ExportAdd == "" || !path.IsAbs(*cmdData.ExportAdd) {
+		return build.ExportImageFilesOptions{}, fmt.Errorf("--add option with absolute path required")
+	}
+
+	for _, p := range append(append([]string{}, *cmdData.ExportIncludePaths...), *cmdData.ExportExcludePaths...) {
+		if path.IsAbs(p) {
+			return build.ExportImageFilesOptions{}, fmt.Errorf("--include-path and --exclude-path should be relative paths: %s", p)
+		}
+	}
+
+	if *cmdData.ExportTo == "" {
+		return build.ExportImageFilesOptions{}, fmt.Errorf("--to option required")
+	}
+
+	return build.ExportImageFilesOptions{
+		Add:          *cmdData.ExportAdd,
+		To:           *cmdData.ExportTo,
+		IncludePaths: *cmdData.ExportIncludePaths,
+		ExcludePaths: *cmdData.ExportExcludePaths,
+	}, nil
+}
+
 func SetupImagesCleanupPolicies(cmdData *CmdData, cmd *cobra.Command) {
 	cmdData.GitTagStrategyLimit = new(int64)
 	cmdData.GitTagStrategyExpiryDays = new(int64)
@@ -810,6 +867,210 @@ func SetupThreeWayMergeMode(cmdData *CmdData, cmd *cobra.Command) {
 Supported 'enabled', 'disabled' and 'onlyNewReleases', see docs for more info https://werf.io/documentation/reference/deploy_process/experimental_three_way_merge.html`)
 }
 
+func SetupAutoRollback(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.AutoRollback = new(bool)
+	cmd.Flags().BoolVarP(cmdData.AutoRollback, "auto-rollback", "", GetBoolEnvironmentDefaultFalse("WERF_AUTO_ROLLBACK"), `Roll back the release to the latest successfully deployed revision when resources tracking fails or times out,
+the command still exits with an error (default $WERF_AUTO_ROLLBACK or deploy.autoRollback in werf.yaml)`)
+}
+
+// GetAutoRollback returns the explicitly set --auto-rollback option or $WERF_AUTO_ROLLBACK, then deploy.autoRollback from werf.yaml
+func GetAutoRollback(cmdData *CmdData, cmd *cobra.Command, werfConfig *config.WerfConfig) bool {
+	if cmd.Flags().Changed("auto-rollback") || os.Getenv("WERF_AUTO_ROLLBACK") != "" {
+		return *cmdData.AutoRollback
+	}
+
+	autoRollback := werfConfig.Meta.DeployTemplates.AutoRollback
+	return autoRollback != nil && *autoRollback
+}
+
+func SetupHelmVersion(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.HelmVersion = new(string)
+	cmd.Flags().StringVarP(cmdData.HelmVersion, "helm-version", "", os.Getenv("WERF_HELM_VERSION"), fmt.Sprintf(`Helm version used to manage the release: %[1]s (Helm 2 with the embedded Tiller) or %[2]s
+(default $WERF_HELM_VERSION or deploy.helmVersion from werf.yaml or %[1]s)`, config.HelmVersion2, config.HelmVersion3))
+}
+
+// GetHelmVersion returns the helm version from the command line or werf.yaml (werfConfig can be nil),
+// legacy WERF_HELM3=1 is equal to the helm version 3
+func GetHelmVersion(cmdData *CmdData, werfConfig *config.WerfConfig) (string, error) {
+	helmVersion := config.HelmVersion2
+	switch {
+	case *cmdData.HelmVersion != "":
+		helmVersion = *cmdData.HelmVersion
+	case werfConfig != nil && werfConfig.Meta.DeployTemplates.HelmVersion != nil:
+		helmVersion = *werfConfig.Meta.DeployTemplates.HelmVersion
+	case os.Getenv("WERF_HELM3") == "1":
+		helmVersion = config.HelmVersion3
+	}
+
+	if helmVersion != config.HelmVersion2 && helmVersion != config.HelmVersion3 {
+		return "", fmt.Errorf("bad helm version '%s': %s or %s expected", helmVersion, config.HelmVersion2, config.HelmVersion3)
+	}
+
+	return helmVersion, nil
+}
+
+func SetupLintRules(cmdData *CmdData, cmd *cobra.Command) {
+	rules := predefinedValuesByEnvNamePrefix("WERF_LINT_RULE_")
+
+	cmdData.LintRules = &rules
+	cmd.Flags().StringArrayVarP(cmdData.LintRules, "rule", "", rules, fmt.Sprintf(`Set severity of the lint rule in the form RULE=SEVERITY, where SEVERITY is error, warning, info or off (can specify multiple).
+Available rules: %s.
+Also, can be specified with $WERF_LINT_RULE_* (e.g. $WERF_LINT_RULE_1=container-resources=error)`, strings.Join(helmLintRulesIDs(), ", ")))
+}
+
+func GetLintRulesSeverity(cmdData *CmdData) (map[string]helm.LintSeverity, error) {
+	return helm.ParseLintRulesSeverity(*cmdData.LintRules)
+}
+
+func SetupHostCleanupAllowedDiskUsage(cmdData *CmdData, cmd *cobra.Command) {
+	cmdData.HostCleanupAllowedDiskUsage = new(string)
+	cmd.Flags().StringVarP(cmdData.HostCleanupAllowedDiskUsage, "host-cleanup-allowed-disk-usage", "", os.Getenv("WERF_HOST_CLEANUP_ALLOWED_DISK_USAGE"), `Run host cleanup of least recently used local stages, remote git clones and git worktrees after the build
+if the usage of the docker storage volume or werf local cache volume exceeds the allowed percentage (e.g. 70%).
+Host cleanup is disabled by default ($WERF_HOST_CLEANUP_ALLOWED_DISK_USAGE by default)`)
+}
+
+func RunHostCleanupByDiskUsage(ctx context.Context, cmdData *CmdData) error {
+	if *cmdData.HostCleanupAllowedDiskUsage == "" {
+		return nil
+	}
+
+	allowedDiskUsagePercentage, err := host_cleaning.ParseAllowedDiskUsagePercentage(*cmdData.HostCleanupAllowedDiskUsage)
+	if err != nil {
+		return fmt.Errorf("bad --host-cleanup-allowed-disk-usage value: %s", err)
+	}
+
+	return host_cleaning.CleanupByDiskUsage(ctx, allowedDiskUsagePercentage, false)
+}
+
+func helmLintRulesIDs() []string {
+	var res []string
+	for _, rule := range helm.LintRules() {
+		res = append(res, rule.ID)
+	}
+
+	return res
+}
+
+func SetupDeployClusters(cmdData *CmdData, cmd *cobra.Command) {
+	clusters := predefinedValuesByEnvNamePrefix("WERF_CLUSTER_")
+
+	cmdData.Clusters = &clusters
+	cmd.Flags().StringArrayVarP(cmdData.Clusters, "cluster", "", clusters, `Deploy into the specified target cluster instead of deploy.clusters from werf.yaml (can specify multiple).
+Cluster is specified in the form name=NAME,kube-context=CONTEXT,kube-config=PATH,namespace=NAMESPACE,values=PATH1;PATH2,
+name and either kube-context or kube-config are required.
+Also, can be specified with $WERF_CLUSTER_* (e.g. $WERF_CLUSTER_1=name=eu,kube-context=eu-prod)`)
+
+	cmdData.ClustersStrategy = new(string)
+	cmd.Flags().StringVarP(cmdData.ClustersStrategy, "clusters-strategy", "", os.Getenv("WERF_CLUSTERS_STRATEGY"), fmt.Sprintf(`Deploy into target clusters sequentially or in parallel: %[1]s or %[2]s
+(default $WERF_CLUSTERS_STRATEGY or deploy.clustersStrategy from werf.yaml or %[1]s)`, config.ClustersStrategySequential, config.ClustersStrategyParallel))
+
+	cmdData.ClustersContinueOnFailure = new(bool)
+	cmd.Flags().BoolVarP(cmdData.ClustersContinueOnFailure, "clusters-continue-on-failure", "", GetBoolEnvironmentDefaultFalse("WERF_CLUSTERS_CONTINUE_ON_FAILURE"), `Continue the sequential deploy into the next target clusters after the failure,
+by default the rest clusters are skipped unless deploy.clustersStopOnFailure is false in werf.yaml (default $WERF_CLUSTERS_CONTINUE_ON_FAILURE)`)
+}
+
+// GetDeployClusters returns target clusters from the command line or werf.yaml,
+// clusters are not used in the child process which deploys into the single cluster
+func GetDeployClusters(cmdData *CmdData, werfConfig *config.WerfConfig) ([]*multicluster.Cluster, multicluster.Options, error) {
+	var clusters []*multicluster.Cluster
+	opts := multicluster.Options{StopOnFailure: true}
+
+	if multicluster.IsClusterProcess() {
+		return nil, opts, nil
+	}
+
+	if len(*cmdData.Clusters) != 0 {
+		for _, value := range *cmdData.Clusters {
+			cluster, err := multicluster.ParseCluster(value)
+			if err != nil {
+				return nil, opts, err
+			}
+			clusters = append(clusters, cluster)
+		}
+	} else {
+		for _, cluster := range werfConfig.Meta.DeployTemplates.Clusters {
+			clusters = append(clusters, &multicluster.Cluster{
+				Name:        cluster.Name,
+				KubeContext: cluster.KubeContext,
+				KubeConfig:  cluster.KubeConfig,
+				Namespace:   cluster.Namespace,
+				Values:      cluster.Values,
+			})
+		}
+	}
+
+	strategy := config.ClustersStrategySequential
+	if *cmdData.ClustersStrategy != "" {
+		strategy = *cmdData.ClustersStrategy
+	} else if werfConfig.Meta.DeployTemplates.ClustersStrategy != nil {
+		strategy = *werfConfig.Meta.DeployTemplates.ClustersStrategy
+	}
+
+	switch strategy {
+	case config.ClustersStrategySequential:
+	case config.ClustersStrategyParallel:
+		opts.Parallel = true
+	default:
+		return nil, opts, fmt.Errorf("bad clusters strategy '%s': %s or %s expected", strategy, config.ClustersStrategySequential, config.ClustersStrategyParallel)
+	}
+
+	if *cmdData.ClustersContinueOnFailure {
+		opts.StopOnFailure = false
+	} else if stopOnFailure := werfConfig.Meta.DeployTemplates.ClustersStopOnFailure; stopOnFailure != nil {
+		opts.StopOnFailure = *stopOnFailure
+	}
+
+	return clusters, opts, nil
+}
+
+func SetupDeployEvents(cmdData *CmdData, cmd *cobra.Command) {
+	eventsWebhooks := predefinedValuesByEnvNamePrefix("WERF_EVENTS_WEBHOOK_")
+
+	cmdData.EventsWebhooks = &eventsWebhooks
+	cmd.Flags().StringArrayVarP(cmdData.EventsWebhooks, "events-webhook", "", eventsWebhooks, `Send deploy lifecycle events as JSON to the specified HTTP webhook URL (can specify multiple).
+Also, can be specified with $WERF_EVENTS_WEBHOOK_* (e.g. $WERF_EVENTS_WEBHOOK_1=https://chat.example.com/hook)`)
+
+	cmdData.EventsFile = new(string)
+	cmd.Flags().StringVarP(cmdData.EventsFile, "events-file", "", os.Getenv("WERF_EVENTS_FILE"), "Append deploy lifecycle events in the JSON lines format to the specified file (default $WERF_EVENTS_FILE)")
+
+	defaultRetriesP, err := getIntEnvVar("WERF_EVENTS_WEBHOOK_RETRIES")
+	if err != nil {
+		TerminateWithError(fmt.Sprintf("bad WERF_EVENTS_WEBHOOK_RETRIES value: %s", err), 1)
+	}
+
+	defaultRetries := 3
+	if defaultRetriesP != nil {
+		defaultRetries = int(*defaultRetriesP)
+	}
+
+	cmdData.EventsWebhookRetries = new(int)
+	cmd.Flags().IntVarP(cmdData.EventsWebhookRetries, "events-webhook-retries", "", defaultRetries, "Number of retries of the failed events webhook request, the i-th retry is made in i seconds (default $WERF_EVENTS_WEBHOOK_RETRIES or 3)")
+}
+
+func InitDeployEvents(ctx context.Context, cmdData *CmdData, projectName, projectDir string) error {
+	if len(*cmdData.EventsWebhooks) == 0 && *cmdData.EventsFile == "" {
+		return nil
+	}
+
+	var gitCommit string
+	if localGitRepo, err := git_repo.OpenLocalRepo("own", projectDir); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: unable to open local git repo to get commit for deploy events: %s\n", err)
+	} else if localGitRepo != nil {
+		if gitCommit, err = localGitRepo.HeadCommit(ctx); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: unable to get head commit for deploy events: %s\n", err)
+		}
+	}
+
+	return events.Init(events.InitOptions{
+		Webhooks:       *cmdData.EventsWebhooks,
+		File:           *cmdData.EventsFile,
+		WebhookRetries: *cmdData.EventsWebhookRetries,
+		Project:        projectName,
+		GitCommit:      gitCommit,
+		Cluster:        os.Getenv(multicluster.ClusterEnvName),
+	})
+}
+
 func GetThreeWayMergeMode(threeWayMergeModeParam string) (helm.ThreeWayMergeModeType, error) {
 	switch threeWayMergeModeParam {
 	case "enabled", "disabled", "onlyNewReleases", "":
@@ -1077,6 +1338,20 @@ func getImagesRepo(ctx context.Context, projectName string, cmdData *CmdData, op
 		return nil, err
 	}
 
+	return newImagesRepo(ctx, projectName, cmdData, imagesRepoAddress, imagesRepoMode)
+}
+
+// GetImagesRepoWithMode returns the images repo in the specified mode instead of --images-repo-mode
+func GetImagesRepoWithMode(ctx context.Context, projectName string, cmdData *CmdData, imagesRepoMode string) (storage.ImagesRepo, error) {
+	imagesRepoAddress, err := getImagesRepoAddress(projectName, cmdData)
+	if err != nil {
+		return nil, err
+	}
+
+	return newImagesRepo(ctx, projectName, cmdData, imagesRepoAddress, imagesRepoMode)
+}
+
+func newImagesRepo(ctx context.Context, projectName string, cmdData *CmdData, imagesRepoAddress, imagesRepoMode string) (storage.ImagesRepo, error) {
 	repoData := MergeRepoData(cmdData.ImagesRepoData, cmdData.CommonRepoData)
 
 	if err := ValidateRepoImplementation(*repoData.Implementation); err != nil {
@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/build/stage"
@@ -18,6 +19,7 @@ func GetConveyorOptions(commonCmdData *CmdData) build.ConveyorOptions {
 		},
 		GitUnshallow:         *commonCmdData.GitUnshallow,
 		AllowGitShallowClone: *commonCmdData.AllowGitShallowClone,
+		BuildSecretsDir:      os.Getenv("WERF_BUILD_SECRETS_DIR"),
 	}
 }
 
@@ -3,12 +3,17 @@ package common
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/werf/werf/pkg/werf/locker_with_retry"
+	"github.com/go-redis/redis/v7"
 
 	"github.com/werf/lockgate/pkg/distributed_locker"
+
+	"github.com/werf/werf/pkg/werf/locker_with_retry"
+
 	"github.com/werf/logboek"
 
 	"github.com/spf13/cobra"
@@ -30,7 +35,7 @@ func SetupSynchronization(cmdData *CmdData, cmd *cobra.Command) {
 
 	defaultValue := os.Getenv("WERF_SYNCHRONIZATION")
 
-	cmd.Flags().StringVarP(cmdData.Synchronization, "synchronization", "S", defaultValue, fmt.Sprintf("Address of synchronizer for multiple werf processes to work with a single stages storage (default :local if --stages-storage=:local or %s if non-local stages-storage specified or $WERF_SYNCHRONIZATION if set). The same address should be specified for all werf processes that work with a single stages storage. :local address allows execution of werf processes from a single host only.", storage.DefaultKubernetesStorageAddress))
+	cmd.Flags().StringVarP(cmdData.Synchronization, "synchronization", "S", defaultValue, fmt.Sprintf("Address of synchronizer for multiple werf processes to work with a single stages storage (default :local if --stages-storage=:local or %s if non-local stages-storage specified or $WERF_SYNCHRONIZATION if set). The same address should be specified for all werf processes that work with a single stages storage. :local address allows execution of werf processes from a single host only. Authentication and TLS options of the http[s]://HOST:PORT address are specified with the query params token, token-file, ca-file, cert-file, key-file and skip-tls-verify or with $WERF_SYNCHRONIZATION_TOKEN, $WERF_SYNCHRONIZATION_TOKEN_FILE, $WERF_SYNCHRONIZATION_CA_FILE, $WERF_SYNCHRONIZATION_CERT_FILE, $WERF_SYNCHRONIZATION_KEY_FILE and $WERF_SYNCHRONIZATION_SKIP_TLS_VERIFY. The redis[s]://[[USER]:PASSWORD@]HOST[:PORT][/DB][?namespace=NAMESPACE] address allows to use Redis-compatible server, the password can also be specified with $WERF_SYNCHRONIZATION_REDIS_PASSWORD.", storage.DefaultKubernetesStorageAddress))
 }
 
 type SynchronizationType string
@@ -39,12 +44,20 @@ const (
 	LocalSynchronization      SynchronizationType = "LocalSynchronization"
 	KubernetesSynchronization SynchronizationType = "KubernetesSynchronization"
 	HttpSynchronization       SynchronizationType = "HttpSynchronization"
+	RedisSynchronization      SynchronizationType = "RedisSynchronization"
 )
 
 type SynchronizationParams struct {
 	Address             string
 	SynchronizationType SynchronizationType
 	KubeParams          *storage.KubernetesSynchronizationParams
+	// HttpClient authenticates requests to the http synchronization server
+	HttpClient *http.Client
+	// ServerAddress and ClientID of the http synchronization server, Address includes the client id
+	ServerAddress string
+	ClientID      string
+	RedisParams   *storage.RedisSynchronizationParams
+	RedisClient   *redis.Client
 }
 
 func checkSynchronizationKubernetesParamsForWarnings(cmdData *CmdData) {
@@ -127,11 +140,17 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 		}
 	}
 
-	getHttpParamsFunc := func(synchronization string, stagesStorage storage.StagesStorage) (*SynchronizationParams, error) {
-		var address string
+	getHttpParamsFunc := func(synchronization string, clientOptions synchronization_server.ClientOptions, stagesStorage storage.StagesStorage) (*SynchronizationParams, error) {
+		httpClient, err := synchronization_server.NewHttpClient(clientOptions)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create synchronization server client: %s", err)
+		}
+
+		var address, clientID string
 		if err := logboek.Default().LogProcess(fmt.Sprintf("Getting client id for the http syncrhonization server")).
 			DoError(func() error {
-				if clientID, err := synchronization_server.GetOrCreateClientID(ctx, projectName, synchronization_server.NewSynchronizationClient(synchronization), stagesStorage); err != nil {
+				var err error
+				if clientID, err = synchronization_server.GetOrCreateClientID(ctx, projectName, synchronization_server.NewSynchronizationClient(synchronization, httpClient), stagesStorage); err != nil {
 					return fmt.Errorf("unable to get synchronization client id: %s", err)
 				} else {
 					address = fmt.Sprintf("%s/%s", synchronization, clientID)
@@ -142,14 +161,15 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 			return nil, err
 		}
 
-		return &SynchronizationParams{Address: address, SynchronizationType: HttpSynchronization}, nil
+		return &SynchronizationParams{Address: address, SynchronizationType: HttpSynchronization, HttpClient: httpClient, ServerAddress: synchronization, ClientID: clientID}, nil
 	}
 
 	if *cmdData.Synchronization == "" {
 		if stagesStorage.Address() == storage.LocalStorageAddress {
 			return &SynchronizationParams{SynchronizationType: LocalSynchronization, Address: storage.LocalStorageAddress}, nil
 		} else {
-			return getHttpParamsFunc("https://synchronization.werf.io", stagesStorage)
+			// credentials and the project name should not be sent to the default public synchronization server
+			return getHttpParamsFunc("https://synchronization.werf.io", synchronization_server.ClientOptions{}, stagesStorage)
 		}
 	} else if *cmdData.Synchronization == storage.LocalStorageAddress {
 		return &SynchronizationParams{Address: *cmdData.Synchronization, SynchronizationType: LocalSynchronization}, nil
@@ -157,9 +177,20 @@ func GetSynchronization(ctx context.Context, cmdData *CmdData, projectName strin
 		checkSynchronizationKubernetesParamsForWarnings(cmdData)
 		return getKubeParamsFunc(*cmdData.Synchronization)
 	} else if strings.HasPrefix(*cmdData.Synchronization, "http://") || strings.HasPrefix(*cmdData.Synchronization, "https://") {
-		return getHttpParamsFunc(*cmdData.Synchronization, stagesStorage)
+		address, clientOptions, err := synchronization_server.ParseSynchronizationAddress(*cmdData.Synchronization)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse synchronization address: %s", err)
+		}
+		clientOptions.ProjectName = projectName
+		return getHttpParamsFunc(address, clientOptions, stagesStorage)
+	} else if strings.HasPrefix(*cmdData.Synchronization, "redis://") || strings.HasPrefix(*cmdData.Synchronization, "rediss://") {
+		params, err := storage.ParseRedisSynchronization(*cmdData.Synchronization)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse synchronization address: %s", err)
+		}
+		return &SynchronizationParams{Address: *cmdData.Synchronization, SynchronizationType: RedisSynchronization, RedisParams: params, RedisClient: redis.NewClient(params.Options)}, nil
 	} else {
-		return nil, fmt.Errorf("only --synchronization=%s or --synchronization=kubernetes://NAMESPACE or --synchronization=http[s]://HOST:PORT/CLIENT_ID is supported, got %q", storage.LocalStorageAddress, *cmdData.Synchronization)
+		return nil, fmt.Errorf("only --synchronization=%s or --synchronization=kubernetes://NAMESPACE or --synchronization=http[s]://HOST:PORT/CLIENT_ID or --synchronization=redis[s]://HOST:PORT[/DB] is supported, got %q", storage.LocalStorageAddress, *cmdData.Synchronization)
 	}
 }
 
@@ -182,7 +213,9 @@ func GetStagesStorageCache(synchronization *SynchronizationParams) (storage.Stag
 			}), nil
 		}
 	case HttpSynchronization:
-		return synchronization_server.NewStagesStorageCacheHttpClient(fmt.Sprintf("%s/stages-storage-cache", synchronization.Address)), nil
+		return synchronization_server.NewStagesStorageCacheHttpClient(fmt.Sprintf("%s/stages-storage-cache", synchronization.Address), synchronization.HttpClient), nil
+	case RedisSynchronization:
+		return storage.NewRedisStagesStorageCache(synchronization.RedisClient, synchronization.RedisParams.Namespace), nil
 	default:
 		panic(fmt.Sprintf("unsupported synchronization address %q", synchronization.Address))
 	}
@@ -191,7 +224,10 @@ func GetStagesStorageCache(synchronization *SynchronizationParams) (storage.Stag
 func GetStorageLockManager(ctx context.Context, synchronization *SynchronizationParams) (storage.LockManager, error) {
 	switch synchronization.SynchronizationType {
 	case LocalSynchronization:
-		return storage.NewGenericLockManager(werf.GetHostLocker()), nil
+		lockManager := storage.NewGenericLockManager(werf.GetHostLocker())
+		// host locks cannot be enumerated: the lock manager records the held locks
+		lockManager.Inspector = storage.NewFileLocksInspector(filepath.Join(werf.GetServiceDir(), "held_locks"))
+		return lockManager, nil
 	case KubernetesSynchronization:
 		if config, err := kube.GetKubeConfig(kube.KubeConfigOptions{
 			ConfigPath:       synchronization.KubeParams.ConfigPath,
@@ -209,9 +245,17 @@ func GetStorageLockManager(ctx context.Context, synchronization *Synchronization
 			}), nil
 		}
 	case HttpSynchronization:
-		locker := distributed_locker.NewHttpLocker(fmt.Sprintf("%s/locker", synchronization.Address))
+		locker := synchronization_server.NewHttpLocker(fmt.Sprintf("%s/locker", synchronization.Address), synchronization.HttpClient)
 		lockerWithRetry := locker_with_retry.NewLockerWithRetry(ctx, locker, locker_with_retry.LockerWithRetryOptions{MaxAcquireAttempts: 10, MaxReleaseAttempts: 10})
-		return storage.NewGenericLockManager(lockerWithRetry), nil
+		lockManager := storage.NewGenericLockManager(lockerWithRetry)
+		lockManager.Inspector = synchronization_server.NewHttpLocksInspector(synchronization.ServerAddress, synchronization.ClientID, synchronization.HttpClient)
+		return lockManager, nil
+	case RedisSynchronization:
+		backend := storage.NewRedisDistributedLockerBackend(synchronization.RedisClient, synchronization.RedisParams.Namespace)
+		lockerWithRetry := locker_with_retry.NewLockerWithRetry(ctx, distributed_locker.NewDistributedLocker(backend), locker_with_retry.LockerWithRetryOptions{MaxAcquireAttempts: 10, MaxReleaseAttempts: 10})
+		lockManager := storage.NewGenericLockManager(lockerWithRetry)
+		lockManager.Inspector = backend
+		return lockManager, nil
 	default:
 		panic(fmt.Sprintf("unsupported synchronization address %q", synchronization.Address))
 	}
@@ -1,6 +1,7 @@
 package converge
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -13,7 +14,9 @@ import (
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/deploy"
+	"github.com/werf/werf/pkg/deploy/events"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/multicluster"
 	"github.com/werf/werf/pkg/docker"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/images_manager"
@@ -63,7 +66,9 @@ werf converge --stages-storage registry.mydomain.com/web/back/stages --images-re
 			common.LogVersion()
 
 			return common.LogRunningTime(func() error {
-				return runConverge()
+				err := runConverge(cmd)
+				events.Finish(common.BackgroundContext(), err)
+				return err
 			})
 		},
 	}
@@ -108,6 +113,10 @@ werf converge --stages-storage registry.mydomain.com/web/back/stages --images-re
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupAutoRollback(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
+	common.SetupDeployEvents(&commonCmdData, cmd)
+	common.SetupDeployClusters(&commonCmdData, cmd)
 
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
@@ -122,7 +131,7 @@ werf converge --stages-storage registry.mydomain.com/web/back/stages --images-re
 	return cmd
 }
 
-func runConverge() error {
+func runConverge(cmd *cobra.Command) error {
 	tmp_manager.AutoGCEnabled = true
 	ctx := common.BackgroundContext()
 
@@ -166,6 +175,10 @@ func runConverge() error {
 
 	projectName := werfConfig.Meta.Project
 
+	if err := common.InitDeployEvents(ctx, &commonCmdData, projectName, projectDir); err != nil {
+		return err
+	}
+
 	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
 	if err != nil {
 		return fmt.Errorf("getting project tmp dir failed: %s", err)
@@ -212,33 +225,20 @@ func runConverge() error {
 		return err
 	}
 
-	deployInitOptions := deploy.InitOptions{
-		HelmInitOptions: helm.InitOptions{
-			KubeConfig:                  *commonCmdData.KubeConfig,
-			KubeConfigBase64:            *commonCmdData.KubeConfigBase64,
-			KubeContext:                 *commonCmdData.KubeContext,
-			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
-			HelmReleaseStorageType:      helmReleaseStorageType,
-			StatusProgressPeriod:        common.GetStatusProgressPeriod(&commonCmdData),
-			HooksStatusProgressPeriod:   common.GetHooksStatusProgressPeriod(&commonCmdData),
-			ReleasesMaxHistory:          *commonCmdData.ReleasesHistoryMax,
-			InitNamespace:               true,
-		},
-	}
-	if err := deploy.Init(ctx, deployInitOptions); err != nil {
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, werfConfig)
+	if err != nil {
 		return err
 	}
 
-	if err := kube.Init(kube.InitOptions{kube.KubeConfigOptions{
-		Context:          *commonCmdData.KubeContext,
-		ConfigPath:       *commonCmdData.KubeConfig,
-		ConfigDataBase64: *commonCmdData.KubeConfigBase64,
-	}}); err != nil {
-		return fmt.Errorf("cannot initialize kube: %s", err)
+	clusters, clustersOpts, err := common.GetDeployClusters(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
 	}
 
-	if err := common.InitKubedog(ctx); err != nil {
-		return fmt.Errorf("cannot init kubedog: %s", err)
+	if len(clusters) == 0 {
+		if err := initKube(ctx, helmReleaseStorageType); err != nil {
+			return err
+		}
 	}
 
 	buildAndPublishOptions := build.BuildAndPublishOptions{
@@ -293,21 +293,37 @@ func runConverge() error {
 		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, nil, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, imagesRepo, storageLockManager, conveyorOptions)
 		defer conveyorWithRetry.Terminate()
 
-		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
-			if err := c.BuildAndPublish(ctx, buildAndPublishOptions); err != nil {
+		events.SetReleaseInfo(events.ReleaseInfo{Release: release, Namespace: namespace, Env: *commonCmdData.Environment})
+		events.Emit(events.Event{Type: events.BuildStarted})
+
+		err = conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+			// images are built and published by the parent process of the multi-cluster deploy
+			if multicluster.IsClusterProcess() {
+				if err := c.ShouldBeBuilt(ctx, build.ShouldBeBuiltOptions{}); err != nil {
+					return err
+				}
+			} else if err := c.BuildAndPublish(ctx, buildAndPublishOptions); err != nil {
 				return err
 			}
 
 			imagesInfoGetters = c.GetImageInfoGetters(werfConfig.StapelImages, werfConfig.ImagesFromDockerfile, "", tag_strategy.StagesSignature, false)
 
 			return nil
-		}); err != nil {
+		})
+
+		events.Emit(events.Event{Type: events.BuildFinished, Status: events.StatusByError(err), Error: events.ErrorString(err)})
+
+		if err != nil {
 			return err
 		}
 
 		logboek.LogOptionalLn()
 	}
 
+	if len(clusters) != 0 {
+		return multicluster.Deploy(ctx, clusters, clustersOpts)
+	}
+
 	return deploy.Deploy(ctx, projectName, projectDir, helmChartDir, imagesRepository, imagesInfoGetters, release, namespace, "", tag_strategy.StagesSignature, werfConfig, *commonCmdData.HelmReleaseStorageNamespace, helmReleaseStorageType, deploy.DeployOptions{
 		Set:                  *commonCmdData.Set,
 		SetString:            *commonCmdData.SetString,
@@ -319,5 +335,40 @@ func runConverge() error {
 		UserExtraLabels:      userExtraLabels,
 		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
 		ThreeWayMergeMode:    helm.ThreeWayMergeEnabled,
+		AutoRollback:         common.GetAutoRollback(&commonCmdData, cmd, werfConfig),
+		HelmVersion:          helmVersion,
 	})
 }
+
+func initKube(ctx context.Context, helmReleaseStorageType string) error {
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeConfigBase64:            *commonCmdData.KubeConfigBase64,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			StatusProgressPeriod:        common.GetStatusProgressPeriod(&commonCmdData),
+			HooksStatusProgressPeriod:   common.GetHooksStatusProgressPeriod(&commonCmdData),
+			ReleasesMaxHistory:          *commonCmdData.ReleasesHistoryMax,
+			InitNamespace:               true,
+		},
+	}
+	if err := deploy.Init(ctx, deployInitOptions); err != nil {
+		return err
+	}
+
+	if err := kube.Init(kube.InitOptions{kube.KubeConfigOptions{
+		Context:          *commonCmdData.KubeContext,
+		ConfigPath:       *commonCmdData.KubeConfig,
+		ConfigDataBase64: *commonCmdData.KubeConfigBase64,
+	}}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	if err := common.InitKubedog(ctx); err != nil {
+		return fmt.Errorf("cannot init kubedog: %s", err)
+	}
+
+	return nil
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/werf/werf/pkg/build"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/deploy"
+	"github.com/werf/werf/pkg/deploy/events"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/multicluster"
 	"github.com/werf/werf/pkg/docker"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/images_manager"
@@ -64,7 +66,9 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 			common.LogVersion()
 
 			return common.LogRunningTime(func() error {
-				return runDeploy()
+				err := runDeploy(cmd)
+				events.Finish(common.BackgroundContext(), err)
+				return err
 			})
 		},
 	}
@@ -112,6 +116,10 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
 
 	common.SetupThreeWayMergeMode(&commonCmdData, cmd)
+	common.SetupAutoRollback(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
+	common.SetupDeployEvents(&commonCmdData, cmd)
+	common.SetupDeployClusters(&commonCmdData, cmd)
 
 	common.SetupVirtualMerge(&commonCmdData, cmd)
 	common.SetupVirtualMergeFromCommit(&commonCmdData, cmd)
@@ -125,7 +133,7 @@ Read more info about Helm chart structure, Helm Release name, Kubernetes Namespa
 	return cmd
 }
 
-func runDeploy() error {
+func runDeploy(cmd *cobra.Command) error {
 	tmp_manager.AutoGCEnabled = true
 	ctx := common.BackgroundContext()
 
@@ -141,6 +149,31 @@ func runDeploy() error {
 		return err
 	}
 
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	if err := common.InitDeployEvents(ctx, &commonCmdData, werfConfig.Meta.Project, projectDir); err != nil {
+		return err
+	}
+
+	clusters, clustersOpts, err := common.GetDeployClusters(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
+	if len(clusters) != 0 {
+		return multicluster.Deploy(ctx, clusters, clustersOpts)
+	}
+
 	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
 	if err != nil {
 		return err
@@ -195,13 +228,6 @@ func runDeploy() error {
 		return fmt.Errorf("cannot init kubedog: %s", err)
 	}
 
-	projectDir, err := common.GetProjectDir(&commonCmdData)
-	if err != nil {
-		return fmt.Errorf("getting project dir failed: %s", err)
-	}
-
-	common.ProcessLogProjectDir(&commonCmdData, projectDir)
-
 	helmChartDir, err := common.GetHelmChartDir(projectDir, &commonCmdData)
 	if err != nil {
 		return fmt.Errorf("getting helm chart dir failed: %s", err)
@@ -213,11 +239,6 @@ func runDeploy() error {
 	}
 	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
 
-	werfConfig, err := common.GetRequiredWerfConfig(ctx, projectDir, &commonCmdData, true)
-	if err != nil {
-		return fmt.Errorf("unable to load werf config: %s", err)
-	}
-
 	var imagesRepository string
 	var tag string
 	var tagStrategy tag_strategy.TagStrategy
@@ -309,6 +330,11 @@ func runDeploy() error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
 	logboek.LogOptionalLn()
 	return deploy.Deploy(ctx, projectName, projectDir, helmChartDir, imagesRepository, imagesInfoGetters, release, namespace, tag, tagStrategy, werfConfig, *commonCmdData.HelmReleaseStorageNamespace, helmReleaseStorageType, deploy.DeployOptions{
 		Set:                  *commonCmdData.Set,
@@ -321,5 +347,7 @@ func runDeploy() error {
 		UserExtraLabels:      userExtraLabels,
 		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
 		ThreeWayMergeMode:    threeWayMergeMode,
+		AutoRollback:         common.GetAutoRollback(&commonCmdData, cmd, werfConfig),
+		HelmVersion:          helmVersion,
 	})
 }
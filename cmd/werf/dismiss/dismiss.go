@@ -78,6 +78,7 @@ Read more info about Helm Release name, Kubernetes Namespace and how to change i
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
 	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupDockerConfig(&commonCmdData, cmd, "")
 
@@ -172,8 +173,14 @@ func runDismiss() error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
 	return deploy.RunDismiss(ctx, projectName, release, namespace, *commonCmdData.KubeContext, deploy.DismissOptions{
 		WithNamespace: cmdData.WithNamespace,
 		WithHooks:     cmdData.WithHooks,
+		HelmVersion:   helmVersion,
 	})
 }
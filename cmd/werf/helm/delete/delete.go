@@ -3,13 +3,17 @@ package delete
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -18,6 +22,7 @@ var commonCmdData common.CmdData
 
 var cmdData struct {
 	helm.DeleteOptions
+	Namespace string
 }
 
 func NewCmd() *cobra.Command {
@@ -50,12 +55,14 @@ func NewCmd() *cobra.Command {
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
 	cmd.Flags().BoolVar(&cmdData.DisableHooks, "no-hooks", false, "Prevent hooks from running during deletion")
 	cmd.Flags().BoolVar(&cmdData.Purge, "purge", false, "Remove the release from the store and make its name free for later use")
 	cmd.Flags().Int64Var(&cmdData.Timeout, "timeout", 300, "Time in seconds to wait for any individual Kubernetes operation (like Jobs for hooks)")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", os.Getenv("WERF_NAMESPACE"), "Release namespace, used with the helm version 3 only (default $WERF_NAMESPACE or the namespace of the kube context)")
 
 	return cmd
 }
@@ -88,9 +95,26 @@ func runDelete(ctx context.Context, releaseNames []string) error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
 	errors := []string{}
 	for _, releaseName := range releaseNames {
-		if err := helm.Delete(ctx, releaseName, cmdData.DeleteOptions); err != nil {
+		var err error
+		if helmVersion == config.HelmVersion3 {
+			err = helm_v3.Uninstall(ctx, releaseName, helm_v3.UninstallOptions{
+				Namespace:    cmdData.Namespace,
+				DisableHooks: cmdData.DisableHooks,
+				KeepHistory:  !cmdData.Purge,
+				Timeout:      time.Duration(cmdData.Timeout) * time.Second,
+			})
+		} else {
+			err = helm.Delete(ctx, releaseName, cmdData.DeleteOptions)
+		}
+
+		if err != nil {
 			errors = append(errors, err.Error())
 		}
 	}
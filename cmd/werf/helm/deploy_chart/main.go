@@ -81,6 +81,7 @@ func NewCmd() *cobra.Command {
 	common.SetupValues(&commonCmdData, cmd)
 
 	common.SetupThreeWayMergeMode(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	helm_common.SetupHelmHome(&helmCmdData, cmd)
 
@@ -195,8 +196,13 @@ func runDeployChart(chartDirOrChartReference string, releaseName string) error {
 		}
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
 	logboek.LogOptionalLn()
-	werfChart := &werf_chart.WerfChart{ChartDir: chartDir}
+	werfChart := &werf_chart.WerfChart{ChartDir: chartDir, HelmVersion: helmVersion}
 	if err := werfChart.Deploy(ctx, releaseName, namespace, helm.ChartOptions{
 		Timeout: time.Duration(cmdData.Timeout) * time.Second,
 		ChartValuesOptions: helm.ChartValuesOptions{
@@ -0,0 +1,149 @@
+package drift
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/kubedog/pkg/kube"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy"
+	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const DriftExitCode = 2
+
+var cmdData struct {
+	helm.DriftOptions
+	Namespace string
+	ExitCode  bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drift RELEASE_NAME",
+		Short: "Show changes of release resources made outside werf",
+		Long: common.GetLongCommandDescription(fmt.Sprintf(`Compare live objects of the release resources with the manifest of the last deployed release revision and show field-level differences that were made outside werf (kubectl edit, kubectl scale, etc.).
+
+Only fields that are set in the manifest are compared, fields that were last changed by Kubernetes controllers (%s and managers specified by --ignore-manager) are not considered as drift.
+
+Unlike werf diff, the command compares live objects with the manifest that werf last applied, not with the next render of the chart.`, strings.Join(helm.DefaultDriftIgnoredManagers, ", "))),
+		Example: `  # Show drift of the release resources
+  $ werf helm drift myproject-production
+
+  # Check the release in the scheduled job: exit with code 2 if drift is found
+  $ werf helm drift myproject-production --output json --exit-code`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer werf.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if err := common.ValidateArgumentCount(1, args, cmd); err != nil {
+				return err
+			}
+
+			return runDrift(args[0])
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().StringVar(&cmdData.OutputFormat, "output", "table", "Output the specified format (json, yaml or table)")
+	cmd.Flags().UintVar(&cmdData.ColWidth, "col-width", 60, "Specifies the max column width of output")
+	cmd.Flags().StringArrayVar(&cmdData.IgnoreManagers, "ignore-manager", []string{}, "Do not consider fields that were last changed by the specified field manager as drift (can specify multiple)")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", os.Getenv("WERF_NAMESPACE"), "Release namespace, used with the helm version 3 only (default $WERF_NAMESPACE or the namespace of the kube context)")
+	cmd.Flags().BoolVar(&cmdData.ExitCode, "exit-code", common.GetBoolEnvironmentDefaultFalse("WERF_DRIFT_EXIT_CODE"), fmt.Sprintf("Exit with code %d if drift is found (default $WERF_DRIFT_EXIT_CODE)", DriftExitCode))
+
+	return cmd
+}
+
+func runDrift(releaseName string) error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
+	if err != nil {
+		return err
+	}
+
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeConfigBase64:            *commonCmdData.KubeConfigBase64,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			ReleasesMaxHistory:          0,
+		},
+	}
+
+	if err := deploy.Init(ctx, deployInitOptions); err != nil {
+		return err
+	}
+
+	if err := kube.Init(kube.InitOptions{KubeConfigOptions: kube.KubeConfigOptions{
+		Context:          *commonCmdData.KubeContext,
+		ConfigPath:       *commonCmdData.KubeConfig,
+		ConfigDataBase64: *commonCmdData.KubeConfigBase64,
+	}}); err != nil {
+		return fmt.Errorf("cannot initialize kube: %s", err)
+	}
+
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
+	var result *helm.DriftResult
+	if helmVersion == config.HelmVersion3 {
+		result, err = helm_v3.Drift(ctx, os.Stdout, releaseName, helm_v3.DriftOptions{
+			DriftOptions: cmdData.DriftOptions,
+			Namespace:    cmdData.Namespace,
+		})
+	} else {
+		result, err = helm.Drift(ctx, os.Stdout, releaseName, cmdData.DriftOptions)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if cmdData.ExitCode && result.Drifted {
+		common.TerminateWithError(fmt.Sprintf("drift found in %d resources of release %q", len(result.Resources), releaseName), DriftExitCode)
+	}
+
+	return nil
+}
@@ -7,14 +7,17 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
 var cmdData struct {
 	helm.GetOptions
+	Namespace string
 }
 
 var commonCmdData common.CmdData
@@ -48,11 +51,13 @@ func NewCmd() *cobra.Command {
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
 	cmd.Flags().Int32Var(&cmdData.Revision, "revision", 0, "Get the named release by revision (use latest revision by default)")
 	cmd.Flags().StringVar(&cmdData.Template, "template", "", "Go template for formatting the output, eg: {{.Release.Name}}")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", os.Getenv("WERF_NAMESPACE"), "Release namespace, used with the helm version 3 only (default $WERF_NAMESPACE or the namespace of the kube context)")
 
 	return cmd
 }
@@ -87,6 +92,19 @@ func runGet(releaseName string) error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
+	if helmVersion == config.HelmVersion3 {
+		return helm_v3.Get(ctx, os.Stdout, releaseName, helm_v3.GetOptions{
+			Namespace: cmdData.Namespace,
+			Revision:  int(cmdData.Revision),
+			Template:  cmdData.Template,
+		})
+	}
+
 	if err := helm.Get(common.BackgroundContext(), os.Stdout, releaseName, cmdData.GetOptions); err != nil {
 		return err
 	}
@@ -7,14 +7,17 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
 var cmdData struct {
 	helm.HistoryOptions
+	Namespace string
 }
 
 var commonCmdData common.CmdData
@@ -51,12 +54,14 @@ func NewCmd() *cobra.Command {
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
 	cmd.Flags().Int64VarP(&cmdData.Max, "max", "m", 256, "Maximum number of releases to fetch")
 	cmd.Flags().UintVar(&cmdData.ColWidth, "col-width", 60, "Specifies the max column width of output")
 	cmd.Flags().StringVar(&cmdData.OutputFormat, "output", "table", "Output the specified format (json, yaml or table)")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", os.Getenv("WERF_NAMESPACE"), "Release namespace, used with the helm version 3 only (default $WERF_NAMESPACE or the namespace of the kube context)")
 
 	return cmd
 }
@@ -92,6 +97,20 @@ func runHistory(releaseName string) error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
+	if helmVersion == config.HelmVersion3 {
+		return helm_v3.History(ctx, os.Stdout, releaseName, helm_v3.HistoryOptions{
+			Namespace:    cmdData.Namespace,
+			Max:          int(cmdData.Max),
+			OutputFormat: cmdData.OutputFormat,
+			ColWidth:     cmdData.ColWidth,
+		})
+	}
+
 	if err := helm.History(os.Stdout, releaseName, cmdData.HistoryOptions); err != nil {
 		return err
 	}
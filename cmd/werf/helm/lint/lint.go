@@ -2,9 +2,13 @@ package lint
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 
+	"github.com/werf/logboek"
+
 	"github.com/werf/werf/cmd/werf/common"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
@@ -16,6 +20,11 @@ import (
 	"github.com/werf/werf/pkg/werf"
 )
 
+var cmdData struct {
+	OutputFormat   string
+	OutputFilePath string
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
@@ -51,6 +60,12 @@ func NewCmd() *cobra.Command {
 	common.SetupValues(&commonCmdData, cmd)
 	common.SetupSecretValues(&commonCmdData, cmd)
 	common.SetupIgnoreSecretKey(&commonCmdData, cmd)
+	common.SetupLintRules(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.OutputFormat, "output", "", os.Getenv("WERF_LINT_OUTPUT"), "Output the specified format: text, json or sarif (default $WERF_LINT_OUTPUT or text)")
+	cmd.Flags().StringVarP(&cmdData.OutputFilePath, "output-file-path", "", os.Getenv("WERF_LINT_OUTPUT_FILE_PATH"), `Write the lint report to the file instead of stdout (default $WERF_LINT_OUTPUT_FILE_PATH).
+werf logs are written to stderr when json or sarif report is written to stdout`)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -60,6 +75,28 @@ func NewCmd() *cobra.Command {
 func runLint() error {
 	ctx := common.BackgroundContext()
 
+	var out io.Writer = os.Stdout
+	if cmdData.OutputFilePath != "" {
+		f, err := os.Create(cmdData.OutputFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to create lint report file: %s", err)
+		}
+		defer f.Close()
+
+		out = f
+	} else if cmdData.OutputFormat == helm.LintOutputFormatJSON || cmdData.OutputFormat == helm.LintOutputFormatSARIF {
+		// the report is written to stdout and should not be mixed with werf logs
+		logger := logboek.NewLogger(os.Stderr, os.Stderr)
+		logger.GetStreamsSettingsFrom(logboek.Context(ctx))
+		logger.SetAcceptedLevel(logboek.Context(ctx).AcceptedLevel())
+		ctx = logboek.NewContext(ctx, logger)
+	}
+
+	rulesSeverity, err := common.GetLintRulesSeverity(&commonCmdData)
+	if err != nil {
+		return err
+	}
+
 	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
 		return fmt.Errorf("initialization error: %s", err)
 	}
@@ -99,6 +136,11 @@ func runLint() error {
 
 	projectName := werfConfig.Meta.Project
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
 	stubImagesRepo, err := storage.NewImagesRepo(
 		ctx,
 		projectName,
@@ -132,12 +174,15 @@ func runLint() error {
 		imagesInfoGetters = append(imagesInfoGetters, d)
 	}
 
-	return deploy.RunLint(ctx, projectDir, helmChartDir, werfConfig, stubImagesRepo.String(), imagesInfoGetters, tag, tagStrategy, deploy.LintOptions{
+	return deploy.RunLint(ctx, out, projectDir, helmChartDir, werfConfig, stubImagesRepo.String(), imagesInfoGetters, tag, tagStrategy, deploy.LintOptions{
 		Values:          *commonCmdData.Values,
 		SecretValues:    *commonCmdData.SecretValues,
 		Set:             *commonCmdData.Set,
 		SetString:       *commonCmdData.SetString,
 		Env:             *commonCmdData.Environment,
 		IgnoreSecretKey: *commonCmdData.IgnoreSecretKey,
+		OutputFormat:    cmdData.OutputFormat,
+		RulesSeverity:   rulesSeverity,
+		HelmVersion:     helmVersion,
 	})
 }
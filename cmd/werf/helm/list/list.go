@@ -7,8 +7,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
@@ -54,6 +56,7 @@ func NewCmd() *cobra.Command {
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
@@ -102,6 +105,27 @@ func runLs(filter string) error {
 		return err
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
+	if helmVersion == config.HelmVersion3 {
+		return helm_v3.List(ctx, os.Stdout, helm_v3.ListOptions{
+			Namespace:    CmdData.Namespace,
+			Filter:       filter,
+			Short:        CmdData.Short,
+			Reverse:      CmdData.Reverse,
+			Max:          int(CmdData.Max),
+			All:          CmdData.All,
+			Uninstalled:  CmdData.Deleted,
+			Uninstalling: CmdData.Deleting,
+			Pending:      CmdData.Pending,
+			OutputFormat: CmdData.OutputFormat,
+			ColWidth:     CmdData.ColWidth,
+		})
+	}
+
 	if err := helm.Ls(ctx, os.Stdout, filter, CmdData.LsOptions); err != nil {
 		return err
 	}
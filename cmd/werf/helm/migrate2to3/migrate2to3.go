@@ -0,0 +1,168 @@
+package migrate2to3
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/deploy"
+	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var cmdData struct {
+	All              bool
+	DryRun           bool
+	DeleteV2Releases bool
+	Namespace        string
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate2to3 [RELEASE_NAME...]",
+		Short: "Migrate Helm 2 releases to Helm 3",
+		Long: common.GetLongCommandDescription(`Convert all revisions of the Helm 2 releases from the werf release storage (--helm-release-storage-namespace and --helm-release-storage-type) into Helm 3 releases in the namespaces of the releases.
+
+The releases are not redeployed and the release resources are kept as is. After the migration the releases should be deployed with the helm version 3 (deploy.helmVersion in werf.yaml, --helm-version or $WERF_HELM_VERSION).
+
+Helm 2 releases are kept in the release storage unless --delete-v2-releases is specified.`),
+		Example: `  # Show what will be migrated
+  $ werf helm migrate2to3 --all --dry-run
+
+  # Migrate the release and remove it from the Helm 2 release storage
+  $ werf helm migrate2to3 myproject-production --delete-v2-releases`,
+		DisableFlagsInUseLine: true,
+		Annotations: map[string]string{
+			common.CmdEnvAnno: common.EnvsDescription(),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer werf.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) == 0 && !cmdData.All {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("release names or --all required")
+			}
+
+			if len(args) != 0 && cmdData.All {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("release names cannot be used with --all")
+			}
+
+			return runMigrate2To3(args)
+		},
+	}
+
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
+	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVar(&cmdData.All, "all", false, "Migrate all releases of the Helm 2 release storage")
+	cmd.Flags().BoolVar(&cmdData.DryRun, "dry-run", false, "Show releases and revisions to migrate without changing release storages")
+	cmd.Flags().BoolVar(&cmdData.DeleteV2Releases, "delete-v2-releases", common.GetBoolEnvironmentDefaultFalse("WERF_DELETE_V2_RELEASES"), "Remove the migrated releases from the Helm 2 release storage (default $WERF_DELETE_V2_RELEASES)")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", "", "Namespace for the Helm 2 releases without namespace (default the namespace of the kube context or default)")
+
+	return cmd
+}
+
+func runMigrate2To3(releaseNames []string) error {
+	ctx := common.BackgroundContext()
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return err
+	}
+
+	helmReleaseStorageType, err := common.GetHelmReleaseStorageType(*commonCmdData.HelmReleaseStorageType)
+	if err != nil {
+		return err
+	}
+
+	deployInitOptions := deploy.InitOptions{
+		HelmInitOptions: helm.InitOptions{
+			KubeConfig:                  *commonCmdData.KubeConfig,
+			KubeConfigBase64:            *commonCmdData.KubeConfigBase64,
+			KubeContext:                 *commonCmdData.KubeContext,
+			HelmReleaseStorageNamespace: *commonCmdData.HelmReleaseStorageNamespace,
+			HelmReleaseStorageType:      helmReleaseStorageType,
+			ReleasesMaxHistory:          0,
+		},
+	}
+
+	if err := deploy.Init(ctx, deployInitOptions); err != nil {
+		return err
+	}
+
+	if cmdData.All {
+		if releaseNames, err = helm.ReleaseStorageReleaseNames(); err != nil {
+			return err
+		}
+
+		if len(releaseNames) == 0 {
+			logboek.Context(ctx).Default().LogLn("No releases found in the Helm 2 release storage")
+			return nil
+		}
+	}
+
+	var errors []string
+	for _, releaseName := range releaseNames {
+		if err := logboek.Context(ctx).Default().LogProcess("Migrating release %q", releaseName).DoError(func() error {
+			return migrateRelease(releaseName)
+		}); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("following errors have occurred during migration of specified releases: %s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+func migrateRelease(releaseName string) error {
+	ctx := common.BackgroundContext()
+
+	v2Releases, err := helm.ReleaseStorageHistory(releaseName)
+	if err != nil {
+		return fmt.Errorf("unable to get release %q from Helm 2 release storage: %s", releaseName, err)
+	}
+
+	if err := helm_v3.Migrate2To3(ctx, releaseName, v2Releases, helm_v3.Migrate2To3Options{
+		Namespace: cmdData.Namespace,
+		DryRun:    cmdData.DryRun,
+	}); err != nil {
+		return err
+	}
+
+	if cmdData.DeleteV2Releases && !cmdData.DryRun {
+		if err := helm.DeleteReleaseFromStorage(releaseName); err != nil {
+			return fmt.Errorf("unable to delete release %q from Helm 2 release storage: %s", releaseName, err)
+		}
+		logboek.Context(ctx).Default().LogF("Release %q has been removed from Helm 2 release storage\n", releaseName)
+	}
+
+	return nil
+}
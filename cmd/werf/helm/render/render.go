@@ -19,14 +19,24 @@ import (
 	"github.com/werf/werf/pkg/werf"
 )
 
+var cmdData struct {
+	OutputFilePath string
+	OutputDir      string
+	Kustomization  bool
+	RedactSecrets  bool
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
-	var outputFilePath string
-
 	cmd := &cobra.Command{
-		Use:                   "render",
-		Short:                 "Render werf chart templates to stdout",
+		Use:   "render",
+		Short: "Render werf chart templates to stdout",
+		Long: common.GetLongCommandDescription(`Render werf chart templates to stdout, to the file or to the directory.
+
+With --output-dir option each rendered manifest is written to the <dir>/<kind>-<name>.yaml file, the files written by the previous render are listed in the <dir>/.werf-render file and removed, other files of the directory are kept. The rendered manifests include werf extra annotations and labels, service values and decrypted werf_secret_file content, use --redact-secrets option to replace secrets with the placeholder (e.g. to commit rendered manifests to the git repository).`),
+		Example: `  # Render manifests of production environment to the directory of the GitOps repository with kustomization.yaml
+  $ werf helm render --env production --images-repo registry.mydomain.com/myproject --output-dir ../deploy-repo/production --kustomization --redact-secrets`,
 		DisableFlagsInUseLine: true,
 		Annotations: map[string]string{
 			common.CmdEnvAnno: common.EnvsDescription(common.WerfSecretKey),
@@ -37,7 +47,17 @@ func NewCmd() *cobra.Command {
 				return err
 			}
 
-			return runRender(outputFilePath)
+			if cmdData.OutputFilePath != "" && cmdData.OutputDir != "" {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("--output-file-path and --output-dir options cannot be used together")
+			}
+
+			if cmdData.Kustomization && cmdData.OutputDir == "" {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("--kustomization option requires --output-dir")
+			}
+
+			return runRender()
 		},
 	}
 
@@ -54,6 +74,7 @@ func NewCmd() *cobra.Command {
 	common.SetupDockerConfig(&commonCmdData, cmd, "")
 	common.SetupAddAnnotations(&commonCmdData, cmd)
 	common.SetupAddLabels(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 
 	common.SetupSet(&commonCmdData, cmd)
 	common.SetupSetString(&commonCmdData, cmd)
@@ -67,12 +88,15 @@ func NewCmd() *cobra.Command {
 
 	common.SetupLogOptions(&commonCmdData, cmd)
 
-	cmd.Flags().StringVarP(&outputFilePath, "output-file-path", "o", "", "Write to file instead of stdout")
+	cmd.Flags().StringVarP(&cmdData.OutputFilePath, "output-file-path", "o", "", "Write to file instead of stdout")
+	cmd.Flags().StringVarP(&cmdData.OutputDir, "output-dir", "", os.Getenv("WERF_RENDER_OUTPUT_DIR"), "Write each rendered manifest to the <dir>/[<namespace>_]<kind>-<name>.yaml file instead of stdout, the namespace prefix is added for the manifests with metadata.namespace (default $WERF_RENDER_OUTPUT_DIR)")
+	cmd.Flags().BoolVarP(&cmdData.Kustomization, "kustomization", "", common.GetBoolEnvironmentDefaultFalse("WERF_RENDER_KUSTOMIZATION"), "Also write kustomization.yaml listing rendered manifests into the --output-dir (default $WERF_RENDER_KUSTOMIZATION)")
+	cmd.Flags().BoolVarP(&cmdData.RedactSecrets, "redact-secrets", "", common.GetBoolEnvironmentDefaultFalse("WERF_REDACT_SECRETS"), "Replace secret values, werf_secret_file content and data of Secret resources with the <redacted> placeholder (default $WERF_REDACT_SECRETS)")
 
 	return cmd
 }
 
-func runRender(outputFilePath string) error {
+func runRender() error {
 	ctx := common.BackgroundContext()
 
 	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
@@ -114,6 +138,11 @@ func runRender(outputFilePath string) error {
 
 	projectName := werfConfig.Meta.Project
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, werfConfig)
+	if err != nil {
+		return err
+	}
+
 	optionalImagesRepo, err := common.GetOptionalImagesRepoAddress(projectName, &commonCmdData)
 	if err != nil {
 		return err
@@ -187,12 +216,20 @@ func runRender(outputFilePath string) error {
 		UserExtraAnnotations: userExtraAnnotations,
 		UserExtraLabels:      userExtraLabels,
 		IgnoreSecretKey:      *commonCmdData.IgnoreSecretKey,
+		HelmVersion:          helmVersion,
+		RedactSecrets:        cmdData.RedactSecrets,
+		OutputDir:            cmdData.OutputDir,
+		Kustomization:        cmdData.Kustomization,
 	}); err != nil {
 		return err
 	}
 
-	if outputFilePath != "" {
-		if err := saveRenderedChart(outputFilePath, buf); err != nil {
+	if cmdData.OutputDir != "" {
+		return nil
+	}
+
+	if cmdData.OutputFilePath != "" {
+		if err := saveRenderedChart(cmdData.OutputFilePath, buf); err != nil {
 			return err
 		}
 	} else {
@@ -2,20 +2,25 @@ package rollback
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/werf/kubedog/pkg/kube"
 
 	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
 var cmdData struct {
 	helm.RollbackOptions
+	Namespace string
 }
 
 var commonCmdData common.CmdData
@@ -57,6 +62,7 @@ func NewCmd() *cobra.Command {
 	common.SetupKubeContext(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageNamespace(&commonCmdData, cmd)
 	common.SetupHelmReleaseStorageType(&commonCmdData, cmd)
+	common.SetupHelmVersion(&commonCmdData, cmd)
 	common.SetupReleasesHistoryMax(&commonCmdData, cmd)
 
 	common.SetupLogOptions(&commonCmdData, cmd)
@@ -67,6 +73,7 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&cmdData.Force, "force", false, "Force resource update through delete/recreate if needed")
 	cmd.Flags().BoolVar(&cmdData.CleanupOnFail, "cleanup-on-fail", false, "Allow deletion of new resources created in this rollback when rollback failed")
 	cmd.Flags().Int64Var(&cmdData.Timeout, "timeout", 300, "Time in seconds to wait for any individual Kubernetes operation (like Jobs for hooks)")
+	cmd.Flags().StringVar(&cmdData.Namespace, "namespace", os.Getenv("WERF_NAMESPACE"), "Release namespace, used with the helm version 3 only (default $WERF_NAMESPACE or the namespace of the kube context)")
 
 	return cmd
 }
@@ -116,6 +123,24 @@ func runRollback(releaseName string, revision int32) error {
 		return fmt.Errorf("cannot init kubedog: %s", err)
 	}
 
+	helmVersion, err := common.GetHelmVersion(&commonCmdData, nil)
+	if err != nil {
+		return err
+	}
+
+	if helmVersion == config.HelmVersion3 {
+		return helm_v3.Rollback(ctx, releaseName, helm_v3.RollbackOptions{
+			Namespace:     cmdData.Namespace,
+			Version:       int(revision),
+			Recreate:      cmdData.Recreate,
+			Force:         cmdData.Force,
+			DisableHooks:  cmdData.DisableHooks,
+			Timeout:       time.Duration(cmdData.Timeout) * time.Second,
+			Wait:          cmdData.Wait,
+			CleanupOnFail: cmdData.CleanupOnFail,
+		})
+	}
+
 	if err := helm.Rollback(ctx, releaseName, revision, cmdData.RollbackOptions); err != nil {
 		return err
 	}
@@ -2,6 +2,9 @@ package cleanup
 
 import (
 	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
 
 	"github.com/werf/werf/pkg/image"
 
@@ -16,6 +19,11 @@ import (
 	"github.com/werf/werf/pkg/werf"
 )
 
+var cmdData struct {
+	BuildCachesSizeLimit string
+	AllowedDiskUsage     string
+}
+
 var commonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
@@ -30,6 +38,9 @@ The data include:
 * Local cache:
   * Remote git clones cache.
   * Git worktree cache.
+* Build caches (cache mounts) exceeding their quotas and least recently used build caches exceeding the total size limit.
+
+With --allowed-disk-usage option werf checks the usage of the docker storage volume and the volume of werf local cache (~/.werf/local_cache) and removes least recently used local stages, remote git clones and git worktrees until the usage is under the allowed percentage. Local stages of projects being built and caches used by running werf processes are not removed.
 
 It is safe to run this command periodically by automated cleanup job in parallel with other werf commands such as build, deploy, stages and images cleanup.`),
 		DisableFlagsInUseLine: true,
@@ -56,6 +67,13 @@ It is safe to run this command periodically by automated cleanup job in parallel
 
 	common.SetupDryRun(&commonCmdData, cmd)
 
+	defaultBuildCachesSizeLimit := os.Getenv("WERF_BUILD_CACHES_SIZE_LIMIT")
+	if defaultBuildCachesSizeLimit == "" {
+		defaultBuildCachesSizeLimit = "10G"
+	}
+	cmd.Flags().StringVarP(&cmdData.BuildCachesSizeLimit, "build-caches-size-limit", "", defaultBuildCachesSizeLimit, "Total size limit of build caches (cache mounts) on host, least recently used caches will be removed to fit the limit, 0 to disable (default $WERF_BUILD_CACHES_SIZE_LIMIT or 10G)")
+	cmd.Flags().StringVarP(&cmdData.AllowedDiskUsage, "allowed-disk-usage", "", os.Getenv("WERF_ALLOWED_DISK_USAGE"), "Allowed usage of the docker storage volume and werf local cache volume in percents (e.g. 70%), least recently used local stages, remote git clones and git worktrees will be removed to fit the threshold, disabled by default ($WERF_ALLOWED_DISK_USAGE by default)")
+
 	return cmd
 }
 
@@ -84,8 +102,25 @@ func runGC() error {
 	}
 	ctx = ctxWithDockerCli
 
+	buildCachesSizeLimit, err := units.RAMInBytes(cmdData.BuildCachesSizeLimit)
+	if err != nil {
+		return fmt.Errorf("bad --build-caches-size-limit value %q: %s", cmdData.BuildCachesSizeLimit, err)
+	}
+
+	var allowedDiskUsagePercentage float64
+	if cmdData.AllowedDiskUsage != "" {
+		allowedDiskUsagePercentage, err = host_cleaning.ParseAllowedDiskUsagePercentage(cmdData.AllowedDiskUsage)
+		if err != nil {
+			return fmt.Errorf("bad --allowed-disk-usage value: %s", err)
+		}
+	}
+
 	logboek.LogOptionalLn()
-	hostCleanupOptions := host_cleaning.HostCleanupOptions{DryRun: *commonCmdData.DryRun}
+	hostCleanupOptions := host_cleaning.HostCleanupOptions{
+		BuildCachesSizeLimit:       buildCachesSizeLimit,
+		AllowedDiskUsagePercentage: allowedDiskUsagePercentage,
+		DryRun:                     *commonCmdData.DryRun,
+	}
 	if err := host_cleaning.HostCleanup(ctx, hostCleanupOptions); err != nil {
 		return err
 	}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+var cmdData struct {
+	ToMode          string
+	DeleteOldLayout bool
+}
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "migrate",
+		DisableFlagsInUseLine: true,
+		Short:                 "Migrate project images between multirepo and monorepo images repo modes",
+		Long: common.GetLongCommandDescription(`Migrate project images between multirepo and monorepo images repo modes.
+
+Command copies the tags of the project images from the current layout (--images-repo-mode) to the layout of the specified mode (--to-mode) without pulling the images: manifests are copied with labels and layers are mounted by the registry. The digest of each copied image is verified.
+
+Images metadata by commit in the stages storage is checked after copying: all images referenced by the metadata should be found in the new layout, so that the cleanup keeps the images by git history.
+
+The old layout is deleted only with --delete-old-layout option. The same --to-mode should be specified as --images-repo-mode for all werf invocations after the migration.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer werf.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runMigrate()
+			})
+		},
+	}
+
+	common.SetupDir(&commonCmdData, cmd)
+	common.SetupConfigPath(&commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(&commonCmdData, cmd)
+	common.SetupTmpDir(&commonCmdData, cmd)
+	common.SetupHomeDir(&commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(&commonCmdData, cmd)
+	common.SetupImagesRepoOptions(&commonCmdData, cmd)
+
+	common.SetupDockerConfig(&commonCmdData, cmd, "Command needs granted permissions to read, push and delete images in the specified images repo")
+	common.SetupInsecureRegistry(&commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(&commonCmdData, cmd)
+
+	common.SetupLogOptions(&commonCmdData, cmd)
+	common.SetupLogProjectDir(&commonCmdData, cmd)
+
+	common.SetupSynchronization(&commonCmdData, cmd)
+	common.SetupKubeConfig(&commonCmdData, cmd)
+	common.SetupKubeConfigBase64(&commonCmdData, cmd)
+	common.SetupKubeContext(&commonCmdData, cmd)
+
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	cmd.Flags().StringVarP(&cmdData.ToMode, "to-mode", "", "", fmt.Sprintf("Images repo mode to migrate to: %s or %s (required)", docker_registry.MultirepoRepoMode, docker_registry.MonorepoRepoMode))
+	cmd.Flags().BoolVarP(&cmdData.DeleteOldLayout, "delete-old-layout", "", common.GetBoolEnvironmentDefaultFalse("WERF_DELETE_OLD_LAYOUT"), "Delete images of the old layout after successful migration (default $WERF_DELETE_OLD_LAYOUT)")
+
+	return cmd
+}
+
+func runMigrate() error {
+	ctx := common.BackgroundContext()
+
+	switch cmdData.ToMode {
+	case docker_registry.MultirepoRepoMode, docker_registry.MonorepoRepoMode:
+	default:
+		return fmt.Errorf("bad --to-mode '%s': only %s or %s supported", cmdData.ToMode, docker_registry.MultirepoRepoMode, docker_registry.MonorepoRepoMode)
+	}
+
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return err
+	}
+
+	if err := common.DockerRegistryInit(&commonCmdData); err != nil {
+		return err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(&commonCmdData)
+	if err != nil {
+		return fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(&commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, projectDir, &commonCmdData, true)
+	if err != nil {
+		return fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	logboek.LogOptionalLn()
+
+	projectName := werfConfig.Meta.Project
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	stagesStorage, err := common.GetStagesStorage(containerRuntime, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, &commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return err
+	}
+
+	fromImagesRepo, err := common.GetImagesRepo(ctx, projectName, &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	toImagesRepo, err := common.GetImagesRepoWithMode(ctx, projectName, &commonCmdData, cmdData.ToMode)
+	if err != nil {
+		return err
+	}
+
+	imageNameList, err := common.GetManagedImagesNames(ctx, projectName, stagesStorage, werfConfig)
+	if err != nil {
+		return err
+	}
+	logboek.Debug().LogF("Managed images names: %v\n", imageNameList)
+
+	logboek.LogOptionalLn()
+	return storage.MigrateImagesRepo(ctx, projectName, fromImagesRepo, toImagesRepo, stagesStorage, storageLockManager, storage.MigrateImagesRepoOptions{
+		ImageNameList:   imageNameList,
+		DeleteOldLayout: cmdData.DeleteOldLayout,
+		DryRun:          *commonCmdData.DryRun,
+	})
+}
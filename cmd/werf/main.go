@@ -25,6 +25,8 @@ import (
 	"github.com/werf/werf/cmd/werf/purge"
 	"github.com/werf/werf/cmd/werf/run"
 	"github.com/werf/werf/cmd/werf/synchronization"
+	synchronization_locks_ls "github.com/werf/werf/cmd/werf/synchronization/locks/ls"
+	synchronization_locks_release "github.com/werf/werf/cmd/werf/synchronization/locks/release"
 
 	helm_secret_decrypt "github.com/werf/werf/cmd/werf/helm/secret/decrypt"
 	helm_secret_encrypt "github.com/werf/werf/cmd/werf/helm/secret/encrypt"
@@ -45,10 +47,13 @@ import (
 	managed_images_rm "github.com/werf/werf/cmd/werf/managed_images/rm"
 
 	images_cleanup "github.com/werf/werf/cmd/werf/images/cleanup"
+	images_migrate "github.com/werf/werf/cmd/werf/images/migrate"
 	images_publish "github.com/werf/werf/cmd/werf/images/publish"
 	images_purge "github.com/werf/werf/cmd/werf/images/purge"
 
 	stages_build "github.com/werf/werf/cmd/werf/stages/build"
+	stages_cache_rebuild "github.com/werf/werf/cmd/werf/stages/cache/rebuild"
+	stages_cache_verify "github.com/werf/werf/cmd/werf/stages/cache/verify"
 	stages_cleanup "github.com/werf/werf/cmd/werf/stages/cleanup"
 	stages_purge "github.com/werf/werf/cmd/werf/stages/purge"
 	stages_switch "github.com/werf/werf/cmd/werf/stages/switch_from_local"
@@ -56,6 +61,8 @@ import (
 
 	stage_image "github.com/werf/werf/cmd/werf/stage/image"
 
+	artifact_export "github.com/werf/werf/cmd/werf/artifact/export"
+
 	host_cleanup "github.com/werf/werf/cmd/werf/host/cleanup"
 	host_project_list "github.com/werf/werf/cmd/werf/host/project/list"
 	host_project_purge "github.com/werf/werf/cmd/werf/host/project/purge"
@@ -64,6 +71,7 @@ import (
 	helm_delete "github.com/werf/werf/cmd/werf/helm/delete"
 	helm_dependency "github.com/werf/werf/cmd/werf/helm/dependency"
 	helm_deploy_chart "github.com/werf/werf/cmd/werf/helm/deploy_chart"
+	helm_drift "github.com/werf/werf/cmd/werf/helm/drift"
 	helm_get "github.com/werf/werf/cmd/werf/helm/get"
 	helm_get_autogenerated_values "github.com/werf/werf/cmd/werf/helm/get_autogenerated_values"
 	helm_get_namespace "github.com/werf/werf/cmd/werf/helm/get_namespace"
@@ -71,6 +79,7 @@ import (
 	helm_history "github.com/werf/werf/cmd/werf/helm/history"
 	helm_lint "github.com/werf/werf/cmd/werf/helm/lint"
 	helm_list "github.com/werf/werf/cmd/werf/helm/list"
+	helm_migrate2to3 "github.com/werf/werf/cmd/werf/helm/migrate2to3"
 	helm_render "github.com/werf/werf/cmd/werf/helm/render"
 	helm_repo "github.com/werf/werf/cmd/werf/helm/repo"
 	helm_rollback "github.com/werf/werf/cmd/werf/helm/rollback"
@@ -132,7 +141,7 @@ Find more information at https://werf.io`),
 				dismiss.NewCmd(),
 				cleanup.NewCmd(),
 				purge.NewCmd(),
-				synchronization.NewCmd(),
+				synchronizationCmd(),
 			},
 		},
 		{
@@ -148,6 +157,7 @@ Find more information at https://werf.io`),
 				configCmd(),
 				stagesCmd(),
 				imagesCmd(),
+				artifactCmd(),
 				managedImagesCmd(),
 				helmCmd(),
 				helm_v3.NewCmd(),
@@ -169,6 +179,23 @@ Find more information at https://werf.io`),
 	return rootCmd
 }
 
+func synchronizationCmd() *cobra.Command {
+	cmd := synchronization.NewCmd()
+
+	locksCmd := &cobra.Command{
+		Use:   "locks",
+		Short: "Work with locks of the synchronization",
+	}
+	locksCmd.AddCommand(
+		synchronization_locks_ls.NewCmd(),
+		synchronization_locks_release.NewCmd(),
+	)
+
+	cmd.AddCommand(locksCmd)
+
+	return cmd
+}
+
 func configCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "config",
@@ -205,6 +232,19 @@ func imagesCmd() *cobra.Command {
 		images_publish.NewCmd(),
 		images_cleanup.NewCmd(),
 		images_purge.NewCmd(),
+		images_migrate.NewCmd(),
+	)
+
+	return cmd
+}
+
+func artifactCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "artifact",
+		Short: "Work with artifacts",
+	}
+	cmd.AddCommand(
+		artifact_export.NewCmd(),
 	)
 
 	return cmd
@@ -221,6 +261,20 @@ func stagesCmd() *cobra.Command {
 		stages_purge.NewCmd(),
 		stages_switch.NewCmd(),
 		stages_sync.NewCmd(),
+		stagesCacheCmd(),
+	)
+
+	return cmd
+}
+
+func stagesCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Work with stages storage cache",
+	}
+	cmd.AddCommand(
+		stages_cache_verify.NewCmd(),
+		stages_cache_rebuild.NewCmd(),
 	)
 
 	return cmd
@@ -255,6 +309,8 @@ func helmCmd() *cobra.Command {
 		helm_rollback.NewCmd(),
 		helm_get.NewCmd(),
 		helm_history.NewCmd(),
+		helm_drift.NewCmd(),
+		helm_migrate2to3.NewCmd(),
 		secretCmd(),
 		helm_repo.NewRepoCmd(),
 		helm_dependency.NewDependencyCmd(),
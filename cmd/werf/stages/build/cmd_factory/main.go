@@ -101,6 +101,8 @@ If one or more IMAGE_NAME parameters specified, werf will build only these image
 	common.SetupAllowGitShallowClone(commonCmdData, cmd)
 	common.SetupParallelOptions(commonCmdData, cmd)
 
+	common.SetupHostCleanupAllowedDiskUsage(commonCmdData, cmd)
+
 	return cmd
 }
 
@@ -216,5 +218,5 @@ func runStagesBuild(cmdData *CmdData, commonCmdData *common.CmdData, imagesToPro
 		return err
 	}
 
-	return nil
+	return common.RunHostCleanupByDiskUsage(ctx, commonCmdData)
 }
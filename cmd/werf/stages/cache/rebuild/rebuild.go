@@ -0,0 +1,57 @@
+package rebuild
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	stages_common "github.com/werf/werf/cmd/werf/stages/common"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "rebuild",
+		DisableFlagsInUseLine: true,
+		Short:                 "Rebuild stages storage cache of the project from the stages storage",
+		Long: common.GetLongCommandDescription(`Rebuild stages storage cache of the project from the stages storage.
+
+Command resets the cache and stores all stages existing in the stages storage by signatures. With --dry-run option command lists the stages which would be added to and removed from the cache records without changing the cache.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer werf.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runRebuild()
+			})
+		},
+	}
+
+	stages_common.SetupStagesCacheCmdFlags(&commonCmdData, cmd)
+	common.SetupDryRun(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runRebuild() error {
+	ctx, stagesManager, err := stages_common.GetStagesManager(common.BackgroundContext(), &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	changes, err := stagesManager.RebuildStagesStorageCache(ctx, *commonCmdData.DryRun)
+	if err != nil {
+		return err
+	}
+
+	stages_common.LogStagesStorageCacheChanges(ctx, changes, *commonCmdData.DryRun)
+
+	return nil
+}
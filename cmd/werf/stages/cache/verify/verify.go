@@ -0,0 +1,62 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	stages_common "github.com/werf/werf/cmd/werf/stages/common"
+	"github.com/werf/werf/pkg/werf"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "verify",
+		DisableFlagsInUseLine: true,
+		Short:                 "Verify stages storage cache of the project against the stages storage",
+		Long: common.GetLongCommandDescription(`Verify stages storage cache of the project against the stages storage.
+
+Command reports stale stages (cached stages which do not exist in the stages storage), outdated signatures (cached signatures without some of the existing stages) and missing signatures (not cached yet). Command fails when stale stages or outdated signatures found, use "werf stages cache rebuild" command to fix the cache.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			defer werf.PrintGlobalWarnings(common.BackgroundContext())
+
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			common.LogVersion()
+
+			return common.LogRunningTime(func() error {
+				return runVerify()
+			})
+		},
+	}
+
+	stages_common.SetupStagesCacheCmdFlags(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func runVerify() error {
+	ctx, stagesManager, err := stages_common.GetStagesManager(common.BackgroundContext(), &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	report, err := stagesManager.VerifyStagesStorageCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	stages_common.LogStagesStorageCacheReport(ctx, report)
+
+	if !report.IsConsistent() {
+		return fmt.Errorf("stages storage cache %s is inconsistent with the stages storage %s", stagesManager.StagesStorageCache.String(), stagesManager.StagesStorage.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/stages_manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func SetupStagesCacheCmdFlags(commonCmdData *common.CmdData, cmd *cobra.Command) {
+	common.SetupDir(commonCmdData, cmd)
+	common.SetupConfigPath(commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(commonCmdData, cmd)
+	common.SetupTmpDir(commonCmdData, cmd)
+	common.SetupHomeDir(commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(commonCmdData, cmd)
+
+	common.SetupDockerConfig(commonCmdData, cmd, "Command needs granted permissions to read images from the specified stages storage")
+	common.SetupInsecureRegistry(commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(commonCmdData, cmd)
+
+	common.SetupLogOptions(commonCmdData, cmd)
+	common.SetupLogProjectDir(commonCmdData, cmd)
+
+	common.SetupSynchronization(commonCmdData, cmd)
+	common.SetupKubeConfig(commonCmdData, cmd)
+	common.SetupKubeConfigBase64(commonCmdData, cmd)
+	common.SetupKubeContext(commonCmdData, cmd)
+}
+
+// GetStagesManager initializes the stages manager of the project with the stages storage and the stages storage cache
+func GetStagesManager(ctx context.Context, commonCmdData *common.CmdData) (context.Context, *stages_manager.StagesManager, error) {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return nil, nil, fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := common.DockerRegistryInit(commonCmdData); err != nil {
+		return nil, nil, err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return nil, nil, err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(commonCmdData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	common.ProcessLogProjectDir(commonCmdData, projectDir)
+
+	werfConfig, err := common.GetRequiredWerfConfig(ctx, projectDir, commonCmdData, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	logboek.LogOptionalLn()
+
+	projectName := werfConfig.Meta.Project
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	stagesStorage, err := common.GetStagesStorage(containerRuntime, commonCmdData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return nil, nil, err
+	}
+	stagesStorageCache, err := common.GetStagesStorageCache(synchronization)
+	if err != nil {
+		return nil, nil, err
+	}
+	storageLockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+	if err := stagesManager.UseStagesStorage(ctx, stagesStorage); err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, stagesManager, nil
+}
+
+// LogStagesStorageCacheReport prints the problems found in the stages storage cache
+func LogStagesStorageCacheReport(ctx context.Context, report *stages_manager.StagesStorageCacheReport) {
+	for _, stageID := range report.StaleStages {
+		logboek.Context(ctx).Warn().LogF("Stale stage %s: not found in the stages storage\n", stageID.String())
+	}
+
+	for _, signature := range report.OutdatedSignatures {
+		logboek.Context(ctx).Warn().LogF("Outdated signature %s: some stages of the stages storage are not cached\n", signature)
+	}
+
+	for _, signature := range report.MissingSignatures {
+		logboek.Context(ctx).Default().LogF("Missing signature %s: not cached yet\n", signature)
+	}
+
+	logboek.Context(ctx).Default().LogF("Stale stages: %d, outdated signatures: %d, missing signatures: %d\n", len(report.StaleStages), len(report.OutdatedSignatures), len(report.MissingSignatures))
+}
+
+// LogStagesStorageCacheChanges prints the changes of the stages storage cache records made by the rebuild
+func LogStagesStorageCacheChanges(ctx context.Context, changes []stages_manager.StagesStorageCacheChange, dryRun bool) {
+	action := "Changed"
+	if dryRun {
+		action = "Would change"
+	}
+
+	for _, change := range changes {
+		logboek.Context(ctx).Default().LogF("%s signature %s:\n", action, change.Signature)
+		for _, stageID := range change.AddedStages {
+			logboek.Context(ctx).Default().LogF("  + %s\n", stageID.String())
+		}
+		for _, stageID := range change.RemovedStages {
+			logboek.Context(ctx).Default().LogF("  - %s\n", stageID.String())
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("%s signatures: %d\n", action, len(changes))
+}
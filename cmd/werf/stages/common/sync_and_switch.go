@@ -3,11 +3,16 @@ package common
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
 
 	"github.com/werf/werf/cmd/werf/common"
 
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/stages_manager"
 	"github.com/werf/werf/pkg/storage"
 	"github.com/spf13/cobra"
 )
@@ -21,6 +26,15 @@ type SyncCmdData struct {
 
 	RemoveSource      *bool
 	CleanupLocalCache *bool
+
+	Images    *[]string
+	ForCommit *string
+	NewerThan *string
+
+	ParallelTasksLimit *int64
+	BandwidthLimit     *string
+	CheckpointFile     *string
+	SummaryFile        *string
 }
 
 func SetupRemoveSource(cmdData *SyncCmdData, cmd *cobra.Command) {
@@ -33,6 +47,66 @@ func SetupCleanupLocalCache(cmdData *SyncCmdData, cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(cmdData.CleanupLocalCache, "cleanup-local-cache", "", common.GetBoolEnvironmentDefaultFalse("WERF_CLEANUP_LOCAL_CACHE"), "Remove intermediate docker images which were created on localhost during sync procedure of two remote stages storages (default $WERF_CLEANUP_LOCAL_CACHE)")
 }
 
+func SetupStagesSelection(cmdData *SyncCmdData, cmd *cobra.Command) {
+	cmdData.Images = new([]string)
+	cmd.Flags().StringArrayVarP(cmdData.Images, "image", "", []string{}, "Sync only the stages of the specified images and the images they depend on (can specify multiple)")
+
+	cmdData.ForCommit = new(string)
+	cmd.Flags().StringVarP(cmdData.ForCommit, "for-commit", "", os.Getenv("WERF_FOR_COMMIT"), "Sync only the stages of the images built for the specified commit of the project git repo, werf config and stages signatures are calculated for the commit instead of the current project dir state (default $WERF_FOR_COMMIT)")
+
+	cmdData.NewerThan = new(string)
+	cmd.Flags().StringVarP(cmdData.NewerThan, "newer-than", "", os.Getenv("WERF_NEWER_THAN"), "Sync only the stages created during the specified period, e.g. 48h or 30m (default $WERF_NEWER_THAN)")
+}
+
+func SetupSyncParallelTasksLimit(cmdData *SyncCmdData, cmd *cobra.Command) {
+	parallelTasksLimit := int64(stages_manager.DefaultSyncStagesParallelTasksLimit)
+	if v, err := strconv.ParseInt(os.Getenv("WERF_PARALLEL_TASKS_LIMIT"), 10, 64); err == nil {
+		parallelTasksLimit = v
+	}
+
+	cmdData.ParallelTasksLimit = new(int64)
+	cmd.Flags().Int64VarP(cmdData.ParallelTasksLimit, "parallel-tasks-limit", "", parallelTasksLimit, fmt.Sprintf("Number of stages synced in parallel (default $WERF_PARALLEL_TASKS_LIMIT or %d)", stages_manager.DefaultSyncStagesParallelTasksLimit))
+}
+
+func SetupBandwidthLimit(cmdData *SyncCmdData, cmd *cobra.Command) {
+	cmdData.BandwidthLimit = new(string)
+	cmd.Flags().StringVarP(cmdData.BandwidthLimit, "bandwidth-limit", "", os.Getenv("WERF_BANDWIDTH_LIMIT"), "Limit the average transfer rate of the stages images in bytes per second, e.g. 50M. With the limit the stages are copied between two repos directly, the transfers of the docker daemon when syncing with :local are not throttled (default $WERF_BANDWIDTH_LIMIT)")
+}
+
+func SetupCheckpointFile(cmdData *SyncCmdData, cmd *cobra.Command) {
+	cmdData.CheckpointFile = new(string)
+	cmd.Flags().StringVarP(cmdData.CheckpointFile, "checkpoint-file", "", os.Getenv("WERF_CHECKPOINT_FILE"), "Record the synced stages into the specified file to resume the interrupted sync, the file is removed when all stages are synced (default $WERF_CHECKPOINT_FILE)")
+}
+
+func SetupSummaryFile(cmdData *SyncCmdData, cmd *cobra.Command) {
+	cmdData.SummaryFile = new(string)
+	cmd.Flags().StringVarP(cmdData.SummaryFile, "summary-file", "", os.Getenv("WERF_SUMMARY_FILE"), "Write the JSON summary of the sync into the specified file (default $WERF_SUMMARY_FILE)")
+}
+
+func GetNewerThan(cmdData *SyncCmdData) (time.Duration, error) {
+	if *cmdData.NewerThan == "" {
+		return 0, nil
+	}
+
+	newerThan, err := time.ParseDuration(*cmdData.NewerThan)
+	if err != nil {
+		return 0, fmt.Errorf("bad --newer-than value %q: %s", *cmdData.NewerThan, err)
+	}
+	return newerThan, nil
+}
+
+func GetBandwidthLimit(cmdData *SyncCmdData) (int64, error) {
+	if *cmdData.BandwidthLimit == "" {
+		return 0, nil
+	}
+
+	bandwidthLimit, err := units.RAMInBytes(*cmdData.BandwidthLimit)
+	if err != nil {
+		return 0, fmt.Errorf("bad --bandwidth-limit value %q: %s", *cmdData.BandwidthLimit, err)
+	}
+	return bandwidthLimit, nil
+}
+
 func SetupFromStagesStorage(commonCmdData *common.CmdData, cmdData *SyncCmdData, cmd *cobra.Command) {
 	if commonCmdData.CommonRepoData == nil {
 		common.SetupCommonRepoData(commonCmdData, cmd)
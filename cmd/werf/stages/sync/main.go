@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/werf/werf/pkg/stages_manager"
@@ -11,8 +12,15 @@ import (
 	"github.com/werf/logboek"
 	"github.com/werf/werf/cmd/werf/common"
 	stages_common "github.com/werf/werf/cmd/werf/stages/common"
+	"github.com/werf/werf/pkg/build"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/ssh_agent"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/true_git"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -24,7 +32,11 @@ func NewCmd() *cobra.Command {
 		Use:                   "sync",
 		DisableFlagsInUseLine: true,
 		Short:                 "Sync project stages from one stages storage to another",
-		Long:                  common.GetLongCommandDescription("Sync project stages from one stages storage to another"),
+		Long: common.GetLongCommandDescription(`Sync project stages from one stages storage to another.
+
+All project stages are synced by default. With --image and --for-commit options only the stages of the specified images (all images of werf config by default) at the specified commit (the current state of the project dir by default) are synced. With --newer-than option only recently created stages are synced.
+
+With --checkpoint-file option the synced stages are recorded into the file, so the interrupted sync can be resumed by running the command with the same options again.`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			defer werf.PrintGlobalWarnings(common.BackgroundContext())
 
@@ -59,6 +71,16 @@ func NewCmd() *cobra.Command {
 	stages_common.SetupRemoveSource(&cmdData, cmd)
 	stages_common.SetupCleanupLocalCache(&cmdData, cmd)
 
+	stages_common.SetupStagesSelection(&cmdData, cmd)
+	stages_common.SetupSyncParallelTasksLimit(&cmdData, cmd)
+	stages_common.SetupBandwidthLimit(&cmdData, cmd)
+	stages_common.SetupCheckpointFile(&cmdData, cmd)
+	stages_common.SetupSummaryFile(&cmdData, cmd)
+
+	common.SetupSSHKey(&commonCmdData, cmd)
+	common.SetupGitUnshallow(&commonCmdData, cmd)
+	common.SetupAllowGitShallowClone(&commonCmdData, cmd)
+
 	common.SetupSynchronization(&commonCmdData, cmd)
 	common.SetupKubeConfig(&commonCmdData, cmd)
 	common.SetupKubeConfigBase64(&commonCmdData, cmd)
@@ -132,7 +154,123 @@ func runSync() error {
 	if err != nil {
 		return err
 	}
-	_ = stagesStorageCache
 
-	return stages_manager.SyncStages(ctx, projectName, fromStagesStorage, toStagesStorage, storageLockManager, containerRuntime, stages_manager.SyncStagesOptions{RemoveSource: *cmdData.RemoveSource, CleanupLocalCache: *cmdData.CleanupLocalCache})
+	newerThan, err := stages_common.GetNewerThan(&cmdData)
+	if err != nil {
+		return err
+	}
+
+	bandwidthLimit, err := stages_common.GetBandwidthLimit(&cmdData)
+	if err != nil {
+		return err
+	}
+
+	opts := stages_manager.SyncStagesOptions{
+		RemoveSource:       *cmdData.RemoveSource,
+		CleanupLocalCache:  *cmdData.CleanupLocalCache,
+		NewerThan:          newerThan,
+		ParallelTasksLimit: int(*cmdData.ParallelTasksLimit),
+		BandwidthLimit:     bandwidthLimit,
+		CheckpointFile:     *cmdData.CheckpointFile,
+		SummaryFile:        *cmdData.SummaryFile,
+	}
+
+	if len(*cmdData.Images) > 0 || *cmdData.ForCommit != "" {
+		stagesManager := stages_manager.NewStagesManager(projectName, storageLockManager, stagesStorageCache)
+		if err := stagesManager.UseStagesStorage(ctx, fromStagesStorage); err != nil {
+			return err
+		}
+
+		if stageIDs, err := getImagesStageIDs(ctx, projectDir, werfConfig, containerRuntime, stagesManager, storageLockManager); err != nil {
+			return err
+		} else {
+			opts.StageIDs = stageIDs
+		}
+	}
+
+	return stages_manager.SyncStages(ctx, projectName, fromStagesStorage, toStagesStorage, storageLockManager, containerRuntime, opts)
+}
+
+// getImagesStageIDs selects the stages of the images by calculating the stages signatures with the source stages storage
+func getImagesStageIDs(ctx context.Context, projectDir string, werfConfig *config.WerfConfig, containerRuntime container_runtime.ContainerRuntime, stagesManager *stages_manager.StagesManager, storageLockManager storage.LockManager) ([]image.StageID, error) {
+	if err := true_git.Init(true_git.Options{LiveGitOutput: *commonCmdData.LogVerbose || *commonCmdData.LogDebug}); err != nil {
+		return nil, err
+	}
+
+	if err := ssh_agent.Init(ctx, *commonCmdData.SSHKeys); err != nil {
+		return nil, fmt.Errorf("cannot initialize ssh agent: %s", err)
+	}
+	defer func() {
+		err := ssh_agent.Terminate()
+		if err != nil {
+			logboek.Warn().LogF("WARNING: ssh agent termination failed: %s\n", err)
+		}
+	}()
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	getStageIDsFunc := func(projectDir string, werfConfig *config.WerfConfig) ([]image.StageID, error) {
+		for _, imageName := range *cmdData.Images {
+			if !werfConfig.HasImage(imageName) {
+				return nil, fmt.Errorf("image %q is not defined in werf.yaml", imageName)
+			}
+		}
+
+		var stageIDs []image.StageID
+		conveyorOptions := build.ConveyorOptions{GitUnshallow: *commonCmdData.GitUnshallow, AllowGitShallowClone: *commonCmdData.AllowGitShallowClone}
+		conveyorWithRetry := build.NewConveyorWithRetryWrapper(werfConfig, *cmdData.Images, projectDir, projectTmpDir, ssh_agent.SSHAuthSock, containerRuntime, stagesManager, nil, storageLockManager, conveyorOptions)
+		defer conveyorWithRetry.Terminate()
+
+		if err := conveyorWithRetry.WithRetryBlock(ctx, func(c *build.Conveyor) error {
+			if err := c.ShouldBeBuilt(ctx, build.ShouldBeBuiltOptions{}); err != nil {
+				return err
+			}
+
+			stageIDs = c.GetStageIDs()
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+
+		return stageIDs, nil
+	}
+
+	var stageIDs []image.StageID
+	if err := logboek.Context(ctx).Default().LogProcess("Selecting stages of images").DoError(func() error {
+		if *cmdData.ForCommit == "" {
+			stageIDs, err = getStageIDsFunc(projectDir, werfConfig)
+			return err
+		}
+
+		localGitRepo, err := git_repo.OpenLocalRepo("own", projectDir)
+		if err != nil {
+			return fmt.Errorf("unable to open local repo %s: %s", projectDir, err)
+		} else if localGitRepo == nil {
+			return fmt.Errorf("project dir %s is not a git repo: --for-commit option cannot be used", projectDir)
+		}
+
+		return localGitRepo.WithWorkTree(ctx, *cmdData.ForCommit, func(workTreeDir string) error {
+			commitWerfConfig, err := common.GetRequiredWerfConfig(ctx, workTreeDir, &commonCmdData, false)
+			if err != nil {
+				return fmt.Errorf("unable to load werf config for commit %s: %s", *cmdData.ForCommit, err)
+			}
+
+			stageIDs, err = getStageIDsFunc(workTreeDir, commitWerfConfig)
+			return err
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	logboek.Context(ctx).Default().LogFDetails("Stages of images: %d\n", len(stageIDs))
+
+	if len(stageIDs) == 0 {
+		return nil, fmt.Errorf("no stages of images selected")
+	}
+
+	return stageIDs, nil
 }
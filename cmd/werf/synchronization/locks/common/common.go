@@ -0,0 +1,122 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/tmp_manager"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func SetupLocksCmdFlags(commonCmdData *common.CmdData, cmd *cobra.Command) {
+	common.SetupProjectName(commonCmdData, cmd)
+	common.SetupDir(commonCmdData, cmd)
+	common.SetupConfigPath(commonCmdData, cmd)
+	common.SetupConfigTemplatesDir(commonCmdData, cmd)
+	common.SetupTmpDir(commonCmdData, cmd)
+	common.SetupHomeDir(commonCmdData, cmd)
+	common.SetupSSHKey(commonCmdData, cmd)
+
+	common.SetupStagesStorageOptions(commonCmdData, cmd)
+
+	common.SetupDockerConfig(commonCmdData, cmd, "Command needs granted permissions to read images from the specified stages storage")
+	common.SetupInsecureRegistry(commonCmdData, cmd)
+	common.SetupSkipTlsVerifyRegistry(commonCmdData, cmd)
+
+	common.SetupLogOptions(commonCmdData, cmd)
+	common.SetupLogProjectDir(commonCmdData, cmd)
+
+	common.SetupSynchronization(commonCmdData, cmd)
+	common.SetupKubeConfig(commonCmdData, cmd)
+	common.SetupKubeConfigBase64(commonCmdData, cmd)
+	common.SetupKubeContext(commonCmdData, cmd)
+}
+
+// GetProjectLocks initializes the lock manager of the project synchronization and returns currently held locks of the project
+func GetProjectLocks(ctx context.Context, commonCmdData *common.CmdData) (context.Context, string, storage.LockManager, []storage.LockInfo, error) {
+	if err := werf.Init(*commonCmdData.TmpDir, *commonCmdData.HomeDir); err != nil {
+		return nil, "", nil, nil, fmt.Errorf("initialization error: %s", err)
+	}
+
+	if err := image.Init(); err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	if err := common.DockerRegistryInit(commonCmdData); err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	if err := docker.Init(ctx, *commonCmdData.DockerConfig, *commonCmdData.LogVerbose, *commonCmdData.LogDebug); err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	ctxWithDockerCli, err := docker.NewContext(ctx)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	ctx = ctxWithDockerCli
+
+	projectDir, err := common.GetProjectDir(commonCmdData)
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("getting project dir failed: %s", err)
+	}
+
+	projectTmpDir, err := tmp_manager.CreateProjectDir(ctx)
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("getting project tmp dir failed: %s", err)
+	}
+	defer tmp_manager.ReleaseProjectDir(projectTmpDir)
+
+	werfConfig, err := common.GetOptionalWerfConfig(ctx, projectDir, commonCmdData, false)
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("unable to load werf config: %s", err)
+	}
+
+	var projectName string
+	if werfConfig != nil {
+		projectName = werfConfig.Meta.Project
+	} else if *commonCmdData.ProjectName != "" {
+		projectName = *commonCmdData.ProjectName
+	} else {
+		return nil, "", nil, nil, fmt.Errorf("run command in the project directory with werf.yaml or specify --project-name=PROJECT_NAME param")
+	}
+
+	containerRuntime := &container_runtime.LocalDockerServerRuntime{} // TODO
+
+	stagesStorage, err := common.GetStagesStorage(containerRuntime, commonCmdData)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	synchronization, err := common.GetSynchronization(ctx, commonCmdData, projectName, stagesStorage)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	lockManager, err := common.GetStorageLockManager(ctx, synchronization)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+
+	locks, err := lockManager.ListLocks(ctx, projectName)
+	if err != nil {
+		return nil, "", nil, nil, fmt.Errorf("unable to list locks of project %q: %s", projectName, err)
+	}
+
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].Kind != locks[j].Kind {
+			return locks[i].Kind < locks[j].Kind
+		}
+		return locks[i].Name < locks[j].Name
+	})
+
+	return ctx, projectName, lockManager, locks, nil
+}
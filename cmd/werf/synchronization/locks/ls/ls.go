@@ -0,0 +1,95 @@
+package ls
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/werf/cmd/werf/common"
+	locks_common "github.com/werf/werf/cmd/werf/synchronization/locks/common"
+	"github.com/werf/werf/pkg/storage"
+)
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "ls",
+		DisableFlagsInUseLine: true,
+		Short:                 "List held locks of the project",
+		Long: common.GetLongCommandDescription(`List held stage, stage-cache, image and stages-and-images locks of the project with the owner and the lease age.
+
+Expired leases are not renewed by the holder anymore (e.g. the werf process has been killed) and can be safely released with "werf synchronization locks release" command.
+
+Host locks of the :local synchronization are released by the OS when the holder process exits: the locks held by the running werf processes are listed with the pid of the holder.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			return run()
+		},
+	}
+
+	locks_common.SetupLocksCmdFlags(&commonCmdData, cmd)
+
+	return cmd
+}
+
+func run() error {
+	_, _, _, locks, err := locks_common.GetProjectLocks(common.BackgroundContext(), &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tOWNER\tMODE\tHOLDERS\tAGE\tLEASE")
+	for _, lock := range locks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", lock.Kind, lock.Name, valueOrDash(lock.Owner), lockMode(lock), lockHolders(lock), lockAge(lock), lockLease(lock))
+	}
+
+	return w.Flush()
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func lockMode(lock storage.LockInfo) string {
+	if lock.Shared {
+		return "shared"
+	}
+	return "exclusive"
+}
+
+func lockHolders(lock storage.LockInfo) string {
+	if lock.Holders == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", lock.Holders)
+}
+
+func lockAge(lock storage.LockInfo) string {
+	if lock.AcquiredAt.IsZero() {
+		return "-"
+	}
+	return time.Since(lock.AcquiredAt).Round(time.Second).String()
+}
+
+func lockLease(lock storage.LockInfo) string {
+	switch {
+	case lock.ExpireAt.IsZero():
+		return "-"
+	case lock.IsExpired():
+		return fmt.Sprintf("expired %s ago", time.Since(lock.ExpireAt).Round(time.Second))
+	default:
+		return "active"
+	}
+}
@@ -0,0 +1,103 @@
+package release
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/cmd/werf/common"
+	locks_common "github.com/werf/werf/cmd/werf/synchronization/locks/common"
+	"github.com/werf/werf/pkg/storage"
+)
+
+var cmdData struct {
+	AllExpired bool
+	Force      bool
+}
+
+var commonCmdData common.CmdData
+
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "release [LOCK_NAME...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Force release held locks of the project",
+		Long: common.GetLongCommandDescription(`Force release held locks of the project by the names listed with "werf synchronization locks ls" command.
+
+Only locks with expired leases are released by default: the lease is not renewed by the holder anymore (e.g. the werf process has been killed), so the release is safe. Use --force option to release the locks with active leases: the holders lose the locks on the next lease renewal.`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := common.ProcessLogOptions(&commonCmdData); err != nil {
+				common.PrintHelp(cmd)
+				return err
+			}
+
+			if len(args) == 0 && !cmdData.AllExpired {
+				common.PrintHelp(cmd)
+				return fmt.Errorf("lock names or --all-expired option required")
+			}
+
+			return run(args)
+		},
+	}
+
+	locks_common.SetupLocksCmdFlags(&commonCmdData, cmd)
+
+	cmd.Flags().BoolVarP(&cmdData.AllExpired, "all-expired", "", common.GetBoolEnvironmentDefaultFalse("WERF_ALL_EXPIRED"), "Release all locks with expired leases (default $WERF_ALL_EXPIRED)")
+	cmd.Flags().BoolVarP(&cmdData.Force, "force", "", common.GetBoolEnvironmentDefaultFalse("WERF_FORCE"), "Release the locks with active leases (default $WERF_FORCE)")
+
+	return cmd
+}
+
+func run(lockNames []string) error {
+	ctx, projectName, lockManager, locks, err := locks_common.GetProjectLocks(common.BackgroundContext(), &commonCmdData)
+	if err != nil {
+		return err
+	}
+
+	locksByName := make(map[string]storage.LockInfo)
+	for _, lock := range locks {
+		locksByName[lock.Name] = lock
+	}
+
+	var locksToRelease []storage.LockInfo
+	selected := make(map[string]bool)
+	for _, lockName := range lockNames {
+		lock, hasKey := locksByName[lockName]
+		if !hasKey {
+			return fmt.Errorf("lock %q is not held", lockName)
+		}
+
+		if !lock.IsExpired() && !cmdData.Force {
+			return fmt.Errorf("lock %q has active lease: use --force option to release it anyway", lockName)
+		}
+
+		if !selected[lockName] {
+			selected[lockName] = true
+			locksToRelease = append(locksToRelease, lock)
+		}
+	}
+
+	if cmdData.AllExpired {
+		for _, lock := range locks {
+			if lock.IsExpired() && !selected[lock.Name] {
+				selected[lock.Name] = true
+				locksToRelease = append(locksToRelease, lock)
+			}
+		}
+	}
+
+	for _, lock := range locksToRelease {
+		if err := lockManager.ForceUnlock(ctx, projectName, lock); err != nil {
+			return fmt.Errorf("unable to release lock %q: %s", lock.Name, err)
+		}
+		logboek.Context(ctx).Default().LogF("Released %s lock %q\n", lock.Kind, lock.Name)
+	}
+
+	if len(locksToRelease) == 0 {
+		logboek.Context(ctx).Default().LogLnDetails("No locks to release")
+	}
+
+	return nil
+}
@@ -30,19 +30,41 @@ var cmdData struct {
 	Local                          bool
 	LocalLockManagerBaseDir        string
 	LocalStagesStorageCacheBaseDir string
+	LocalDatabaseFile              string
 
 	TTL  string
 	Host string
 	Port string
+
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	AccessConfig    string
 }
 
 var commonCmdData common.CmdData
 
 func NewCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:                   "synchronization",
-		Short:                 "Run synchronization server",
-		Long:                  common.GetLongCommandDescription(`Run synchronization server`),
+		Use:   "synchronization",
+		Short: "Run synchronization server",
+		Long: common.GetLongCommandDescription(`Run synchronization server.
+
+The server is available over https with --tls-cert-file and --tls-key-file options, --tls-client-ca-file option enables mTLS authentication of the clients.
+
+With --access-config option the clients are authenticated by the bearer token or by the common name of the client certificate and have access only to the allowed projects:
+
+  rules:
+  - token: secret-token
+    projects: ["project", "team-*"]
+  - commonName: ci-runner
+    projects: ["*"]
+  - token: admin-token
+    admin: true
+
+Each client id is bound to the project it has been issued for, so the client has access only to the client ids of the allowed projects.
+
+The server exposes metrics in the Prometheus text format at /metrics. Currently held locks with the owner client id and age are listed at /admin page and /admin/locks JSON API, a lock can be force released with POST /admin/locks/release request ({"clientID": "...", "lockName": "..."}). Admin endpoints are disabled unless the access config has the rule with admin access, metrics are available for any authenticated client.`),
 		DisableFlagsInUseLine: true,
 		Annotations:           map[string]string{},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -72,6 +94,8 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.LocalLockManagerBaseDir, "local-lock-manager-base-dir", "", os.Getenv("WERF_LOCAL_LOCK_MANAGER_BASE_DIR"), "Use specified directory as base for file lock-manager (~/.werf/synchronization_server/lock_manager by default or $WERF_LOCAL_LOCK_MANAGER_BASE_DIR)")
 	cmd.Flags().StringVarP(&cmdData.LocalStagesStorageCacheBaseDir, "local-stages-storage-cache-base-dir", "", os.Getenv("WERF_LOCAL_STAGES_STORAGE_CACHE_BASE_DIR"), "Use specified directory as base for file stages-storage-cache (~/.werf/synchronization_server/stages_storage_cache by default or $WERF_LOCAL_STAGES_STORAGE_CACHE_BASE_DIR)")
 
+	cmd.Flags().StringVarP(&cmdData.LocalDatabaseFile, "local-database-file", "", os.Getenv("WERF_LOCAL_DATABASE_FILE"), "Use specified database file for persistent lock-manager and stages-storage-cache instead of in-memory lock-manager and file stages-storage-cache: locks and cache survive the server restart. The file should be on the local filesystem or block volume and must not be shared between replicas (default $WERF_LOCAL_DATABASE_FILE)")
+
 	cmd.Flags().BoolVarP(&cmdData.Kubernetes, "kubernetes", "", common.GetBoolEnvironmentDefaultFalse("WERF_KUBERNETES"), "Use kubernetes lock-manager stages-storage-cache (default $WERF_KUBERNETES)")
 	cmd.Flags().StringVarP(&cmdData.KubernetesNamespacePrefix, "kubernetes-namespace-prefix", "", os.Getenv("WERF_KUBERNETES_NAMESPACE_PREFIX"), "Use specified prefix for namespaces created for lock-manager and stages-storage-cache (defaults to 'werf-synchronization-' when --kubernetes option is used or $WERF_KUBERNETES_NAMESPACE_PREFIX)")
 
@@ -79,6 +103,11 @@ func NewCmd() *cobra.Command {
 	cmd.Flags().StringVarP(&cmdData.Host, "host", "", os.Getenv("WERF_HOST"), "Bind synchronization server to the specified host (default localhost or $WERF_HOST)")
 	cmd.Flags().StringVarP(&cmdData.Port, "port", "", os.Getenv("WERF_PORT"), "Bind synchronization server to the specified port (default 55581 or $WERF_PORT)")
 
+	cmd.Flags().StringVarP(&cmdData.TLSCertFile, "tls-cert-file", "", os.Getenv("WERF_TLS_CERT_FILE"), "Serve https with the specified certificate (default $WERF_TLS_CERT_FILE)")
+	cmd.Flags().StringVarP(&cmdData.TLSKeyFile, "tls-key-file", "", os.Getenv("WERF_TLS_KEY_FILE"), "Serve https with the specified certificate key (default $WERF_TLS_KEY_FILE)")
+	cmd.Flags().StringVarP(&cmdData.TLSClientCAFile, "tls-client-ca-file", "", os.Getenv("WERF_TLS_CLIENT_CA_FILE"), "Require client certificates signed by the specified CA (default $WERF_TLS_CLIENT_CA_FILE)")
+	cmd.Flags().StringVarP(&cmdData.AccessConfig, "access-config", "", os.Getenv("WERF_ACCESS_CONFIG"), "Restrict access of the clients to the projects with the specified access config (default $WERF_ACCESS_CONFIG)")
+
 	return cmd
 }
 
@@ -99,6 +128,7 @@ func runSynchronization() error {
 
 	var distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error)
 	var stagesStorageCacheFactoryFunc func(clientID string) (storage.StagesStorageCache, error)
+	var clientProjectStore synchronization_server.ClientProjectStore
 
 	if cmdData.Kubernetes {
 		if err := kube.Init(kube.InitOptions{kube.KubeConfigOptions{
@@ -136,6 +166,24 @@ func runSynchronization() error {
 				return fmt.Sprintf("werf-%s", clientID)
 			}), nil
 		}
+
+		clientProjectStore = synchronization_server.NewKubernetesClientProjectStore(kube.Client, "werf-synchronization")
+	} else if cmdData.LocalDatabaseFile != "" {
+		db, err := synchronization_server.OpenBoltDatabase(ctx, cmdData.LocalDatabaseFile)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		distributedLockerBackendFactoryFunc = func(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+			return synchronization_server.NewBoltDistributedLockerBackend(db, clientID), nil
+		}
+
+		stagesStorageCacheFactoryFunc = func(clientID string) (storage.StagesStorageCache, error) {
+			return storage.NewBoltStagesStorageCache(db, clientID), nil
+		}
+
+		clientProjectStore = synchronization_server.NewBoltClientProjectStore(db)
 	} else {
 		stagesStorageCacheBaseDir := cmdData.LocalStagesStorageCacheBaseDir
 		if stagesStorageCacheBaseDir == "" {
@@ -150,7 +198,28 @@ func runSynchronization() error {
 		stagesStorageCacheFactoryFunc = func(clientID string) (storage.StagesStorageCache, error) {
 			return storage.NewFileStagesStorageCache(filepath.Join(stagesStorageCacheBaseDir, clientID)), nil
 		}
+
+		clientProjectStore = synchronization_server.NewFileClientProjectStore(filepath.Join(werf.GetHomeDir(), "synchronization_server", "client_projects"))
+	}
+
+	if (cmdData.TLSCertFile == "") != (cmdData.TLSKeyFile == "") {
+		return fmt.Errorf("both --tls-cert-file and --tls-key-file should be specified")
+	}
+
+	serverOptions := synchronization_server.SynchronizationServerOptions{
+		TLSCertFile:        cmdData.TLSCertFile,
+		TLSKeyFile:         cmdData.TLSKeyFile,
+		TLSClientCAFile:    cmdData.TLSClientCAFile,
+		ClientProjectStore: clientProjectStore,
+	}
+
+	if cmdData.AccessConfig != "" {
+		access, err := synchronization_server.LoadAccessConfig(cmdData.AccessConfig)
+		if err != nil {
+			return err
+		}
+		serverOptions.Access = access
 	}
 
-	return synchronization_server.RunSynchronizationServer(ctx, host, port, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc)
+	return synchronization_server.RunSynchronizationServer(ctx, host, port, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc, serverOptions)
 }
@@ -16,11 +16,14 @@ import (
 	"github.com/werf/logboek/pkg/types"
 
 	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/build_cache"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
 	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/stapel"
+	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/util/secretvalues"
 	"github.com/werf/werf/pkg/werf"
 )
 
@@ -168,7 +171,7 @@ func (phase *BuildPhase) onImageStage(ctx context.Context, img *Image, stg stage
 		if stg.GetImage().GetStageDescription() != nil {
 			logboek.Context(ctx).Default().LogFHighlight("Use cache image for %s\n", stg.LogDetailedName())
 
-			logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true)
+			logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), true, phase.Conveyor.GetSecretValuesToMask())
 
 			logboek.Context(ctx).LogOptionalLn()
 
@@ -254,6 +257,10 @@ func (phase *BuildPhase) calculateStage(ctx context.Context, img *Image, stg sta
 			i := phase.Conveyor.GetOrCreateStageImage(castToStageImage(phase.StagesIterator.GetPrevImage(img, stg)), stageDesc.Info.Name)
 			i.SetStageDescription(stageDesc)
 			stg.SetImage(i)
+
+			if err := phase.touchStageLastUse(stageDesc); err != nil {
+				return err
+			}
 		} else {
 			if shouldBeBuiltMode {
 				phase.printShouldBeBuiltError(ctx, img, stg)
@@ -334,13 +341,19 @@ func (phase *BuildPhase) buildStage(ctx context.Context, img *Image, stg stage.I
 	infoSectionFunc := func(err error) {
 		if err != nil {
 			logboek.Context(ctx).Streams().DoWithIndent(func() {
-				logImageCommands(ctx, stg.GetImage())
+				logImageCommands(ctx, stg.GetImage(), phase.Conveyor.GetSecretValuesToMask())
 			})
 			return
 		}
-		logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), false)
+		logImageInfo(ctx, stg.GetImage(), phase.getPrevNonEmptyStageImageSize(), false, phase.Conveyor.GetSecretValuesToMask())
 	}
 
+	defer func() {
+		if err := stg.PostRunHook(ctx, phase.Conveyor); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: %s postRunHook failed: %s\n", stg.LogDetailedName(), err)
+		}
+	}()
+
 	if err := logboek.Context(ctx).Default().LogProcess("Building stage %s", stg.LogDetailedName()).
 		Options(func(options types.LogProcessOptionsInterface) {
 			options.InfoSectionFunc(infoSectionFunc)
@@ -375,8 +388,15 @@ func (phase *BuildPhase) atomicBuildStageImage(ctx context.Context, img *Image,
 		time.Sleep(time.Duration(seconds) * time.Second)
 	}
 
+	buildCaches, err := stg.GetBuildCaches()
+	if err != nil {
+		return err
+	}
+
 	if err := logboek.Context(ctx).Streams().DoErrorWithTag(fmt.Sprintf("%s/%s", img.LogName(), stg.Name()), img.LogTagStyle(), func() error {
-		return stageImage.Build(ctx, phase.ImageBuildOptions)
+		return build_cache.WithCachesLock(ctx, buildCaches, func() error {
+			return stageImage.Build(ctx, phase.ImageBuildOptions)
+		})
 	}); err != nil {
 		return fmt.Errorf("failed to build image for stage %s with signature %s: %s", stg.Name(), stg.GetSignature(), err)
 	}
@@ -464,7 +484,7 @@ var (
 	logImageInfoFormat        = fmt.Sprintf("  %%%ds: %%s\n", logImageInfoLeftPartWidth)
 )
 
-func logImageInfo(ctx context.Context, img container_runtime.ImageInterface, prevStageImageSize int64, isUsingCache bool) {
+func logImageInfo(ctx context.Context, img container_runtime.ImageInterface, prevStageImageSize int64, isUsingCache bool, secretValuesToMask []string) {
 	repository, tag := image.ParseRepositoryAndTag(img.Name())
 	logboek.Context(ctx).Default().LogFDetails(logImageInfoFormat, "repository", repository)
 	logboek.Context(ctx).Default().LogFDetails(logImageInfoFormat, "image_id", stringid.TruncateID(img.GetStageDescription().Info.ID))
@@ -485,15 +505,16 @@ func logImageInfo(ctx context.Context, img container_runtime.ImageInterface, pre
 			logboek.Context(ctx).Default().LogFDetails(logImageInfoFormat, "instructions", formattedCommands)
 		}
 
-		logImageCommands(ctx, img)
+		logImageCommands(ctx, img, secretValuesToMask)
 	}
 }
 
-func logImageCommands(ctx context.Context, img container_runtime.ImageInterface) {
+func logImageCommands(ctx context.Context, img container_runtime.ImageInterface, secretValuesToMask []string) {
 	commands := img.Container().UserRunCommands()
 	if len(commands) != 0 {
 		fitTextOptions := types.FitTextOptions{ExtraIndentWidth: logImageInfoLeftPartWidth + 4}
-		formattedCommands := strings.TrimLeft(logboek.Context(ctx).FitText(strings.Join(commands, "\n"), fitTextOptions), " ")
+		maskedCommands := secretvalues.MaskSecretValuesInString(secretValuesToMask, strings.Join(commands, "\n"))
+		formattedCommands := strings.TrimLeft(logboek.Context(ctx).FitText(maskedCommands, fitTextOptions), " ")
 		logboek.Context(ctx).Default().LogFDetails(logImageInfoFormat, "commands", formattedCommands)
 	}
 }
@@ -593,3 +614,16 @@ func (phase *BuildPhase) Clone() Phase {
 	u := *phase
 	return &u
 }
+
+// touchStageLastUse records the use of the local stage, the least recently used local stages are removed first by the host cleanup
+func (phase *BuildPhase) touchStageLastUse(stageDesc *image.StageDescription) error {
+	if phase.Conveyor.StagesManager.StagesStorage.Address() != storage.LocalStorageAddress {
+		return nil
+	}
+
+	if err := werf.TouchLastUse(stageDesc.Info.Name); err != nil {
+		return fmt.Errorf("unable to record last use of stage %s: %s", stageDesc.Info.Name, err)
+	}
+
+	return nil
+}
@@ -28,8 +28,10 @@ import (
 	"github.com/werf/werf/pkg/build/stage"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/deploy/secret"
 	"github.com/werf/werf/pkg/docker"
 	"github.com/werf/werf/pkg/git_repo"
+	imagePkg "github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/images_manager"
 	"github.com/werf/werf/pkg/logging"
 	"github.com/werf/werf/pkg/path_matcher"
@@ -37,6 +39,8 @@ import (
 	"github.com/werf/werf/pkg/storage"
 	"github.com/werf/werf/pkg/tag_strategy"
 	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/util/secretvalues"
+	"github.com/werf/werf/pkg/werf"
 )
 
 type Conveyor struct {
@@ -72,6 +76,8 @@ type Conveyor struct {
 	onTerminateFuncs []func() error
 	importServers    map[string]import_server.ImportServer
 
+	secretValuesToMask []string
+
 	ConveyorOptions
 
 	mutex               sync.Mutex
@@ -85,6 +91,7 @@ type ConveyorOptions struct {
 	LocalGitRepoVirtualMergeOptions stage.VirtualMergeOptions
 	GitUnshallow                    bool
 	AllowGitShallowClone            bool
+	BuildSecretsDir                 string
 }
 
 func NewConveyor(werfConfig *config.WerfConfig, imageNamesToProcess []string, projectDir, baseTmpDir, sshAuthSock string, containerRuntime container_runtime.ContainerRuntime, stagesManager *stages_manager.StagesManager, imagesRepo storage.ImagesRepo, storageLockManager storage.LockManager, opts ConveyorOptions) (*Conveyor, error) {
@@ -192,6 +199,20 @@ func (c *Conveyor) GetStageSignatureMutex(stage string) *sync.Mutex {
 	return m
 }
 
+func (c *Conveyor) addSecretValueToMask(value string) {
+	c.getServiceRWMutex("SecretValuesToMask").Lock()
+	defer c.getServiceRWMutex("SecretValuesToMask").Unlock()
+
+	c.secretValuesToMask = append(c.secretValuesToMask, secretvalues.ExtractSecretValuesFromString(value)...)
+}
+
+func (c *Conveyor) GetSecretValuesToMask() []string {
+	c.getServiceRWMutex("SecretValuesToMask").RLock()
+	defer c.getServiceRWMutex("SecretValuesToMask").RUnlock()
+
+	return c.secretValuesToMask
+}
+
 func (c *Conveyor) GetLocalGitRepoVirtualMergeOptions() stage.VirtualMergeOptions {
 	return c.ConveyorOptions.LocalGitRepoVirtualMergeOptions
 }
@@ -432,6 +453,7 @@ func (c *Conveyor) BuildStages(ctx context.Context, opts BuildStagesOptions) err
 			IntrospectOptions: opts.IntrospectOptions,
 			ImageBuildOptions: opts.ImageBuildOptions,
 		}),
+		NewExportPhase(c),
 	}
 
 	return c.runPhases(ctx, phases, true)
@@ -473,6 +495,7 @@ func (c *Conveyor) BuildAndPublish(ctx context.Context, opts BuildAndPublishOpti
 	phases := []Phase{
 		NewBuildPhase(c, BuildPhaseOptions{ImageBuildOptions: opts.ImageBuildOptions, IntrospectOptions: opts.IntrospectOptions}),
 		NewPublishImagesPhase(c, c.ImagesRepo, opts.PublishImagesOptions),
+		NewExportPhase(c),
 	}
 
 	if opts.DryRun {
@@ -917,6 +940,34 @@ func (c *Conveyor) GetImageContentSignature(imageName string) string {
 	return c.GetImage(imageName).GetContentSignature()
 }
 
+// GetStageIDs returns the stages of the processed images and the images they depend on, the stages should be selected by ShouldBeBuilt or BuildStages
+func (c *Conveyor) GetStageIDs() []imagePkg.StageID {
+	var stageIDs []imagePkg.StageID
+	for _, img := range c.images {
+		for _, stg := range img.GetStages() {
+			if stg.GetImage() == nil || stg.GetImage().GetStageDescription() == nil {
+				continue
+			}
+
+			stageID := *stg.GetImage().GetStageDescription().StageID
+			if !containsStageID(stageIDs, stageID) {
+				stageIDs = append(stageIDs, stageID)
+			}
+		}
+	}
+
+	return stageIDs
+}
+
+func containsStageID(stageIDs []imagePkg.StageID, stageID imagePkg.StageID) bool {
+	for _, id := range stageIDs {
+		if id == stageID {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Conveyor) getImageStage(imageName, stageName string) stage.Interface {
 	if stg := c.GetImage(imageName).GetStage(stage.StageName(stageName)); stg != nil {
 		return stg
@@ -1035,6 +1086,55 @@ func getImageConfigsToProcess(ctx context.Context, c *Conveyor) []config.ImageIn
 	return imageConfigsToProcess
 }
 
+func getBuildSecrets(secretsConfig []*config.Secret, c *Conveyor) []*stage.BuildSecret {
+	var buildSecrets []*stage.BuildSecret
+	for _, secretConfig := range secretsConfig {
+		secretConfig := secretConfig
+		buildSecrets = append(buildSecrets, &stage.BuildSecret{
+			Id: secretConfig.Id,
+			To: secretConfig.To,
+			GetValue: func() ([]byte, error) {
+				return getBuildSecretValue(secretConfig, c)
+			},
+		})
+	}
+
+	return buildSecrets
+}
+
+func getBuildSecretValue(secretConfig *config.Secret, c *Conveyor) ([]byte, error) {
+	var value []byte
+	switch {
+	case secretConfig.Env != "":
+		envValue := os.Getenv(secretConfig.Env)
+		if envValue == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", secretConfig.Env)
+		}
+		value = []byte(envValue)
+	case secretConfig.Src != "":
+		data, err := ioutil.ReadFile(filepath.Join(c.projectDir, secretConfig.Src))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file: %s", err)
+		}
+		value = data
+	case secretConfig.EncryptedValue != "":
+		m, err := secret.GetManager(c.projectDir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get secret manager: %s", err)
+		}
+
+		data, err := m.Decrypt([]byte(secretConfig.EncryptedValue))
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt value: %s", err)
+		}
+		value = data
+	}
+
+	c.addSecretValueToMask(string(value))
+
+	return value, nil
+}
+
 func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.StapelImageInterface, c *Conveyor) error {
 	var stages []stage.Interface
 
@@ -1045,6 +1145,9 @@ func initStages(ctx context.Context, image *Image, imageInterfaceConfig config.S
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:        imageName,
 		ConfigMounts:     imageBaseConfig.Mount,
+		ConfigBuild:      imageBaseConfig.Build,
+		BuildSecrets:     getBuildSecrets(imageBaseConfig.Secrets, c),
+		BuildSecretsDir:  c.BuildSecretsDir,
 		ImageTmpDir:      c.GetImageTmpDir(imageBaseConfig.Name),
 		ContainerWerfDir: c.containerWerfDir,
 		ProjectName:      c.werfConfig.Meta.Project,
@@ -1153,6 +1256,15 @@ func generateGitMappings(ctx context.Context, imageBaseConfig *config.StapelImag
 				return nil, fmt.Errorf("unable to open remote git repo %s by url %s: %s", remoteGitMappingConfig.Name, remoteGitMappingConfig.Url, err)
 			}
 
+			// the clone is held from being removed by the host cleanup until the end of the build
+			cacheLock, err := remoteGitRepo.AcquireCacheLock(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("unable to lock remote git repo %s cache: %s", remoteGitMappingConfig.Name, err)
+			}
+			c.AppendOnTerminateFunc(func() error {
+				return werf.ReleaseHostLock(cacheLock)
+			})
+
 			if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Refreshing %s repository", remoteGitMappingConfig.Name)).
 				DoError(func() error {
 					return remoteGitRepo.CloneAndFetch(ctx)
@@ -1484,6 +1596,7 @@ func prepareImageBasedOnImageFromDockerfile(ctx context.Context, imageFromDocker
 
 	baseStageOptions := &stage.NewBaseStageOptions{
 		ImageName:   imageFromDockerfileConfig.Name,
+		ConfigBuild: imageFromDockerfileConfig.Build,
 		ProjectName: c.werfConfig.Meta.Project,
 	}
 
@@ -0,0 +1,170 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/build/import_server"
+	"github.com/werf/werf/pkg/build/stage"
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/stapel"
+)
+
+const exportContainerDir = "/.werf/export"
+
+type ExportImageFilesOptions struct {
+	Add          string
+	To           string
+	IncludePaths []string
+	ExcludePaths []string
+}
+
+// ExportImageFiles copies the add path of the docker image to the host to path,
+// includePaths and excludePaths are applied the same way as for the import directive
+func ExportImageFiles(ctx context.Context, dockerImageName string, opts ExportImageFilesOptions) error {
+	hostTo, err := filepath.Abs(opts.To)
+	if err != nil {
+		return fmt.Errorf("unable to get absolute path for %s: %s", opts.To, err)
+	}
+
+	hostToParentDir := filepath.Dir(hostTo)
+	if err := os.MkdirAll(hostToParentDir, os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", hostToParentDir, err)
+	}
+
+	stapelContainerName, err := stapel.GetOrCreateContainer(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rsyncChownOption string
+	if runtime.GOOS != "windows" {
+		rsyncChownOption = fmt.Sprintf("--chown=%d:%d", os.Getuid(), os.Getgid())
+	}
+
+	add := path.Clean(opts.Add)
+
+	var args []string
+	// set optional trailing slash when exporting directory so that rsync will merge it into the target directory
+	args = append(args, fmt.Sprintf("if [ -d %s ] ; then EXPORT_PATH_TRAILING_SLASH_OPTIONAL=/ ; fi", add))
+	rsyncCommand := fmt.Sprintf("%s --archive --links %s", stapel.RsyncBinPath(), rsyncChownOption)
+	rsyncCommand += import_server.RsyncFilterOptions(add, opts.IncludePaths, opts.ExcludePaths)
+	rsyncCommand += fmt.Sprintf(" %s$EXPORT_PATH_TRAILING_SLASH_OPTIONAL %s", add, path.Join(exportContainerDir, filepath.Base(hostTo)))
+	args = append(args, rsyncCommand)
+
+	runArgs := []string{
+		"--rm",
+		"--user=0:0",
+		"--workdir=/",
+		fmt.Sprintf("--volumes-from=%s", stapelContainerName),
+		fmt.Sprintf("--volume=%s:%s", hostToParentDir, exportContainerDir),
+		fmt.Sprintf("--entrypoint=%s", stapel.BashBinPath()),
+		dockerImageName,
+		"-ec",
+		strings.Join(args, " && "),
+	}
+
+	logboek.Context(ctx).Debug().LogF("Run export command: %q\n", fmt.Sprintf("docker run %s", strings.Join(runArgs, " ")))
+	if output, err := docker.CliRun_RecordedOutput(ctx, runArgs...); err != nil {
+		logboek.Context(ctx).Error().LogF("%s", output)
+		return fmt.Errorf("unable to export %s to %s: %s", add, hostTo, err)
+	}
+
+	return nil
+}
+
+func NewExportPhase(c *Conveyor) *ExportPhase {
+	return &ExportPhase{BasePhase: BasePhase{c}}
+}
+
+type ExportPhase struct {
+	BasePhase
+}
+
+func (phase *ExportPhase) Name() string {
+	return "export"
+}
+
+func (phase *ExportPhase) BeforeImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *ExportPhase) AfterImages(_ context.Context) error {
+	return nil
+}
+
+func (phase *ExportPhase) BeforeImageStages(_ context.Context, _ *Image) error {
+	return nil
+}
+
+func (phase *ExportPhase) OnImageStage(_ context.Context, _ *Image, _ stage.Interface) error {
+	return nil
+}
+
+func (phase *ExportPhase) AfterImageStages(ctx context.Context, img *Image) error {
+	hostExports := phase.getImageHostExports(img)
+	if len(hostExports) == 0 {
+		return nil
+	}
+
+	if err := phase.Conveyor.StagesManager.FetchStage(ctx, img.GetLastNonEmptyStage()); err != nil {
+		return err
+	}
+
+	dockerImageName := img.GetLastNonEmptyStage().GetImage().Name()
+	for _, hostExport := range hostExports {
+		opts := ExportImageFilesOptions{
+			Add:          hostExport.Add,
+			To:           filepath.Join(phase.Conveyor.projectDir, hostExport.To),
+			IncludePaths: hostExport.IncludePaths,
+			ExcludePaths: hostExport.ExcludePaths,
+		}
+
+		if err := logboek.Context(ctx).Default().LogProcess("Exporting %s to %s", hostExport.Add, hostExport.To).
+			DoError(func() error {
+				return ExportImageFiles(ctx, dockerImageName, opts)
+			}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (phase *ExportPhase) ImageProcessingShouldBeStopped(_ context.Context, _ *Image) bool {
+	return false
+}
+
+func (phase *ExportPhase) Clone() Phase {
+	u := *phase
+	return &u
+}
+
+func (phase *ExportPhase) getImageHostExports(img *Image) []*config.HostExport {
+	if img.isDockerfileImage {
+		return nil
+	}
+
+	var imageBaseConfig *config.StapelImageBase
+	if img.isArtifact {
+		if artifactConfig := phase.Conveyor.werfConfig.GetArtifact(img.GetName()); artifactConfig != nil {
+			imageBaseConfig = artifactConfig.StapelImageBase
+		}
+	} else if imageConfig := phase.Conveyor.werfConfig.GetStapelImage(img.GetName()); imageConfig != nil {
+		imageBaseConfig = imageConfig.StapelImageBase
+	}
+
+	if imageBaseConfig == nil {
+		return nil
+	}
+
+	return imageBaseConfig.Export
+}
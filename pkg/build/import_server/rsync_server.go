@@ -141,7 +141,24 @@ func (srv *RsyncServer) GetCopyCommand(ctx context.Context, importConfig *config
 	}
 	rsyncCommand := fmt.Sprintf("RSYNC_PASSWORD='%s' %s --archive --links --inplace %s", srv.AuthPassword, stapel.RsyncBinPath(), rsyncChownOption)
 
-	if len(importConfig.IncludePaths) != 0 {
+	rsyncCommand += RsyncFilterOptions(importConfig.Add, importConfig.IncludePaths, importConfig.ExcludePaths)
+
+	rsyncCommand += fmt.Sprintf(" %s$IMPORT_PATH_TRAILING_SLASH_OPTIONAL %s", rsyncImportPathSpec, importConfig.To)
+	// run rsync itself
+	args = append(args, rsyncCommand)
+
+	command := strings.Join(args, " && ")
+
+	logboek.Context(ctx).Debug().LogF("Rsync server copy commands for import: artifact=%q image=%q add=%s to=%s includePaths=%v excludePaths=%v: %q\n", importConfig.ArtifactName, importConfig.ImageName, importConfig.Add, importConfig.To, importConfig.IncludePaths, importConfig.ExcludePaths, command)
+
+	return command
+}
+
+// RsyncFilterOptions generates rsync filter options which implement includePaths and excludePaths semantics relative to the add path
+func RsyncFilterOptions(add string, includePaths, excludePaths []string) string {
+	var filterOptions string
+
+	if len(includePaths) != 0 {
 		/**
 				Если указали include_paths — это означает, что надо копировать
 				только указанные пути. Поэтому exclude_paths в приоритете, т.к. в данном режиме
@@ -149,16 +166,16 @@ func (srv *RsyncServer) GetCopyCommand(ctx context.Context, importConfig *config
 		        При этом случай, когда в include_paths указали более специальный путь, чем в exclude_paths,
 		        будет обрабатываться в пользу exclude, этот путь не скопируется.
 		*/
-		for _, p := range importConfig.ExcludePaths {
-			rsyncCommand += fmt.Sprintf(" --filter='-/ %s'", path.Join(importConfig.Add, p))
+		for _, p := range excludePaths {
+			filterOptions += fmt.Sprintf(" --filter='-/ %s'", path.Join(add, p))
 		}
 
-		for _, p := range importConfig.IncludePaths {
-			targetPath := path.Join(importConfig.Add, p)
+		for _, p := range includePaths {
+			targetPath := path.Join(add, p)
 
 			// Генерируем разрешающее правило для каждого элемента пути
 			for _, pathPart := range descentPath(targetPath) {
-				rsyncCommand += fmt.Sprintf(" --filter='+/ %s'", pathPart)
+				filterOptions += fmt.Sprintf(" --filter='+/ %s'", pathPart)
 			}
 
 			/**
@@ -168,27 +185,19 @@ func (srv *RsyncServer) GetCopyCommand(ctx context.Context, importConfig *config
 					Автоматом подставляем паттерн ** для включения файлов, содержащихся в
 			        директории, которую пользователь указал в include_paths.
 			*/
-			rsyncCommand += fmt.Sprintf(" --filter='+/ %s'", targetPath)
-			rsyncCommand += fmt.Sprintf(" --filter='+/ %s'", path.Join(targetPath, "**"))
+			filterOptions += fmt.Sprintf(" --filter='+/ %s'", targetPath)
+			filterOptions += fmt.Sprintf(" --filter='+/ %s'", path.Join(targetPath, "**"))
 		}
 
 		// Все что не подошло по include — исключается
-		rsyncCommand += fmt.Sprintf(" --filter='-/ %s'", path.Join(importConfig.Add, "**"))
+		filterOptions += fmt.Sprintf(" --filter='-/ %s'", path.Join(add, "**"))
 	} else {
-		for _, p := range importConfig.ExcludePaths {
-			rsyncCommand += fmt.Sprintf(" --filter='-/ %s'", path.Join(importConfig.Add, p))
+		for _, p := range excludePaths {
+			filterOptions += fmt.Sprintf(" --filter='-/ %s'", path.Join(add, p))
 		}
 	}
 
-	rsyncCommand += fmt.Sprintf(" %s$IMPORT_PATH_TRAILING_SLASH_OPTIONAL %s", rsyncImportPathSpec, importConfig.To)
-	// run rsync itself
-	args = append(args, rsyncCommand)
-
-	command := strings.Join(args, " && ")
-
-	logboek.Context(ctx).Debug().LogF("Rsync server copy commands for import: artifact=%q image=%q add=%s to=%s includePaths=%v excludePaths=%v: %q\n", importConfig.ArtifactName, importConfig.ImageName, importConfig.Add, importConfig.To, importConfig.IncludePaths, importConfig.ExcludePaths, command)
-
-	return command
+	return filterOptions
 }
 
 func descentPath(filePath string) []string {
@@ -0,0 +1,46 @@
+package import_server
+
+import "testing"
+
+func TestRsyncFilterOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		add          string
+		includePaths []string
+		excludePaths []string
+		expected     string
+	}{
+		{
+			name:     "no filters",
+			add:      "/app",
+			expected: "",
+		},
+		{
+			name:         "exclude paths only",
+			add:          "/app",
+			excludePaths: []string{"tmp", "log/*.log"},
+			expected:     " --filter='-/ /app/tmp' --filter='-/ /app/log/*.log'",
+		},
+		{
+			name:         "include paths",
+			add:          "/app",
+			includePaths: []string{"dist"},
+			expected:     " --filter='+/ /app/dist' --filter='+/ /app' --filter='+/ /app/dist' --filter='+/ /app/dist/**' --filter='-/ /app/**'",
+		},
+		{
+			name:         "exclude paths take precedence over include paths",
+			add:          "/app",
+			includePaths: []string{"dist"},
+			excludePaths: []string{"dist/tmp"},
+			expected:     " --filter='-/ /app/dist/tmp' --filter='+/ /app/dist' --filter='+/ /app' --filter='+/ /app/dist' --filter='+/ /app/dist/**' --filter='-/ /app/**'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if filterOptions := RsyncFilterOptions(test.add, test.includePaths, test.excludePaths); filterOptions != test.expected {
+				t.Errorf("expected filter options:\n%q\ngot:\n%q", test.expected, filterOptions)
+			}
+		})
+	}
+}
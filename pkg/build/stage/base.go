@@ -11,6 +11,7 @@ import (
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/build_cache"
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
@@ -63,6 +64,9 @@ var (
 type NewBaseStageOptions struct {
 	ImageName        string
 	ConfigMounts     []*config.Mount
+	ConfigBuild      *config.Build
+	BuildSecrets     []*BuildSecret
+	BuildSecretsDir  string
 	ImageTmpDir      string
 	ContainerWerfDir string
 	ProjectName      string
@@ -73,6 +77,7 @@ func newBaseStage(name StageName, options *NewBaseStageOptions) *BaseStage {
 	s.name = name
 	s.imageName = options.ImageName
 	s.configMounts = options.ConfigMounts
+	s.configBuild = options.ConfigBuild
 	s.imageTmpDir = options.ImageTmpDir
 	s.containerWerfDir = options.ContainerWerfDir
 	s.projectName = options.ProjectName
@@ -89,6 +94,7 @@ type BaseStage struct {
 	imageTmpDir      string
 	containerWerfDir string
 	configMounts     []*config.Mount
+	configBuild      *config.Build
 	projectName      string
 }
 
@@ -247,9 +253,108 @@ func (s *BaseStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage
 		return fmt.Errorf("error adding mounts volumes: %s", err)
 	}
 
+	if err := s.addBuildCacheVolumes(image); err != nil {
+		return fmt.Errorf("error adding build caches volumes: %s", err)
+	}
+
+	s.addBuildRunOptions(image)
+
+	return nil
+}
+
+func (s *BaseStage) GetBuildCaches() ([]*build_cache.Cache, error) {
+	var caches []*build_cache.Cache
+	for _, mountCfg := range s.configMounts {
+		if mountCfg.Type != "cache" {
+			continue
+		}
+
+		cache, err := build_cache.NewCache(mountCfg.Name, mountCfg.Scope, mountCfg.Quota, s.projectName, s.imageName)
+		if err != nil {
+			return nil, err
+		}
+		caches = append(caches, cache)
+	}
+
+	return caches, nil
+}
+
+func (s *BaseStage) addBuildCacheVolumes(image container_runtime.ImageInterface) error {
+	for _, mountCfg := range s.configMounts {
+		if mountCfg.Type != "cache" {
+			continue
+		}
+
+		cache, err := build_cache.NewCache(mountCfg.Name, mountCfg.Scope, mountCfg.Quota, s.projectName, s.imageName)
+		if err != nil {
+			return err
+		}
+
+		if err := cache.Prepare(); err != nil {
+			return err
+		}
+
+		absoluteMountpoint := path.Join("/", path.Clean(mountCfg.To))
+		image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s", cache.GetContentDir(), absoluteMountpoint))
+	}
+
 	return nil
 }
 
+func (s *BaseStage) addBuildRunOptions(image container_runtime.ImageInterface) {
+	if s.configBuild == nil {
+		return
+	}
+
+	runOptions := image.Container().RunOptions()
+	runOptions.AddMemory(s.configBuild.Memory)
+	runOptions.AddCpus(s.configBuild.Cpus)
+	runOptions.AddNetwork(s.configBuild.Network)
+	runOptions.AddShmSize(s.configBuild.ShmSize)
+}
+
+func (s *BaseStage) getBuildDockerBuildArgs() ([]string, error) {
+	if s.configBuild == nil {
+		return nil, nil
+	}
+
+	var args []string
+
+	if s.configBuild.Memory != "" {
+		args = append(args, fmt.Sprintf("--memory=%s", s.configBuild.Memory))
+	}
+
+	// docker build does not support --cpus option
+	if s.configBuild.Cpus != "" {
+		cpuQuota, err := s.configBuild.CpuQuota()
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, "--cpu-period=100000", fmt.Sprintf("--cpu-quota=%d", cpuQuota))
+	}
+
+	if s.configBuild.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", s.configBuild.Network))
+	}
+
+	if s.configBuild.ShmSize != "" {
+		args = append(args, fmt.Sprintf("--shm-size=%s", s.configBuild.ShmSize))
+	}
+
+	return args, nil
+}
+
+// Only the build network can affect the stage result (memory, cpus and shmSize cannot),
+// the default network is not taken into account to keep signatures of already built stages
+func (s *BaseStage) withBuildNetworkDependency(dependencies string) string {
+	if s.configBuild == nil || s.configBuild.IsDefaultNetwork() {
+		return dependencies
+	}
+
+	return util.Sha256Hash(dependencies, s.configBuild.Network)
+}
+
 func (s *BaseStage) addProjectRepoCommitToLabels(ctx context.Context, c Conveyor, image container_runtime.ImageInterface) error {
 	if commit, err := c.GetProjectRepoCommit(ctx); err != nil {
 		return fmt.Errorf("unable to get project repo commit: %s", err)
@@ -263,6 +368,10 @@ func (s *BaseStage) PreRunHook(_ context.Context, _ Conveyor) error {
 	return nil
 }
 
+func (s *BaseStage) PostRunHook(_ context.Context, _ Conveyor) error {
+	return nil
+}
+
 func (s *BaseStage) getServiceMounts(prevBuiltImage container_runtime.ImageInterface) map[string][]string {
 	return mergeMounts(s.getServiceMountsFromLabels(prevBuiltImage), s.getServiceMountsFromConfig())
 }
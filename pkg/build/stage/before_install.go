@@ -28,11 +28,11 @@ type BeforeInstallStage struct {
 }
 
 func (s *BeforeInstallStage) GetDependencies(ctx context.Context, _ Conveyor, _, _ container_runtime.ImageInterface) (string, error) {
-	return s.builder.BeforeInstallChecksum(ctx), nil
+	return s.withBuildNetworkDependency(s.builder.BeforeInstallChecksum(ctx)), nil
 }
 
 func (s *BeforeInstallStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
-	if err := s.BaseStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
+	if err := s.UserStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
 		return err
 	}
 
@@ -34,7 +34,7 @@ func (s *BeforeSetupStage) GetDependencies(ctx context.Context, c Conveyor, _, _
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.BeforeSetupChecksum(ctx), stageDependenciesChecksum), nil
+	return s.withBuildNetworkDependency(util.Sha256Hash(s.builder.BeforeSetupChecksum(ctx), stageDependenciesChecksum)), nil
 }
 
 func (s *BeforeSetupStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -0,0 +1,99 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	uuid "github.com/satori/go.uuid"
+
+	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/stapel"
+)
+
+// BuildSecret is available in the user stage container only while the stage is running,
+// the secret is neither committed into the stage image nor taken into account in the stage signature
+type BuildSecret struct {
+	Id string
+	To string
+	// GetValue reads the secret value from the source, it is called only when the stage is being built
+	GetValue func() ([]byte, error)
+}
+
+func (s *UserStage) getBuildSecretsDir() string {
+	if s.buildSecretsDir == "" {
+		if s.buildSecretsBaseDir != "" {
+			s.buildSecretsDir = filepath.Join(s.buildSecretsBaseDir, fmt.Sprintf("werf-build-secrets-%s", uuid.NewV4().String()))
+		} else {
+			s.buildSecretsDir = filepath.Join(s.imageTmpDir, "secrets", string(s.Name()))
+		}
+	}
+
+	return s.buildSecretsDir
+}
+
+func (s *UserStage) getBuildSecretsContainerDir() string {
+	return path.Join(s.containerWerfDir, "build_secrets")
+}
+
+// addBuildSecretsVolumes mounts the secrets dir into the werf service dir and links the secrets to the configured paths,
+// the links are removed after the user commands, so that the mountpoints do not get into the stage image
+func (s *UserStage) addBuildSecretsVolumes(image container_runtime.ImageInterface) {
+	if len(s.buildSecrets) == 0 {
+		return
+	}
+
+	image.Container().RunOptions().AddVolume(fmt.Sprintf("%s:%s:ro", s.getBuildSecretsDir(), s.getBuildSecretsContainerDir()))
+
+	for _, secret := range s.buildSecrets {
+		image.Container().AddServiceRunCommands(
+			fmt.Sprintf("%s -p %s", stapel.MkdirBinPath(), path.Dir(secret.To)),
+			fmt.Sprintf("%s -sfn %s %s", stapel.LnBinPath(), path.Join(s.getBuildSecretsContainerDir(), secret.Id), secret.To),
+		)
+		image.Container().AddServiceCleanupCommands(fmt.Sprintf("%s -f %s", stapel.RmBinPath(), secret.To))
+	}
+}
+
+func (s *UserStage) PreRunHook(_ context.Context, _ Conveyor) error {
+	if len(s.buildSecrets) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.getBuildSecretsDir(), 0700); err != nil {
+		return fmt.Errorf("unable to create build secrets dir %s: %s", s.getBuildSecretsDir(), err)
+	}
+
+	for _, secret := range s.buildSecrets {
+		value, err := secret.GetValue()
+		if err != nil {
+			_ = s.removeBuildSecretsDir()
+			return fmt.Errorf("unable to get build secret %q: %s", secret.Id, err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(s.getBuildSecretsDir(), secret.Id), value, 0400); err != nil {
+			_ = s.removeBuildSecretsDir()
+			return fmt.Errorf("unable to write build secret %q: %s", secret.Id, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *UserStage) PostRunHook(_ context.Context, _ Conveyor) error {
+	if len(s.buildSecrets) == 0 {
+		return nil
+	}
+
+	return s.removeBuildSecretsDir()
+}
+
+func (s *UserStage) removeBuildSecretsDir() error {
+	if err := os.RemoveAll(s.getBuildSecretsDir()); err != nil {
+		return fmt.Errorf("unable to remove build secrets dir %s: %s", s.getBuildSecretsDir(), err)
+	}
+
+	return nil
+}
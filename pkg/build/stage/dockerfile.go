@@ -272,7 +272,7 @@ func (s *DockerfileStage) GetDependencies(ctx context.Context, _ Conveyor, _, _
 		}
 	}
 
-	return util.Sha256Hash(stagesDependencies[s.dockerTargetStageIndex]...), nil
+	return s.withBuildNetworkDependency(util.Sha256Hash(stagesDependencies[s.dockerTargetStageIndex]...)), nil
 }
 
 func (s *DockerfileStage) dockerfileInstructionDependencies(ctx context.Context, cmd interface{}) ([]string, []string, error) {
@@ -344,6 +344,12 @@ func (s *DockerfileStage) dockerfileOnBuildInstructionDependencies(ctx context.C
 }
 
 func (s *DockerfileStage) PrepareImage(_ context.Context, c Conveyor, prevBuiltImage, img container_runtime.ImageInterface) error {
+	buildArgs, err := s.getBuildDockerBuildArgs()
+	if err != nil {
+		return err
+	}
+
+	img.DockerfileImageBuilder().AppendBuildArgs(buildArgs...)
 	img.DockerfileImageBuilder().AppendBuildArgs(s.DockerBuildArgs()...)
 	return nil
 }
@@ -59,6 +59,10 @@ func (s *FromStage) GetDependencies(_ context.Context, c Conveyor, prevImage, _
 
 	for _, mount := range s.configMounts {
 		args = append(args, filepath.ToSlash(filepath.Clean(mount.From)), path.Clean(mount.To), mount.Type)
+
+		if mount.Type == "cache" {
+			args = append(args, mount.Name, mount.Scope)
+		}
 	}
 
 	if s.fromImageOrArtifactImageName != "" {
@@ -90,5 +94,7 @@ func (s *FromStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage
 		image.Container().AddServiceRunCommands(fmt.Sprintf("%s -rf %s", stapel.RmBinPath(), mountpointsStr))
 	}
 
+	s.addBuildRunOptions(image)
+
 	return nil
 }
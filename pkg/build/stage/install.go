@@ -34,7 +34,7 @@ func (s *InstallStage) GetDependencies(ctx context.Context, c Conveyor, _, _ con
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.InstallChecksum(ctx), stageDependenciesChecksum), nil
+	return s.withBuildNetworkDependency(util.Sha256Hash(s.builder.InstallChecksum(ctx), stageDependenciesChecksum)), nil
 }
 
 func (s *InstallStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -3,6 +3,7 @@ package stage
 import (
 	"context"
 
+	"github.com/werf/werf/pkg/build_cache"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
 )
@@ -20,6 +21,9 @@ type Interface interface {
 	PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error
 
 	PreRunHook(context.Context, Conveyor) error
+	PostRunHook(context.Context, Conveyor) error
+
+	GetBuildCaches() ([]*build_cache.Cache, error)
 
 	SetSignature(signature string)
 	GetSignature() string
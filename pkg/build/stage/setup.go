@@ -34,7 +34,7 @@ func (s *SetupStage) GetDependencies(ctx context.Context, c Conveyor, _, _ conta
 		return "", err
 	}
 
-	return util.Sha256Hash(s.builder.SetupChecksum(ctx), stageDependenciesChecksum), nil
+	return s.withBuildNetworkDependency(util.Sha256Hash(s.builder.SetupChecksum(ctx), stageDependenciesChecksum)), nil
 }
 
 func (s *SetupStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/werf/werf/pkg/build/builder"
 	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/util"
 )
 
@@ -26,6 +27,8 @@ func getBuilder(imageBaseConfig *config.StapelImageBase, baseStageOptions *NewBa
 func newUserStage(builder builder.Builder, name StageName, baseStageOptions *NewBaseStageOptions) *UserStage {
 	s := &UserStage{}
 	s.builder = builder
+	s.buildSecrets = baseStageOptions.BuildSecrets
+	s.buildSecretsBaseDir = baseStageOptions.BuildSecretsDir
 	s.BaseStage = newBaseStage(name, baseStageOptions)
 	return s
 }
@@ -33,7 +36,20 @@ func newUserStage(builder builder.Builder, name StageName, baseStageOptions *New
 type UserStage struct {
 	*BaseStage
 
-	builder builder.Builder
+	builder             builder.Builder
+	buildSecrets        []*BuildSecret
+	buildSecretsBaseDir string
+	buildSecretsDir     string
+}
+
+func (s *UserStage) PrepareImage(ctx context.Context, c Conveyor, prevBuiltImage, image container_runtime.ImageInterface) error {
+	if err := s.BaseStage.PrepareImage(ctx, c, prevBuiltImage, image); err != nil {
+		return err
+	}
+
+	s.addBuildSecretsVolumes(image)
+
+	return nil
 }
 
 func (s *UserStage) getStageDependenciesChecksum(ctx context.Context, c Conveyor, name StageName) (string, error) {
@@ -0,0 +1,152 @@
+package build_cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/slug"
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	ImageScope   = "image"
+	ProjectScope = "project"
+	HostScope    = "host"
+
+	BuildCacheVersion = "1"
+
+	// cacheLockTimeout limits the waiting for the build cache used by another build
+	cacheLockTimeout = 600 * time.Second
+
+	contentDirName   = "content"
+	metadataFileName = "metadata.json"
+)
+
+func GetBuildCachesDir() string {
+	return filepath.Join(werf.GetLocalCacheDir(), "build_caches", BuildCacheVersion)
+}
+
+// Cache is a persistent named directory, which is mounted into the build containers
+type Cache struct {
+	Name  string
+	Scope string
+	Quota int64
+
+	relDir string
+}
+
+type Metadata struct {
+	Name       string    `json:"name"`
+	Scope      string    `json:"scope"`
+	Quota      int64     `json:"quota"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
+
+func NewCache(name, scope string, quota int64, projectName, imageName string) (*Cache, error) {
+	var relDir string
+	switch scope {
+	case HostScope:
+		relDir = filepath.Join("host", name)
+	case ProjectScope:
+		relDir = filepath.Join("projects", projectName, name)
+	case ImageScope:
+		if imageName == "" {
+			imageName = "~"
+		}
+		relDir = filepath.Join("projects", projectName, "images", slug.LimitedSlug(imageName, slug.DefaultSlugMaxSize), name)
+	default:
+		return nil, fmt.Errorf("unknown build cache scope %q: expected %q, %q or %q", scope, ImageScope, ProjectScope, HostScope)
+	}
+
+	return &Cache{Name: name, Scope: scope, Quota: quota, relDir: relDir}, nil
+}
+
+func (c *Cache) String() string {
+	return filepath.ToSlash(c.relDir)
+}
+
+func (c *Cache) GetDir() string {
+	return filepath.Join(GetBuildCachesDir(), c.relDir)
+}
+
+func (c *Cache) GetContentDir() string {
+	return filepath.Join(c.GetDir(), contentDirName)
+}
+
+func (c *Cache) LockName() string {
+	return LockName(c.String())
+}
+
+func LockName(cacheID string) string {
+	return fmt.Sprintf("build_cache.%s", cacheID)
+}
+
+func (c *Cache) Prepare() error {
+	if err := os.MkdirAll(c.GetContentDir(), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create build cache dir %s: %s", c.GetContentDir(), err)
+	}
+
+	return c.writeMetadata()
+}
+
+func (c *Cache) writeMetadata() error {
+	data, err := json.Marshal(Metadata{Name: c.Name, Scope: c.Scope, Quota: c.Quota, LastUsedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	metadataPath := filepath.Join(c.GetDir(), metadataFileName)
+	if err := ioutil.WriteFile(metadataPath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write build cache metadata %s: %s", metadataPath, err)
+	}
+
+	return nil
+}
+
+// WithCachesLock holds exclusive host locks of the caches while f is running and marks the caches as used
+func WithCachesLock(ctx context.Context, caches []*Cache, f func() error) error {
+	if len(caches) == 0 {
+		return f()
+	}
+
+	// Locks are always acquired in the same order to prevent deadlocks between parallel builds
+	sortedCaches := append([]*Cache{}, caches...)
+	sort.Slice(sortedCaches, func(i, j int) bool {
+		return sortedCaches[i].String() < sortedCaches[j].String()
+	})
+
+	var locks []lockgate.LockHandle
+	defer func() {
+		for i := len(locks) - 1; i >= 0; i-- {
+			werf.ReleaseHostLock(locks[i])
+		}
+	}()
+
+	for _, cache := range sortedCaches {
+		if _, lock, err := werf.AcquireHostLock(ctx, cache.LockName(), lockgate.AcquireOptions{Timeout: cacheLockTimeout}); err != nil {
+			return fmt.Errorf("unable to lock build cache %s: %s", cache.String(), err)
+		} else {
+			locks = append(locks, lock)
+		}
+	}
+
+	if err := f(); err != nil {
+		return err
+	}
+
+	for _, cache := range sortedCaches {
+		if err := cache.writeMetadata(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
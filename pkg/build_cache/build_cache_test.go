@@ -0,0 +1,217 @@
+package build_cache
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func initTestHome(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-build-cache-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	if err := werf.Init(tmpDir, filepath.Join(tmpDir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	oldRemoveCacheDir := removeCacheDir
+	removeCacheDir = func(_ context.Context, dir string) error {
+		return os.RemoveAll(dir)
+	}
+	t.Cleanup(func() { removeCacheDir = oldRemoveCacheDir })
+}
+
+func newTestCache(t *testing.T, name string, quota, size int64, lastUsedAt time.Time) *Cache {
+	cache, err := NewCache(name, ProjectScope, quota, "project", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(cache.GetContentDir(), "data"), make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(Metadata{Name: cache.Name, Scope: cache.Scope, Quota: cache.Quota, LastUsedAt: lastUsedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(cache.GetDir(), metadataFileName), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return cache
+}
+
+func cacheExists(t *testing.T, cache *Cache) bool {
+	exists, err := util.DirExists(cache.GetDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+func TestNewCache(t *testing.T) {
+	initTestHome(t)
+
+	tests := []struct {
+		scope     string
+		imageName string
+		id        string
+	}{
+		{HostScope, "app", "host/apt"},
+		{ProjectScope, "app", "projects/project/apt"},
+		{ImageScope, "app", "projects/project/images/app/apt"},
+	}
+
+	for _, test := range tests {
+		cache, err := NewCache("apt", test.scope, 0, "project", test.imageName)
+		if err != nil {
+			t.Errorf("unexpected error for scope %q: %s", test.scope, err)
+			continue
+		}
+
+		if cache.String() != test.id {
+			t.Errorf("expected cache %q for scope %q, got %q", test.id, test.scope, cache.String())
+		}
+	}
+
+	if _, err := NewCache("apt", "stage", 0, "project", "app"); err == nil {
+		t.Errorf("expected error for unknown scope")
+	}
+}
+
+func TestCleanup_Quota(t *testing.T) {
+	initTestHome(t)
+
+	overQuota := newTestCache(t, "over-quota", 100, 200, time.Now())
+	underQuota := newTestCache(t, "under-quota", 100, 50, time.Now())
+	noQuota := newTestCache(t, "no-quota", 0, 200, time.Now())
+
+	if err := Cleanup(context.Background(), CleanupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cacheExists(t, overQuota) {
+		t.Errorf("cache %s exceeding quota should be removed", overQuota)
+	}
+	if !cacheExists(t, underQuota) {
+		t.Errorf("cache %s under quota should be kept", underQuota)
+	}
+	if !cacheExists(t, noQuota) {
+		t.Errorf("cache %s without quota should be kept", noQuota)
+	}
+}
+
+func TestCleanup_LeastRecentlyUsed(t *testing.T) {
+	initTestHome(t)
+
+	now := time.Now()
+	oldest := newTestCache(t, "oldest", 0, 100, now.Add(-3*time.Hour))
+	old := newTestCache(t, "old", 0, 100, now.Add(-2*time.Hour))
+	recent := newTestCache(t, "recent", 0, 100, now.Add(-time.Hour))
+
+	if err := Cleanup(context.Background(), CleanupOptions{SizeLimit: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cacheExists(t, oldest) || cacheExists(t, old) {
+		t.Errorf("least recently used caches should be removed until the total size is under the limit")
+	}
+	if !cacheExists(t, recent) {
+		t.Errorf("recently used cache %s should be kept", recent)
+	}
+}
+
+func TestCleanup_DryRun(t *testing.T) {
+	initTestHome(t)
+
+	overQuota := newTestCache(t, "over-quota", 100, 200, time.Now())
+
+	if err := Cleanup(context.Background(), CleanupOptions{SizeLimit: 1, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cacheExists(t, overQuota) {
+		t.Errorf("cache %s should not be removed in dry run mode", overQuota)
+	}
+}
+
+func TestCleanup_LockedCache(t *testing.T) {
+	initTestHome(t)
+
+	now := time.Now()
+	locked := newTestCache(t, "locked", 100, 200, now.Add(-2*time.Hour))
+	unlocked := newTestCache(t, "unlocked", 0, 100, now.Add(-time.Hour))
+
+	_, lock, err := werf.AcquireHostLock(context.Background(), locked.LockName(), lockgate.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	if err := Cleanup(context.Background(), CleanupOptions{SizeLimit: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cacheExists(t, locked) {
+		t.Errorf("cache %s used by another process should be kept", locked)
+	}
+	if cacheExists(t, unlocked) {
+		t.Errorf("cache %s should be removed instead of the locked cache", unlocked)
+	}
+}
+
+func TestWithCachesLock(t *testing.T) {
+	initTestHome(t)
+
+	lastUsedAt := time.Now().Add(-time.Hour)
+	cache := newTestCache(t, "cache", 0, 0, lastUsedAt)
+
+	if err := WithCachesLock(context.Background(), []*Cache{cache}, func() error {
+		isAcquired, lock, err := werf.AcquireHostLock(context.Background(), cache.LockName(), lockgate.AcquireOptions{NonBlocking: true})
+		if err != nil {
+			return err
+		} else if isAcquired {
+			werf.ReleaseHostLock(lock)
+			t.Errorf("cache %s should be locked while the build is running", cache)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	caches, err := getCaches(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(caches) != 1 || !caches[0].Metadata.LastUsedAt.After(lastUsedAt) {
+		t.Errorf("cache %s should be marked as used", cache)
+	}
+
+	isAcquired, lock, err := werf.AcquireHostLock(context.Background(), cache.LockName(), lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if !isAcquired {
+		t.Errorf("cache %s lock should be released", cache)
+	} else {
+		werf.ReleaseHostLock(lock)
+	}
+}
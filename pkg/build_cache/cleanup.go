@@ -0,0 +1,190 @@
+package build_cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/docker/go-units"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type CleanupOptions struct {
+	// SizeLimit of all build caches on the host, 0 — no limit
+	SizeLimit int64
+	DryRun    bool
+}
+
+type cacheDesc struct {
+	ID       string
+	Dir      string
+	Size     int64
+	Metadata Metadata
+}
+
+// Cleanup removes build caches exceeding their quotas and then least recently used build caches until the total size is under the limit.
+// Build caches used by running builds are skipped.
+func Cleanup(ctx context.Context, options CleanupOptions) error {
+	caches, err := getCaches(ctx)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(caches, func(i, j int) bool {
+		return caches[i].Metadata.LastUsedAt.Before(caches[j].Metadata.LastUsedAt)
+	})
+
+	var totalSize int64
+	var restCaches []*cacheDesc
+	for _, cache := range caches {
+		if cache.Metadata.Quota > 0 && cache.Size > cache.Metadata.Quota {
+			reason := fmt.Sprintf("size %s exceeds quota %s", units.HumanSize(float64(cache.Size)), units.HumanSize(float64(cache.Metadata.Quota)))
+			if removed, err := removeCache(ctx, cache, reason, options.DryRun); err != nil {
+				return err
+			} else if removed {
+				continue
+			}
+		}
+
+		totalSize += cache.Size
+		restCaches = append(restCaches, cache)
+	}
+
+	if options.SizeLimit == 0 {
+		return nil
+	}
+
+	for _, cache := range restCaches {
+		if totalSize <= options.SizeLimit {
+			break
+		}
+
+		reason := fmt.Sprintf("total size %s exceeds limit %s", units.HumanSize(float64(totalSize)), units.HumanSize(float64(options.SizeLimit)))
+		if removed, err := removeCache(ctx, cache, reason, options.DryRun); err != nil {
+			return err
+		} else if removed {
+			totalSize -= cache.Size
+		}
+	}
+
+	return nil
+}
+
+func removeCache(ctx context.Context, cache *cacheDesc, reason string, dryRun bool) (bool, error) {
+	isLocked, lock, err := werf.AcquireHostLock(ctx, LockName(cache.ID), lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to lock build cache %s: %s", cache.ID, err)
+	}
+
+	if !isLocked {
+		logboek.Context(ctx).Default().LogFDetails("Ignore build cache %s used by another process\n", cache.ID)
+		return false, nil
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	logboek.Context(ctx).Default().LogFDetails("Removing build cache %s: %s\n", cache.ID, reason)
+
+	if dryRun {
+		return true, nil
+	}
+
+	if err := removeCacheDir(ctx, cache.Dir); err != nil {
+		return false, fmt.Errorf("unable to remove build cache %s: %s", cache.Dir, err)
+	}
+
+	return true, nil
+}
+
+// removeCacheDir removes the files created by the build containers using the linux container (except windows)
+var removeCacheDir = func(ctx context.Context, dir string) error {
+	if runtime.GOOS == "windows" {
+		return os.RemoveAll(dir)
+	}
+	return util.RemoveHostDirsWithLinuxContainer(ctx, werf.GetHomeDir(), []string{dir})
+}
+
+func getCaches(ctx context.Context) ([]*cacheDesc, error) {
+	cachesDir := GetBuildCachesDir()
+	if exist, err := util.DirExists(cachesDir); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, nil
+	}
+
+	var caches []*cacheDesc
+	if err := filepath.Walk(cachesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		metadataPath := filepath.Join(path, metadataFileName)
+		data, err := ioutil.ReadFile(metadataPath)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read %s: %s", metadataPath, err)
+		}
+
+		cache := &cacheDesc{Dir: path}
+		if err := json.Unmarshal(data, &cache.Metadata); err != nil {
+			return fmt.Errorf("unable to parse %s: %s", metadataPath, err)
+		}
+
+		relDir, err := filepath.Rel(cachesDir, path)
+		if err != nil {
+			return err
+		}
+		cache.ID = filepath.ToSlash(relDir)
+
+		// the size of the cache with unreadable files is unknown, such cache is neither counted nor removed
+		if cache.Size, err = dirSize(filepath.Join(path, contentDirName)); os.IsPermission(err) {
+			logboek.Context(ctx).Warn().LogF("WARNING: Skip build cache %s: unable to calculate size: %s\n", cache.ID, err)
+			return filepath.SkipDir
+		} else if err != nil {
+			return err
+		}
+
+		caches = append(caches, cache)
+
+		return filepath.SkipDir
+	}); err != nil {
+		return nil, fmt.Errorf("unable to list build caches in %s: %s", cachesDir, err)
+	}
+
+	return caches, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files can be removed by the running build
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
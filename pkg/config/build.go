@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Build struct {
+	Memory  string
+	Cpus    string
+	Network string
+	ShmSize string
+
+	raw *rawBuild
+}
+
+// IsDefaultNetwork returns false when the network differs from the docker default network
+func (c *Build) IsDefaultNetwork() bool {
+	return c.Network == "" || c.Network == "bridge"
+}
+
+// CpuQuota converts cpus into the cpu quota for the default 100000 cpu period
+func (c *Build) CpuQuota() (int64, error) {
+	cpus, err := strconv.ParseFloat(c.Cpus, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad cpus value %q: %s", c.Cpus, err)
+	}
+
+	return int64(cpus * 100000), nil
+}
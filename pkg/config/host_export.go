@@ -0,0 +1,10 @@
+package config
+
+type HostExport struct {
+	Add          string
+	To           string
+	IncludePaths []string
+	ExcludePaths []string
+
+	raw *rawHostExport
+}
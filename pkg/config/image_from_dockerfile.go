@@ -7,6 +7,7 @@ type ImageFromDockerfile struct {
 	Target     string
 	Args       map[string]interface{}
 	AddHost    []string
+	Build      *Build
 
 	raw *rawImageFromDockerfile
 }
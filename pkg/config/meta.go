@@ -5,4 +5,6 @@ type Meta struct {
 	Project         string
 	DeployTemplates MetaDeployTemplates
 	Cleanup         MetaCleanup
+
+	TemplateLibraries []*MetaTemplateLibrary
 }
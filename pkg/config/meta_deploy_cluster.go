@@ -0,0 +1,9 @@
+package config
+
+type MetaDeployCluster struct {
+	Name        string
+	KubeContext string
+	KubeConfig  string
+	Namespace   string
+	Values      []string
+}
@@ -5,4 +5,20 @@ type MetaDeployTemplates struct {
 	HelmReleaseSlug *bool
 	Namespace       *string
 	NamespaceSlug   *bool
+	AutoRollback    *bool
+	HelmVersion     *string
+
+	Clusters              []*MetaDeployCluster
+	ClustersStrategy      *string
+	ClustersStopOnFailure *bool
 }
+
+const (
+	ClustersStrategySequential = "sequential"
+	ClustersStrategyParallel   = "parallel"
+)
+
+const (
+	HelmVersion2 = "2"
+	HelmVersion3 = "3"
+)
@@ -0,0 +1,8 @@
+package config
+
+type MetaTemplateLibrary struct {
+	Name   string
+	Url    string
+	Commit string
+	Add    string
+}
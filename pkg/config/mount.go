@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 )
 
 type Mount struct {
@@ -9,6 +10,10 @@ type Mount struct {
 	From string
 	Type string
 
+	Name  string
+	Scope string
+	Quota int64
+
 	raw *rawMount
 }
 
@@ -19,8 +24,18 @@ func (c *Mount) validate() error {
 		if c.From == "" {
 			return newDetailedConfigError("`fromPath: PATH` absolute or relative path required for mount!", c.raw, c.raw.rawStapelImage.doc)
 		}
+	} else if c.Type == "cache" {
+		if c.Name == "" {
+			return newDetailedConfigError("`name: NAME` required for cache mount!", c.raw, c.raw.rawStapelImage.doc)
+		} else if !regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`).MatchString(c.Name) {
+			return newDetailedConfigError(fmt.Sprintf("invalid `name: %s` for cache mount: only alphanumeric chars, dots, dashes and underscores are allowed!", c.Name), c.raw, c.raw.rawStapelImage.doc)
+		}
+
+		if c.Scope != "image" && c.Scope != "project" && c.Scope != "host" {
+			return newDetailedConfigError(fmt.Sprintf("invalid `scope: %s` for cache mount: expected `image`, `project` or `host`!", c.Scope), c.raw, c.raw.rawStapelImage.doc)
+		}
 	} else if c.Type != "tmp_dir" && c.Type != "build_dir" {
-		return newDetailedConfigError(fmt.Sprintf("invalid `from: %s` for mount: expected `tmp_dir` or `build_dir`!", c.Type), c.raw, c.raw.rawStapelImage.doc)
+		return newDetailedConfigError(fmt.Sprintf("invalid `from: %s` for mount: expected `tmp_dir`, `build_dir` or `cache`!", c.Type), c.raw, c.raw.rawStapelImage.doc)
 	}
 	return nil
 }
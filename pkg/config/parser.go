@@ -212,6 +212,17 @@ func parseWerfConfigYaml(ctx context.Context, werfConfigPath, werfConfigTemplate
 	tmpl := template.New("werfConfig")
 	tmpl.Funcs(funcMap(tmpl))
 
+	templateLibraries, err := getWerfConfigTemplateLibraries(werfConfigPath, data)
+	if err != nil {
+		return "", err
+	}
+
+	for _, templateLibrary := range templateLibraries {
+		if err := addTemplateLibraryTemplates(ctx, tmpl, templateLibrary); err != nil {
+			return "", fmt.Errorf("unable to add templates of library %s: %s", templateLibrary.Url, err)
+		}
+	}
+
 	werfConfigsTemplates, err := getWerfConfigTemplates(werfConfigTemplatesDir)
 	if err != nil {
 		return "", err
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/docker/go-units"
+)
+
+type rawBuild struct {
+	Memory  string `yaml:"memory,omitempty"`
+	Cpus    string `yaml:"cpus,omitempty"`
+	Network string `yaml:"network,omitempty"`
+	ShmSize string `yaml:"shmSize,omitempty"`
+
+	doc *doc `yaml:"-"` // parent doc
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawBuild) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	switch parent := parentStack.Peek().(type) {
+	case *rawStapelImage:
+		c.doc = parent.doc
+	case *rawImageFromDockerfile:
+		c.doc = parent.doc
+	}
+
+	type plain rawBuild
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawBuild) toDirective() (build *Build, err error) {
+	build = &Build{}
+	build.Memory = c.Memory
+	build.Cpus = c.Cpus
+	build.Network = c.Network
+	build.ShmSize = c.ShmSize
+
+	build.raw = c
+
+	if err := c.validateDirective(build); err != nil {
+		return nil, err
+	}
+
+	return build, nil
+}
+
+func (c *rawBuild) validateDirective(build *Build) error {
+	if build.Memory != "" {
+		if _, err := units.RAMInBytes(build.Memory); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid memory value `%s`: %s", build.Memory, err), c, c.doc)
+		}
+	}
+
+	if build.ShmSize != "" {
+		if _, err := units.RAMInBytes(build.ShmSize); err != nil {
+			return newDetailedConfigError(fmt.Sprintf("invalid shmSize value `%s`: %s", build.ShmSize, err), c, c.doc)
+		}
+	}
+
+	if build.Cpus != "" {
+		if cpus, err := strconv.ParseFloat(build.Cpus, 64); err != nil || cpus <= 0 {
+			return newDetailedConfigError(fmt.Sprintf("invalid cpus value `%s`: positive number expected", build.Cpus), c, c.doc)
+		}
+	}
+
+	switch build.Network {
+	case "", "none", "host", "bridge":
+	default:
+		return newDetailedConfigError(fmt.Sprintf("invalid network value `%s`: `none`, `host` or `bridge` expected", build.Network), c, c.doc)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func parseTestWerfConfig(content string) (*WerfConfig, error) {
+	docs, err := splitByDocs(content, "werf.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	meta, rawStapelImages, rawImagesFromDockerfile, err := splitByMetaAndRawImages(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	return prepareWerfConfig(rawStapelImages, rawImagesFromDockerfile, meta)
+}
+
+var _ = Describe("build resources", func() {
+	It("should be parsed for the stapel image", func() {
+		werfConfig, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+build:
+  memory: 512m
+  cpus: "1.5"
+  network: none
+  shmSize: 64m
+`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werfConfig.StapelImages).Should(HaveLen(1))
+
+		build := werfConfig.StapelImages[0].Build
+		Ω(build).ShouldNot(BeNil())
+		Ω(build.Memory).Should(Equal("512m"))
+		Ω(build.Cpus).Should(Equal("1.5"))
+		Ω(build.Network).Should(Equal("none"))
+		Ω(build.ShmSize).Should(Equal("64m"))
+		Ω(build.IsDefaultNetwork()).Should(BeFalse())
+		Ω(build.CpuQuota()).Should(Equal(int64(150000)))
+	})
+
+	It("should be parsed for the dockerfile image", func() {
+		werfConfig, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+dockerfile: Dockerfile
+build:
+  memory: 1g
+  network: bridge
+`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werfConfig.ImagesFromDockerfile).Should(HaveLen(1))
+
+		build := werfConfig.ImagesFromDockerfile[0].Build
+		Ω(build).ShouldNot(BeNil())
+		Ω(build.Memory).Should(Equal("1g"))
+		Ω(build.Cpus).Should(BeEmpty())
+		Ω(build.IsDefaultNetwork()).Should(BeTrue())
+	})
+
+	It("should not be set when the directive is omitted", func() {
+		werfConfig, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(werfConfig.StapelImages[0].Build).Should(BeNil())
+	})
+
+	It("should not allow unknown attributes", func() {
+		_, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+build:
+  memoryLimit: 512m
+`)
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+var _ = DescribeTable("build resources validation", func(buildContent string, errorSubstring string) {
+	_, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+build:
+` + buildContent)
+
+	if errorSubstring == "" {
+		Ω(err).ShouldNot(HaveOccurred())
+	} else {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(errorSubstring))
+	}
+},
+	Entry("memory in bytes", "  memory: \"536870912\"\n", ""),
+	Entry("memory with unit", "  memory: 2GiB\n", ""),
+	Entry("bad memory", "  memory: lots\n", "invalid memory value `lots`"),
+	Entry("bad shmSize", "  shmSize: 64x\n", "invalid shmSize value `64x`"),
+	Entry("fractional cpus", "  cpus: \"0.5\"\n", ""),
+	Entry("zero cpus", "  cpus: \"0\"\n", "invalid cpus value `0`"),
+	Entry("negative cpus", "  cpus: \"-1\"\n", "invalid cpus value `-1`"),
+	Entry("not a number cpus", "  cpus: two\n", "invalid cpus value `two`"),
+	Entry("host network", "  network: host\n", ""),
+	Entry("unknown network", "  network: overlay\n", "invalid network value `overlay`"),
+)
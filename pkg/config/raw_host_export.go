@@ -0,0 +1,81 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type rawHostExport struct {
+	Add          string      `yaml:"add,omitempty"`
+	To           string      `yaml:"to,omitempty"`
+	IncludePaths interface{} `yaml:"includePaths,omitempty"`
+	ExcludePaths interface{} `yaml:"excludePaths,omitempty"`
+
+	rawStapelImage *rawStapelImage `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawHostExport) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawStapelImage); ok {
+		c.rawStapelImage = parent
+	}
+
+	type plain rawHostExport
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawStapelImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawHostExport) toDirective() (hostExport *HostExport, err error) {
+	hostExport = &HostExport{}
+	hostExport.Add = path.Clean(c.Add)
+	hostExport.To = filepath.Clean(filepath.FromSlash(c.To))
+
+	if includePaths, err := InterfaceToStringArray(c.IncludePaths, c, c.rawStapelImage.doc); err != nil {
+		return nil, err
+	} else {
+		for _, p := range includePaths {
+			hostExport.IncludePaths = append(hostExport.IncludePaths, path.Clean(p))
+		}
+	}
+
+	if excludePaths, err := InterfaceToStringArray(c.ExcludePaths, c, c.rawStapelImage.doc); err != nil {
+		return nil, err
+	} else {
+		for _, p := range excludePaths {
+			hostExport.ExcludePaths = append(hostExport.ExcludePaths, path.Clean(p))
+		}
+	}
+
+	hostExport.raw = c
+
+	if err := c.validateDirective(hostExport); err != nil {
+		return nil, err
+	}
+
+	return hostExport, nil
+}
+
+func (c *rawHostExport) validateDirective(hostExport *HostExport) error {
+	if c.Add == "" || !isAbsolutePath(hostExport.Add) {
+		return newDetailedConfigError("`add: PATH` absolute path required for export!", c, c.rawStapelImage.doc)
+	} else if c.To == "" || !isRelativePath(c.To) {
+		return newDetailedConfigError("`to: PATH` path relative to the project directory required for export!", c, c.rawStapelImage.doc)
+	} else if hostExport.To == "." || hostExport.To == ".." || strings.HasPrefix(hostExport.To, ".."+string(filepath.Separator)) {
+		return newDetailedConfigError("`to: PATH` should be inside the project directory for export!", c, c.rawStapelImage.doc)
+	} else if !allRelativePaths(hostExport.IncludePaths) {
+		return newDetailedConfigError("`includePaths: [PATH, ...]|PATH` should be relative paths!", c, c.rawStapelImage.doc)
+	} else if !allRelativePaths(hostExport.ExcludePaths) {
+		return newDetailedConfigError("`excludePaths: [PATH, ...]|PATH` should be relative paths!", c, c.rawStapelImage.doc)
+	}
+
+	return nil
+}
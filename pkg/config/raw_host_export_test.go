@@ -0,0 +1,88 @@
+package config
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("export", func() {
+	It("should be parsed for the image and artifact", func() {
+		werfConfig, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+artifact: builder
+from: golang
+export:
+- add: /app/bin/
+  to: build/bin
+  includePaths: app-*
+  excludePaths:
+  - app-*.debug
+---
+image: app
+from: alpine
+export:
+- add: /etc/app
+  to: ./out/../config
+`)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(werfConfig.Artifacts).Should(HaveLen(1))
+		Ω(werfConfig.Artifacts[0].Export).Should(HaveLen(1))
+		artifactExport := werfConfig.Artifacts[0].Export[0]
+		Ω(artifactExport.Add).Should(Equal("/app/bin"))
+		Ω(artifactExport.To).Should(Equal(filepath.Join("build", "bin")))
+		Ω(artifactExport.IncludePaths).Should(Equal([]string{"app-*"}))
+		Ω(artifactExport.ExcludePaths).Should(Equal([]string{"app-*.debug"}))
+
+		Ω(werfConfig.StapelImages).Should(HaveLen(1))
+		Ω(werfConfig.StapelImages[0].Export).Should(HaveLen(1))
+		imageExport := werfConfig.StapelImages[0].Export[0]
+		Ω(imageExport.Add).Should(Equal("/etc/app"))
+		Ω(imageExport.To).Should(Equal("config"))
+	})
+
+	It("should not allow unknown attributes", func() {
+		_, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+export:
+- add: /app
+  to: out
+  owner: app
+`)
+		Ω(err).Should(HaveOccurred())
+	})
+})
+
+var _ = DescribeTable("export validation", func(exportContent string, errorSubstring string) {
+	_, err := parseTestWerfConfig(`project: test
+configVersion: 1
+---
+image: app
+from: alpine
+export:
+` + exportContent)
+
+	if errorSubstring == "" {
+		Ω(err).ShouldNot(HaveOccurred())
+	} else {
+		Ω(err).Should(HaveOccurred())
+		Ω(err.Error()).Should(ContainSubstring(errorSubstring))
+	}
+},
+	Entry("valid", "- add: /app\n  to: out\n", ""),
+	Entry("add is required", "- to: out\n", "`add: PATH` absolute path required"),
+	Entry("relative add", "- add: app\n  to: out\n", "`add: PATH` absolute path required"),
+	Entry("to is required", "- add: /app\n", "`to: PATH` path relative to the project directory required"),
+	Entry("absolute to", "- add: /app\n  to: /tmp/out\n", "`to: PATH` path relative to the project directory required"),
+	Entry("project directory", "- add: /app\n  to: out/..\n", "`to: PATH` should be inside the project directory"),
+	Entry("outside the project directory", "- add: /app\n  to: ../out\n", "`to: PATH` should be inside the project directory"),
+	Entry("absolute include path", "- add: /app\n  to: out\n  includePaths: /app/dist\n", "`includePaths: [PATH, ...]|PATH` should be relative paths"),
+	Entry("absolute exclude path", "- add: /app\n  to: out\n  excludePaths: [/app/tmp]\n", "`excludePaths: [PATH, ...]|PATH` should be relative paths"),
+)
@@ -12,6 +12,7 @@ type rawImageFromDockerfile struct {
 	Target     string                 `yaml:"target,omitempty"`
 	Args       map[string]interface{} `yaml:"args,omitempty"`
 	AddHost    interface{}            `yaml:"addHost,omitempty"`
+	RawBuild   *rawBuild              `yaml:"build,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -88,6 +89,14 @@ func (c *rawImageFromDockerfile) toImageFromDockerfileDirective(imageName string
 		image.AddHost = addHost
 	}
 
+	if c.RawBuild != nil {
+		if build, err := c.RawBuild.toDirective(); err != nil {
+			return nil, err
+		} else {
+			image.Build = build
+		}
+	}
+
 	image.raw = c
 
 	return image, nil
@@ -13,6 +13,8 @@ type rawMeta struct {
 	DeployTemplates *rawMetaDeployTemplates `yaml:"deploy,omitempty"`
 	Cleanup         *rawMetaCleanup         `yaml:"cleanup,omitempty"`
 
+	TemplateLibraries []*rawMetaTemplateLibrary `yaml:"templateLibraries,omitempty"`
+
 	doc *doc `yaml:"-"` // parent
 
 	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
@@ -70,5 +72,9 @@ func (c *rawMeta) toMeta() *Meta {
 		meta.DeployTemplates = c.DeployTemplates.toDeployTemplates()
 	}
 
+	for _, rawTemplateLibrary := range c.TemplateLibraries {
+		meta.TemplateLibraries = append(meta.TemplateLibraries, rawTemplateLibrary.toMetaTemplateLibrary())
+	}
+
 	return meta
 }
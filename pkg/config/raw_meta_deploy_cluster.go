@@ -0,0 +1,61 @@
+package config
+
+import "fmt"
+
+type rawMetaDeployCluster struct {
+	Name        string      `yaml:"name,omitempty"`
+	KubeContext string      `yaml:"kubeContext,omitempty"`
+	KubeConfig  string      `yaml:"kubeConfig,omitempty"`
+	Namespace   string      `yaml:"namespace,omitempty"`
+	Values      interface{} `yaml:"values,omitempty"`
+
+	values []string
+
+	rawMetaDeployTemplates *rawMetaDeployTemplates
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaDeployCluster) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMetaDeployTemplates); ok {
+		c.rawMetaDeployTemplates = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaDeployCluster
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, nil, c.rawMetaDeployTemplates.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.Name == "" {
+		return newDetailedConfigError("deploy.clusters[].name field cannot be empty!", nil, c.rawMetaDeployTemplates.rawMeta.doc)
+	}
+
+	if c.KubeContext == "" && c.KubeConfig == "" {
+		return newDetailedConfigError(fmt.Sprintf("deploy cluster '%s': kubeContext or kubeConfig field required!", c.Name), nil, c.rawMetaDeployTemplates.rawMeta.doc)
+	}
+
+	values, err := InterfaceToStringArray(c.Values, nil, c.rawMetaDeployTemplates.rawMeta.doc)
+	if err != nil {
+		return err
+	}
+	c.values = values
+
+	return nil
+}
+
+func (c *rawMetaDeployCluster) toMetaDeployCluster() *MetaDeployCluster {
+	return &MetaDeployCluster{
+		Name:        c.Name,
+		KubeContext: c.KubeContext,
+		KubeConfig:  c.KubeConfig,
+		Namespace:   c.Namespace,
+		Values:      c.values,
+	}
+}
@@ -1,10 +1,18 @@
 package config
 
+import "fmt"
+
 type rawMetaDeployTemplates struct {
 	HelmRelease     *string `yaml:"helmRelease,omitempty"`
 	HelmReleaseSlug *bool   `yaml:"helmReleaseSlug,omitempty"`
 	Namespace       *string `yaml:"namespace,omitempty"`
 	NamespaceSlug   *bool   `yaml:"namespaceSlug,omitempty"`
+	AutoRollback    *bool   `yaml:"autoRollback,omitempty"`
+	HelmVersion     *string `yaml:"helmVersion,omitempty"`
+
+	Clusters              []*rawMetaDeployCluster `yaml:"clusters,omitempty"`
+	ClustersStrategy      *string                 `yaml:"clustersStrategy,omitempty"`
+	ClustersStopOnFailure *bool                   `yaml:"clustersStopOnFailure,omitempty"`
 
 	rawMeta *rawMeta
 
@@ -36,6 +44,22 @@ func (c *rawMetaDeployTemplates) UnmarshalYAML(unmarshal func(interface{}) error
 		return newDetailedConfigError("namespace field cannot be empty!", nil, c.rawMeta.doc)
 	}
 
+	if c.HelmVersion != nil && *c.HelmVersion != HelmVersion2 && *c.HelmVersion != HelmVersion3 {
+		return newDetailedConfigError(fmt.Sprintf("unsupported helmVersion '%s': '%s' or '%s' expected!", *c.HelmVersion, HelmVersion2, HelmVersion3), nil, c.rawMeta.doc)
+	}
+
+	if c.ClustersStrategy != nil && *c.ClustersStrategy != ClustersStrategySequential && *c.ClustersStrategy != ClustersStrategyParallel {
+		return newDetailedConfigError(fmt.Sprintf("unsupported clustersStrategy '%s': '%s' or '%s' expected!", *c.ClustersStrategy, ClustersStrategySequential, ClustersStrategyParallel), nil, c.rawMeta.doc)
+	}
+
+	clusterNames := map[string]bool{}
+	for _, cluster := range c.Clusters {
+		if clusterNames[cluster.Name] {
+			return newDetailedConfigError(fmt.Sprintf("duplicate deploy cluster '%s'!", cluster.Name), nil, c.rawMeta.doc)
+		}
+		clusterNames[cluster.Name] = true
+	}
+
 	return nil
 }
 
@@ -45,5 +69,13 @@ func (c *rawMetaDeployTemplates) toDeployTemplates() MetaDeployTemplates {
 	deployTemplates.HelmReleaseSlug = c.HelmReleaseSlug
 	deployTemplates.Namespace = c.Namespace
 	deployTemplates.NamespaceSlug = c.NamespaceSlug
+	deployTemplates.AutoRollback = c.AutoRollback
+	deployTemplates.HelmVersion = c.HelmVersion
+
+	for _, cluster := range c.Clusters {
+		deployTemplates.Clusters = append(deployTemplates.Clusters, cluster.toMetaDeployCluster())
+	}
+	deployTemplates.ClustersStrategy = c.ClustersStrategy
+	deployTemplates.ClustersStopOnFailure = c.ClustersStopOnFailure
 	return deployTemplates
 }
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"regexp"
+)
+
+type rawMetaTemplateLibrary struct {
+	Url    string `yaml:"url,omitempty"`
+	Commit string `yaml:"commit,omitempty"`
+	Add    string `yaml:"add,omitempty"`
+
+	rawMeta *rawMeta
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawMetaTemplateLibrary) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawMeta); ok {
+		c.rawMeta = parent
+	}
+
+	parentStack.Push(c)
+	type plain rawMetaTemplateLibrary
+	err := unmarshal((*plain)(c))
+	parentStack.Pop()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawMeta.doc); err != nil {
+		return err
+	}
+
+	if c.Url == "" {
+		return newDetailedConfigError("url field cannot be empty!", c, c.rawMeta.doc)
+	}
+
+	if c.Commit == "" {
+		return newDetailedConfigError("commit field cannot be empty: template library should be pinned to the specific commit!", c, c.rawMeta.doc)
+	} else if !regexp.MustCompile(`^[0-9a-f]{40}$`).MatchString(c.Commit) {
+		return newDetailedConfigError("commit field should be the full 40-character commit hash!", c, c.rawMeta.doc)
+	}
+
+	if c.Add != "" && !isAbsolutePath(c.Add) {
+		return newDetailedConfigError("`add: PATH` should be absolute path for template library!", c, c.rawMeta.doc)
+	}
+
+	return nil
+}
+
+func (c *rawMetaTemplateLibrary) toMetaTemplateLibrary() *MetaTemplateLibrary {
+	templateLibrary := &MetaTemplateLibrary{}
+	templateLibrary.Name = getRepositoryID(c.Url)
+	templateLibrary.Url = c.Url
+	templateLibrary.Commit = c.Commit
+
+	if c.Add == "" {
+		templateLibrary.Add = "/"
+	} else {
+		templateLibrary.Add = c.Add
+	}
+
+	return templateLibrary
+}
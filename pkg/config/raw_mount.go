@@ -1,11 +1,18 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/docker/go-units"
+)
 
 type rawMount struct {
 	To       string `yaml:"to,omitempty"`
 	From     string `yaml:"from,omitempty"`
 	FromPath string `yaml:"fromPath,omitempty"`
+	Name     string `yaml:"name,omitempty"`
+	Scope    string `yaml:"scope,omitempty"`
+	Quota    string `yaml:"quota,omitempty"`
 
 	rawStapelImage *rawStapelImage `yaml:"-"` // parent
 
@@ -40,6 +47,22 @@ func (c *rawMount) toDirective() (mount *Mount, err error) {
 		mount.Type = c.From
 	}
 
+	if mount.Type == "cache" {
+		mount.Name = c.Name
+		mount.Scope = c.Scope
+		if mount.Scope == "" {
+			mount.Scope = "project"
+		}
+
+		if c.Quota != "" {
+			if quota, err := units.RAMInBytes(c.Quota); err != nil {
+				return nil, newDetailedConfigError(fmt.Sprintf("invalid `quota: %s` for mount: %s", c.Quota, err), c, c.rawStapelImage.doc)
+			} else {
+				mount.Quota = quota
+			}
+		}
+	}
+
 	mount.raw = c
 
 	if err := c.validateDirective(mount); err != nil {
@@ -54,6 +77,10 @@ func (c *rawMount) validateDirective(mount *Mount) (err error) {
 		return newDetailedConfigError(fmt.Sprintf("cannot use `from: %s` and `fromPath: %s` at the same time for mount!", c.From, c.FromPath), c, c.rawStapelImage.doc)
 	}
 
+	if c.From != "cache" && (c.Name != "" || c.Scope != "" || c.Quota != "") {
+		return newDetailedConfigError("`name`, `scope` and `quota` can be used only with `from: cache` mount!", c, c.rawStapelImage.doc)
+	}
+
 	if err := mount.validate(); err != nil {
 		return err
 	}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+)
+
+type rawSecret struct {
+	Id             string `yaml:"id,omitempty"`
+	Env            string `yaml:"env,omitempty"`
+	Src            string `yaml:"src,omitempty"`
+	EncryptedValue string `yaml:"encryptedValue,omitempty"`
+	To             string `yaml:"to,omitempty"`
+
+	rawStapelImage *rawStapelImage `yaml:"-"` // parent
+
+	UnsupportedAttributes map[string]interface{} `yaml:",inline"`
+}
+
+func (c *rawSecret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if parent, ok := parentStack.Peek().(*rawStapelImage); ok {
+		c.rawStapelImage = parent
+	}
+
+	type plain rawSecret
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if err := checkOverflow(c.UnsupportedAttributes, c, c.rawStapelImage.doc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *rawSecret) toDirective() (secret *Secret, err error) {
+	secret = &Secret{}
+	secret.Id = c.Id
+	secret.Env = c.Env
+	secret.Src = filepath.FromSlash(c.Src)
+	secret.EncryptedValue = c.EncryptedValue
+
+	if c.To == "" {
+		secret.To = path.Join("/run/secrets", c.Id)
+	} else {
+		secret.To = path.Clean(c.To)
+	}
+
+	secret.raw = c
+
+	if err := c.validateDirective(secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func (c *rawSecret) validateDirective(secret *Secret) error {
+	if secret.Id == "" {
+		return newDetailedConfigError("`id: ID` required for secret!", c, c.rawStapelImage.doc)
+	} else if !regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`).MatchString(secret.Id) {
+		return newDetailedConfigError(fmt.Sprintf("invalid `id: %s` for secret: only alphanumeric chars, dots, dashes and underscores are allowed!", secret.Id), c, c.rawStapelImage.doc)
+	}
+
+	var sourcesNumber int
+	for _, source := range []string{secret.Env, secret.Src, secret.EncryptedValue} {
+		if source != "" {
+			sourcesNumber++
+		}
+	}
+
+	if sourcesNumber != 1 {
+		return newDetailedConfigError("exactly one secret source `env`, `src` or `encryptedValue` required!", c, c.rawStapelImage.doc)
+	}
+
+	if secret.Src != "" && !isRelativePath(c.Src) {
+		return newDetailedConfigError("`src: PATH` should be relative to the project directory for secret!", c, c.rawStapelImage.doc)
+	}
+
+	if !isAbsolutePath(secret.To) {
+		return newDetailedConfigError("`to: PATH` absolute path required for secret!", c, c.rawStapelImage.doc)
+	}
+
+	return nil
+}
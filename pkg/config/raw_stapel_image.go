@@ -6,21 +6,24 @@ import (
 )
 
 type rawStapelImage struct {
-	Images                                              []string     `yaml:"-"`
-	Artifact                                            string       `yaml:"artifact,omitempty"`
-	From                                                string       `yaml:"from,omitempty"`
-	FromLatest                                          bool         `yaml:"fromLatest,omitempty"`
-	HerebyIAdmitThatFromLatestMightBreakReproducibility bool         `yaml:"herebyIAdmitThatFromLatestMightBreakReproducibility,omitempty"`
-	FromCacheVersion                                    string       `yaml:"fromCacheVersion,omitempty"`
-	FromImage                                           string       `yaml:"fromImage,omitempty"`
-	FromImageArtifact                                   string       `yaml:"fromImageArtifact,omitempty"`
-	RawGit                                              []*rawGit    `yaml:"git,omitempty"`
-	RawShell                                            *rawShell    `yaml:"shell,omitempty"`
-	RawAnsible                                          *rawAnsible  `yaml:"ansible,omitempty"`
-	RawMount                                            []*rawMount  `yaml:"mount,omitempty"`
-	RawDocker                                           *rawDocker   `yaml:"docker,omitempty"`
-	RawImport                                           []*rawImport `yaml:"import,omitempty"`
-	AsLayers                                            bool         `yaml:"asLayers,omitempty"`
+	Images                                              []string         `yaml:"-"`
+	Artifact                                            string           `yaml:"artifact,omitempty"`
+	From                                                string           `yaml:"from,omitempty"`
+	FromLatest                                          bool             `yaml:"fromLatest,omitempty"`
+	HerebyIAdmitThatFromLatestMightBreakReproducibility bool             `yaml:"herebyIAdmitThatFromLatestMightBreakReproducibility,omitempty"`
+	FromCacheVersion                                    string           `yaml:"fromCacheVersion,omitempty"`
+	FromImage                                           string           `yaml:"fromImage,omitempty"`
+	FromImageArtifact                                   string           `yaml:"fromImageArtifact,omitempty"`
+	RawGit                                              []*rawGit        `yaml:"git,omitempty"`
+	RawShell                                            *rawShell        `yaml:"shell,omitempty"`
+	RawAnsible                                          *rawAnsible      `yaml:"ansible,omitempty"`
+	RawMount                                            []*rawMount      `yaml:"mount,omitempty"`
+	RawDocker                                           *rawDocker       `yaml:"docker,omitempty"`
+	RawImport                                           []*rawImport     `yaml:"import,omitempty"`
+	RawBuild                                            *rawBuild        `yaml:"build,omitempty"`
+	RawSecrets                                          []*rawSecret     `yaml:"secrets,omitempty"`
+	RawExport                                           []*rawHostExport `yaml:"export,omitempty"`
+	AsLayers                                            bool             `yaml:"asLayers,omitempty"`
 
 	doc *doc `yaml:"-"` // parent
 
@@ -356,6 +359,10 @@ func (c *rawStapelImage) toStapelImageTopLayerDirective(name string) (mainImageL
 		return nil, err
 	}
 
+	if mainImageLayer.Export, err = c.toHostExportDirectives(); err != nil {
+		return nil, err
+	}
+
 	if c.RawDocker != nil {
 		if docker, err := c.RawDocker.toDirective(); err != nil {
 			return nil, err
@@ -508,6 +515,10 @@ func (c *rawStapelImage) toStapelImageArtifactTopLayerDirective() (mainImageArti
 		return nil, err
 	}
 
+	if mainImageArtifactLayer.Export, err = c.toHostExportDirectives(); err != nil {
+		return nil, err
+	}
+
 	return mainImageArtifactLayer, nil
 }
 
@@ -594,6 +605,18 @@ func (c *rawStapelImage) validateStapelImageArtifactDirective(imageArtifact *Sta
 	return nil
 }
 
+func (c *rawStapelImage) toHostExportDirectives() (hostExports []*HostExport, err error) {
+	for _, rawExport := range c.RawExport {
+		if hostExport, err := rawExport.toDirective(); err != nil {
+			return nil, err
+		} else {
+			hostExports = append(hostExports, hostExport)
+		}
+	}
+
+	return hostExports, nil
+}
+
 func (c *rawStapelImage) toStapelImageBaseDirective(name string) (imageBase *StapelImageBase, err error) {
 	if imageBase, err = c.toBaseStapelImageBaseDirective(name); err != nil {
 		return nil, err
@@ -606,6 +629,10 @@ func (c *rawStapelImage) toStapelImageBaseDirective(name string) (imageBase *Sta
 	imageBase.HerebyIAdmitThatFromLatestMightBreakReproducibility = c.HerebyIAdmitThatFromLatestMightBreakReproducibility
 	imageBase.FromCacheVersion = c.FromCacheVersion
 
+	if imageBase.Export, err = c.toHostExportDirectives(); err != nil {
+		return nil, err
+	}
+
 	for _, git := range c.RawGit {
 		if git.gitType() == "local" {
 			if gitLocal, err := git.toGitLocalDirective(); err != nil {
@@ -673,6 +700,22 @@ func (c *rawStapelImage) toBaseStapelImageBaseDirective(name string) (imageBase
 		}
 	}
 
+	if c.RawBuild != nil {
+		if build, err := c.RawBuild.toDirective(); err != nil {
+			return nil, err
+		} else {
+			imageBase.Build = build
+		}
+	}
+
+	for _, rawSecret := range c.RawSecrets {
+		if secret, err := rawSecret.toDirective(); err != nil {
+			return nil, err
+		} else {
+			imageBase.Secrets = append(imageBase.Secrets, secret)
+		}
+	}
+
 	imageBase.Git = &GitManager{}
 
 	imageBase.raw = c
@@ -0,0 +1,11 @@
+package config
+
+type Secret struct {
+	Id             string
+	Env            string
+	Src            string
+	EncryptedValue string
+	To             string
+
+	raw *rawSecret
+}
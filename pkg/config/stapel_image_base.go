@@ -17,6 +17,9 @@ type StapelImageBase struct {
 	Ansible                                             *Ansible
 	Mount                                               []*Mount
 	Import                                              []*Import
+	Build                                               *Build
+	Secrets                                             []*Secret
+	Export                                              []*HostExport
 
 	raw *rawStapelImage
 }
@@ -103,6 +106,15 @@ We do not recommend using the actual base image such way. Use a particular uncha
 		mountByTo[mount.To] = true
 	}
 
+	secretById := map[string]bool{}
+	for _, secret := range c.Secrets {
+		if secretById[secret.Id] {
+			return newDetailedConfigError(fmt.Sprintf("duplicate secret id `%s`!", secret.Id), nil, c.raw.doc)
+		}
+
+		secretById[secret.Id] = true
+	}
+
 	if !oneOrNone([]bool{c.From != "", c.raw.FromImage != "", c.raw.FromImageArtifact != ""}) {
 		return newDetailedConfigError("conflict between `from`, `fromImage` and `fromImageArtifact` directives!", nil, c.raw.doc)
 	}
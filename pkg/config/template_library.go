@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+// getWerfConfigTemplateLibraries extracts template libraries from the meta config section before werf.yaml rendering,
+// therefore the meta config section that defines templateLibraries cannot contain go templates
+func getWerfConfigTemplateLibraries(werfConfigPath string, data []byte) ([]*MetaTemplateLibrary, error) {
+	for _, docContent := range splitContent(data) {
+		if emptyDocContent(docContent) {
+			continue
+		}
+
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(docContent, &raw); err != nil {
+			if bytes.Contains(docContent, []byte("templateLibraries")) {
+				return nil, fmt.Errorf("unable to parse meta config section with templateLibraries: the section cannot contain go templates: %s", err)
+			}
+
+			continue
+		}
+
+		if !isMetaDoc(raw) {
+			continue
+		}
+
+		if _, ok := raw["templateLibraries"]; !ok {
+			return nil, nil
+		}
+
+		parentStack = util.NewStack()
+		rawMeta := &rawMeta{doc: &doc{Content: docContent, RenderFilePath: werfConfigPath}}
+		if err := yaml.UnmarshalStrict(docContent, &rawMeta); err != nil {
+			return nil, newYamlUnmarshalError(err, rawMeta.doc)
+		}
+
+		return rawMeta.toMeta().TemplateLibraries, nil
+	}
+
+	return nil, nil
+}
+
+func addTemplateLibraryTemplates(ctx context.Context, tmpl *template.Template, templateLibrary *MetaTemplateLibrary) error {
+	remoteGitRepo, err := git_repo.OpenRemoteRepo(templateLibrary.Name, templateLibrary.Url)
+	if err != nil {
+		return fmt.Errorf("unable to open remote git repo %s by url %s: %s", templateLibrary.Name, templateLibrary.Url, err)
+	}
+
+	// the clone is held from being removed by the host cleanup until the templates are read
+	cacheLock, err := remoteGitRepo.AcquireCacheLock(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to lock template library %s cache: %s", templateLibrary.Name, err)
+	}
+	defer werf.ReleaseHostLock(cacheLock)
+
+	if err := logboek.Context(ctx).Info().LogProcess(fmt.Sprintf("Refreshing %s template library", templateLibrary.Name)).
+		DoError(func() error {
+			if _, err := remoteGitRepo.Clone(ctx); err != nil {
+				return err
+			}
+
+			if exist, err := remoteGitRepo.IsCommitExists(ctx, templateLibrary.Commit); err != nil {
+				return err
+			} else if exist {
+				return nil
+			}
+
+			if err := remoteGitRepo.Fetch(ctx); err != nil {
+				return err
+			}
+
+			if exist, err := remoteGitRepo.IsCommitExists(ctx, templateLibrary.Commit); err != nil {
+				return err
+			} else if !exist {
+				return fmt.Errorf("commit %s not found in repo %s", templateLibrary.Commit, templateLibrary.Url)
+			}
+
+			return nil
+		}); err != nil {
+		return err
+	}
+
+	if err := werf.TouchLastUse(remoteGitRepo.GetClonePath()); err != nil {
+		return fmt.Errorf("unable to record last use of %s: %s", remoteGitRepo.GetClonePath(), err)
+	}
+
+	return remoteGitRepo.WalkCommitFiles(ctx, templateLibrary.Commit, templateLibrary.Add, func(relPath string, content []byte) error {
+		matched, err := filepath.Match("*.tmpl", path.Base(relPath))
+		if err != nil {
+			return err
+		}
+
+		if !matched {
+			return nil
+		}
+
+		templateName := path.Join(templateLibrary.Name, relPath)
+		if err := addTemplate(tmpl, templateName, string(content)); err != nil {
+			return fmt.Errorf("unable to parse template %s: %s", templateName, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("template libraries", func() {
+	It("should be extracted from the meta config section", func() {
+		data := []byte(`project: test
+configVersion: 1
+templateLibraries:
+- url: https://github.com/company/werf-templates.git
+  commit: 9c2a2c0dd28fdcbec1a08c8ff0b4ed8db1ff5bbf
+  add: /templates
+---
+image: {{ .Name }}
+from: alpine
+`)
+
+		templateLibraries, err := getWerfConfigTemplateLibraries("werf.yaml", data)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(templateLibraries).Should(Equal([]*MetaTemplateLibrary{{
+			Name:   "company/werf-templates",
+			Url:    "https://github.com/company/werf-templates.git",
+			Commit: "9c2a2c0dd28fdcbec1a08c8ff0b4ed8db1ff5bbf",
+			Add:    "/templates",
+		}}))
+	})
+
+	It("should not be required", func() {
+		templateLibraries, err := getWerfConfigTemplateLibraries("werf.yaml", []byte("project: test\nconfigVersion: 1\n"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(templateLibraries).Should(BeEmpty())
+	})
+
+	It("should be pinned to the full commit hash", func() {
+		data := []byte(`project: test
+configVersion: 1
+templateLibraries:
+- url: https://github.com/company/werf-templates.git
+  commit: master
+`)
+
+		_, err := getWerfConfigTemplateLibraries("werf.yaml", data)
+		Ω(err).Should(HaveOccurred())
+	})
+})
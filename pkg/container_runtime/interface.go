@@ -48,6 +48,8 @@ type Container interface {
 
 	AddServiceRunCommands(commands ...string)
 	AddRunCommands(commands ...string)
+	// AddServiceCleanupCommands adds the commands running after the user commands, e.g. to remove the files which should not be committed
+	AddServiceCleanupCommands(commands ...string)
 
 	RunOptions() ContainerOptions
 	CommitChangeOptions() ContainerOptions
@@ -76,4 +78,8 @@ type ContainerOptions interface {
 	AddUser(user string)
 	AddEntrypoint(entrypoint string)
 	AddHealthCheck(check string)
+	AddMemory(memory string)
+	AddCpus(cpus string)
+	AddNetwork(network string)
+	AddShmSize(shmSize string)
 }
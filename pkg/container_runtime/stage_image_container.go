@@ -21,6 +21,7 @@ type StageImageContainer struct {
 	name                       string
 	runCommands                []string
 	serviceRunCommands         []string
+	serviceCleanupCommands     []string
 	runOptions                 *StageImageContainerOptions
 	commitChangeOptions        *StageImageContainerOptions
 	serviceCommitChangeOptions *StageImageContainerOptions
@@ -56,6 +57,10 @@ func (c *StageImageContainer) AddServiceRunCommands(commands ...string) {
 	c.serviceRunCommands = append(c.serviceRunCommands, commands...)
 }
 
+func (c *StageImageContainer) AddServiceCleanupCommands(commands ...string) {
+	c.serviceCleanupCommands = append(c.serviceCleanupCommands, commands...)
+}
+
 func (c *StageImageContainer) RunOptions() ContainerOptions {
 	return c.runOptions
 }
@@ -117,6 +122,7 @@ func (c *StageImageContainer) prepareAllRunCommands() []string {
 
 	commands = append(commands, c.serviceRunCommands...)
 	commands = append(commands, c.runCommands...)
+	commands = append(commands, c.serviceCleanupCommands...)
 
 	return commands
 }
@@ -20,6 +20,10 @@ type StageImageContainerOptions struct {
 	User        string
 	Entrypoint  string
 	HealthCheck string
+	Memory      string
+	Cpus        string
+	Network     string
+	ShmSize     string
 }
 
 func newStageContainerOptions() *StageImageContainerOptions {
@@ -73,6 +77,22 @@ func (co *StageImageContainerOptions) AddEntrypoint(entrypoint string) {
 	co.Entrypoint = entrypoint
 }
 
+func (co *StageImageContainerOptions) AddMemory(memory string) {
+	co.Memory = memory
+}
+
+func (co *StageImageContainerOptions) AddCpus(cpus string) {
+	co.Cpus = cpus
+}
+
+func (co *StageImageContainerOptions) AddNetwork(network string) {
+	co.Network = network
+}
+
+func (co *StageImageContainerOptions) AddShmSize(shmSize string) {
+	co.ShmSize = shmSize
+}
+
 func (co *StageImageContainerOptions) merge(co2 *StageImageContainerOptions) *StageImageContainerOptions {
 	mergedCo := newStageContainerOptions()
 	mergedCo.Volume = append(co.Volume, co2.Volume...)
@@ -123,6 +143,30 @@ func (co *StageImageContainerOptions) merge(co2 *StageImageContainerOptions) *St
 		mergedCo.HealthCheck = co2.HealthCheck
 	}
 
+	if co2.Memory == "" {
+		mergedCo.Memory = co.Memory
+	} else {
+		mergedCo.Memory = co2.Memory
+	}
+
+	if co2.Cpus == "" {
+		mergedCo.Cpus = co.Cpus
+	} else {
+		mergedCo.Cpus = co2.Cpus
+	}
+
+	if co2.Network == "" {
+		mergedCo.Network = co.Network
+	} else {
+		mergedCo.Network = co2.Network
+	}
+
+	if co2.ShmSize == "" {
+		mergedCo.ShmSize = co.ShmSize
+	} else {
+		mergedCo.ShmSize = co2.ShmSize
+	}
+
 	return mergedCo
 }
 
@@ -157,6 +201,22 @@ func (co *StageImageContainerOptions) toRunArgs() ([]string, error) {
 		args = append(args, fmt.Sprintf("--entrypoint=%s", co.Entrypoint))
 	}
 
+	if co.Memory != "" {
+		args = append(args, fmt.Sprintf("--memory=%s", co.Memory))
+	}
+
+	if co.Cpus != "" {
+		args = append(args, fmt.Sprintf("--cpus=%s", co.Cpus))
+	}
+
+	if co.Network != "" {
+		args = append(args, fmt.Sprintf("--network=%s", co.Network))
+	}
+
+	if co.ShmSize != "" {
+		args = append(args, fmt.Sprintf("--shm-size=%s", co.ShmSize))
+	}
+
 	return args, nil
 }
 
@@ -15,9 +15,11 @@ import (
 	"github.com/werf/logboek"
 
 	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/events"
 	"github.com/werf/werf/pkg/deploy/helm"
 	"github.com/werf/werf/pkg/deploy/werf_chart"
 	"github.com/werf/werf/pkg/images_manager"
+	"github.com/werf/werf/pkg/logging"
 	"github.com/werf/werf/pkg/tag_strategy"
 	"github.com/werf/werf/pkg/util/secretvalues"
 )
@@ -33,6 +35,8 @@ type DeployOptions struct {
 	UserExtraLabels      map[string]string
 	IgnoreSecretKey      bool
 	ThreeWayMergeMode    helm.ThreeWayMergeModeType
+	AutoRollback         bool
+	HelmVersion          string
 	DryRun               bool
 }
 
@@ -60,8 +64,12 @@ func Deploy(ctx context.Context, projectName, projectDir, helmChartDir string, i
 			logboek.Context(ctx).LogF("Kube-config context: %s\n", kube.Context)
 		}
 		logboek.Context(ctx).LogF("Kubernetes namespace: %s\n", namespace)
-		logboek.Context(ctx).LogF("Helm release storage namespace: %s\n", helmReleaseStorageNamespace)
-		logboek.Context(ctx).LogF("Helm release storage type: %s\n", helmReleaseStorageType)
+		if opts.HelmVersion == config.HelmVersion3 {
+			logboek.Context(ctx).LogF("Helm version: %s\n", opts.HelmVersion)
+		} else {
+			logboek.Context(ctx).LogF("Helm release storage namespace: %s\n", helmReleaseStorageNamespace)
+			logboek.Context(ctx).LogF("Helm release storage type: %s\n", helmReleaseStorageType)
+		}
 		logboek.Context(ctx).LogF("Helm release name: %s\n", release)
 
 		m, err := GetSafeSecretManager(ctx, projectDir, helmChartDir, opts.SecretValues, opts.IgnoreSecretKey)
@@ -84,6 +92,7 @@ func Deploy(ctx context.Context, projectName, projectDir, helmChartDir string, i
 		if err != nil {
 			return err
 		}
+		werfChart.HelmVersion = opts.HelmVersion
 		helm.SetReleaseLogSecretValuesToMask(werfChart.SecretValuesToMask)
 
 		werfChart.MergeExtraAnnotations(opts.UserExtraAnnotations)
@@ -91,7 +100,11 @@ func Deploy(ctx context.Context, projectName, projectDir, helmChartDir string, i
 		werfChart.LogExtraAnnotations(ctx)
 		werfChart.LogExtraLabels(ctx)
 
-		return nil
+		return werfChart.ValidateValues(helm.ChartValuesOptions{
+			Set:       opts.Set,
+			SetString: opts.SetString,
+			Values:    opts.Values,
+		}, serviceValuesSchemas())
 	}); err != nil {
 		logboek.Context(ctx).LogOptionalLn()
 		return err
@@ -102,6 +115,13 @@ func Deploy(ctx context.Context, projectName, projectDir, helmChartDir string, i
 	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
 	patchLoadChartfile(werfChart.Name)
 
+	eventsImages := map[string]string{}
+	for _, image := range images {
+		eventsImages[logging.ImageLogName(image.GetName(), false)] = image.GetImageName()
+	}
+	events.SetReleaseInfo(events.ReleaseInfo{Release: release, Namespace: namespace, Env: opts.Env, Images: eventsImages})
+	events.Emit(events.Event{Type: events.ReleaseUpgradeStarted})
+
 	err = helm.WerfTemplateEngineWithExtraAnnotationsAndLabels(werfChart.ExtraAnnotations, werfChart.ExtraLabels, func() error {
 		return werfChart.Deploy(ctx, release, namespace, helm.ChartOptions{
 			Timeout: opts.Timeout,
@@ -111,6 +131,7 @@ func Deploy(ctx context.Context, projectName, projectDir, helmChartDir string, i
 				Values:    opts.Values,
 			},
 			ThreeWayMergeMode: opts.ThreeWayMergeMode,
+			AutoRollback:      opts.AutoRollback,
 		})
 	})
 
@@ -5,13 +5,16 @@ import (
 	"fmt"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/kubeutils"
 )
 
 type DismissOptions struct {
 	WithNamespace bool
 	WithHooks     bool
+	HelmVersion   string
 }
 
 func RunDismiss(ctx context.Context, projectName, release, namespace, _ string, opts DismissOptions) error {
@@ -29,8 +32,12 @@ func RunDismiss(ctx context.Context, projectName, release, namespace, _ string,
 
 		if err := logboek.Context(ctx).Default().LogBlock("Deploy options").DoError(func() error {
 			logboek.Context(ctx).LogF("Kubernetes namespace: %s\n", namespace)
-			logboek.Context(ctx).LogF("Helm release storage namespace: %s\n", helm.HelmReleaseStorageNamespace)
-			logboek.Context(ctx).LogF("Helm release storage type: %s\n", helm.HelmReleaseStorageType)
+			if opts.HelmVersion == config.HelmVersion3 {
+				logboek.Context(ctx).LogF("Helm version: %s\n", opts.HelmVersion)
+			} else {
+				logboek.Context(ctx).LogF("Helm release storage namespace: %s\n", helm.HelmReleaseStorageNamespace)
+				logboek.Context(ctx).LogF("Helm release storage type: %s\n", helm.HelmReleaseStorageType)
+			}
 			logboek.Context(ctx).LogF("Helm release name: %s\n", release)
 
 			return nil
@@ -40,6 +47,14 @@ func RunDismiss(ctx context.Context, projectName, release, namespace, _ string,
 
 		logboek.Context(ctx).Debug().LogF("Dismiss options: %#v\n", opts)
 		logboek.Context(ctx).Debug().LogF("Namespace: %s\n", namespace)
+
+		if opts.HelmVersion == config.HelmVersion3 {
+			return helm_v3.Uninstall(ctx, release, helm_v3.UninstallOptions{
+				Namespace:    namespace,
+				DisableHooks: !opts.WithHooks,
+			})
+		}
+
 		return helm.PurgeHelmRelease(ctx, release, namespace, opts.WithHooks)
 	}(); err != nil {
 		return err
@@ -0,0 +1,355 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+)
+
+type Type string
+
+const (
+	BuildStarted          Type = "build-started"
+	BuildFinished         Type = "build-finished"
+	ReleaseUpgradeStarted Type = "release-upgrade-started"
+	ResourceReady         Type = "resource-ready"
+	ResourceFailed        Type = "resource-failed"
+	Rollback              Type = "rollback"
+	Done                  Type = "done"
+)
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Event is the deploy lifecycle event, the release info is filled by the emitter
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+
+	Project   string            `json:"project,omitempty"`
+	Release   string            `json:"release,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Env       string            `json:"env,omitempty"`
+	Cluster   string            `json:"cluster,omitempty"`
+	GitCommit string            `json:"gitCommit,omitempty"`
+	Images    map[string]string `json:"images,omitempty"`
+
+	Status   Status `json:"status,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Revision int    `json:"revision,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type InitOptions struct {
+	Webhooks       []string
+	File           string
+	WebhookRetries int
+	WebhookTimeout time.Duration
+	// FinishTimeout limits the delivery of the queued events on Finish, the undelivered events are dropped
+	FinishTimeout time.Duration
+
+	Project   string
+	GitCommit string
+	// Cluster is set for the child process of the multi-cluster deploy:
+	// the process emits only the events of the release in the cluster, the build and done events are emitted by the parent process
+	Cluster string
+}
+
+type ReleaseInfo struct {
+	Release   string
+	Namespace string
+	Env       string
+	Images    map[string]string
+}
+
+var (
+	defaultEmitter      *emitter
+	defaultEmitterMutex sync.Mutex
+)
+
+type emitter struct {
+	opts InitOptions
+
+	mutex       sync.Mutex
+	releaseInfo ReleaseInfo
+	file        *os.File
+	closed      bool
+
+	queue chan *Event
+	done  chan struct{}
+	stop  chan struct{}
+
+	// warnMutex prevents the delivery warnings after Finish has returned
+	warnMutex sync.Mutex
+}
+
+// Init enables events emitting, events are not emitted when neither webhooks nor file are specified
+func Init(opts InitOptions) error {
+	if len(opts.Webhooks) == 0 && opts.File == "" {
+		return nil
+	}
+
+	if opts.WebhookTimeout == 0 {
+		opts.WebhookTimeout = 10 * time.Second
+	}
+
+	if opts.FinishTimeout == 0 {
+		opts.FinishTimeout = 30 * time.Second
+	}
+
+	e := &emitter{
+		opts:  opts,
+		queue: make(chan *Event, 1000),
+		done:  make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("unable to open events file %s: %s", opts.File, err)
+		}
+		e.file = f
+	}
+
+	go e.run()
+
+	defaultEmitterMutex.Lock()
+	defaultEmitter = e
+	defaultEmitterMutex.Unlock()
+
+	return nil
+}
+
+// Enabled returns true when events are emitted, it allows to skip the extra work needed only for events
+func Enabled() bool {
+	return getDefaultEmitter() != nil
+}
+
+// SetReleaseInfo sets the release info that is added to all subsequent events
+func SetReleaseInfo(info ReleaseInfo) {
+	e := getDefaultEmitter()
+	if e == nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.releaseInfo = info
+}
+
+// Emit queues the event for delivery, delivery errors do not affect the deploy process
+func Emit(event Event) {
+	e := getDefaultEmitter()
+	if e == nil {
+		return
+	}
+
+	e.emit(event)
+}
+
+// Finish emits the done event with the status of the whole process and waits until all queued events are delivered,
+// the waiting is limited by the FinishTimeout and the context
+func Finish(ctx context.Context, err error) {
+	defaultEmitterMutex.Lock()
+	e := defaultEmitter
+	defaultEmitter = nil
+	defaultEmitterMutex.Unlock()
+
+	if e == nil {
+		return
+	}
+
+	if e.opts.Cluster == "" {
+		doneEvent := Event{Type: Done, Status: StatusSucceeded}
+		if err != nil {
+			doneEvent.Status = StatusFailed
+			doneEvent.Error = err.Error()
+		}
+		e.emit(doneEvent)
+	}
+	e.close()
+
+	select {
+	case <-e.done:
+	case <-time.After(e.opts.FinishTimeout):
+		e.stopDelivery()
+		logboek.Context(ctx).Warn().LogF("WARNING: events delivery has not been finished in %s, undelivered events are dropped\n", e.opts.FinishTimeout)
+		return
+	case <-ctx.Done():
+		e.stopDelivery()
+		logboek.Context(ctx).Warn().LogF("WARNING: events delivery has been cancelled: %s\n", ctx.Err())
+		return
+	}
+
+	if e.file != nil {
+		if err := e.file.Close(); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: unable to close events file %s: %s\n", e.opts.File, err)
+		}
+	}
+}
+
+func getDefaultEmitter() *emitter {
+	defaultEmitterMutex.Lock()
+	defer defaultEmitterMutex.Unlock()
+
+	return defaultEmitter
+}
+
+func StatusByError(err error) Status {
+	if err != nil {
+		return StatusFailed
+	}
+	return StatusSucceeded
+}
+
+func ErrorString(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func (e *emitter) emit(event Event) {
+	if e.opts.Cluster != "" {
+		switch event.Type {
+		case BuildStarted, BuildFinished, Done:
+			return
+		}
+	}
+
+	e.mutex.Lock()
+	event.Time = time.Now().UTC()
+	event.Project = e.opts.Project
+	event.GitCommit = e.opts.GitCommit
+	event.Cluster = e.opts.Cluster
+	event.Release = e.releaseInfo.Release
+	event.Namespace = e.releaseInfo.Namespace
+	event.Env = e.releaseInfo.Env
+	event.Images = e.releaseInfo.Images
+	defer e.mutex.Unlock()
+
+	// the event emitted concurrently with Finish is dropped
+	if e.closed {
+		return
+	}
+
+	// the send does not block the deploy process and the emitter mutex when the delivery is stuck
+	select {
+	case e.queue <- &event:
+	default:
+		e.warn("WARNING: events queue is full, %s event is dropped\n", event.Type)
+	}
+}
+
+func (e *emitter) close() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.closed = true
+	close(e.queue)
+}
+
+func (e *emitter) run() {
+	defer close(e.done)
+
+	for event := range e.queue {
+		if e.isStopped() {
+			continue
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			e.warn("WARNING: unable to marshal %s event: %s\n", event.Type, err)
+			continue
+		}
+
+		if e.file != nil {
+			if _, err := e.file.Write(append(data, '\n')); err != nil {
+				e.warn("WARNING: unable to write %s event to %s: %s\n", event.Type, e.opts.File, err)
+			}
+		}
+
+		for _, url := range e.opts.Webhooks {
+			if err := e.sendWithRetries(url, data); err != nil {
+				e.warn("WARNING: unable to send %s event to webhook %s: %s\n", event.Type, url, err)
+			}
+		}
+	}
+}
+
+// sendWithRetries retries the failed request with the linear backoff: the i-th retry is made in i seconds after the previous attempt
+func (e *emitter) sendWithRetries(url string, data []byte) error {
+	var err error
+	for i := 0; i <= e.opts.WebhookRetries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(time.Duration(i) * time.Second):
+			case <-e.stop:
+				return err
+			}
+		}
+
+		if err = e.send(url, data); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func (e *emitter) stopDelivery() {
+	e.warnMutex.Lock()
+	defer e.warnMutex.Unlock()
+
+	close(e.stop)
+}
+
+func (e *emitter) warn(format string, a ...interface{}) {
+	e.warnMutex.Lock()
+	defer e.warnMutex.Unlock()
+
+	if e.isStopped() {
+		return
+	}
+
+	logboek.Warn().LogF(format, a...)
+}
+
+func (e *emitter) isStopped() bool {
+	select {
+	case <-e.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *emitter) send(url string, data []byte) error {
+	client := &http.Client{Timeout: e.opts.WebhookTimeout}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	var mutex sync.Mutex
+	var requests int
+	var received []Event
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("unable to decode event: %s", err)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "werf-events-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	eventsFile := filepath.Join(tmpDir, "events.jsonl")
+
+	if err := Init(InitOptions{
+		Webhooks:       []string{server.URL},
+		File:           eventsFile,
+		WebhookRetries: 1,
+		Project:        "myproject",
+		GitCommit:      "deadbeef",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	SetReleaseInfo(ReleaseInfo{Release: "myproject-dev", Namespace: "myproject-dev", Env: "dev", Images: map[string]string{"backend": "registry/backend:tag"}})
+	Emit(Event{Type: ReleaseUpgradeStarted})
+	Emit(Event{Type: ResourceFailed, Resource: "deploy/backend", Error: "pod crashed"})
+	Finish(context.Background(), errors.New("deploy failed"))
+
+	expectedTypes := []Type{ReleaseUpgradeStarted, ResourceFailed, Done}
+
+	if len(received) != len(expectedTypes) {
+		t.Fatalf("expected %d webhook events, got %d", len(expectedTypes), len(received))
+	}
+
+	for i, event := range received {
+		if event.Type != expectedTypes[i] {
+			t.Errorf("expected event %s, got %s", expectedTypes[i], event.Type)
+		}
+
+		if event.Release != "myproject-dev" || event.GitCommit != "deadbeef" || event.Images["backend"] != "registry/backend:tag" {
+			t.Errorf("unexpected release info in event %+v", event)
+		}
+	}
+
+	if received[2].Status != StatusFailed || received[2].Error != "deploy failed" {
+		t.Errorf("unexpected done event %+v", received[2])
+	}
+
+	f, err := os.Open(eventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Errorf("unable to decode events file line: %s", err)
+		}
+		lines++
+	}
+
+	if lines != len(expectedTypes) {
+		t.Errorf("expected %d events file lines, got %d", len(expectedTypes), lines)
+	}
+}
+
+func TestFinish_DeadWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := Init(InitOptions{
+		Webhooks:       []string{server.URL},
+		WebhookRetries: 10,
+		FinishTimeout:  500 * time.Millisecond,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	Emit(Event{Type: ReleaseUpgradeStarted})
+
+	start := time.Now()
+	Finish(context.Background(), nil)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Finish should not wait for the dead webhook retries, waited %s", elapsed)
+	}
+
+	if Enabled() {
+		t.Errorf("events should be disabled after Finish")
+	}
+}
+
+func TestEmit_ConcurrentWithFinish(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-events-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := Init(InitOptions{File: filepath.Join(tmpDir, "events.jsonl")}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				Emit(Event{Type: ResourceReady, Resource: "deploy/backend"})
+			}
+		}()
+	}
+
+	Finish(context.Background(), nil)
+	wg.Wait()
+
+	Emit(Event{Type: ResourceReady, Resource: "deploy/backend"})
+}
+
+func TestEmit_FullQueue(t *testing.T) {
+	e := &emitter{
+		queue: make(chan *Event, 1),
+		done:  make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+
+	emitted := make(chan struct{})
+	go func() {
+		defer close(emitted)
+
+		e.emit(Event{Type: ResourceReady, Resource: "deploy/backend"})
+		e.emit(Event{Type: ResourceReady, Resource: "deploy/frontend"})
+	}()
+
+	select {
+	case <-emitted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("emit should not block when the queue is full")
+	}
+
+	if event := <-e.queue; event.Resource != "deploy/backend" {
+		t.Errorf("expected the first event to be queued, got %+v", event)
+	}
+	if len(e.queue) != 0 {
+		t.Errorf("expected the event exceeding the queue to be dropped")
+	}
+}
+
+func TestEvents_ClusterProcess(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-events-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	eventsFile := filepath.Join(tmpDir, "events.jsonl")
+
+	if err := Init(InitOptions{File: eventsFile, Cluster: "eu"}); err != nil {
+		t.Fatal(err)
+	}
+
+	Emit(Event{Type: BuildStarted})
+	Emit(Event{Type: BuildFinished, Status: StatusSucceeded})
+	Emit(Event{Type: ReleaseUpgradeStarted})
+	Finish(context.Background(), nil)
+
+	data, err := ioutil.ReadFile(eventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("single event expected, got %q: %s", data, err)
+	}
+
+	if event.Type != ReleaseUpgradeStarted || event.Cluster != "eu" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
@@ -0,0 +1,164 @@
+package generic_tracker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Condition describes the state of the resource in one of the forms:
+//   - `Type=Status` — status.conditions item with the specified type has the specified status (Ready=True);
+//   - `field.path=value` — field of the resource has the specified value (status.phase=Bound);
+//   - `field.path` — field of the resource is set and not empty (status.loadBalancer.ingress).
+type Condition struct {
+	ConditionType string
+	FieldPath     []string
+	Value         string
+}
+
+func (c *Condition) String() string {
+	var left string
+	if c.ConditionType != "" {
+		left = c.ConditionType
+	} else {
+		left = strings.Join(c.FieldPath, ".")
+	}
+
+	if c.Value == "" {
+		return left
+	}
+	return fmt.Sprintf("%s=%s", left, c.Value)
+}
+
+// ParseConditions parses comma separated list of conditions
+func ParseConditions(value string) ([]*Condition, error) {
+	var conditions []*Condition
+
+	for _, expr := range strings.Split(value, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			return nil, fmt.Errorf("conditions separated by comma expected")
+		}
+
+		parts := strings.SplitN(expr, "=", 2)
+		left := strings.TrimSpace(parts[0])
+		if left == "" {
+			return nil, fmt.Errorf("invalid condition %q: condition type or field path expected", expr)
+		}
+
+		cond := &Condition{}
+		if len(parts) == 2 {
+			cond.Value = strings.TrimSpace(parts[1])
+			if cond.Value == "" {
+				return nil, fmt.Errorf("invalid condition %q: value expected after =", expr)
+			}
+		}
+
+		if strings.Contains(left, ".") {
+			for _, p := range strings.Split(strings.TrimPrefix(left, "."), ".") {
+				if p == "" {
+					return nil, fmt.Errorf("invalid condition %q: bad field path %q", expr, left)
+				}
+				cond.FieldPath = append(cond.FieldPath, p)
+			}
+		} else {
+			if cond.Value == "" {
+				return nil, fmt.Errorf("invalid condition %q: status expected for condition type %s (%s=True)", expr, left, left)
+			}
+			cond.ConditionType = left
+		}
+
+		conditions = append(conditions, cond)
+	}
+
+	return conditions, nil
+}
+
+// Check returns whether the condition is satisfied by the object and the actual state description
+func (c *Condition) Check(obj map[string]interface{}) (bool, string) {
+	if c.ConditionType != "" {
+		statusCondition := findStatusCondition(obj, c.ConditionType)
+		if statusCondition == nil {
+			return false, fmt.Sprintf("%s condition is not set", c.ConditionType)
+		}
+
+		status := fmt.Sprintf("%v", statusCondition["status"])
+		desc := fmt.Sprintf("%s=%s", c.ConditionType, status)
+		if reason, ok := statusCondition["reason"]; ok && reason != "" {
+			desc += fmt.Sprintf(" %v", reason)
+		}
+		if message, ok := statusCondition["message"]; ok && message != "" {
+			desc += fmt.Sprintf(": %v", message)
+		}
+
+		return status == c.Value, desc
+	}
+
+	fieldValue, found := getField(obj, c.FieldPath)
+	fieldPath := strings.Join(c.FieldPath, ".")
+
+	if c.Value == "" {
+		if !found || isEmptyValue(fieldValue) {
+			return false, fmt.Sprintf("%s is empty", fieldPath)
+		}
+		return true, fmt.Sprintf("%s is set", fieldPath)
+	}
+
+	if !found {
+		return false, fmt.Sprintf("%s is not set", fieldPath)
+	}
+
+	value := fmt.Sprintf("%v", fieldValue)
+	return value == c.Value, fmt.Sprintf("%s=%s", fieldPath, value)
+}
+
+func findStatusCondition(obj map[string]interface{}, conditionType string) map[string]interface{} {
+	conditions, found := getField(obj, []string{"status", "conditions"})
+	if !found {
+		return nil
+	}
+
+	conditionsList, ok := conditions.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, c := range conditionsList {
+		if condition, ok := c.(map[string]interface{}); ok && condition["type"] == conditionType {
+			return condition
+		}
+	}
+
+	return nil
+}
+
+func getField(obj map[string]interface{}, fieldPath []string) (interface{}, bool) {
+	var current interface{} = obj
+	for _, field := range fieldPath {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = m[field]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package generic_tracker
+
+import (
+	"testing"
+)
+
+func TestParseConditions(t *testing.T) {
+	conditions, err := ParseConditions("Ready=True, status.phase=Bound,.status.loadBalancer.ingress")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"Ready=True", "status.phase=Bound", "status.loadBalancer.ingress"}
+	if len(conditions) != len(expected) {
+		t.Fatalf("expected %d conditions, got %d", len(expected), len(conditions))
+	}
+
+	for i, cond := range conditions {
+		if cond.String() != expected[i] {
+			t.Errorf("expected condition %q, got %q", expected[i], cond.String())
+		}
+	}
+
+	for _, value := range []string{"", "Ready", "Ready=", "=True", "status..phase=Bound", "Ready=True,"} {
+		if _, err := ParseConditions(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestCondition_Check(t *testing.T) {
+	obj := map[string]interface{}{
+		"status": map[string]interface{}{
+			"phase": "Bound",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False", "reason": "Issuing", "message": "certificate is being issued"},
+			},
+			"loadBalancer": map[string]interface{}{},
+		},
+	}
+
+	for _, tc := range []struct {
+		condition string
+		expected  bool
+	}{
+		{"status.phase=Bound", true},
+		{"status.phase=Lost", false},
+		{"Ready=True", false},
+		{"Ready=False", true},
+		{"Synced=True", false},
+		{"status.loadBalancer", false},
+		{"status.loadBalancer.ingress", false},
+		{"status.conditions", true},
+	} {
+		conditions, err := ParseConditions(tc.condition)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if ok, desc := conditions[0].Check(obj); ok != tc.expected {
+			t.Errorf("condition %q: expected %v, got %v (%s)", tc.condition, tc.expected, ok, desc)
+		}
+	}
+}
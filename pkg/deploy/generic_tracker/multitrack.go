@@ -0,0 +1,70 @@
+package generic_tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/deploy/events"
+)
+
+// Multitrack tracks workloads with the kubedog multitrack and other resources with the generic tracker simultaneously,
+// the first error stops tracking of all resources
+func Multitrack(ctx context.Context, specs multitrack.MultitrackSpecs, genericSpecs []*Spec, opts multitrack.MultitrackOptions) error {
+	if len(genericSpecs) == 0 {
+		return kubedogMultitrack(specs, opts)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts.ParentContext = ctx
+
+	errCh := make(chan error, 2)
+	go func() {
+		errCh <- kubedogMultitrack(specs, opts)
+	}()
+	go func() {
+		errCh <- Track(ctx, genericSpecs, Options{Timeout: opts.Timeout})
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// kubedogMultitrack runs the kubedog multitrack, the resources events are emitted by the kubedog trackers callbacks
+func kubedogMultitrack(specs multitrack.MultitrackSpecs, opts multitrack.MultitrackOptions) error {
+	if events.Enabled() {
+		eventsTracker := startWorkloadEventsTracker(specs, opts.Options)
+		defer eventsTracker.stop()
+	}
+
+	if err := multitrack.Multitrack(kube.Client, specs, opts); err != nil {
+		return err
+	}
+
+	if opts.ParentContext != nil && opts.ParentContext.Err() != nil {
+		return fmt.Errorf("tracking cancelled: %s", opts.ParentContext.Err())
+	}
+
+	return nil
+}
+
+// LogFailedResources prints the failed resources and the reasons of the failure from the tracking error
+func LogFailedResources(ctx context.Context, trackingErr error) {
+	logboek.Context(ctx).LogOptionalLn()
+	logboek.Context(ctx).Default().LogBlock("Failed resources").Do(func() {
+		for _, line := range strings.Split(strings.TrimSpace(trackingErr.Error()), "\n") {
+			logboek.Context(ctx).Default().LogLn(line)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package generic_tracker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+	"github.com/werf/logboek"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+const (
+	ReadyConditionAnnoName  = "werf.io/ready-condition"
+	FailedConditionAnnoName = "werf.io/failed-condition"
+
+	trackTerminationModeAnnoName = "werf.io/track-termination-mode"
+	failModeAnnoName             = "werf.io/fail-mode"
+	showEventsAnnoName           = "werf.io/show-service-messages"
+)
+
+// MakeSpec returns the spec of the resource or nil when the resource should not be tracked,
+// the resource with invalid annotations is not tracked and the warning is printed
+func MakeSpec(ctx context.Context, info *resource.Info, trackByDefault bool) *Spec {
+	spec, err := NewSpec(info, trackByDefault)
+	if err != nil {
+		logboek.Context(ctx).Warn().LogLn()
+		logboek.Context(ctx).Warn().LogF("WARNING %s\n", err)
+		return nil
+	}
+
+	return spec
+}
+
+// NewSpec configures the spec of the resource by the werf.io/* annotations,
+// resources not tracked by default are tracked only when werf.io/ready-condition or werf.io/failed-condition annotation is set
+func NewSpec(info *resource.Info, trackByDefault bool) (*Spec, error) {
+	if info.Mapping == nil {
+		return nil, nil
+	}
+
+	objMeta, err := meta.Accessor(info.Object)
+	if err != nil {
+		return nil, nil
+	}
+
+	annotations := objMeta.GetAnnotations()
+	_, hasReadyCondition := annotations[ReadyConditionAnnoName]
+	_, hasFailedCondition := annotations[FailedConditionAnnoName]
+	if !trackByDefault && !hasReadyCondition && !hasFailedCondition {
+		return nil, nil
+	}
+
+	spec := &Spec{
+		ResourceName:         info.Name,
+		Namespace:            info.Namespace,
+		Kind:                 info.Mapping.GroupVersionKind.Kind,
+		GroupVersionResource: info.Mapping.Resource,
+	}
+
+mainLoop:
+	for annoName, annoValue := range annotations {
+		invalidAnnoValueError := fmt.Errorf("%s annotation %s with invalid value %s", spec.FullName(), annoName, annoValue)
+
+		switch annoName {
+		case ReadyConditionAnnoName:
+			conditions, err := ParseConditions(annoValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", invalidAnnoValueError, err)
+			}
+
+			spec.ReadyConditions = conditions
+		case FailedConditionAnnoName:
+			conditions, err := ParseConditions(annoValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", invalidAnnoValueError, err)
+			}
+
+			spec.FailedConditions = conditions
+		case showEventsAnnoName:
+			boolValue, err := strconv.ParseBool(annoValue)
+			if err != nil {
+				return nil, fmt.Errorf("%s: bool expected: %s", invalidAnnoValueError, err)
+			}
+
+			spec.ShowServiceMessages = boolValue
+		case trackTerminationModeAnnoName:
+			trackTerminationModeValue := multitrack.TrackTerminationMode(annoValue)
+			values := []multitrack.TrackTerminationMode{multitrack.WaitUntilResourceReady, multitrack.NonBlocking}
+			for _, value := range values {
+				if value == trackTerminationModeValue {
+					spec.TrackTerminationMode = trackTerminationModeValue
+					continue mainLoop
+				}
+			}
+
+			return nil, fmt.Errorf("%s: choose one of %v", invalidAnnoValueError, values)
+		case failModeAnnoName:
+			failModeValue := multitrack.FailMode(annoValue)
+			values := []multitrack.FailMode{multitrack.IgnoreAndContinueDeployProcess, multitrack.FailWholeDeployProcessImmediately, multitrack.HopeUntilEndOfDeployProcess}
+			for _, value := range values {
+				if value == failModeValue {
+					spec.FailMode = failModeValue
+					continue mainLoop
+				}
+			}
+
+			return nil, fmt.Errorf("%s: choose one of %v", invalidAnnoValueError, values)
+		}
+	}
+
+	return spec, nil
+}
@@ -0,0 +1,98 @@
+package generic_tracker
+
+import (
+	"testing"
+
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func newTestResourceInfo(kind string, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("networking.k8s.io/v1beta1")
+	obj.SetKind(kind)
+	obj.SetName("app")
+	obj.SetNamespace("default")
+	obj.SetAnnotations(annotations)
+
+	return &resource.Info{
+		Name:      "app",
+		Namespace: "default",
+		Object:    obj,
+		Mapping: &meta.RESTMapping{
+			GroupVersionKind: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: kind},
+			Resource:         schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"},
+		},
+	}
+}
+
+func TestNewSpec(t *testing.T) {
+	spec, err := NewSpec(newTestResourceInfo("Ingress", nil), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if spec == nil {
+		t.Fatalf("resource tracked by default expected")
+	}
+
+	if spec.FullName() != "ingress/app" || spec.Namespace != "default" || spec.GroupVersionResource.Resource != "ingresses" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+
+	if spec, err := NewSpec(newTestResourceInfo("Ingress", nil), false); err != nil || spec != nil {
+		t.Errorf("resource without annotations should not be tracked: %+v, %v", spec, err)
+	}
+
+	spec, err = NewSpec(newTestResourceInfo("Ingress", map[string]string{
+		ReadyConditionAnnoName:       "status.loadBalancer.ingress",
+		FailedConditionAnnoName:      "Failed=True",
+		trackTerminationModeAnnoName: string(multitrack.NonBlocking),
+		failModeAnnoName:             string(multitrack.HopeUntilEndOfDeployProcess),
+		showEventsAnnoName:           "true",
+	}), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if spec == nil {
+		t.Fatalf("resource with ready condition should be tracked")
+	}
+
+	if len(spec.ReadyConditions) != 1 || spec.ReadyConditions[0].String() != "status.loadBalancer.ingress" {
+		t.Errorf("unexpected ready conditions %v", spec.ReadyConditions)
+	}
+	if len(spec.FailedConditions) != 1 || spec.FailedConditions[0].String() != "Failed=True" {
+		t.Errorf("unexpected failed conditions %v", spec.FailedConditions)
+	}
+	if spec.TrackTerminationMode != multitrack.NonBlocking || spec.FailMode != multitrack.HopeUntilEndOfDeployProcess || !spec.ShowServiceMessages {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+
+	for _, annotations := range []map[string]string{
+		{ReadyConditionAnnoName: "Ready"},
+		{trackTerminationModeAnnoName: "Forever"},
+		{failModeAnnoName: "Never"},
+		{showEventsAnnoName: "yes"},
+	} {
+		if _, err := NewSpec(newTestResourceInfo("Ingress", annotations), true); err == nil {
+			t.Errorf("expected error for annotations %v", annotations)
+		}
+	}
+}
+
+func TestCheckResourceState_Ingress(t *testing.T) {
+	obj := newTestResourceInfo("Ingress", nil).Object.(*unstructured.Unstructured)
+	spec := &Spec{ResourceName: "app", Kind: "Ingress"}
+
+	if state, desc := checkResourceState(obj, spec); state != resourceWaiting {
+		t.Errorf("ingress without load balancer address should not be ready: %s (%s)", state, desc)
+	}
+
+	if err := unstructured.SetNestedSlice(obj.Object, []interface{}{map[string]interface{}{"ip": "10.0.0.1"}}, "status", "loadBalancer", "ingress"); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, desc := checkResourceState(obj, spec); state != resourceReady {
+		t.Errorf("ingress with load balancer address should be ready: %s (%s)", state, desc)
+	}
+}
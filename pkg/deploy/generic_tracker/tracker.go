@@ -0,0 +1,354 @@
+package generic_tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/kubedog/pkg/tracker"
+	"github.com/werf/kubedog/pkg/tracker/event"
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+	"github.com/werf/logboek"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/werf/werf/pkg/deploy/events"
+)
+
+const pollPeriod = 2 * time.Second
+
+// Spec describes the resource which readiness is determined by conditions,
+// default conditions are used when ReadyConditions or FailedConditions are not set
+type Spec struct {
+	ResourceName         string
+	Namespace            string
+	Kind                 string
+	GroupVersionResource schema.GroupVersionResource
+
+	ReadyConditions  []*Condition
+	FailedConditions []*Condition
+
+	TrackTerminationMode multitrack.TrackTerminationMode
+	FailMode             multitrack.FailMode
+	ShowServiceMessages  bool
+}
+
+func (spec *Spec) FullName() string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(spec.Kind), spec.ResourceName)
+}
+
+type Options struct {
+	Timeout time.Duration
+}
+
+// Track waits until all resources with the WaitUntilResourceReady track termination mode become ready
+func Track(ctx context.Context, specs []*Spec, opts Options) error {
+	var blockingSpecsCount int
+	for _, spec := range specs {
+		if spec.TrackTerminationMode == "" {
+			spec.TrackTerminationMode = multitrack.WaitUntilResourceReady
+		}
+
+		if spec.FailMode == "" {
+			spec.FailMode = multitrack.FailWholeDeployProcessImmediately
+		}
+
+		if spec.TrackTerminationMode != multitrack.NonBlocking {
+			blockingSpecsCount++
+		}
+	}
+
+	if blockingSpecsCount == 0 {
+		return nil
+	}
+
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	errCh := make(chan error, len(specs))
+	doneCh := make(chan *Spec, len(specs))
+
+	for _, spec := range specs {
+		go func(spec *Spec) {
+			if err := newResourceTracker(spec).Track(ctx); err != nil {
+				errCh <- err
+			} else {
+				doneCh <- spec
+			}
+		}(spec)
+	}
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case spec := <-doneCh:
+			if spec.TrackTerminationMode != multitrack.NonBlocking {
+				blockingSpecsCount--
+				if blockingSpecsCount == 0 {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+type resourceState string
+
+const (
+	resourceWaiting resourceState = "waiting"
+	resourceReady   resourceState = "ready"
+	resourceFailed  resourceState = "failed"
+)
+
+type resourceTracker struct {
+	spec *Spec
+
+	lastStateDesc     string
+	serviceMessages   []string
+	isFailed          bool
+	volumeBindingMode storagev1.VolumeBindingMode
+}
+
+func newResourceTracker(spec *Spec) *resourceTracker {
+	return &resourceTracker{spec: spec}
+}
+
+func (t *resourceTracker) Track(ctx context.Context) error {
+	ticker := time.NewTicker(pollPeriod)
+	defer ticker.Stop()
+
+	messagesCh := make(chan string, 10)
+	failuresCh := make(chan string, 10)
+	informerErrCh := make(chan error, 1)
+	var informerStarted bool
+
+	for {
+		obj, err := kube.DynamicClient.Resource(t.spec.GroupVersionResource).Namespace(t.spec.Namespace).Get(ctx, t.spec.ResourceName, metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+			t.logState(ctx, "resource not found")
+		case err != nil && ctx.Err() == nil:
+			return fmt.Errorf("unable to get %s: %s", t.spec.FullName(), err)
+		case err == nil:
+			if !informerStarted {
+				trk := &tracker.Tracker{Kube: kube.Client, Namespace: t.spec.Namespace, FullResourceName: t.spec.FullName()}
+				event.NewEventInformer(trk, obj).WithChannels(messagesCh, failuresCh, informerErrCh).Run(ctx)
+				informerStarted = true
+			}
+
+			state, desc := checkResourceState(obj, t.spec)
+			if state == resourceWaiting && t.isVolumeBindingDelayed(ctx, obj) {
+				state, desc = resourceReady, "binding is delayed until the first consumer pod is created (WaitForFirstConsumer volume binding mode)"
+			}
+
+			switch state {
+			case resourceReady:
+				logboek.Context(ctx).Default().LogF("%s ready: %s\n", t.spec.FullName(), desc)
+				events.Emit(events.Event{Type: events.ResourceReady, Resource: t.spec.FullName(), Message: desc})
+				return nil
+			case resourceFailed:
+				if err := t.handleFailure(ctx, desc); err != nil {
+					return err
+				}
+
+				if t.spec.FailMode == multitrack.IgnoreAndContinueDeployProcess {
+					return nil
+				}
+			default:
+				t.isFailed = false
+				t.logState(ctx, desc)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				t.logServiceMessages(ctx)
+				events.Emit(events.Event{Type: events.ResourceFailed, Resource: t.spec.FullName(), Error: fmt.Sprintf("timed out waiting for the resource to become ready: %s", t.lastStateDesc)})
+				return fmt.Errorf("%s: timed out waiting for the resource to become ready: %s", t.spec.FullName(), t.lastStateDesc)
+			}
+			return fmt.Errorf("%s: tracking cancelled: %s", t.spec.FullName(), ctx.Err())
+		case msg := <-messagesCh:
+			t.serviceMessages = append(t.serviceMessages, msg)
+			if t.spec.ShowServiceMessages {
+				logboek.Context(ctx).Default().LogF("%s event: %s\n", t.spec.FullName(), msg)
+			}
+		case <-failuresCh:
+		case err := <-informerErrCh:
+			logboek.Context(ctx).Warn().LogF("WARNING: %s\n", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// isVolumeBindingDelayed returns true for the pending PVC with the storage class in the WaitForFirstConsumer volume binding mode:
+// such PVC is bound only when the pod using the PVC is scheduled, so the PVC cannot become Bound before the pod is created
+func (t *resourceTracker) isVolumeBindingDelayed(ctx context.Context, obj *unstructured.Unstructured) bool {
+	if obj.GroupVersionKind().GroupKind() != (schema.GroupKind{Kind: "PersistentVolumeClaim"}) || t.spec.ReadyConditions != nil {
+		return false
+	}
+
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != string(corev1.ClaimPending) {
+		return false
+	}
+
+	if t.volumeBindingMode == "" {
+		t.volumeBindingMode = getVolumeBindingMode(ctx, obj)
+	}
+
+	return t.volumeBindingMode == storagev1.VolumeBindingWaitForFirstConsumer
+}
+
+// getVolumeBindingMode returns the volume binding mode of the storage class of the PVC (the default storage class when storageClassName is not set)
+func getVolumeBindingMode(ctx context.Context, obj *unstructured.Unstructured) storagev1.VolumeBindingMode {
+	storageClassName, found, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+	if !found {
+		storageClassName = obj.GetAnnotations()["volume.beta.kubernetes.io/storage-class"]
+	}
+
+	if storageClassName != "" {
+		storageClass, err := kube.Client.StorageV1().StorageClasses().Get(ctx, storageClassName, metav1.GetOptions{})
+		if err != nil || storageClass.VolumeBindingMode == nil {
+			return storagev1.VolumeBindingImmediate
+		}
+		return *storageClass.VolumeBindingMode
+	}
+
+	storageClasses, err := kube.Client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return storagev1.VolumeBindingImmediate
+	}
+
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" && storageClass.VolumeBindingMode != nil {
+			return *storageClass.VolumeBindingMode
+		}
+	}
+
+	return storagev1.VolumeBindingImmediate
+}
+
+func (t *resourceTracker) logState(ctx context.Context, desc string) {
+	if desc == t.lastStateDesc {
+		return
+	}
+
+	t.lastStateDesc = desc
+	logboek.Context(ctx).Default().LogF("%s: %s\n", t.spec.FullName(), desc)
+}
+
+func (t *resourceTracker) logServiceMessages(ctx context.Context) {
+	if t.spec.ShowServiceMessages {
+		return
+	}
+
+	for _, msg := range t.serviceMessages {
+		logboek.Context(ctx).Default().LogF("%s event: %s\n", t.spec.FullName(), msg)
+	}
+}
+
+func (t *resourceTracker) handleFailure(ctx context.Context, desc string) error {
+	t.lastStateDesc = desc
+
+	switch t.spec.FailMode {
+	case multitrack.IgnoreAndContinueDeployProcess:
+		logboek.Context(ctx).Warn().LogF("WARNING: %s failed: %s\n", t.spec.FullName(), desc)
+		events.Emit(events.Event{Type: events.ResourceFailed, Resource: t.spec.FullName(), Error: desc})
+		return nil
+	case multitrack.HopeUntilEndOfDeployProcess:
+		if !t.isFailed {
+			t.isFailed = true
+			logboek.Context(ctx).Warn().LogF("WARNING: %s failed: %s, will continue waiting until the end of the deploy process\n", t.spec.FullName(), desc)
+		}
+		return nil
+	default:
+		t.logServiceMessages(ctx)
+		events.Emit(events.Event{Type: events.ResourceFailed, Resource: t.spec.FullName(), Error: desc})
+		return fmt.Errorf("%s failed: %s", t.spec.FullName(), desc)
+	}
+}
+
+func checkResourceState(obj *unstructured.Unstructured, spec *Spec) (resourceState, string) {
+	failedConditions := spec.FailedConditions
+	if failedConditions == nil {
+		failedConditions = defaultFailedConditions(obj)
+	}
+
+	for _, cond := range failedConditions {
+		if ok, desc := cond.Check(obj.Object); ok {
+			return resourceFailed, desc
+		}
+	}
+
+	if observedGeneration, found, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration"); found && observedGeneration < obj.GetGeneration() {
+		return resourceWaiting, fmt.Sprintf("waiting for the controller to observe generation %d", obj.GetGeneration())
+	}
+
+	readyConditions := spec.ReadyConditions
+	if readyConditions == nil {
+		readyConditions = defaultReadyConditions(obj)
+	}
+
+	var readyDescs, waitingDescs []string
+	for _, cond := range readyConditions {
+		if ok, desc := cond.Check(obj.Object); ok {
+			readyDescs = append(readyDescs, desc)
+		} else {
+			waitingDescs = append(waitingDescs, fmt.Sprintf("waiting for %s (%s)", cond, desc))
+		}
+	}
+
+	if len(waitingDescs) != 0 {
+		return resourceWaiting, strings.Join(waitingDescs, "; ")
+	}
+
+	if len(readyDescs) == 0 {
+		return resourceReady, "no readiness conditions"
+	}
+
+	return resourceReady, strings.Join(readyDescs, "; ")
+}
+
+func defaultReadyConditions(obj *unstructured.Unstructured) []*Condition {
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Kind: "Service"}:
+		if serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type"); serviceType == "LoadBalancer" {
+			return []*Condition{{FieldPath: []string{"status", "loadBalancer", "ingress"}}}
+		}
+		return nil
+	case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+		return []*Condition{{FieldPath: []string{"status", "phase"}, Value: "Bound"}}
+	case schema.GroupKind{Group: "extensions", Kind: "Ingress"}, schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}:
+		return []*Condition{{FieldPath: []string{"status", "loadBalancer", "ingress"}}}
+	}
+
+	if findStatusCondition(obj.Object, "Ready") != nil {
+		return []*Condition{{ConditionType: "Ready", Value: "True"}}
+	}
+
+	return nil
+}
+
+func defaultFailedConditions(obj *unstructured.Unstructured) []*Condition {
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+		return []*Condition{{FieldPath: []string{"status", "phase"}, Value: "Lost"}}
+	}
+
+	return []*Condition{
+		{ConditionType: "Failed", Value: "True"},
+		{ConditionType: "Stalled", Value: "True"},
+	}
+}
@@ -0,0 +1,163 @@
+package generic_tracker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/werf/kubedog/pkg/kube"
+	"github.com/werf/kubedog/pkg/tracker"
+	"github.com/werf/kubedog/pkg/tracker/controller"
+	"github.com/werf/kubedog/pkg/tracker/daemonset"
+	"github.com/werf/kubedog/pkg/tracker/deployment"
+	"github.com/werf/kubedog/pkg/tracker/job"
+	"github.com/werf/kubedog/pkg/tracker/pod"
+	"github.com/werf/kubedog/pkg/tracker/replicaset"
+	"github.com/werf/kubedog/pkg/tracker/statefulset"
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/werf/pkg/deploy/events"
+)
+
+// workloadEventsGracePeriod is the time to deliver the last resources states to the events trackers after the multitrack is done
+const workloadEventsGracePeriod = 5 * time.Second
+
+type workloadFeed interface {
+	Track(name, namespace string, kube kubernetes.Interface, opts tracker.Options) error
+}
+
+// workloadEventsTracker emits the resource events from the callbacks of the kubedog trackers,
+// the trackers watch the same workloads as the multitrack, but only report the state changes as events
+type workloadEventsTracker struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+func startWorkloadEventsTracker(specs multitrack.MultitrackSpecs, opts tracker.Options) *workloadEventsTracker {
+	parentContext := opts.ParentContext
+	if parentContext == nil {
+		parentContext = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(parentContext)
+	opts.ParentContext = ctx
+
+	t := &workloadEventsTracker{cancel: cancel}
+
+	for _, spec := range specs.Deployments {
+		feed := deployment.NewFeed()
+		t.trackController(feed, feed, "deploy", spec, opts)
+	}
+
+	for _, spec := range specs.StatefulSets {
+		feed := statefulset.NewFeed()
+		t.trackController(feed, feed, "sts", spec, opts)
+	}
+
+	for _, spec := range specs.DaemonSets {
+		feed := daemonset.NewFeed()
+		t.trackController(feed, feed, "ds", spec, opts)
+	}
+
+	for _, spec := range specs.Jobs {
+		t.trackJob(spec, opts)
+	}
+
+	return t
+}
+
+// stop waits for the last states of the resources during the grace period and stops the trackers
+func (t *workloadEventsTracker) stop() {
+	doneCh := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+	case <-time.After(workloadEventsGracePeriod):
+	}
+
+	t.cancel()
+	<-doneCh
+}
+
+func (t *workloadEventsTracker) trackController(controllerFeed controller.ControllerFeed, feed workloadFeed, kind string, spec multitrack.MultitrackSpec, opts tracker.Options) {
+	state := newWorkloadEventsState(kind, spec)
+
+	controllerFeed.OnReady(state.ready)
+	controllerFeed.OnFailed(state.failure)
+	controllerFeed.OnPodError(func(podError replicaset.ReplicaSetPodError) error {
+		if !podError.ReplicaSet.IsNew {
+			return nil
+		}
+
+		return state.podError(podError.PodError)
+	})
+
+	t.track(feed, state, opts)
+}
+
+func (t *workloadEventsTracker) trackJob(spec multitrack.MultitrackSpec, opts tracker.Options) {
+	state := newWorkloadEventsState("job", spec)
+
+	feed := job.NewFeed()
+	feed.OnSucceeded(state.ready)
+	feed.OnFailed(state.failure)
+	feed.OnPodError(state.podError)
+
+	t.track(feed, state, opts)
+}
+
+func (t *workloadEventsTracker) track(feed workloadFeed, state *workloadEventsState, opts tracker.Options) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		// tracking errors are reported by the multitrack
+		_ = feed.Track(state.spec.ResourceName, state.spec.Namespace, kube.Client, opts)
+	}()
+}
+
+// workloadEventsState counts the pod errors the same way as the multitrack does to report the failure when the allowed failures count is exceeded
+type workloadEventsState struct {
+	resource      string
+	spec          multitrack.MultitrackSpec
+	failuresCount int
+}
+
+func newWorkloadEventsState(kind string, spec multitrack.MultitrackSpec) *workloadEventsState {
+	return &workloadEventsState{resource: fmt.Sprintf("%s/%s", kind, spec.ResourceName), spec: spec}
+}
+
+func (s *workloadEventsState) ready() error {
+	events.Emit(events.Event{Type: events.ResourceReady, Resource: s.resource})
+	return tracker.StopTrack
+}
+
+func (s *workloadEventsState) podError(podError pod.PodError) error {
+	return s.failure(fmt.Sprintf("po/%s container/%s: %s", podError.PodName, podError.ContainerName, podError.Message))
+}
+
+func (s *workloadEventsState) failure(reason string) error {
+	if s.spec.FailMode == multitrack.IgnoreAndContinueDeployProcess {
+		return nil
+	}
+
+	// the multitrack allows 1 failure by default
+	allowFailuresCount := 1
+	if s.spec.AllowFailuresCount != nil {
+		allowFailuresCount = *s.spec.AllowFailuresCount
+	}
+
+	s.failuresCount++
+	if s.failuresCount <= allowFailuresCount {
+		return nil
+	}
+
+	events.Emit(events.Event{Type: events.ResourceFailed, Resource: s.resource, Error: reason})
+	return tracker.StopTrack
+}
@@ -0,0 +1,84 @@
+package generic_tracker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/kubedog/pkg/tracker"
+	"github.com/werf/kubedog/pkg/tracker/pod"
+	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
+
+	"github.com/werf/werf/pkg/deploy/events"
+)
+
+func TestWorkloadEventsState(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "werf-workload-events-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	eventsFile := filepath.Join(tmpDir, "events.jsonl")
+	if err := events.Init(events.InitOptions{File: eventsFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	allowFailuresCount := 1
+	backend := newWorkloadEventsState("deploy", multitrack.MultitrackSpec{ResourceName: "backend", AllowFailuresCount: &allowFailuresCount})
+	podError := pod.PodError{PodName: "backend-1", ContainerError: pod.ContainerError{ContainerName: "main", Message: "CrashLoopBackOff"}}
+
+	if err := backend.podError(podError); err != nil {
+		t.Errorf("allowed failure should not stop tracking: %v", err)
+	}
+	if err := backend.podError(podError); err != tracker.StopTrack {
+		t.Errorf("exceeded allowed failures count should stop tracking: %v", err)
+	}
+
+	ignored := newWorkloadEventsState("job", multitrack.MultitrackSpec{ResourceName: "migrate", FailMode: multitrack.IgnoreAndContinueDeployProcess})
+	if err := ignored.failure("BackoffLimitExceeded"); err != nil {
+		t.Errorf("ignored failure should not stop tracking: %v", err)
+	}
+
+	if err := newWorkloadEventsState("sts", multitrack.MultitrackSpec{ResourceName: "db"}).ready(); err != tracker.StopTrack {
+		t.Errorf("ready resource should stop tracking: %v", err)
+	}
+
+	events.Finish(context.Background(), nil)
+
+	f, err := os.Open(eventsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var received []events.Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event events.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, event)
+	}
+
+	expected := []events.Event{
+		{Type: events.ResourceFailed, Resource: "deploy/backend", Error: "po/backend-1 container/main: CrashLoopBackOff"},
+		{Type: events.ResourceReady, Resource: "sts/db"},
+		{Type: events.Done},
+	}
+
+	if len(received) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(received), received)
+	}
+
+	for i := range expected {
+		if received[i].Type != expected[i].Type || received[i].Resource != expected[i].Resource || received[i].Error != expected[i].Error {
+			t.Errorf("expected event %+v, got %+v", expected[i], received[i])
+		}
+	}
+}
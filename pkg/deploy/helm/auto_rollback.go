@@ -0,0 +1,46 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/deploy/events"
+	"github.com/werf/werf/pkg/deploy/generic_tracker"
+)
+
+// autoRollbackRelease rolls back the release to the latest successfully deployed revision after the failed resources tracking
+// and waits for the rolled back resources to become ready, the deploy error is returned in any case
+func autoRollbackRelease(ctx context.Context, releaseName string, opts ChartOptions, trackingErr, deployErr error) error {
+	generic_tracker.LogFailedResources(ctx, trackingErr)
+
+	revision, err := latestSuccessfullyDeployedReleaseRevision(releaseName)
+	if err == ErrNoSuccessfullyDeployedReleaseRevisionFound {
+		logboek.Context(ctx).Warn().LogF("WARNING: Auto rollback skipped: release %s has no successfully deployed revision\n", releaseName)
+		return deployErr
+	} else if err != nil {
+		return fmt.Errorf("%s\nauto rollback failed: get latest successfully deployed release revision failed: %s", deployErr, err)
+	}
+
+	err = logboek.Context(ctx).Default().LogProcess("Rolling back release %s to revision %d", releaseName, revision).DoError(func() error {
+		resourcesWaiter.LogsFromTime = time.Now()
+
+		return ReleaseRollback(releaseName, revision, opts.ThreeWayMergeMode, ReleaseRollbackOptions{
+			releaseRollbackOptions: releaseRollbackOptions{
+				Timeout:       int64(opts.Timeout / time.Second),
+				CleanupOnFail: true,
+				Wait:          true,
+			},
+		})
+	})
+
+	events.Emit(events.Event{Type: events.Rollback, Revision: int(revision), Status: events.StatusByError(err), Error: events.ErrorString(err), Message: "auto rollback after failed resources tracking"})
+
+	if err != nil {
+		return fmt.Errorf("%s\nauto rollback to revision %d failed: %s", deployErr, revision, err)
+	}
+
+	return fmt.Errorf("%s\nrelease %s has been rolled back to revision %d", deployErr, releaseName, revision)
+}
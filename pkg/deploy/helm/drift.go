@@ -0,0 +1,615 @@
+package helm
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gosuri/uitable"
+	yaml_v2 "gopkg.in/yaml.v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/werf/kubedog/pkg/kube"
+)
+
+// DefaultDriftIgnoredManagers are field managers of Kubernetes controllers,
+// fields that were last changed by these managers are not considered as drift (e.g. spec.replicas changed by HPA)
+var DefaultDriftIgnoredManagers = []string{"kube-controller-manager", "kube-scheduler", "kubelet"}
+
+var driftIgnoredMetadataFields = map[string]bool{
+	"creationTimestamp": true,
+	"deletionTimestamp": true,
+	"generation":        true,
+	"managedFields":     true,
+	"resourceVersion":   true,
+	"selfLink":          true,
+	"uid":               true,
+}
+
+type DriftOptions struct {
+	OutputFormat   string
+	ColWidth       uint
+	IgnoreManagers []string
+}
+
+type DriftResult struct {
+	Release   string           `json:"release"`
+	Namespace string           `json:"namespace"`
+	Revision  int32            `json:"revision"`
+	Drifted   bool             `json:"drifted"`
+	Resources []*ResourceDrift `json:"resources"`
+}
+
+type ResourceDrift struct {
+	Resource  string        `json:"resource"`
+	Namespace string        `json:"namespace,omitempty"`
+	Missing   bool          `json:"missing,omitempty"`
+	Fields    []*FieldDrift `json:"fields,omitempty"`
+}
+
+type FieldDrift struct {
+	Path    string      `json:"path"`
+	Applied interface{} `json:"applied"`
+	Live    interface{} `json:"live"`
+}
+
+// driftListMergeKeys are fields that identify items of the lists (containers, env, volumes, ports, etc.),
+// list items are compared by the first key that is set and unique for all items
+var driftListMergeKeys = []string{"name", "mountPath", "containerPort", "port", "devicePath", "ip"}
+
+// Drift compares live objects with the manifest of the last deployed release revision
+// and prints field-level differences that were made outside werf
+func Drift(ctx context.Context, out io.Writer, releaseName string, opts DriftOptions) (*DriftResult, error) {
+	historyResp, err := releaseHistory(releaseName, releaseHistoryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(historyResp.Releases) == 0 {
+		return nil, fmt.Errorf("release %q not found", releaseName)
+	}
+
+	var rls *release.Release
+	for _, r := range historyResp.Releases {
+		if r.Info.Status.Code == release.Status_DEPLOYED && (rls == nil || r.Version > rls.Version) {
+			rls = r
+		}
+	}
+
+	if rls == nil {
+		return nil, fmt.Errorf("release %q has no deployed revision", releaseName)
+	}
+
+	templates, err := GetTemplatesFromReleaseRevision(releaseName, rls.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ReleaseDrift(ctx, releaseName, rls.Namespace, rls.Version, templates, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := PrintDrift(out, result, opts); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ReleaseDrift compares live objects with the templates of the release revision, helm hooks are skipped
+func ReleaseDrift(ctx context.Context, releaseName, namespace string, revision int32, templates ChartTemplates, opts DriftOptions) (*DriftResult, error) {
+	result := &DriftResult{
+		Release:   releaseName,
+		Namespace: namespace,
+		Revision:  revision,
+		Resources: []*ResourceDrift{},
+	}
+
+	ignoredManagers := DefaultDriftIgnoredManagers
+	if len(opts.IgnoreManagers) != 0 {
+		ignoredManagers = append(append([]string{}, ignoredManagers...), opts.IgnoreManagers...)
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kube.Client.Discovery()))
+
+	for _, t := range templates {
+		if _, isHook := t.Metadata.Annotations["helm.sh/hook"]; isHook {
+			continue
+		}
+
+		resourceDrift, err := templateDrift(ctx, mapper, t, namespace, ignoredManagers)
+		if err != nil {
+			return nil, err
+		}
+
+		if resourceDrift != nil {
+			result.Resources = append(result.Resources, resourceDrift)
+		}
+	}
+
+	result.Drifted = len(result.Resources) != 0
+
+	return result, nil
+}
+
+// PrintDrift prints the drift result in the json, yaml or table format
+func PrintDrift(out io.Writer, result *DriftResult, opts DriftOptions) error {
+	var output []byte
+	var err error
+	switch opts.OutputFormat {
+	case "yaml":
+		output, err = yaml.Marshal(result)
+	case "json":
+		output, err = json.Marshal(result)
+	case "table":
+		output = formatDriftAsTable(result, opts.ColWidth)
+	default:
+		return fmt.Errorf("unknown output format %q", opts.OutputFormat)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, string(output))
+
+	return nil
+}
+
+func templateDrift(ctx context.Context, mapper meta.RESTMapper, t Template, namespace string, ignoredManagers []string) (*ResourceDrift, error) {
+	resourceID := fmt.Sprintf("%s/%s", strings.ToLower(t.Kind), t.Metadata.Name)
+
+	gv, err := schema.ParseGroupVersion(t.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: bad apiVersion %q: %s", resourceID, t.Version, err)
+	}
+
+	mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gv.Group, Kind: t.Kind}, gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to get resource mapping: %s", resourceID, err)
+	}
+
+	resourceDrift := &ResourceDrift{Resource: resourceID}
+
+	var resourceClient dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resourceDrift.Namespace = t.Namespace(namespace)
+		resourceClient = kube.DynamicClient.Resource(mapping.Resource).Namespace(resourceDrift.Namespace)
+	} else {
+		resourceClient = kube.DynamicClient.Resource(mapping.Resource)
+	}
+
+	obj, err := resourceClient.Get(ctx, t.Metadata.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		resourceDrift.Missing = true
+		return resourceDrift, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: unable to get live object: %s", resourceID, err)
+	}
+
+	applied, err := templateObject(t)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", resourceID, err)
+	}
+
+	live, err := normalizeObject(obj.Object)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", resourceID, err)
+	}
+
+	controllerFields := managedFieldPaths(obj.GetManagedFields(), ignoredManagers)
+
+	resourceDrift.Fields = compareObjects(applied, live, controllerFields)
+	if len(resourceDrift.Fields) == 0 {
+		return nil, nil
+	}
+
+	return resourceDrift, nil
+}
+
+func templateObject(t Template) (map[string]interface{}, error) {
+	data, err := yaml_v2.Marshal(t)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal manifest: %s", err)
+	}
+
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert manifest: %s", err)
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(jsonData, &obj); err != nil {
+		return nil, fmt.Errorf("unable to convert manifest: %s", err)
+	}
+
+	// stringData is merged into data by the api server
+	if t.Kind == "Secret" {
+		if stringData, ok := obj["stringData"].(map[string]interface{}); ok {
+			data, _ := obj["data"].(map[string]interface{})
+			if data == nil {
+				data = map[string]interface{}{}
+			}
+
+			for key, value := range stringData {
+				data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%v", value)))
+			}
+
+			obj["data"] = data
+			delete(obj, "stringData")
+		}
+	}
+
+	return obj, nil
+}
+
+func normalizeObject(obj map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal live object: %s", err)
+	}
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal live object: %s", err)
+	}
+
+	return res, nil
+}
+
+// managedFieldPaths returns paths of fields owned by the specified managers, list items are denoted by [*]
+func managedFieldPaths(entries []metav1.ManagedFieldsEntry, managers []string) map[string]bool {
+	paths := map[string]bool{}
+
+	for _, entry := range entries {
+		isIgnored := false
+		for _, manager := range managers {
+			if entry.Manager == manager {
+				isIgnored = true
+				break
+			}
+		}
+
+		if !isIgnored || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+
+		collectManagedFieldPaths(fields, nil, paths)
+	}
+
+	return paths
+}
+
+func collectManagedFieldPaths(fields map[string]interface{}, prefix []string, paths map[string]bool) {
+	for key, value := range fields {
+		if key == "." {
+			paths[joinFieldPath(prefix)] = true
+			continue
+		}
+
+		var segment string
+		switch {
+		case strings.HasPrefix(key, "f:"):
+			segment = strings.TrimPrefix(key, "f:")
+		case strings.HasPrefix(key, "k:"), strings.HasPrefix(key, "v:"), strings.HasPrefix(key, "i:"):
+			segment = "[*]"
+		default:
+			continue
+		}
+
+		path := append(append([]string{}, prefix...), segment)
+
+		if child, ok := value.(map[string]interface{}); ok && len(child) != 0 {
+			collectManagedFieldPaths(child, path, paths)
+		} else {
+			paths[joinFieldPath(path)] = true
+		}
+	}
+}
+
+// compareObjects returns differences of fields that are set in the applied object,
+// fields added by the api server and controllers are not compared
+func compareObjects(applied, live map[string]interface{}, controllerFields map[string]bool) []*FieldDrift {
+	var diffs []*FieldDrift
+	compareFields(applied, live, true, nil, controllerFields, &diffs)
+	return diffs
+}
+
+func compareFields(applied, live interface{}, liveExists bool, path []string, controllerFields map[string]bool, diffs *[]*FieldDrift) {
+	if isIgnoredDriftPath(path) || isControllerField(path, controllerFields) {
+		return
+	}
+
+	if !liveExists {
+		if !isEmptyValue(applied) {
+			*diffs = append(*diffs, &FieldDrift{Path: formatFieldPath(path), Applied: applied})
+		}
+		return
+	}
+
+	switch appliedValue := applied.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		var keys []string
+		for key := range appliedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			liveFieldValue, exists := liveValue[key]
+			compareFields(appliedValue[key], liveFieldValue, exists, appendPath(path, key), controllerFields, diffs)
+		}
+
+		return
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			break
+		}
+
+		if key := listMergeKey(appliedValue); key != "" {
+			compareListsByKey(key, appliedValue, liveValue, path, controllerFields, diffs)
+			return
+		}
+
+		for i := 0; i < len(appliedValue) || i < len(liveValue); i++ {
+			itemPath := appendPath(path, fmt.Sprintf("[%d]", i))
+
+			switch {
+			case i >= len(liveValue):
+				compareFields(appliedValue[i], nil, false, itemPath, controllerFields, diffs)
+			case i >= len(appliedValue):
+				if !isControllerField(itemPath, controllerFields) {
+					*diffs = append(*diffs, &FieldDrift{Path: formatFieldPath(itemPath), Live: liveValue[i]})
+				}
+			default:
+				compareFields(appliedValue[i], liveValue[i], true, itemPath, controllerFields, diffs)
+			}
+		}
+
+		return
+	default:
+		if scalarsEqual(applied, live) {
+			return
+		}
+	}
+
+	if isEmptyValue(applied) && isEmptyValue(live) {
+		return
+	}
+
+	*diffs = append(*diffs, &FieldDrift{Path: formatFieldPath(path), Applied: applied, Live: live})
+}
+
+// compareListsByKey compares the list items with the same key value, so the order of the items does not matter
+func compareListsByKey(key string, applied, live []interface{}, path []string, controllerFields map[string]bool, diffs *[]*FieldDrift) {
+	liveItems := map[string]interface{}{}
+	var liveKeys []string
+	for _, item := range live {
+		if keyValue, ok := listItemKeyValue(item, key); ok {
+			liveItems[keyValue] = item
+			liveKeys = append(liveKeys, keyValue)
+		}
+	}
+
+	appliedKeys := map[string]bool{}
+	for _, item := range applied {
+		keyValue, _ := listItemKeyValue(item, key)
+		appliedKeys[keyValue] = true
+
+		liveItem, exists := liveItems[keyValue]
+		compareFields(item, liveItem, exists, appendPath(path, fmt.Sprintf("[%s=%s]", key, keyValue)), controllerFields, diffs)
+	}
+
+	for _, keyValue := range liveKeys {
+		if appliedKeys[keyValue] {
+			continue
+		}
+
+		itemPath := appendPath(path, fmt.Sprintf("[%s=%s]", key, keyValue))
+		if !isControllerField(itemPath, controllerFields) {
+			*diffs = append(*diffs, &FieldDrift{Path: formatFieldPath(itemPath), Live: liveItems[keyValue]})
+		}
+	}
+}
+
+// listMergeKey returns the key that is set and unique for all items of the list or empty string
+func listMergeKey(items []interface{}) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+keysLoop:
+	for _, key := range driftListMergeKeys {
+		values := map[string]bool{}
+		for _, item := range items {
+			value, ok := listItemKeyValue(item, key)
+			if !ok || values[value] {
+				continue keysLoop
+			}
+			values[value] = true
+		}
+
+		return key
+	}
+
+	return ""
+}
+
+func listItemKeyValue(item interface{}, key string) (string, bool) {
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+
+	switch value := itemMap[key].(type) {
+	case string:
+		return value, true
+	case float64, int64, int:
+		return fmt.Sprintf("%v", value), true
+	default:
+		return "", false
+	}
+}
+
+func scalarsEqual(applied, live interface{}) bool {
+	if reflect.DeepEqual(applied, live) {
+		return true
+	}
+
+	if applied == nil || live == nil {
+		return false
+	}
+
+	// quantities are normalized by the api server (cpu: 0.5 → 500m)
+	appliedQuantity, err := resource.ParseQuantity(fmt.Sprintf("%v", applied))
+	if err != nil {
+		return false
+	}
+
+	liveQuantity, err := resource.ParseQuantity(fmt.Sprintf("%v", live))
+	if err != nil {
+		return false
+	}
+
+	return appliedQuantity.Cmp(liveQuantity) == 0
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+
+	return false
+}
+
+func isIgnoredDriftPath(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	if path[0] == "status" {
+		return true
+	}
+
+	return len(path) == 2 && path[0] == "metadata" && driftIgnoredMetadataFields[path[1]]
+}
+
+func isControllerField(path []string, controllerFields map[string]bool) bool {
+	if len(controllerFields) == 0 {
+		return false
+	}
+
+	var normalizedPath []string
+	for _, segment := range path {
+		if strings.HasPrefix(segment, "[") {
+			segment = "[*]"
+		}
+		normalizedPath = append(normalizedPath, segment)
+	}
+
+	for i := 1; i <= len(normalizedPath); i++ {
+		if controllerFields[joinFieldPath(normalizedPath[:i])] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func appendPath(path []string, segment string) []string {
+	return append(append([]string{}, path...), segment)
+}
+
+func joinFieldPath(path []string) string {
+	return strings.Join(path, "\n")
+}
+
+func formatFieldPath(path []string) string {
+	var b strings.Builder
+	for _, segment := range path {
+		switch {
+		case strings.HasPrefix(segment, "["):
+			b.WriteString(segment)
+		case strings.ContainsAny(segment, "./"):
+			fmt.Fprintf(&b, "[%q]", segment)
+		default:
+			if b.Len() != 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(segment)
+		}
+	}
+
+	return b.String()
+}
+
+func formatDriftValue(value interface{}) string {
+	if value == nil {
+		return "<none>"
+	}
+
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(data)
+}
+
+func formatDriftAsTable(result *DriftResult, colWidth uint) []byte {
+	if !result.Drifted {
+		return []byte(fmt.Sprintf("No drift found for release %q revision %d", result.Release, result.Revision))
+	}
+
+	tbl := uitable.New()
+	tbl.MaxColWidth = colWidth
+	tbl.AddRow("RESOURCE", "NAMESPACE", "FIELD", "APPLIED", "LIVE")
+
+	for _, resourceDrift := range result.Resources {
+		if resourceDrift.Missing {
+			tbl.AddRow(resourceDrift.Resource, resourceDrift.Namespace, "-", "<exists>", "<not found>")
+			continue
+		}
+
+		for _, fieldDrift := range resourceDrift.Fields {
+			tbl.AddRow(resourceDrift.Resource, resourceDrift.Namespace, fieldDrift.Path, formatDriftValue(fieldDrift.Applied), formatDriftValue(fieldDrift.Live))
+		}
+	}
+
+	return tbl.Bytes()
+}
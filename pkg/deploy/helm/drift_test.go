@@ -0,0 +1,124 @@
+package helm
+
+import (
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCompareObjects(t *testing.T) {
+	applied := parseDriftTestObject(t, `{
+		"metadata": {"name": "app", "annotations": {"werf.io/weight": "1"}},
+		"spec": {
+			"replicas": 2,
+			"template": {"spec": {"containers": [{"name": "app", "image": "app:1", "resources": {"requests": {"cpu": 0.5}}}]}}
+		}
+	}`)
+
+	live := parseDriftTestObject(t, `{
+		"metadata": {"name": "app", "uid": "1", "resourceVersion": "10", "annotations": {"werf.io/weight": "1", "deployment.kubernetes.io/revision": "3"}},
+		"spec": {
+			"replicas": 5,
+			"template": {"spec": {"containers": [
+				{"name": "app", "image": "app:2", "imagePullPolicy": "IfNotPresent", "resources": {"requests": {"cpu": "500m"}}},
+				{"name": "debug", "image": "busybox"}
+			]}}
+		},
+		"status": {"replicas": 5}
+	}`)
+
+	diffs := compareObjects(applied, live, nil)
+
+	expected := map[string]bool{
+		"spec.replicas": true,
+		"spec.template.spec.containers[name=app].image": true,
+		"spec.template.spec.containers[name=debug]":     true,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d differences, got %d: %s", len(expected), len(diffs), driftTestJSON(diffs))
+	}
+
+	for _, diff := range diffs {
+		if !expected[diff.Path] {
+			t.Errorf("unexpected difference %s", driftTestJSON(diff))
+		}
+	}
+
+	controllerFields := managedFieldPaths([]metav1.ManagedFieldsEntry{
+		{Manager: "kube-controller-manager", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)}},
+		{Manager: "kubectl", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{"k:{\"name\":\"debug\"}":{".":{}}}}}}}`)}},
+	}, DefaultDriftIgnoredManagers)
+
+	diffs = compareObjects(applied, live, controllerFields)
+	if len(diffs) != 2 {
+		t.Fatalf("expected 2 differences, got %d: %s", len(diffs), driftTestJSON(diffs))
+	}
+
+	for _, diff := range diffs {
+		if diff.Path == "spec.replicas" {
+			t.Errorf("difference of the field owned by controller is not expected")
+		}
+	}
+}
+
+func TestCompareObjects_ListsByKey(t *testing.T) {
+	applied := parseDriftTestObject(t, `{
+		"spec": {"containers": [{
+			"name": "app",
+			"env": [{"name": "A", "value": "1"}, {"name": "B", "value": "2"}],
+			"volumeMounts": [{"mountPath": "/data", "name": "data"}, {"mountPath": "/cache", "name": "data", "subPath": "cache"}],
+			"args": ["--port", "8080"]
+		}]}
+	}`)
+
+	live := parseDriftTestObject(t, `{
+		"spec": {"containers": [{
+			"name": "app",
+			"env": [{"name": "C", "value": "3"}, {"name": "B", "value": "2"}, {"name": "A", "value": "1"}],
+			"volumeMounts": [{"mountPath": "/cache", "name": "data", "subPath": "cache"}, {"mountPath": "/data", "name": "data"}],
+			"args": ["--port", "9090"]
+		}]}
+	}`)
+
+	diffs := compareObjects(applied, live, nil)
+
+	expected := map[string]bool{
+		"spec.containers[name=app].env[name=C]": true,
+		"spec.containers[name=app].args[1]":     true,
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d differences, got %d: %s", len(expected), len(diffs), driftTestJSON(diffs))
+	}
+
+	for _, diff := range diffs {
+		if !expected[diff.Path] {
+			t.Errorf("unexpected difference %s", driftTestJSON(diff))
+		}
+	}
+}
+
+func TestFormatFieldPath(t *testing.T) {
+	path := formatFieldPath([]string{"metadata", "annotations", "werf.io/weight"})
+	if path != `metadata.annotations["werf.io/weight"]` {
+		t.Errorf("unexpected path %s", path)
+	}
+
+	path = formatFieldPath([]string{"spec", "containers", "[0]", "image"})
+	if path != "spec.containers[0].image" {
+		t.Errorf("unexpected path %s", path)
+	}
+}
+
+func parseDriftTestObject(t *testing.T, data string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &obj); err != nil {
+		t.Fatalf("unable to parse object: %s", err)
+	}
+	return obj
+}
+
+func driftTestJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
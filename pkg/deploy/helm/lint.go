@@ -1,106 +1,334 @@
 package helm
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"path/filepath"
+	"regexp"
 	"strings"
 
+	"github.com/ghodss/yaml"
+
 	"k8s.io/helm/pkg/lint/rules"
 	"k8s.io/helm/pkg/lint/support"
+
+	"github.com/werf/werf/pkg/werf"
+)
+
+const (
+	LintOutputFormatText  = "text"
+	LintOutputFormatJSON  = "json"
+	LintOutputFormatSARIF = "sarif"
+
+	// ChartLintRuleID is the rule of the helm chart checks (values.yaml format, templates rendering, etc.)
+	ChartLintRuleID = "chart"
 )
 
+var manifestSourceRegexp = regexp.MustCompile(`(?m)^# Source: [^/]+/(.+)$`)
+
 type LintOptions struct {
 	Strict bool
+	// OutputFormat is text, json or sarif
+	OutputFormat string
+	// RulesSeverity overrides the default severity of the rules, the rule is disabled with the off severity
+	RulesSeverity map[string]LintSeverity
+	// WerfImages are the full names of the images built by werf
+	WerfImages []string
+	// RenderTemplates renders the chart templates checked by the rules, the chart is rendered with helm 2 by default
+	RenderTemplates func() (string, error)
+}
+
+type LintResult struct {
+	RuleID   string       `json:"ruleId"`
+	Severity LintSeverity `json:"severity"`
+	Source   string       `json:"source,omitempty"`
+	Resource string       `json:"resource,omitempty"`
+	Message  string       `json:"message"`
 }
 
 func Lint(ctx context.Context, out io.Writer, chartPath, namespace string, values []string, secretValues []map[string]interface{}, set, setString []string, opts LintOptions) error {
-	var lowestTolerance int
-	if opts.Strict {
-		lowestTolerance = support.WarningSev
-	} else {
-		lowestTolerance = support.ErrorSev
-	}
-
-	var total int
-	var failures int
-	if linter, err := lintChart(ctx, chartPath, namespace, values, secretValues, set, setString); err != nil {
-		fmt.Fprintln(out, "==> Skipping", chartPath)
-		fmt.Fprintln(out, err)
-	} else {
-		fmt.Fprintln(out, "==> Linting", chartPath)
-
-		if len(linter.Messages) == 0 {
-			fmt.Fprintln(out, "Lint OK")
+	// Using abs path to get directory context
+	chartDir, _ := filepath.Abs(chartPath)
+
+	linter := support.Linter{ChartDir: chartDir}
+	rules.Values(&linter)
+
+	renderTemplates := opts.RenderTemplates
+	if renderTemplates == nil {
+		renderTemplates = func() (string, error) {
+			return getRawTemplatesFromChart(ctx, chartPath, "RELEASE_NAME", namespace, values, secretValues, set, setString)
 		}
+	}
+
+	target, err := lintTarget(chartPath, namespace, values, renderTemplates, opts.WerfImages)
+	linter.RunLinterRule(support.ErrorSev, chartPath, err)
+
+	var results []LintResult
+	for _, msg := range linter.Messages {
+		results = append(results, LintResult{
+			RuleID:   ChartLintRuleID,
+			Severity: lintSeverityBySupportSeverity(msg.Severity),
+			Source:   msg.Path,
+			Message:  msg.Err.Error(),
+		})
+	}
+
+	if target != nil {
+		for _, rule := range lintRules {
+			severity := rule.DefaultSeverity
+			if s, ok := opts.RulesSeverity[rule.ID]; ok {
+				severity = s
+			}
+
+			if severity == LintSeverityOff {
+				continue
+			}
 
-		for _, msg := range linter.Messages {
-			fmt.Fprintln(out, msg)
+			for _, problem := range rule.Check(*target) {
+				results = append(results, LintResult{
+					RuleID:   rule.ID,
+					Severity: severity,
+					Source:   problem.Source,
+					Resource: problem.Resource,
+					Message:  problem.Message,
+				})
+			}
 		}
+	}
 
-		total = total + 1
-		if linter.HighestSeverity >= lowestTolerance {
-			failures = failures + 1
+	var failed bool
+	for _, result := range results {
+		if result.Severity == LintSeverityError || (opts.Strict && result.Severity == LintSeverityWarning) {
+			failed = true
 		}
 	}
-	fmt.Fprintln(out)
 
-	msg := fmt.Sprintf("%d chart(s) linted", total)
-	if failures > 0 {
-		return fmt.Errorf("%s, %d chart(s) failed", msg, failures)
+	switch opts.OutputFormat {
+	case LintOutputFormatJSON:
+		err = writeLintJSON(out, chartPath, results, failed)
+	case LintOutputFormatSARIF:
+		err = writeLintSARIF(out, results, opts.RulesSeverity)
+	case LintOutputFormatText, "":
+		writeLintText(out, chartPath, results, failed)
+	default:
+		return fmt.Errorf("unknown output format %q: choose one of %v", opts.OutputFormat, []string{LintOutputFormatText, LintOutputFormatJSON, LintOutputFormatSARIF})
 	}
 
-	fmt.Fprintf(out, "%s, no failures\n", msg)
+	if err != nil {
+		return err
+	}
+
+	if failed {
+		return fmt.Errorf("1 chart(s) linted, 1 chart(s) failed")
+	}
 
 	return nil
 }
 
-func lintChart(ctx context.Context, chartPath string, namespace string, values []string, secretValues []map[string]interface{}, set, setString []string) (support.Linter, error) {
-	linter := support.Linter{}
+func lintTarget(chartPath, namespace string, values []string, renderTemplates func() (string, error), werfImages []string) (*LintTarget, error) {
+	rawTemplates, err := renderTemplates()
+	if err != nil {
+		return nil, err
+	}
 
-	// Using abs path to get directory context
-	chartDir, _ := filepath.Abs(chartPath)
+	target := &LintTarget{Namespace: namespace, WerfImages: map[string]bool{}}
+	if target.Manifests, err = lintManifests(rawTemplates); err != nil {
+		return nil, err
+	}
 
-	linter.ChartDir = chartDir
+	// secret values and --set options are the proper way to pass secrets, so only values files are checked
+	sources, err := valuesSources(chartPath, ValidateValuesOptions{Values: values})
+	if err != nil {
+		return nil, err
+	}
+	target.Values, target.ValuesOrigins = mergeValuesSources(sources)
 
-	rules.Values(&linter)
-	templatesRules(ctx, &linter, chartPath, namespace, values, secretValues, set, setString)
+	for _, image := range werfImages {
+		target.WerfImages[image] = true
+	}
 
-	return linter, nil
+	return target, nil
 }
 
-func templatesRules(ctx context.Context, linter *support.Linter, chartPath, namespace string, values []string, secretValues []map[string]interface{}, set, setString []string) {
-	templates, err := GetTemplatesFromChart(ctx, chartPath, "RELEASE_NAME", namespace, values, secretValues, set, setString)
-	linter.RunLinterRule(support.ErrorSev, chartPath, err)
+func lintManifests(rawTemplates string) ([]LintManifest, error) {
+	manifests, err := readManifests(bytes.NewBufferString(rawTemplates))
+	if err != nil {
+		return nil, err
+	}
 
-	for _, template := range templates {
-		metadataName := template.Metadata.Name
-		kind := strings.ToLower(template.Kind)
+	var res []LintManifest
+	for _, m := range manifests {
+		obj := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(m.Raw), &obj); err != nil {
+			return nil, fmt.Errorf("unable to parse manifest: %s", err)
+		}
 
-		_, err := prepareMultitrackSpec(metadataName, kind, template.Namespace(namespace), template.Metadata.Annotations, allowedFailuresCountOptions{})
-		if err != nil {
-			linter.RunLinterRule(support.WarningSev, "templates/", err)
+		lintManifest := LintManifest{Template: m.Template, Object: obj}
+		if match := manifestSourceRegexp.FindStringSubmatch(m.Raw); match != nil {
+			lintManifest.Source = match[1]
 		}
 
-	templateAnnotationsLoop:
-		for annoName := range template.Metadata.Annotations {
-			if strings.HasPrefix(annoName, "werf.io/") {
-				for _, supportedAnnoName := range werfAnnoList {
-					if annoName == supportedAnnoName {
-						continue templateAnnotationsLoop
-					}
-				}
-
-				for _, supportedAnnoPrefix := range werfAnnoPrefixList {
-					if strings.HasPrefix(annoName, supportedAnnoPrefix) {
-						continue templateAnnotationsLoop
-					}
-				}
-
-				err := fmt.Errorf("%s/%s with unknown werf annotation %s", kind, metadataName, annoName)
-				linter.RunLinterRule(support.WarningSev, "templates/", err)
+		res = append(res, lintManifest)
+	}
+
+	return res, nil
+}
+
+func lintSeverityBySupportSeverity(severity int) LintSeverity {
+	switch severity {
+	case support.ErrorSev:
+		return LintSeverityError
+	case support.WarningSev:
+		return LintSeverityWarning
+	default:
+		return LintSeverityInfo
+	}
+}
+
+func writeLintText(out io.Writer, chartPath string, results []LintResult, failed bool) {
+	fmt.Fprintln(out, "==> Linting", chartPath)
+
+	if len(results) == 0 {
+		fmt.Fprintln(out, "Lint OK")
+	}
+
+	for _, result := range results {
+		var parts []string
+		for _, part := range []string{result.Source, result.Resource} {
+			if part != "" {
+				parts = append(parts, part+": ")
 			}
 		}
+
+		msg := fmt.Sprintf("[%s] %s%s", strings.ToUpper(string(result.Severity)), strings.Join(parts, ""), result.Message)
+		if result.RuleID != ChartLintRuleID {
+			msg += fmt.Sprintf(" (%s)", result.RuleID)
+		}
+
+		fmt.Fprintln(out, msg)
+	}
+	fmt.Fprintln(out)
+
+	if !failed {
+		fmt.Fprintln(out, "1 chart(s) linted, no failures")
+	}
+}
+
+func writeLintJSON(out io.Writer, chartPath string, results []LintResult, failed bool) error {
+	if results == nil {
+		results = []LintResult{}
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"chart":   chartPath,
+		"failed":  failed,
+		"results": results,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+func writeLintSARIF(out io.Writer, results []LintResult, rulesSeverity map[string]LintSeverity) error {
+	chartRule := sarifRule{ID: ChartLintRuleID, ShortDescription: sarifMessage{Text: "Chart should be valid and renderable"}}
+	chartRule.DefaultConfiguration.Level = sarifLevel(LintSeverityError)
+
+	sarifRules := []sarifRule{chartRule}
+	for _, rule := range lintRules {
+		severity := rule.DefaultSeverity
+		if s, ok := rulesSeverity[rule.ID]; ok {
+			severity = s
+		}
+
+		r := sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}}
+		r.DefaultConfiguration.Level = sarifLevel(severity)
+		sarifRules = append(sarifRules, r)
+	}
+
+	sarifResults := []sarifResult{}
+	for _, result := range results {
+		msg := result.Message
+		if result.Resource != "" {
+			msg = fmt.Sprintf("%s: %s", result.Resource, msg)
+		}
+
+		r := sarifResult{RuleID: result.RuleID, Level: sarifLevel(result.Severity), Message: sarifMessage{Text: msg}}
+		if result.Source != "" {
+			var location sarifLocation
+			location.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(result.Source)
+			r.Locations = append(r.Locations, location)
+		}
+
+		sarifResults = append(sarifResults, r)
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"$schema": "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []interface{}{
+			map[string]interface{}{
+				"tool": map[string]interface{}{
+					"driver": map[string]interface{}{
+						"name":           "werf",
+						"version":        werf.Version,
+						"informationUri": "https://werf.io",
+						"rules":          sarifRules,
+					},
+				},
+				"results": sarifResults,
+			},
+		},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+func sarifLevel(severity LintSeverity) string {
+	switch severity {
+	case LintSeverityError:
+		return "error"
+	case LintSeverityWarning:
+		return "warning"
+	case LintSeverityOff:
+		return "none"
+	default:
+		return "note"
 	}
 }
@@ -0,0 +1,409 @@
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/helm/pkg/hooks"
+)
+
+type LintSeverity string
+
+const (
+	LintSeverityError   LintSeverity = "error"
+	LintSeverityWarning LintSeverity = "warning"
+	LintSeverityInfo    LintSeverity = "info"
+	LintSeverityOff     LintSeverity = "off"
+)
+
+var (
+	valueKeyWordBoundaryRegexp  = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	valueKeyWordSeparatorRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+	plainSecretValueKeyWords = map[string]bool{"password": true, "passwd": true, "secret": true, "token": true, "credentials": true, "apikey": true, "privatekey": true}
+	// the keys with these words refer to the secrets instead of containing them, e.g. existingSecret, secretName or tokenFile
+	secretReferenceValueKeyWords = map[string]bool{"existing": true, "name": true, "ref": true, "file": true, "path": true, "create": true, "enabled": true}
+)
+
+type LintRule struct {
+	ID              string
+	Description     string
+	DefaultSeverity LintSeverity
+	Check           func(target LintTarget) []LintProblem
+}
+
+// LintTarget is the rendered chart passed to the lint rules
+type LintTarget struct {
+	Namespace string
+	Manifests []LintManifest
+	// Values are the merged regular values of the chart, secret values, service values and --set options are not included
+	Values        map[string]interface{}
+	ValuesOrigins map[string]string
+	// WerfImages are the full names of the images built by werf
+	WerfImages map[string]bool
+}
+
+type LintManifest struct {
+	// Source is the path of the template in the chart
+	Source   string
+	Template Template
+	Object   map[string]interface{}
+}
+
+func (m LintManifest) Resource() string {
+	return fmt.Sprintf("%s/%s", strings.ToLower(m.Template.Kind), m.Template.Metadata.Name)
+}
+
+type LintProblem struct {
+	// Source is the path of the template or the values file
+	Source   string
+	Resource string
+	Message  string
+}
+
+var lintRules = []LintRule{
+	{
+		ID:              "container-resources",
+		Description:     "Containers should have resources requests and limits",
+		DefaultSeverity: LintSeverityInfo,
+		Check:           checkContainerResources,
+	},
+	{
+		ID:              "werf-container-image",
+		Description:     "Images of containers should be built by werf and set with werf_container_image",
+		DefaultSeverity: LintSeverityInfo,
+		Check:           checkWerfContainerImage,
+	},
+	{
+		ID:              "werf-annotation-name",
+		Description:     "Annotations with werf.io/ prefix should be known by werf",
+		DefaultSeverity: LintSeverityWarning,
+		Check:           checkWerfAnnotationName,
+	},
+	{
+		ID:              "werf-annotation-value",
+		Description:     "Values of werf annotations should be valid",
+		DefaultSeverity: LintSeverityError,
+		Check:           checkWerfAnnotationValue,
+	},
+	{
+		ID:              "werf-log-regex",
+		Description:     "Patterns of log-regex annotations should be valid regular expressions",
+		DefaultSeverity: LintSeverityError,
+		Check:           checkWerfLogRegex,
+	},
+	{
+		ID:              "hook-delete-policy",
+		Description:     "Helm hooks should have delete policy",
+		DefaultSeverity: LintSeverityInfo,
+		Check:           checkHookDeletePolicy,
+	},
+	{
+		ID:              "plain-secret-values",
+		Description:     "Secrets should be stored in secret values instead of regular values",
+		DefaultSeverity: LintSeverityInfo,
+		Check:           checkPlainSecretValues,
+	},
+}
+
+// RegisterLintRule adds the rule to the rules used by werf helm lint
+func RegisterLintRule(rule LintRule) {
+	lintRules = append(lintRules, rule)
+}
+
+func LintRules() []LintRule {
+	return lintRules
+}
+
+// ParseLintRulesSeverity parses RULE=SEVERITY items
+func ParseLintRulesSeverity(items []string) (map[string]LintSeverity, error) {
+	res := map[string]LintSeverity{}
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid rule severity %q: RULE=SEVERITY expected", item)
+		}
+
+		ruleID, severity := strings.TrimSpace(parts[0]), LintSeverity(strings.TrimSpace(parts[1]))
+		if _, ok := lintRuleByID(ruleID); !ok {
+			return nil, fmt.Errorf("unknown rule %q: choose one of %v", ruleID, lintRulesIDs())
+		}
+
+		switch severity {
+		case LintSeverityError, LintSeverityWarning, LintSeverityInfo, LintSeverityOff:
+		default:
+			return nil, fmt.Errorf("invalid severity %q of rule %q: choose one of %v", severity, ruleID, []LintSeverity{LintSeverityError, LintSeverityWarning, LintSeverityInfo, LintSeverityOff})
+		}
+
+		res[ruleID] = severity
+	}
+
+	return res, nil
+}
+
+func lintRuleByID(id string) (LintRule, bool) {
+	for _, rule := range lintRules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+
+	return LintRule{}, false
+}
+
+func lintRulesIDs() []string {
+	var res []string
+	for _, rule := range lintRules {
+		res = append(res, rule.ID)
+	}
+
+	return res
+}
+
+func checkContainerResources(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		for _, container := range manifestContainers(m) {
+			resources, _ := container["resources"].(map[string]interface{})
+			for _, field := range []string{"requests", "limits"} {
+				if values, _ := resources[field].(map[string]interface{}); len(values) == 0 {
+					res = append(res, LintProblem{
+						Source:   m.Source,
+						Resource: m.Resource(),
+						Message:  fmt.Sprintf("container %q has no resources %s", container["name"], field),
+					})
+				}
+			}
+		}
+	}
+
+	return res
+}
+
+func checkWerfContainerImage(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		for _, container := range manifestContainers(m) {
+			image, _ := container["image"].(string)
+			if target.WerfImages[image] {
+				continue
+			}
+
+			res = append(res, LintProblem{
+				Source:   m.Source,
+				Resource: m.Resource(),
+				Message:  fmt.Sprintf("container %q image %q is not built by werf, use werf_container_image for the images described in werf.yaml", container["name"], image),
+			})
+		}
+	}
+
+	return res
+}
+
+func checkWerfAnnotationName(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		for _, annoName := range sortedAnnotationsNames(m.Template.Metadata.Annotations) {
+			if strings.HasPrefix(annoName, "werf.io/") && !isWerfAnnotation(annoName) {
+				res = append(res, LintProblem{
+					Source:   m.Source,
+					Resource: m.Resource(),
+					Message:  fmt.Sprintf("unknown werf annotation %s", annoName),
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+func checkWerfAnnotationValue(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		// errors are prefixed with the resource that is already in the problem
+		if _, err := templateWeight(m.Template); err != nil {
+			res = append(res, LintProblem{Source: m.Source, Resource: m.Resource(), Message: strings.TrimPrefix(err.Error(), m.Resource()+": ")})
+		}
+
+		for _, annoName := range sortedAnnotationsNames(m.Template.Metadata.Annotations) {
+			if annoName == WeightAnnoName || isLogRegexAnnotation(annoName) || !isWerfAnnotation(annoName) {
+				continue
+			}
+
+			// each annotation is checked separately to report all invalid values
+			annotations := map[string]string{annoName: m.Template.Metadata.Annotations[annoName]}
+			if _, err := prepareMultitrackSpec(m.Template.Metadata.Name, strings.ToLower(m.Template.Kind), m.Template.Namespace(target.Namespace), annotations, allowedFailuresCountOptions{}); err != nil {
+				res = append(res, LintProblem{Source: m.Source, Resource: m.Resource(), Message: strings.TrimPrefix(err.Error(), m.Resource()+" ")})
+			}
+		}
+	}
+
+	return res
+}
+
+func checkWerfLogRegex(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		for _, annoName := range sortedAnnotationsNames(m.Template.Metadata.Annotations) {
+			if !isLogRegexAnnotation(annoName) {
+				continue
+			}
+
+			annoValue := m.Template.Metadata.Annotations[annoName]
+			if _, err := regexp.Compile(annoValue); err != nil {
+				res = append(res, LintProblem{
+					Source:   m.Source,
+					Resource: m.Resource(),
+					Message:  fmt.Sprintf("annotation %s with invalid regexp %q: %s", annoName, annoValue, err),
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+func checkHookDeletePolicy(target LintTarget) []LintProblem {
+	var res []LintProblem
+	for _, m := range target.Manifests {
+		annotations := m.Template.Metadata.Annotations
+		if _, isHook := annotations[hooks.HookAnno]; !isHook {
+			continue
+		}
+
+		if strings.TrimSpace(annotations[hooks.HookDeleteAnno]) == "" {
+			res = append(res, LintProblem{
+				Source:   m.Source,
+				Resource: m.Resource(),
+				Message:  fmt.Sprintf("hook without %s annotation, the hook resource is kept in the cluster after the hook run", hooks.HookDeleteAnno),
+			})
+		}
+	}
+
+	return res
+}
+
+func checkPlainSecretValues(target LintTarget) []LintProblem {
+	var res []LintProblem
+	walkValues(target.Values, "", func(path, key string, value interface{}) {
+		if !isPlainSecretValueKey(key) {
+			return
+		}
+
+		if str, isString := value.(string); !isString || str == "" {
+			return
+		}
+
+		res = append(res, LintProblem{
+			Source:  keyPathOrigin(path, target.ValuesOrigins),
+			Message: fmt.Sprintf("value %s looks like a secret, move it to the secret values", path),
+		})
+	})
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Message < res[j].Message })
+
+	return res
+}
+
+func isPlainSecretValueKey(key string) bool {
+	words := valueKeyWordSeparatorRegexp.Split(strings.ToLower(valueKeyWordBoundaryRegexp.ReplaceAllString(key, "${1}_${2}")), -1)
+
+	var isSecret bool
+	for i, word := range words {
+		if secretReferenceValueKeyWords[word] {
+			return false
+		}
+
+		if plainSecretValueKeyWords[word] {
+			isSecret = true
+		} else if word == "key" && i > 0 && (words[i-1] == "api" || words[i-1] == "private") {
+			isSecret = true
+		}
+	}
+
+	return isSecret
+}
+
+func walkValues(values map[string]interface{}, prefix string, f func(path, key string, value interface{})) {
+	for key, value := range values {
+		path := prefix + key
+		if nested, ok := value.(map[string]interface{}); ok {
+			walkValues(nested, path+".", f)
+			continue
+		}
+
+		f(path, key, value)
+	}
+}
+
+// manifestContainers returns containers and init containers of the pod template of the workload
+func manifestContainers(m LintManifest) []map[string]interface{} {
+	var podSpec interface{}
+	switch m.Template.Kind {
+	case "Pod":
+		podSpec = nestedValue(m.Object, "spec")
+	case "CronJob":
+		podSpec = nestedValue(m.Object, "spec", "jobTemplate", "spec", "template", "spec")
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		podSpec = nestedValue(m.Object, "spec", "template", "spec")
+	}
+
+	spec, _ := podSpec.(map[string]interface{})
+
+	var res []map[string]interface{}
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := spec[field].([]interface{})
+		for _, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				res = append(res, container)
+			}
+		}
+	}
+
+	return res
+}
+
+func nestedValue(obj map[string]interface{}, fields ...string) interface{} {
+	var value interface{} = obj
+	for _, field := range fields {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[field]
+	}
+
+	return value
+}
+
+func isWerfAnnotation(annoName string) bool {
+	for _, supportedAnnoName := range werfAnnoList {
+		if annoName == supportedAnnoName {
+			return true
+		}
+	}
+
+	for _, supportedAnnoPrefix := range werfAnnoPrefixList {
+		if strings.HasPrefix(annoName, supportedAnnoPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLogRegexAnnotation(annoName string) bool {
+	return annoName == LogRegexAnnoName || strings.HasPrefix(annoName, LogRegexForAnnoPrefix)
+}
+
+func sortedAnnotationsNames(annotations map[string]string) []string {
+	var res []string
+	for name := range annotations {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+
+	return res
+}
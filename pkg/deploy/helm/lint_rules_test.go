@@ -0,0 +1,210 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const lintRulesTestManifests = `---
+# Source: project/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    werf.io/fail-mode: Unknown
+    werf.io/log-regex: "[a-"
+    werf.io/unknown: "1"
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.example.com/project/app:tag
+        resources:
+          requests: {cpu: 100m}
+          limits: {cpu: 100m}
+      containers:
+      - name: app
+        image: registry.example.com/project/app:tag
+        resources:
+          requests: {cpu: 100m}
+      - name: redis
+        image: redis:6
+---
+# Source: project/templates/migrate.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: pre-upgrade
+    werf.io/weight: "1"
+    werf.io/log-regex-for-migrate: ".*"
+spec:
+  template:
+    spec:
+      containers:
+      - name: migrate
+        image: registry.example.com/project/app:tag
+        resources:
+          requests: {cpu: 100m}
+          limits: {cpu: 100m}
+`
+
+func TestLintRules(t *testing.T) {
+	manifests, err := lintManifests(lintRulesTestManifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := LintTarget{
+		Manifests: manifests,
+		Values: map[string]interface{}{
+			"db":    map[string]interface{}{"password": "qwerty", "host": "db"},
+			"token": "",
+		},
+		ValuesOrigins: map[string]string{"db": ".helm/values.yaml"},
+		WerfImages:    map[string]bool{"registry.example.com/project/app:tag": true},
+	}
+
+	for ruleID, expected := range map[string][]LintProblem{
+		"container-resources": {
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: `container "app" has no resources limits`},
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: `container "redis" has no resources requests`},
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: `container "redis" has no resources limits`},
+		},
+		"werf-container-image": {
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: `container "redis" image "redis:6" is not built by werf, use werf_container_image for the images described in werf.yaml`},
+		},
+		"werf-annotation-name": {
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: "unknown werf annotation werf.io/unknown"},
+		},
+		"werf-annotation-value": {
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: "annotation werf.io/fail-mode with invalid value Unknown: choose one of [IgnoreAndContinueDeployProcess FailWholeDeployProcessImmediately HopeUntilEndOfDeployProcess]"},
+		},
+		"werf-log-regex": {
+			{Source: "templates/deployment.yaml", Resource: "deployment/app", Message: "annotation werf.io/log-regex with invalid regexp \"[a-\": error parsing regexp: missing closing ]: `[a-`"},
+		},
+		"hook-delete-policy": {
+			{Source: "templates/migrate.yaml", Resource: "job/migrate", Message: "hook without helm.sh/hook-delete-policy annotation, the hook resource is kept in the cluster after the hook run"},
+		},
+		"plain-secret-values": {
+			{Source: ".helm/values.yaml", Message: "value db.password looks like a secret, move it to the secret values"},
+		},
+	} {
+		rule, ok := lintRuleByID(ruleID)
+		if !ok {
+			t.Fatalf("rule %s not found", ruleID)
+		}
+
+		problems := rule.Check(target)
+		if len(problems) != len(expected) {
+			t.Errorf("%s: expected %d problems, got %d: %#v", ruleID, len(expected), len(problems), problems)
+			continue
+		}
+
+		for i := range expected {
+			if problems[i] != expected[i] {
+				t.Errorf("%s: expected problem %#v, got %#v", ruleID, expected[i], problems[i])
+			}
+		}
+	}
+}
+
+func TestIsPlainSecretValueKey(t *testing.T) {
+	for key, expected := range map[string]bool{
+		"password":           true,
+		"dbPassword":         true,
+		"DB_PASSWORD":        true,
+		"apiKey":             true,
+		"api-key":            true,
+		"privateKey":         true,
+		"secretKey":          true,
+		"accessToken":        true,
+		"existingSecret":     false,
+		"secretName":         false,
+		"passwordSecretName": false,
+		"secretKeyRef":       false,
+		"tokenFile":          false,
+		"createSecret":       false,
+		"key":                false,
+		"publicKey":          false,
+		"secretary":          false,
+	} {
+		if res := isPlainSecretValueKey(key); res != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, res)
+		}
+	}
+}
+
+func TestParseLintRulesSeverity(t *testing.T) {
+	severity, err := ParseLintRulesSeverity([]string{"container-resources=error", "werf-container-image = off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if severity["container-resources"] != LintSeverityError || severity["werf-container-image"] != LintSeverityOff {
+		t.Errorf("unexpected severity: %v", severity)
+	}
+
+	for _, item := range []string{"container-resources", "unknown=error", "container-resources=fatal"} {
+		if _, err := ParseLintRulesSeverity([]string{item}); err == nil {
+			t.Errorf("expected error for %q", item)
+		}
+	}
+}
+
+func TestWriteLintSARIF(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	if err := writeLintSARIF(buf, []LintResult{
+		{RuleID: "hook-delete-policy", Severity: LintSeverityWarning, Source: "templates/migrate.yaml", Resource: "job/migrate", Message: "hook without delete policy"},
+		{RuleID: "werf-container-image", Severity: LintSeverityInfo, Message: "image is not built by werf"},
+	}, map[string]LintSeverity{"werf-container-image": LintSeverityOff}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []sarifRule `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []sarifResult `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("unable to parse report: %s", err)
+	}
+
+	if report.Version != "2.1.0" || len(report.Runs) != 1 {
+		t.Fatalf("unexpected report: %s", buf.String())
+	}
+
+	run := report.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(lintRules)+1 {
+		t.Errorf("expected %d rules, got %d", len(lintRules)+1, len(run.Tool.Driver.Rules))
+	}
+
+	for _, rule := range run.Tool.Driver.Rules {
+		if rule.ID == "werf-container-image" && rule.DefaultConfiguration.Level != "none" {
+			t.Errorf("unexpected level %q of the disabled rule", rule.DefaultConfiguration.Level)
+		}
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(run.Results))
+	}
+
+	if res := run.Results[0]; res.Level != "warning" || res.Locations[0].PhysicalLocation.ArtifactLocation.URI != "templates/migrate.yaml" || !strings.HasPrefix(res.Message.Text, "job/migrate: ") {
+		t.Errorf("unexpected result: %#v", res)
+	}
+
+	if res := run.Results[1]; res.Level != "note" || len(res.Locations) != 0 {
+		t.Errorf("unexpected result: %#v", res)
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/werf/logboek"
 	"github.com/werf/logboek/pkg/types"
 
+	"github.com/werf/werf/pkg/deploy/events"
+	"github.com/werf/werf/pkg/deploy/generic_tracker"
 	"github.com/werf/werf/pkg/kubeutils"
 	"github.com/werf/werf/pkg/util"
 	"github.com/werf/werf/pkg/werf"
@@ -33,6 +35,11 @@ const (
 	ShowLogsUntilAnnoName         = "werf.io/show-logs-until"
 
 	ShowEventsAnnoName = "werf.io/show-service-messages"
+
+	ReadyConditionAnnoName  = generic_tracker.ReadyConditionAnnoName
+	FailedConditionAnnoName = generic_tracker.FailedConditionAnnoName
+
+	WeightAnnoName = "werf.io/weight"
 )
 
 var (
@@ -46,6 +53,9 @@ var (
 		ShowLogsOnlyForContainers,
 		ShowLogsUntilAnnoName,
 		ShowEventsAnnoName,
+		ReadyConditionAnnoName,
+		FailedConditionAnnoName,
+		WeightAnnoName,
 		helm_kube.SetReplicasOnlyOnCreationAnnotation,
 		helm_kube.SetResourcesOnlyOnCreationAnnotation,
 	}
@@ -154,6 +164,7 @@ type ChartOptions struct {
 	DryRun            bool
 	Debug             bool
 	ThreeWayMergeMode ThreeWayMergeModeType
+	AutoRollback      bool
 
 	ChartValuesOptions
 }
@@ -356,7 +367,10 @@ func DeployHelmChart(ctx context.Context, chartPath, releaseName, namespace stri
 						panic("unexpected")
 					}
 
-					return runDeployProcess(ctx, releaseName, namespace, opts, templatesFromRevision, rollbackFunc)
+					err := runDeployProcess(ctx, releaseName, namespace, opts, templatesFromRevision, rollbackFunc)
+					events.Emit(events.Event{Type: events.Rollback, Revision: int(latestSuccessfullyDeployedRevision), Status: events.StatusByError(err), Error: events.ErrorString(err), Message: "rollback of the failed release before deploy"})
+
+					return err
 				}); err != nil {
 				return err
 			}
@@ -459,7 +473,15 @@ func DeployHelmChart(ctx context.Context, chartPath, releaseName, namespace stri
 		return err
 	}
 
-	return runDeployProcess(ctx, releaseName, namespace, opts, templatesFromChart, deployFunc)
+	if err := runDeployProcess(ctx, releaseName, namespace, opts, templatesFromChart, deployFunc); err != nil {
+		if opts.AutoRollback && !opts.DryRun && resourcesWaiter.TrackingErr != nil {
+			return autoRollbackRelease(ctx, releaseName, opts, resourcesWaiter.TrackingErr, err)
+		}
+
+		return err
+	}
+
+	return nil
 }
 
 func latestSuccessfullyDeployedReleaseRevision(releaseName string) (int32, error) {
@@ -480,6 +502,7 @@ func latestSuccessfullyDeployedReleaseRevision(releaseName string) (int32, error
 func runDeployProcess(ctx context.Context, releaseName, namespace string, _ ChartOptions, templates ChartTemplates, deployFunc func() error) error {
 	oldLogsFromTime := resourcesWaiter.LogsFromTime
 	resourcesWaiter.LogsFromTime = time.Now()
+	resourcesWaiter.TrackingErr = nil
 	defer func() {
 		resourcesWaiter.LogsFromTime = oldLogsFromTime
 	}()
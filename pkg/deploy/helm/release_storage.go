@@ -0,0 +1,55 @@
+package helm
+
+import (
+	"sort"
+
+	"k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// ReleaseStorageReleaseNames returns names of all releases of the Helm 2 release storage
+func ReleaseStorageReleaseNames() ([]string, error) {
+	releases, err := tillerSettings.Releases.ListReleases()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	namesSet := map[string]bool{}
+	for _, rel := range releases {
+		if !namesSet[rel.Name] {
+			namesSet[rel.Name] = true
+			names = append(names, rel.Name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ReleaseStorageHistory returns all revisions of the release from the Helm 2 release storage sorted by the revision
+func ReleaseStorageHistory(releaseName string) ([]*release.Release, error) {
+	releases, err := tillerSettings.Releases.History(releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Version < releases[j].Version })
+
+	return releases, nil
+}
+
+// DeleteReleaseFromStorage removes all revisions of the release from the Helm 2 release storage, release resources are kept
+func DeleteReleaseFromStorage(releaseName string) error {
+	releases, err := tillerSettings.Releases.History(releaseName)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range releases {
+		if _, err := tillerSettings.Releases.Delete(rel.Name, rel.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package helm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	RedactedValue         = "<redacted>"
+	KustomizationFileName = "kustomization.yaml"
+	// RenderedFilesListName is the list of the files written by the previous render into the output dir
+	RenderedFilesListName = ".werf-render"
+)
+
+var manifestFileNameInvalidCharsRegexp = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+type ManifestsDirOptions struct {
+	Kustomization bool
+}
+
+// RedactSecretValues returns a copy of the secret values with the leaf values redacted keeping their types:
+// strings are replaced with the RedactedValue, numbers with zero and booleans are kept as is,
+// so that the templates that check or format the values are rendered the same way
+func RedactSecretValues(secretValues []map[string]interface{}) []map[string]interface{} {
+	var res []map[string]interface{}
+	for _, values := range secretValues {
+		res = append(res, redactValue(values).(map[string]interface{}))
+	}
+
+	return res
+}
+
+// RedactSecretFilesData returns a copy of the decoded secret files data with the content replaced with the RedactedValue
+func RedactSecretFilesData(secretFilesData map[string]string) map[string]string {
+	res := make(map[string]string, len(secretFilesData))
+	for path := range secretFilesData {
+		res[path] = RedactedValue
+	}
+
+	return res
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[key] = redactValue(val)
+		}
+		return res
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for key, val := range v {
+			res[key] = redactValue(val)
+		}
+		return res
+	case []interface{}:
+		var res []interface{}
+		for _, val := range v {
+			res = append(res, redactValue(val))
+		}
+		return res
+	case string:
+		return RedactedValue
+	case nil, bool:
+		return v
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return reflect.Zero(rv.Type()).Interface()
+		default:
+			return RedactedValue
+		}
+	}
+}
+
+// RedactSecretManifests replaces data of Secret resources in the rendered manifests stream with the RedactedValue
+func RedactSecretManifests(manifestsStream string) (string, error) {
+	manifests, err := readManifests(bytes.NewBufferString(manifestsStream))
+	if err != nil {
+		return "", err
+	}
+
+	for _, m := range manifests {
+		if m.Template.Kind != "Secret" {
+			continue
+		}
+
+		if err := redactSecretManifest(m); err != nil {
+			return "", fmt.Errorf("secret/%s: %s", m.Template.Metadata.Name, err)
+		}
+	}
+
+	var res []string
+	for _, m := range manifests {
+		res = append(res, "---\n"+strings.TrimSuffix(m.Raw, "\n"))
+	}
+
+	return strings.Join(res, "\n") + "\n", nil
+}
+
+func redactSecretManifest(m *resourceManifest) error {
+	var obj yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(m.Raw), &obj); err != nil {
+		return err
+	}
+
+	for i, item := range obj {
+		if item.Key != "data" && item.Key != "stringData" {
+			continue
+		}
+
+		fields, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+
+		for j := range fields {
+			if item.Key == "data" {
+				fields[j].Value = base64.StdEncoding.EncodeToString([]byte(RedactedValue))
+			} else {
+				fields[j].Value = RedactedValue
+			}
+		}
+		obj[i].Value = fields
+	}
+
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	m.Raw = manifestComments(m.Raw) + string(data)
+
+	return nil
+}
+
+// WriteManifestsToDir writes each manifest of the rendered stream to the <dir>/[<namespace>_]<kind>-<name>.yaml file,
+// the namespace prefix is added for the manifests with metadata.namespace. The files written by the previous render are removed,
+// the written files are listed in the RenderedFilesListName file, other files of the directory are kept
+func WriteManifestsToDir(manifestsStream, dir string, opts ManifestsDirOptions) error {
+	manifests, err := readManifests(bytes.NewBufferString(manifestsStream))
+	if err != nil {
+		return err
+	}
+
+	var fileNames []string
+	manifestByFileName := map[string]*resourceManifest{}
+	for _, m := range manifests {
+		fileName := manifestFileName(m.Template)
+		if _, exists := manifestByFileName[fileName]; exists {
+			return fmt.Errorf("unable to write %s/%s: file %s already written for another resource", strings.ToLower(m.Template.Kind), m.Template.Metadata.Name, fileName)
+		}
+
+		manifestByFileName[fileName] = m
+		fileNames = append(fileNames, fileName)
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	if err := removeRenderedFiles(dir); err != nil {
+		return err
+	}
+
+	for _, fileName := range fileNames {
+		data := strings.TrimPrefix(strings.TrimSuffix(manifestByFileName[fileName].Raw, "\n"), "---\n") + "\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, fileName), []byte(data), 0644); err != nil {
+			return err
+		}
+	}
+
+	renderedFiles := fileNames
+	if opts.Kustomization {
+		if err := writeKustomization(dir, fileNames); err != nil {
+			return err
+		}
+		renderedFiles = append(renderedFiles, KustomizationFileName)
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, RenderedFilesListName), []byte(strings.Join(renderedFiles, "\n")+"\n"), 0644)
+}
+
+func writeKustomization(dir string, resources []string) error {
+	kustomization := yaml.MapSlice{
+		{Key: "apiVersion", Value: "kustomize.config.k8s.io/v1beta1"},
+		{Key: "kind", Value: "Kustomization"},
+		{Key: "resources", Value: resources},
+	}
+
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, KustomizationFileName), data, 0644)
+}
+
+func removeRenderedFiles(dir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, RenderedFilesListName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, fileName := range strings.Split(string(data), "\n") {
+		// only the files of the directory itself are listed
+		if fileName == "" || fileName == "." || fileName == ".." || fileName != filepath.Base(fileName) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, fileName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// manifestFileName separates the namespace with the underscore, which is not allowed in the resource names
+func manifestFileName(t Template) string {
+	name := sanitizeManifestFileNamePart(fmt.Sprintf("%s-%s", t.Kind, t.Metadata.Name))
+	if t.Metadata.Namespace != "" {
+		name = sanitizeManifestFileNamePart(t.Metadata.Namespace) + "_" + name
+	}
+
+	return name + ".yaml"
+}
+
+func sanitizeManifestFileNamePart(part string) string {
+	return manifestFileNameInvalidCharsRegexp.ReplaceAllString(strings.ToLower(part), "-")
+}
+
+func manifestComments(raw string) string {
+	var res string
+	for _, line := range strings.Split(raw, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			break
+		}
+		res += line + "\n"
+	}
+
+	return res
+}
@@ -0,0 +1,141 @@
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const renderOutputTestManifests = `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+# Source: app/templates/deployment-staging.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: staging
+---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  password: cXdlcnR5
+stringData:
+  token: secret
+---
+# Source: app/templates/clusterrole.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:app
+`
+
+func TestWriteManifestsToDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-render-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the files of the previous render are removed, other files of the directory are kept
+	if err := WriteManifestsToDir("---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: stale\n", dir, ManifestsDirOptions{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "configmap-user.yaml"), []byte("user"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteManifestsToDir(renderOutputTestManifests, dir, ManifestsDirOptions{Kustomization: true}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fileNames []string
+	for _, f := range files {
+		fileNames = append(fileNames, f.Name())
+	}
+
+	expected := ".werf-render clusterrole-system-app.yaml configmap-user.yaml deployment-app.yaml kustomization.yaml secret-app.yaml staging_deployment-app.yaml"
+	if strings.Join(fileNames, " ") != expected {
+		t.Errorf("expected files %q, got %q", expected, strings.Join(fileNames, " "))
+	}
+
+	kustomization, err := ioutil.ReadFile(filepath.Join(dir, KustomizationFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedKustomization := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- deployment-app.yaml
+- staging_deployment-app.yaml
+- secret-app.yaml
+- clusterrole-system-app.yaml
+`
+	if string(kustomization) != expectedKustomization {
+		t.Errorf("unexpected kustomization:\n%s", kustomization)
+	}
+}
+
+func TestRedactSecretManifests(t *testing.T) {
+	res, err := RedactSecretManifests(renderOutputTestManifests)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, secret := range []string{"cXdlcnR5", "token: secret"} {
+		if strings.Contains(res, secret) {
+			t.Errorf("secret %q is not redacted:\n%s", secret, res)
+		}
+	}
+
+	for _, expected := range []string{"# Source: app/templates/secret.yaml\n", "password: PHJlZGFjdGVkPg==", "token: <redacted>", "name: system:app"} {
+		if !strings.Contains(res, expected) {
+			t.Errorf("expected %q in:\n%s", expected, res)
+		}
+	}
+}
+
+func TestRedactSecretValues(t *testing.T) {
+	res := RedactSecretValues([]map[string]interface{}{
+		{
+			"password": "qwerty",
+			"port":     5432,
+			"ratio":    0.5,
+			"enabled":  true,
+			"empty":    nil,
+			"nested":   map[interface{}]interface{}{"token": "secret", "replicas": uint64(3)},
+			"list":     []interface{}{"a", int64(1), false},
+		},
+	})
+
+	expected := map[string]interface{}{
+		"password": RedactedValue,
+		"port":     0,
+		"ratio":    0.0,
+		"enabled":  true,
+		"empty":    nil,
+		"nested":   map[interface{}]interface{}{"token": RedactedValue, "replicas": uint64(0)},
+		"list":     []interface{}{RedactedValue, int64(0), false},
+	}
+
+	if len(res) != 1 || !reflect.DeepEqual(res[0], expected) {
+		t.Errorf("expected %#v, got %#v", expected, res)
+	}
+}
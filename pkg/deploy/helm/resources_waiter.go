@@ -19,12 +19,16 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes/scheme"
 	helmKube "k8s.io/helm/pkg/kube"
+
+	"github.com/werf/werf/pkg/deploy/generic_tracker"
 )
 
 type ResourcesWaiter struct {
@@ -33,6 +37,9 @@ type ResourcesWaiter struct {
 	LogsFromTime              time.Time
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
+
+	// TrackingErr is the last error of the release resources tracking
+	TrackingErr error
 }
 
 func extractSpecReplicas(specReplicas *int32) int {
@@ -44,6 +51,7 @@ func extractSpecReplicas(specReplicas *int32) int {
 
 func (waiter *ResourcesWaiter) WaitForResources(timeout time.Duration, created helmKube.Result) error {
 	specs := multitrack.MultitrackSpecs{}
+	var genericSpecs []*generic_tracker.Spec
 
 	for _, v := range created {
 		switch value := asVersioned(v).(type) {
@@ -146,20 +154,44 @@ func (waiter *ResourcesWaiter) WaitForResources(timeout time.Duration, created h
 		case *appsv1beta2.ReplicaSet:
 		case *appsv1.ReplicaSet:
 		case *v1.PersistentVolumeClaim:
+			if genericSpec := generic_tracker.MakeSpec(waiter.Ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		case *extensions.Ingress, *networkingv1beta1.Ingress:
+			if genericSpec := generic_tracker.MakeSpec(waiter.Ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
 		case *v1.Service:
+			if genericSpec := generic_tracker.MakeSpec(waiter.Ctx, v, value.Spec.Type == v1.ServiceTypeLoadBalancer); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		case *unstructured.Unstructured:
+			// custom resources
+			if genericSpec := generic_tracker.MakeSpec(waiter.Ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		default:
+			if genericSpec := generic_tracker.MakeSpec(waiter.Ctx, v, false); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
 		}
 	}
 
 	// NOTE: use context from resources-waiter object here, will be changed in helm 3
 	logboek.Context(waiter.Ctx).LogOptionalLn()
 	return logboek.Context(waiter.Ctx).LogProcess("Waiting for release resources to become ready").DoError(func() error {
-		return multitrack.Multitrack(kube.Client, specs, multitrack.MultitrackOptions{
+		err := generic_tracker.Multitrack(waiter.Ctx, specs, genericSpecs, multitrack.MultitrackOptions{
 			StatusProgressPeriod: waiter.StatusProgressPeriod,
 			Options: tracker.Options{
 				Timeout:      timeout,
 				LogsFromTime: waiter.LogsFromTime,
 			},
 		})
+		if err != nil {
+			waiter.TrackingErr = err
+		}
+
+		return err
 	})
 }
 
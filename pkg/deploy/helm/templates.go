@@ -99,6 +99,16 @@ func GetTemplatesFromReleaseRevision(releaseName string, revision int32) (ChartT
 	return chartTemplates, nil
 }
 
+// GetTemplatesFromManifest parses templates of the release manifest
+func GetTemplatesFromManifest(manifest string) (ChartTemplates, error) {
+	chartTemplates, err := parseTemplates(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse release manifest: %s", err)
+	}
+
+	return chartTemplates, nil
+}
+
 func GetTemplatesFromChart(ctx context.Context, chartPath, releaseName, namespace string, values []string, secretValues []map[string]interface{}, set, setString []string) (ChartTemplates, error) {
 	rawTemplates, err := getRawTemplatesFromChart(ctx, chartPath, releaseName, namespace, values, secretValues, set, setString)
 	if err != nil {
@@ -261,7 +271,7 @@ func NewWerfEngine() *WerfEngine {
 
 func (e *WerfEngine) InitWerfEngineExtraTemplatesFunctions(decodedSecretFiles map[string]string) {
 	e.AlterFuncMapHookFunc = func(t *template.Template, funcMap template.FuncMap) template.FuncMap {
-		if _, err := t.Funcs(funcMap).Parse(werfEngineHelpers); err != nil {
+		if _, err := t.Funcs(funcMap).Parse(WerfEngineHelpers); err != nil {
 			panic(fmt.Errorf("parse werf engine helpers failed: %s", err))
 		}
 
@@ -338,7 +348,7 @@ func (e *WerfEngine) InitWerfEngineExtraTemplatesFunctions(decodedSecretFiles ma
 	}
 }
 
-var werfEngineHelpers = `{{- define "_image" -}}
+var WerfEngineHelpers = `{{- define "_image" -}}
 {{-   $context := index . 0 -}}
 {{-   if not $context.Values.global.werf.is_nameless_image -}}
 {{-     required "No image specified for template" nil -}}
@@ -155,7 +155,7 @@ func Init(ctx context.Context, options InitOptions) error {
 	}
 	kubeClient.SetResourcesWaiter(resourcesWaiter)
 
-	tillerSettings.KubeClient = kubeClient
+	tillerSettings.KubeClient = &wavesKubeClient{Client: kubeClient}
 	tillerSettings.EngineYard[WerfTemplateEngineName] = WerfTemplateEngine
 
 	clientset, err := kubeClient.KubernetesClientSet()
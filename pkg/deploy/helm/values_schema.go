@@ -0,0 +1,237 @@
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/xeipuuv/gojsonschema"
+
+	"k8s.io/helm/pkg/strvals"
+)
+
+const ValuesSchemaFileName = "values.schema.json"
+
+type ValuesSource struct {
+	// Origin is the values file path or another description of the values source that is shown in the validation errors
+	Origin string
+	Values map[string]interface{}
+}
+
+type ValuesSchema struct {
+	Name string
+	Data []byte
+}
+
+type ValidateValuesOptions struct {
+	Values       []string
+	SecretValues []ValuesSource
+	Set          []string
+	SetString    []string
+
+	// ExtraSchemas are used in addition to the values.schema.json of the chart
+	ExtraSchemas []ValuesSchema
+}
+
+// ValidateValues merges values the same way as helm does and validates the result against the values.schema.json
+// of the chart and the extra schemas. Each error contains the origin of the bad key: the values file, --set, etc.
+func ValidateValues(chartPath string, opts ValidateValuesOptions) error {
+	var schemas []ValuesSchema
+
+	schemaPath := filepath.Join(chartPath, ValuesSchemaFileName)
+	if data, err := ioutil.ReadFile(schemaPath); err == nil {
+		schemas = append(schemas, ValuesSchema{Name: schemaPath, Data: data})
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %s: %s", schemaPath, err)
+	}
+	schemas = append(schemas, opts.ExtraSchemas...)
+
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	sources, err := valuesSources(chartPath, opts)
+	if err != nil {
+		return err
+	}
+
+	values, origins := mergeValuesSources(sources)
+
+	var errorsMsgs []string
+	for _, schema := range schemas {
+		schemaErrors, err := validateValuesAgainstSchema(values, origins, schema.Data)
+		if err != nil {
+			return fmt.Errorf("unable to validate values against %s: %s", schema.Name, err)
+		}
+
+		if len(schemaErrors) != 0 {
+			errorsMsgs = append(errorsMsgs, fmt.Sprintf("%s:\n- %s", schema.Name, strings.Join(schemaErrors, "\n- ")))
+		}
+	}
+
+	if len(errorsMsgs) != 0 {
+		return fmt.Errorf("values don't meet the specifications of the schemas:\n%s", strings.Join(errorsMsgs, "\n"))
+	}
+
+	return nil
+}
+
+func valuesSources(chartPath string, opts ValidateValuesOptions) ([]ValuesSource, error) {
+	var sources []ValuesSource
+
+	chartValuesPath := filepath.Join(chartPath, "values.yaml")
+	if data, err := ioutil.ReadFile(chartValuesPath); err == nil {
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", chartValuesPath, err)
+		}
+		sources = append(sources, ValuesSource{Origin: chartValuesPath, Values: values})
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, filePath := range opts.Values {
+		// values from stdin are already consumed by this moment
+		if strings.TrimSpace(filePath) == "-" {
+			continue
+		}
+
+		data, err := readFile(filePath, "", "", "")
+		if err != nil {
+			return nil, err
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.UnmarshalStrict(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", filePath, err)
+		}
+		sources = append(sources, ValuesSource{Origin: filePath, Values: values})
+	}
+
+	sources = append(sources, opts.SecretValues...)
+
+	for _, value := range opts.Set {
+		values := map[string]interface{}{}
+		if err := strvals.ParseInto(value, values); err != nil {
+			return nil, fmt.Errorf("failed parsing --set data: %s", err)
+		}
+		sources = append(sources, ValuesSource{Origin: "--set " + value, Values: values})
+	}
+
+	for _, value := range opts.SetString {
+		values := map[string]interface{}{}
+		if err := strvals.ParseIntoString(value, values); err != nil {
+			return nil, fmt.Errorf("failed parsing --set-string data: %s", err)
+		}
+		sources = append(sources, ValuesSource{Origin: "--set-string " + value, Values: values})
+	}
+
+	return sources, nil
+}
+
+// mergeValuesSources merges the sources in order and returns the resulting values
+// and the origin of each key path (keys are joined with dots)
+func mergeValuesSources(sources []ValuesSource) (map[string]interface{}, map[string]string) {
+	values := map[string]interface{}{}
+	origins := map[string]string{}
+
+	for _, source := range sources {
+		mergeValuesWithOrigins(values, source.Values, "", source.Origin, origins)
+	}
+
+	return values, origins
+}
+
+func mergeValuesWithOrigins(dest, src map[string]interface{}, prefix, origin string, origins map[string]string) {
+	for k, v := range src {
+		path := prefix + k
+
+		// null removes the key the same way as helm does for the chart default values
+		if v == nil {
+			delete(dest, k)
+			origins[path] = origin
+			continue
+		}
+
+		srcMap, srcIsMap := v.(map[string]interface{})
+		destMap, destIsMap := dest[k].(map[string]interface{})
+		if srcIsMap && destIsMap {
+			mergeValuesWithOrigins(destMap, srcMap, path+".", origin, origins)
+			continue
+		}
+
+		if srcIsMap {
+			destMap = map[string]interface{}{}
+			mergeValuesWithOrigins(destMap, srcMap, path+".", origin, origins)
+			v = destMap
+		}
+
+		dest[k] = v
+		origins[path] = origin
+	}
+}
+
+func validateValuesAgainstSchema(values map[string]interface{}, origins map[string]string, schema []byte) ([]string, error) {
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(values))
+	if err != nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, resultErr := range result.Errors() {
+		field := resultErr.Field()
+		if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			field = ""
+		}
+
+		msg := resultErr.Description()
+		if field != "" {
+			msg = fmt.Sprintf("%s: %s", field, msg)
+		}
+
+		originPath := field
+		if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "required" {
+			// the origin of the removed key is more useful than the origin of the parent
+			if _, ok := origins[joinKeyPath(field, property)]; ok {
+				originPath = joinKeyPath(field, property)
+			}
+		}
+
+		if origin := keyPathOrigin(originPath, origins); origin != "" {
+			msg = fmt.Sprintf("%s (%s)", msg, origin)
+		}
+
+		res = append(res, msg)
+	}
+	sort.Strings(res)
+
+	return res, nil
+}
+
+func joinKeyPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// keyPathOrigin returns the origin of the key path or the nearest parent key with known origin
+func keyPathOrigin(path string, origins map[string]string) string {
+	for path != "" {
+		if origin, ok := origins[path]; ok {
+			return origin
+		}
+
+		ind := strings.LastIndex(path, ".")
+		if ind == -1 {
+			break
+		}
+		path = path[:ind]
+	}
+
+	return ""
+}
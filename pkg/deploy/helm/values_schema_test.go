@@ -0,0 +1,101 @@
+package helm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const valuesSchemaTestSchema = `{
+  "type": "object",
+  "required": ["image"],
+  "properties": {
+    "replicas": {"type": "integer"},
+    "image": {
+      "type": "object",
+      "required": ["tag"],
+      "properties": {"tag": {"type": "string"}}
+    },
+    "db": {
+      "type": "object",
+      "properties": {"password": {"type": "string", "minLength": 8}}
+    }
+  }
+}`
+
+func TestValidateValues(t *testing.T) {
+	chartDir := valuesSchemaTestChart(t, map[string]string{
+		ValuesSchemaFileName: valuesSchemaTestSchema,
+		"values.yaml":        "replicas: 1\nimage:\n  tag: latest\n",
+		"values-prod.yaml":   "replicas: many\n",
+	})
+	defer os.RemoveAll(chartDir)
+
+	valuesProd := filepath.Join(chartDir, "values-prod.yaml")
+
+	err := ValidateValues(chartDir, ValidateValuesOptions{
+		Values:       []string{valuesProd},
+		SecretValues: []ValuesSource{{Origin: ".helm/secret-values.yaml", Values: map[string]interface{}{"db": map[string]interface{}{"password": "short"}}}},
+		Set:          []string{"image.tag=null"},
+	})
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	for _, expected := range []string{
+		"replicas: Invalid type. Expected: integer, given: string (" + valuesProd + ")",
+		"db.password: String length must be greater than or equal to 8 (.helm/secret-values.yaml)",
+		"image: tag is required (--set image.tag=null)",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in the error:\n%s", expected, err)
+		}
+	}
+
+	if err := ValidateValues(chartDir, ValidateValuesOptions{SetString: []string{"image.tag=1"}}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := ValidateValues(chartDir, ValidateValuesOptions{
+		SetString:    []string{"image.tag=1"},
+		ExtraSchemas: []ValuesSchema{{Name: "extra", Data: []byte(`{"properties": {"replicas": {"maximum": 0}}}`)}},
+	}); err == nil {
+		t.Errorf("expected validation error")
+	} else if !strings.Contains(err.Error(), "extra:\n- replicas: Must be less than or equal to 0 ("+filepath.Join(chartDir, "values.yaml")+")") {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestMergeValuesSources(t *testing.T) {
+	values, origins := mergeValuesSources([]ValuesSource{
+		{Origin: "a", Values: map[string]interface{}{"x": map[string]interface{}{"y": 1, "z": 2}, "w": 1}},
+		{Origin: "b", Values: map[string]interface{}{"x": map[string]interface{}{"y": 3}, "w": nil}},
+	})
+
+	if _, exists := values["w"]; exists {
+		t.Errorf("null value should remove the key")
+	}
+
+	for path, expected := range map[string]string{"x.y": "b", "x.z": "a", "x": "a", "x.y.q": "b"} {
+		if origin := keyPathOrigin(path, origins); origin != expected {
+			t.Errorf("expected origin %q of %s, got %q", expected, path, origin)
+		}
+	}
+}
+
+func valuesSchemaTestChart(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "werf-values-schema-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
@@ -0,0 +1,243 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/helm/pkg/kube"
+)
+
+var manifestSepRegexp = regexp.MustCompile("(?:^|\\s*\n)---\\s*")
+
+// wavesKubeClient applies release resources in waves grouped by the werf.io/weight annotation:
+// each wave is applied and tracked by the resources waiter until ready before the next one starts.
+// Resources without the annotation have weight 0, waves are processed in ascending order of the weight.
+type wavesKubeClient struct {
+	*kube.Client
+}
+
+type manifestWave struct {
+	Weight    int
+	Manifests []*resourceManifest
+}
+
+type resourceManifest struct {
+	Template Template
+	Raw      string
+}
+
+func (c *wavesKubeClient) CreateWithOptions(namespace string, reader io.Reader, opts kube.CreateOptions) error {
+	manifests, err := readManifests(reader)
+	if err != nil {
+		return err
+	}
+
+	waves, err := splitManifestsIntoWaves(manifests)
+	if err != nil {
+		return err
+	}
+
+	if len(waves) <= 1 {
+		return c.Client.CreateWithOptions(namespace, joinManifestsReader(manifests), opts)
+	}
+
+	deadline := newWavesDeadline(opts.Timeout)
+	for _, wave := range waves {
+		c.Log("creating resources of wave with weight %d", wave.Weight)
+
+		if opts.ShouldWait {
+			if opts.Timeout, err = deadline.waveTimeout(wave.Weight); err != nil {
+				return err
+			}
+		}
+
+		if err := c.Client.CreateWithOptions(namespace, joinManifestsReader(wave.Manifests), opts); err != nil {
+			return fmt.Errorf("wave with weight %d: %s", wave.Weight, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateWithOptions updates each wave with the original resources of the same wave,
+// original resources that are not present in the target are deleted with the last wave
+func (c *wavesKubeClient) UpdateWithOptions(namespace string, originalReader, targetReader io.Reader, opts kube.UpdateOptions) error {
+	originalManifests, err := readManifests(originalReader)
+	if err != nil {
+		return err
+	}
+
+	targetManifests, err := readManifests(targetReader)
+	if err != nil {
+		return err
+	}
+
+	waves, err := splitManifestsIntoWaves(targetManifests)
+	if err != nil {
+		return err
+	}
+
+	if len(waves) <= 1 {
+		return c.Client.UpdateWithOptions(namespace, joinManifestsReader(originalManifests), joinManifestsReader(targetManifests), opts)
+	}
+
+	originalByID := map[string]*resourceManifest{}
+	var originalIDs []string
+	for _, manifest := range originalManifests {
+		id := manifest.ID(namespace)
+		originalByID[id] = manifest
+		originalIDs = append(originalIDs, id)
+	}
+
+	deadline := newWavesDeadline(opts.Timeout)
+	for i, wave := range waves {
+		var waveOriginalManifests []*resourceManifest
+		for _, manifest := range wave.Manifests {
+			id := manifest.ID(namespace)
+			if originalManifest, ok := originalByID[id]; ok {
+				waveOriginalManifests = append(waveOriginalManifests, originalManifest)
+				delete(originalByID, id)
+			}
+		}
+
+		if i == len(waves)-1 {
+			for _, id := range originalIDs {
+				if originalManifest, ok := originalByID[id]; ok {
+					waveOriginalManifests = append(waveOriginalManifests, originalManifest)
+				}
+			}
+		}
+
+		c.Log("updating resources of wave with weight %d", wave.Weight)
+
+		if opts.ShouldWait {
+			if opts.Timeout, err = deadline.waveTimeout(wave.Weight); err != nil {
+				return err
+			}
+		}
+
+		if err := c.Client.UpdateWithOptions(namespace, joinManifestsReader(waveOriginalManifests), joinManifestsReader(wave.Manifests), opts); err != nil {
+			return fmt.Errorf("wave with weight %d: %s", wave.Weight, err)
+		}
+	}
+
+	return nil
+}
+
+// wavesDeadline is shared by all waves: the timeout of the release is not multiplied by the number of waves
+type wavesDeadline struct {
+	deadline time.Time
+}
+
+func newWavesDeadline(timeoutSeconds int64) *wavesDeadline {
+	if timeoutSeconds <= 0 {
+		return &wavesDeadline{}
+	}
+	return &wavesDeadline{deadline: time.Now().Add(time.Duration(timeoutSeconds) * time.Second)}
+}
+
+// waveTimeout returns the rest of the release timeout in seconds
+func (d *wavesDeadline) waveTimeout(weight int) (int64, error) {
+	if d.deadline.IsZero() {
+		return 0, nil
+	}
+
+	remaining := time.Until(d.deadline)
+	if remaining <= 0 {
+		return 0, fmt.Errorf("wave with weight %d: timed out waiting for the previous waves", weight)
+	}
+
+	return int64(math.Ceil(remaining.Seconds())), nil
+}
+
+func splitManifestsIntoWaves(manifests []*resourceManifest) ([]*manifestWave, error) {
+	wavesByWeight := map[int]*manifestWave{}
+
+	for _, manifest := range manifests {
+		weight, err := templateWeight(manifest.Template)
+		if err != nil {
+			return nil, err
+		}
+
+		wave, ok := wavesByWeight[weight]
+		if !ok {
+			wave = &manifestWave{Weight: weight}
+			wavesByWeight[weight] = wave
+		}
+		wave.Manifests = append(wave.Manifests, manifest)
+	}
+
+	var waves []*manifestWave
+	for _, wave := range wavesByWeight {
+		waves = append(waves, wave)
+	}
+
+	sort.Slice(waves, func(i, j int) bool {
+		return waves[i].Weight < waves[j].Weight
+	})
+
+	return waves, nil
+}
+
+func templateWeight(t Template) (int, error) {
+	value, ok := t.Metadata.Annotations[WeightAnnoName]
+	if !ok {
+		return 0, nil
+	}
+
+	weight, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%s/%s: invalid value %q for annotation %s: integer expected", strings.ToLower(t.Kind), t.Metadata.Name, value, WeightAnnoName)
+	}
+
+	return weight, nil
+}
+
+func (m *resourceManifest) ID(namespace string) string {
+	return strings.Join([]string{strings.ToLower(m.Template.Kind), m.Template.Namespace(namespace), m.Template.Metadata.Name}, "/")
+}
+
+// readManifests splits the YAML stream into resource manifests preserving the order
+func readManifests(reader io.Reader) ([]*resourceManifest, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifests: %s", err)
+	}
+
+	var manifests []*resourceManifest
+	for _, raw := range manifestSepRegexp.Split(string(data), -1) {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+
+		t, err := parseTemplate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse manifest: %s", err)
+		}
+
+		if t.IsEmpty() {
+			continue
+		}
+
+		manifests = append(manifests, &resourceManifest{Template: t, Raw: raw})
+	}
+
+	return manifests, nil
+}
+
+func joinManifestsReader(manifests []*resourceManifest) io.Reader {
+	var raws []string
+	for _, m := range manifests {
+		raws = append(raws, m.Raw)
+	}
+
+	return bytes.NewBufferString(strings.Join(raws, "\n---\n"))
+}
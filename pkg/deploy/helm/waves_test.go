@@ -0,0 +1,110 @@
+package helm
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSplitManifestsIntoWaves(t *testing.T) {
+	manifests, err := readManifests(bytes.NewBufferString(`---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+# Source: chart/templates/backend.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+  annotations:
+    werf.io/weight: "10"
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    werf.io/weight: "-5"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+  annotations:
+    werf.io/weight: "0"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	waves, err := splitManifestsIntoWaves(manifests)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []struct {
+		weight int
+		names  []string
+	}{
+		{-5, []string{"migrate"}},
+		{0, []string{"config", "secret"}},
+		{10, []string{"backend"}},
+	}
+
+	if len(waves) != len(expected) {
+		t.Fatalf("expected %d waves, got %d", len(expected), len(waves))
+	}
+
+	for i, wave := range waves {
+		if wave.Weight != expected[i].weight {
+			t.Errorf("wave %d: expected weight %d, got %d", i, expected[i].weight, wave.Weight)
+		}
+
+		var names []string
+		for _, m := range wave.Manifests {
+			names = append(names, m.Template.Metadata.Name)
+		}
+
+		if len(names) != len(expected[i].names) {
+			t.Fatalf("wave %d: expected resources %v, got %v", i, expected[i].names, names)
+		}
+		for j := range names {
+			if names[j] != expected[i].names[j] {
+				t.Errorf("wave %d: expected resources %v, got %v", i, expected[i].names, names)
+			}
+		}
+	}
+
+	invalid, err := readManifests(bytes.NewBufferString(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  annotations:
+    werf.io/weight: high
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := splitManifestsIntoWaves(invalid); err == nil {
+		t.Errorf("expected error for invalid weight")
+	}
+}
+
+func TestWavesDeadline(t *testing.T) {
+	if timeout, err := newWavesDeadline(0).waveTimeout(0); err != nil || timeout != 0 {
+		t.Errorf("expected no timeout without deadline, got %d, %v", timeout, err)
+	}
+
+	deadline := newWavesDeadline(300)
+	if timeout, err := deadline.waveTimeout(0); err != nil || timeout <= 0 || timeout > 300 {
+		t.Errorf("expected the rest of the release timeout, got %d, %v", timeout, err)
+	}
+
+	deadline.deadline = time.Now().Add(-time.Second)
+	if _, err := deadline.waveTimeout(10); err == nil {
+		t.Errorf("expected error when the release timeout is exceeded")
+	}
+}
@@ -1,5 +1,7 @@
 package helm_v3
 
+import "github.com/werf/werf/pkg/deploy/generic_tracker"
+
 const (
 	TrackTerminationModeAnnoName = "werf.io/track-termination-mode"
 
@@ -15,4 +17,9 @@ const (
 	ShowLogsUntilAnnoName         = "werf.io/show-logs-until"
 
 	ShowEventsAnnoName = "werf.io/show-service-messages"
+
+	ReadyConditionAnnoName  = generic_tracker.ReadyConditionAnnoName
+	FailedConditionAnnoName = generic_tracker.FailedConditionAnnoName
+
+	WeightAnnoName = "werf.io/weight"
 )
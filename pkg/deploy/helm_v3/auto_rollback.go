@@ -0,0 +1,83 @@
+package helm_v3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/werf/logboek"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+
+	"github.com/werf/werf/pkg/deploy/events"
+	"github.com/werf/werf/pkg/deploy/generic_tracker"
+)
+
+var ErrNoSuccessfullyDeployedReleaseRevisionFound = errors.New("no deployed release revision found")
+
+// autoRollbackRelease rolls back the release to the latest successfully deployed revision after the failed resources tracking
+// and waits for the rolled back resources to become ready, the upgrade error is returned in any case
+func autoRollbackRelease(ctx context.Context, releaseName string, cfg *action.Configuration, opts UpgradeOptions, trackingErr, upgradeErr error) error {
+	generic_tracker.LogFailedResources(ctx, trackingErr)
+
+	revision, err := latestSuccessfullyDeployedReleaseRevision(cfg, releaseName)
+	if err == ErrNoSuccessfullyDeployedReleaseRevisionFound {
+		logboek.Context(ctx).Warn().LogF("WARNING: Auto rollback skipped: release %s has no successfully deployed revision\n", releaseName)
+		return upgradeErr
+	} else if err != nil {
+		return fmt.Errorf("%s\nauto rollback failed: get latest successfully deployed release revision failed: %s", upgradeErr, err)
+	}
+
+	err = Rollback(ctx, releaseName, RollbackOptions{
+		Namespace:     opts.Namespace,
+		Version:       revision,
+		Timeout:       opts.Timeout,
+		Wait:          true,
+		CleanupOnFail: true,
+
+		StatusProgressPeriod:      opts.StatusProgressPeriod,
+		HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod,
+	})
+
+	events.Emit(events.Event{Type: events.Rollback, Revision: revision, Status: events.StatusByError(err), Error: events.ErrorString(err), Message: "auto rollback after failed resources tracking"})
+
+	if err != nil {
+		return fmt.Errorf("%s\nauto rollback to revision %d failed: %s", upgradeErr, revision, err)
+	}
+
+	return fmt.Errorf("%s\nrelease %s has been rolled back to revision %d", upgradeErr, releaseName, revision)
+}
+
+func latestSuccessfullyDeployedReleaseRevision(cfg *action.Configuration, releaseName string) (int, error) {
+	history, err := action.NewHistory(cfg).Run(releaseName)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get release history: %s", err)
+	}
+
+	var revision int
+	for _, rel := range history {
+		if rel.Info.Status == release.StatusDeployed && rel.Version > revision {
+			revision = rel.Version
+		}
+	}
+
+	if revision == 0 {
+		return 0, ErrNoSuccessfullyDeployedReleaseRevisionFound
+	}
+
+	return revision, nil
+}
+
+func getTrackingErr(cfg *action.Configuration) error {
+	kubeClient, ok := getKubeClient(cfg)
+	if !ok {
+		return nil
+	}
+
+	waiter, ok := kubeClient.ResourcesWaiter.(*ResourcesWaiter)
+	if !ok {
+		return nil
+	}
+
+	return waiter.TrackingErr
+}
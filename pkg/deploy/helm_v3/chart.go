@@ -0,0 +1,207 @@
+package helm_v3
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"k8s.io/helm/pkg/ignore"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/werf/pkg/deploy/helm"
+)
+
+const WerfHelpersTemplateName = "templates/_werf_helpers.tpl"
+
+var (
+	templateActionRegexp    = regexp.MustCompile(`(?s){{.*?}}`)
+	templateStringRegexp    = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`")
+	werfFunctionCallRegexp  = regexp.MustCompile(`([\s(|{-])(image|image_id|werf_container_image|werf_container_env|werf_secret_file)([\s)}]|$)`)
+	werfFunctionIncludeRepl = `${1}include "${2}"${3}`
+)
+
+type LoadChartOptions struct {
+	// Name overrides the chart metadata the same way as werf does for Helm 2, Chart.yaml is used as is when the name is not set
+	Name string
+	// SecretFiles are the decoded files of the chart secret directory available with the werf_secret_file template
+	SecretFiles map[string]string
+}
+
+// LoadChart loads the chart from the directory, Chart.yaml is optional when the chart name is set.
+// The werf helpers and werf_secret_file templates are added to the chart and calls of the werf template
+// functions (werf_container_image, werf_secret_file, etc.) are turned into includes of these templates,
+// because Helm 3 engine does not allow to extend the functions map.
+func LoadChart(chartDir string, opts LoadChartOptions) (*chart.Chart, error) {
+	files, err := loadChartFiles(chartDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Name != "" {
+		chartYaml, err := werfChartYaml(files, opts.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		chartFiles := []*loader.BufferedFile{{Name: "Chart.yaml", Data: chartYaml}}
+		for _, f := range files {
+			if f.Name != "Chart.yaml" {
+				chartFiles = append(chartFiles, f)
+			}
+		}
+		files = chartFiles
+	}
+
+	ch, err := loader.LoadFiles(files)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range ch.Templates {
+		t.Data = []byte(RewriteWerfFunctionsCalls(string(t.Data)))
+	}
+
+	ch.Templates = append(ch.Templates, &chart.File{
+		Name: WerfHelpersTemplateName,
+		Data: []byte(helm.WerfEngineHelpers + werfSecretFileTemplate(opts.SecretFiles)),
+	})
+
+	return ch, nil
+}
+
+// RewriteWerfFunctionsCalls replaces calls of the werf template functions in the template actions with includes of the same named templates
+func RewriteWerfFunctionsCalls(data string) string {
+	return templateActionRegexp.ReplaceAllStringFunc(data, func(action string) string {
+		var res string
+		var pos int
+		for _, loc := range templateStringRegexp.FindAllStringIndex(action, -1) {
+			res += rewriteWerfFunctionsCallsInCode(action[pos:loc[0]]) + action[loc[0]:loc[1]]
+			pos = loc[1]
+		}
+
+		return res + rewriteWerfFunctionsCallsInCode(action[pos:])
+	})
+}
+
+func rewriteWerfFunctionsCallsInCode(code string) string {
+	// the regexp consumes the delimiter after the function name, so it is applied twice for the adjacent calls
+	res := werfFunctionCallRegexp.ReplaceAllString(code, werfFunctionIncludeRepl)
+	return werfFunctionCallRegexp.ReplaceAllString(res, werfFunctionIncludeRepl)
+}
+
+func werfSecretFileTemplate(secretFiles map[string]string) string {
+	var paths []string
+	for path := range secretFiles {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var filesDict []string
+	for _, path := range paths {
+		filesDict = append(filesDict, strconv.Quote(path), strconv.Quote(secretFiles[path]))
+	}
+
+	return fmt.Sprintf(`
+{{- define "werf_secret_file" -}}
+{{-   $path := . -}}
+{{-   if kindIs "slice" . -}}
+{{-     $path = index . 0 -}}
+{{-   end -}}
+{{-   $files := dict %s -}}
+{{-   if and (hasPrefix "/" $path) (hasKey $files (trimPrefix "/" $path)) -}}
+{{-     $path = trimPrefix "/" $path -}}
+{{-   end -}}
+{{-   if not (hasKey $files $path) -}}
+{{-     fail (printf "secret file '%%s' not found, you should use one of the following: '%%s'" $path (keys $files | sortAlpha | join "', '")) -}}
+{{-   end -}}
+{{-   get $files $path -}}
+{{- end -}}
+`, strings.Join(filesDict, " "))
+}
+
+func werfChartYaml(files []*loader.BufferedFile, name string) ([]byte, error) {
+	metadata := &chart.Metadata{}
+	for _, f := range files {
+		if f.Name != "Chart.yaml" {
+			continue
+		}
+
+		if err := yaml.Unmarshal(f.Data, metadata); err != nil {
+			return nil, fmt.Errorf("cannot load Chart.yaml: %s", err)
+		}
+	}
+
+	// requirements.yaml of the werf chart is used when Chart.yaml does not specify apiVersion v2
+	apiVersion := chart.APIVersionV1
+	if metadata.APIVersion != "" {
+		apiVersion = metadata.APIVersion
+	}
+
+	return yaml.Marshal(&chart.Metadata{
+		APIVersion:   apiVersion,
+		Name:         name,
+		Version:      "0.1.0",
+		Dependencies: metadata.Dependencies,
+	})
+}
+
+func loadChartFiles(chartDir string) ([]*loader.BufferedFile, error) {
+	topDir, err := filepath.Abs(chartDir)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := ignore.Empty()
+	helmIgnore := filepath.Join(topDir, ignore.HelmIgnore)
+	if _, err := os.Stat(helmIgnore); err == nil {
+		if rules, err = ignore.ParseFile(helmIgnore); err != nil {
+			return nil, err
+		}
+	}
+	rules.AddDefaults()
+
+	var files []*loader.BufferedFile
+	err = filepath.Walk(topDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(topDir, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			if rules.Ignore(relPath, info) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if rules.Ignore(relPath, info) || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", relPath, err)
+		}
+
+		files = append(files, &loader.BufferedFile{Name: relPath, Data: data})
+		return nil
+	})
+
+	return files, err
+}
@@ -0,0 +1,103 @@
+package helm_v3
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+)
+
+func TestRewriteWerfFunctionsCalls(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{`{{ werf_container_image . }}`, `{{ include "werf_container_image" . }}`},
+		{`{{- tuple "app" . | werf_container_env | indent 8 }}`, `{{- tuple "app" . | include "werf_container_env" | indent 8 }}`},
+		{`{{ werf_secret_file "tls/key.pem" | b64enc }}`, `{{ include "werf_secret_file" "tls/key.pem" | b64enc }}`},
+		{`{{ (image_id .) }}`, `{{ (include "image_id" .) }}`},
+		{`{{ include "werf_container_image" . }}`, `{{ include "werf_container_image" . }}`},
+		{`{{ .Values.image }} image: {{ $image }}`, `{{ .Values.image }} image: {{ $image }}`},
+		{`{{ printf "image %s" .Values.tag }}`, `{{ printf "image %s" .Values.tag }}`},
+		{"{{ printf `werf_secret_file %s` (werf_secret_file \"a\") }}", "{{ printf `werf_secret_file %s` (include \"werf_secret_file\" \"a\") }}"},
+	} {
+		if res := RewriteWerfFunctionsCalls(tc.in); res != tc.out {
+			t.Errorf("%s: expected %s, got %s", tc.in, tc.out, res)
+		}
+	}
+}
+
+func TestLoadChart(t *testing.T) {
+	chartDir, err := ioutil.TempDir("", "werf-chart-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(chartDir)
+
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	template := `apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+data:
+  key: {{ werf_secret_file "tls/key.pem" | b64enc }}
+  legacy: {{ include "werf_secret_file" (list "/tls/key.pem") | b64enc }}
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  containers:
+  - name: app
+{{ tuple "app" . | werf_container_image | indent 4 }}
+`
+	if err := ioutil.WriteFile(filepath.Join(chartDir, "templates", "app.yaml"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ch, err := LoadChart(chartDir, LoadChartOptions{Name: "myproject", SecretFiles: map[string]string{"tls/key.pem": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ch.Metadata.Name != "myproject" || ch.Metadata.Version != "0.1.0" {
+		t.Errorf("unexpected chart metadata: %+v", ch.Metadata)
+	}
+
+	values := map[string]interface{}{
+		"global": map[string]interface{}{
+			"werf": map[string]interface{}{
+				"is_nameless_image": false,
+				"ci":                map[string]interface{}{"is_branch": true},
+				"image":             map[string]interface{}{"app": map[string]interface{}{"docker_image": "registry/app:tag"}},
+			},
+		},
+	}
+
+	renderValues, err := chartutil.ToRenderValues(ch, values, chartutil.ReleaseOptions{Name: "myproject"}, chartutil.DefaultCapabilities)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templates, err := engine.Render(ch, renderValues)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := templates["myproject/templates/app.yaml"]
+	for _, expected := range []string{
+		"key: c2VjcmV0",
+		"legacy: c2VjcmV0",
+		"    image: registry/app:tag",
+		"    imagePullPolicy: Always",
+	} {
+		if !strings.Contains(res, expected) {
+			t.Errorf("expected %q in rendered template:\n%s", expected, res)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package helm_v3
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+
+	"github.com/werf/werf/pkg/deploy/helm"
+)
+
+type DriftOptions struct {
+	helm.DriftOptions
+	Namespace string
+}
+
+// Drift compares live objects with the manifest of the last deployed release revision
+// and prints field-level differences that were made outside werf
+func Drift(ctx context.Context, out io.Writer, releaseName string, opts DriftOptions) (*helm.DriftResult, error) {
+	envSettings := NewEnvSettings(ctx, opts.Namespace)
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+
+	history, err := action.NewHistory(cfg).Run(releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	var rls *release.Release
+	for _, r := range history {
+		if r.Info.Status == release.StatusDeployed && (rls == nil || r.Version > rls.Version) {
+			rls = r
+		}
+	}
+
+	if rls == nil {
+		return nil, fmt.Errorf("release %q has no deployed revision", releaseName)
+	}
+
+	templates, err := helm.GetTemplatesFromManifest(rls.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := helm.ReleaseDrift(ctx, releaseName, rls.Namespace, int32(rls.Version), templates, opts.DriftOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := helm.PrintDrift(out, result, opts.DriftOptions); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
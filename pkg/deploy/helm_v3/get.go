@@ -0,0 +1,39 @@
+package helm_v3
+
+import (
+	"context"
+	"io"
+	"text/template"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
+)
+
+type GetOptions struct {
+	Namespace string
+	Revision  int
+	Template  string
+}
+
+// Get prints the release values, hooks, manifest and notes the same way as helm get all does
+func Get(ctx context.Context, out io.Writer, releaseName string, opts GetOptions) error {
+	envSettings := NewEnvSettings(ctx, opts.Namespace)
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+	client := action.NewGet(cfg)
+	client.Version = opts.Revision
+
+	rel, err := client.Run(releaseName)
+	if err != nil {
+		return err
+	}
+
+	if opts.Template != "" {
+		tt, err := template.New("_").Parse(opts.Template)
+		if err != nil {
+			return err
+		}
+		return tt.Execute(out, map[string]interface{}{"Release": rel})
+	}
+
+	return output.Table.Write(out, &statusPrinter{rel, true})
+}
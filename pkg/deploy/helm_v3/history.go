@@ -0,0 +1,116 @@
+package helm_v3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/yaml"
+)
+
+type HistoryOptions struct {
+	Namespace    string
+	Max          int
+	OutputFormat string
+	ColWidth     uint
+}
+
+func History(ctx context.Context, out io.Writer, releaseName string, opts HistoryOptions) error {
+	envSettings := NewEnvSettings(ctx, opts.Namespace)
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+	client := action.NewHistory(cfg)
+	client.Max = opts.Max
+
+	releases, err := client.Run(releaseName)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Version < releases[j].Version })
+	if opts.Max > 0 && len(releases) > opts.Max {
+		releases = releases[len(releases)-opts.Max:]
+	}
+
+	var history []releaseInfo
+	for _, r := range releases {
+		history = append(history, releaseInfo{
+			Revision:    r.Version,
+			Updated:     r.Info.LastDeployed.Format(time.ANSIC),
+			Status:      r.Info.Status.String(),
+			Chart:       formatChartName(r.Chart),
+			AppVersion:  appVersionFromChart(r.Chart),
+			Description: r.Info.Description,
+		})
+	}
+
+	return writeOutput(out, opts.OutputFormat, history, func() []byte {
+		tbl := uitable.New()
+		tbl.MaxColWidth = opts.ColWidth
+		tbl.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DESCRIPTION")
+		for _, r := range history {
+			tbl.AddRow(r.Revision, r.Updated, r.Status, r.Chart, r.AppVersion, r.Description)
+		}
+		return tbl.Bytes()
+	})
+}
+
+type releaseInfo struct {
+	Revision    int    `json:"revision"`
+	Updated     string `json:"updated"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"appVersion"`
+	Description string `json:"description"`
+}
+
+func writeOutput(out io.Writer, outputFormat string, obj interface{}, table func() []byte) error {
+	var data []byte
+	var err error
+
+	switch outputFormat {
+	case "yaml":
+		data, err = yaml.Marshal(obj)
+	case "json":
+		data, err = json.Marshal(obj)
+	case "table":
+		data = table()
+	default:
+		return fmt.Errorf("unknown output format %q", outputFormat)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, string(data))
+
+	return nil
+}
+
+func formatChartName(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return "MISSING"
+	}
+	return fmt.Sprintf("%s-%s", c.Metadata.Name, c.Metadata.Version)
+}
+
+func appVersionFromChart(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return "MISSING"
+	}
+	return c.Metadata.AppVersion
+}
+
+func releaseStatus(r *release.Release) string {
+	if r.Info == nil {
+		return release.StatusUnknown.String()
+	}
+	return r.Info.Status.String()
+}
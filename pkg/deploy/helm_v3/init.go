@@ -3,6 +3,7 @@ package helm_v3
 import (
 	"context"
 	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -21,14 +22,41 @@ import (
 
 	"github.com/spf13/pflag"
 	"helm.sh/helm/v3/pkg/action"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/klog"
+
+	"github.com/werf/werf/pkg/deploy/helm"
 )
 
 type InitOptions struct {
 	Debug bool
+
+	KubeConfig       string
+	KubeConfigBase64 string
+	KubeContext      string
 }
 
+var kubeConfig, kubeContext string
+
+// kubeConfigDataGetter is used instead of the kube config file when the kube config is passed as base64 data
+var kubeConfigDataGetter *helm.ClientGetterFromConfigData
+
 func Init(opts InitOptions) error {
+	kubeConfig = opts.KubeConfig
+	kubeContext = opts.KubeContext
+
+	kubeConfigDataGetter = nil
+	if opts.KubeConfigBase64 != "" {
+		getter, err := helm.NewClientGetterFromConfigData(opts.KubeContext, opts.KubeConfigBase64)
+		if err != nil {
+			return fmt.Errorf("unable to create kube client getter (context=%q): %s", opts.KubeContext, err)
+		}
+		kubeConfigDataGetter = getter
+	}
+
 	gofs := flag.NewFlagSet("klog", flag.ExitOnError)
 	klog.InitFlags(gofs)
 	pflag.CommandLine.AddGoFlagSet(gofs)
@@ -47,6 +75,13 @@ func withEnvs(envsMap map[string]string, do func()) {
 }
 
 func NewEnvSettings(ctx context.Context, namespace string) (res *cli.EnvSettings) {
+	// the default namespace is taken from the kube config data, because the settings only know about the kube config file
+	if namespace == "" && kubeConfigDataGetter != nil {
+		if ns, _, err := kubeConfigDataGetter.ToRawKubeConfigLoader().Namespace(); err == nil {
+			namespace = ns
+		}
+	}
+
 	withEnvs(map[string]string{
 		"HELM_NAMESPACE":         namespace,
 		"HELM_KUBECONTEXT":       "",
@@ -61,14 +96,60 @@ func NewEnvSettings(ctx context.Context, namespace string) (res *cli.EnvSettings
 		res = cli.New()
 	})
 
+	res.KubeConfig = kubeConfig
+	res.KubeContext = kubeContext
 	res.Debug = logboek.Context(ctx).Debug().IsAccepted()
 
 	return
 }
 
+// NewRESTClientGetter returns the getter of the kube config data passed to the Init or the getter of the kube config file from the settings
+func NewRESTClientGetter(envSettings *cli.EnvSettings) genericclioptions.RESTClientGetter {
+	if kubeConfigDataGetter == nil {
+		return envSettings.RESTClientGetter()
+	}
+
+	return &namespacedClientGetter{ClientGetterFromConfigData: kubeConfigDataGetter, namespace: envSettings.Namespace()}
+}
+
+// namespacedClientGetter overrides the namespace of the kube config data the same way as the settings override the namespace of the kube config file
+type namespacedClientGetter struct {
+	*helm.ClientGetterFromConfigData
+	namespace string
+}
+
+func (getter *namespacedClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return &namespacedClientConfig{config: getter.ClientGetterFromConfigData.ToRawKubeConfigLoader(), namespace: getter.namespace}
+}
+
+type namespacedClientConfig struct {
+	config    clientcmd.ClientConfig
+	namespace string
+}
+
+func (config *namespacedClientConfig) RawConfig() (clientcmdapi.Config, error) {
+	return config.config.RawConfig()
+}
+
+func (config *namespacedClientConfig) ClientConfig() (*rest.Config, error) {
+	return config.config.ClientConfig()
+}
+
+func (config *namespacedClientConfig) Namespace() (string, bool, error) {
+	return config.namespace, true, nil
+}
+
+func (config *namespacedClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return config.config.ConfigAccess()
+}
+
 type InitActionConfigOptions struct {
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
+
+	// WaitWaves and Timeout of the release action are used to wait for each werf.io/weight wave before applying the next one
+	WaitWaves bool
+	Timeout   time.Duration
 }
 
 func NewActionConfig(ctx context.Context, envSettings *cli.EnvSettings, opts InitActionConfigOptions) *action.Configuration {
@@ -79,15 +160,28 @@ func NewActionConfig(ctx context.Context, envSettings *cli.EnvSettings, opts Ini
 
 func InitActionConfig(ctx context.Context, envSettings *cli.EnvSettings, actionConfig *action.Configuration, opts InitActionConfigOptions) {
 	helmDriver := os.Getenv("HELM_DRIVER")
-	if err := actionConfig.Init(envSettings.RESTClientGetter(), envSettings.Namespace(), helmDriver, logboek.Context(ctx).Debug().LogF); err != nil {
+	if err := actionConfig.Init(NewRESTClientGetter(envSettings), envSettings.Namespace(), helmDriver, logboek.Context(ctx).Debug().LogF); err != nil {
 		log.Fatal(err)
 	}
 	if helmDriver == "memory" {
 		loadReleasesInMemory(envSettings, actionConfig)
 	}
 
-	kubeClient := actionConfig.KubeClient.(*helm_kube.Client)
-	kubeClient.ResourcesWaiter = NewResourcesWaiter(kubeClient, time.Now(), opts.StatusProgressPeriod, opts.HooksStatusProgressPeriod)
+	if kubeClient, ok := actionConfig.KubeClient.(*helm_kube.Client); ok {
+		kubeClient.ResourcesWaiter = NewResourcesWaiter(kubeClient, time.Now(), opts.StatusProgressPeriod, opts.HooksStatusProgressPeriod)
+		actionConfig.KubeClient = &wavesKubeClient{Client: kubeClient, WaitWaves: opts.WaitWaves, Timeout: opts.Timeout}
+	}
+}
+
+func getKubeClient(cfg *action.Configuration) (*helm_kube.Client, bool) {
+	switch kubeClient := cfg.KubeClient.(type) {
+	case *wavesKubeClient:
+		return kubeClient.Client, true
+	case *helm_kube.Client:
+		return kubeClient, true
+	default:
+		return nil, false
+	}
 }
 
 // This function loads releases into the memory storage if the
@@ -13,18 +13,22 @@ import (
 
 	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 )
 
 type InstallOptions struct {
-	ValuesOptions   values.Options
-	Namespace       string
-	CreateNamespace bool
-	Install         bool
-	Atomic          bool
-	Timeout         time.Duration
+	ValuesOptions    values.Options
+	SecretValues     []map[string]interface{}
+	LoadChartOptions LoadChartOptions
+	ExtraAnnotations map[string]string
+	ExtraLabels      map[string]string
+	Namespace        string
+	CreateNamespace  bool
+	Install          bool
+	Atomic           bool
+	Wait             bool
+	Timeout          time.Duration
 
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
@@ -34,11 +38,15 @@ func Install(ctx context.Context, chart, releaseName string, opts InstallOptions
 	outfmt := output.Table
 
 	envSettings := NewEnvSettings(ctx, opts.Namespace)
-	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod})
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod, WaitWaves: opts.Wait || opts.Atomic, Timeout: opts.Timeout})
 	client := action.NewInstall(cfg)
 	client.Namespace = opts.Namespace
 	client.CreateNamespace = opts.CreateNamespace
 	client.ReleaseName = releaseName
+	client.Atomic = opts.Atomic
+	client.Wait = opts.Wait
+	client.Timeout = opts.Timeout
+	client.PostRenderer = &extraAnnotationsAndLabelsPostRenderer{ExtraAnnotations: opts.ExtraAnnotations, ExtraLabels: opts.ExtraLabels}
 
 	logboek.Context(ctx).Debug().LogF("Original chart version: %q", client.Version)
 	if client.Version == "" && client.Devel {
@@ -54,13 +62,13 @@ func Install(ctx context.Context, chart, releaseName string, opts InstallOptions
 	logboek.Context(ctx).Debug().LogF("CHART PATH: %s\n", cp)
 
 	p := getter.All(envSettings)
-	vals, err := opts.ValuesOptions.MergeValues(p)
+	vals, err := mergeValues(opts.ValuesOptions, opts.SecretValues, p)
 	if err != nil {
 		return err
 	}
 
 	// Check chart dependencies to make sure all are present in /charts
-	chartRequested, err := loader.Load(cp)
+	chartRequested, err := LoadChart(cp, opts.LoadChartOptions)
 	if err != nil {
 		return err
 	}
@@ -90,7 +98,7 @@ func Install(ctx context.Context, chart, releaseName string, opts InstallOptions
 					return err
 				}
 				// Reload the chart with the updated Chart.lock file.
-				if chartRequested, err = loader.Load(cp); err != nil {
+				if chartRequested, err = LoadChart(cp, opts.LoadChartOptions); err != nil {
 					return errors.Wrap(err, "failed reloading chart after repo update")
 				}
 			} else {
@@ -0,0 +1,99 @@
+package helm_v3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"helm.sh/helm/v3/pkg/action"
+
+	"github.com/werf/logboek"
+)
+
+type ListOptions struct {
+	// Namespace limits the releases to the namespace, releases of all namespaces are listed when the namespace is not set
+	Namespace    string
+	Filter       string
+	Short        bool
+	Reverse      bool
+	Max          int
+	All          bool
+	Uninstalled  bool
+	Uninstalling bool
+	Pending      bool
+	OutputFormat string
+	ColWidth     uint
+}
+
+func List(ctx context.Context, out io.Writer, opts ListOptions) error {
+	envSettings := NewEnvSettings(ctx, opts.Namespace)
+	cfg := &action.Configuration{}
+	if err := cfg.Init(NewRESTClientGetter(envSettings), opts.Namespace, os.Getenv("HELM_DRIVER"), logboek.Context(ctx).Debug().LogF); err != nil {
+		return err
+	}
+
+	client := action.NewList(cfg)
+	client.AllNamespaces = opts.Namespace == ""
+	client.Filter = opts.Filter
+	client.Short = opts.Short
+	client.SortReverse = opts.Reverse
+	client.Limit = opts.Max
+	client.All = opts.All
+	client.Uninstalled = opts.Uninstalled
+	client.Uninstalling = opts.Uninstalling
+	client.Pending = opts.Pending
+	client.SetStateMask()
+
+	releases, err := client.Run()
+	if err != nil {
+		return err
+	}
+
+	if opts.Short {
+		for _, r := range releases {
+			fmt.Fprintln(out, r.Name)
+		}
+		return nil
+	}
+
+	type listRelease struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Revision   int    `json:"revision"`
+		Updated    string `json:"updated"`
+		Status     string `json:"status"`
+		Chart      string `json:"chart"`
+		AppVersion string `json:"app_version"`
+	}
+
+	var list []listRelease
+	for _, r := range releases {
+		var updated string
+		if r.Info != nil {
+			updated = r.Info.LastDeployed.Format(time.ANSIC)
+		}
+
+		list = append(list, listRelease{
+			Name:       r.Name,
+			Namespace:  r.Namespace,
+			Revision:   r.Version,
+			Updated:    updated,
+			Status:     releaseStatus(r),
+			Chart:      formatChartName(r.Chart),
+			AppVersion: appVersionFromChart(r.Chart),
+		})
+	}
+
+	return writeOutput(out, opts.OutputFormat, list, func() []byte {
+		tbl := uitable.New()
+		tbl.MaxColWidth = opts.ColWidth
+		tbl.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		for _, r := range list {
+			tbl.AddRow(r.Name, r.Namespace, r.Revision, r.Updated, r.Status, r.Chart, r.AppVersion)
+		}
+		return tbl.Bytes()
+	})
+}
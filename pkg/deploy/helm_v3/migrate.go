@@ -0,0 +1,283 @@
+package helm_v3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	helm_time "helm.sh/helm/v3/pkg/time"
+	chart_v2 "k8s.io/helm/pkg/proto/hapi/chart"
+	release_v2 "k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/timeconv"
+	"sigs.k8s.io/yaml"
+
+	"github.com/werf/logboek"
+)
+
+var (
+	v2StatusCodes = map[release_v2.Status_Code]release.Status{
+		release_v2.Status_UNKNOWN:          release.StatusUnknown,
+		release_v2.Status_DEPLOYED:         release.StatusDeployed,
+		release_v2.Status_DELETED:          release.StatusUninstalled,
+		release_v2.Status_SUPERSEDED:       release.StatusSuperseded,
+		release_v2.Status_FAILED:           release.StatusFailed,
+		release_v2.Status_DELETING:         release.StatusUninstalling,
+		release_v2.Status_PENDING_INSTALL:  release.StatusPendingInstall,
+		release_v2.Status_PENDING_UPGRADE:  release.StatusPendingUpgrade,
+		release_v2.Status_PENDING_ROLLBACK: release.StatusPendingRollback,
+	}
+
+	// crd-install hooks are not supported by Helm 3, CRDs are installed from the crds directory of the chart
+	v2HookEvents = map[release_v2.Hook_Event]release.HookEvent{
+		release_v2.Hook_PRE_INSTALL:          release.HookPreInstall,
+		release_v2.Hook_POST_INSTALL:         release.HookPostInstall,
+		release_v2.Hook_PRE_DELETE:           release.HookPreDelete,
+		release_v2.Hook_POST_DELETE:          release.HookPostDelete,
+		release_v2.Hook_PRE_UPGRADE:          release.HookPreUpgrade,
+		release_v2.Hook_POST_UPGRADE:         release.HookPostUpgrade,
+		release_v2.Hook_PRE_ROLLBACK:         release.HookPreRollback,
+		release_v2.Hook_POST_ROLLBACK:        release.HookPostRollback,
+		release_v2.Hook_RELEASE_TEST_SUCCESS: release.HookTest,
+		release_v2.Hook_RELEASE_TEST_FAILURE: release.HookTest,
+	}
+
+	v2HookDeletePolicies = map[release_v2.Hook_DeletePolicy]release.HookDeletePolicy{
+		release_v2.Hook_SUCCEEDED:            release.HookSucceeded,
+		release_v2.Hook_FAILED:               release.HookFailed,
+		release_v2.Hook_BEFORE_HOOK_CREATION: release.HookBeforeHookCreation,
+	}
+)
+
+type Migrate2To3Options struct {
+	// Namespace is used for the Helm 2 release revisions without namespace, the namespace of the kube context is used by default
+	Namespace string
+	DryRun    bool
+}
+
+// Migrate2To3 creates all revisions of the Helm 2 release in the Helm 3 release storage of the release namespace.
+// The release is not redeployed and the release resources are kept as is.
+func Migrate2To3(ctx context.Context, releaseName string, v2Releases []*release_v2.Release, opts Migrate2To3Options) error {
+	if len(v2Releases) == 0 {
+		return fmt.Errorf("release %q not found", releaseName)
+	}
+
+	defaultNamespace := opts.Namespace
+	if defaultNamespace == "" {
+		defaultNamespace = NewEnvSettings(ctx, "").Namespace()
+	}
+
+	releases, err := convertV2Releases(releaseName, v2Releases, defaultNamespace)
+	if err != nil {
+		return err
+	}
+
+	namespace := releases[len(releases)-1].Namespace
+	envSettings := NewEnvSettings(ctx, namespace)
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+
+	if history, err := cfg.Releases.History(releaseName); err != nil && !IsErrReleaseNotFound(err) {
+		return err
+	} else if len(history) != 0 {
+		return fmt.Errorf("release %q already exists in Helm 3 release storage of namespace %q", releaseName, envSettings.Namespace())
+	}
+
+	for _, rel := range releases {
+		logboek.Context(ctx).Default().LogF("Revision %d (%s) -> namespace %s\n", rel.Version, rel.Info.Status, envSettings.Namespace())
+
+		if opts.DryRun {
+			continue
+		}
+
+		if err := cfg.Releases.Create(rel); err != nil {
+			return fmt.Errorf("unable to create release %q revision %d: %s", releaseName, rel.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// convertV2Releases converts the Helm 2 release revisions sorted by the revision number,
+// revisions without namespace are migrated into the default namespace
+func convertV2Releases(releaseName string, v2Releases []*release_v2.Release, defaultNamespace string) ([]*release.Release, error) {
+	var releases []*release.Release
+	for _, v2Release := range v2Releases {
+		rel, err := ConvertV2Release(v2Release)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert release %q revision %d: %s", releaseName, v2Release.Version, err)
+		}
+
+		if rel.Namespace == "" {
+			rel.Namespace = defaultNamespace
+		}
+
+		releases = append(releases, rel)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Version < releases[j].Version })
+
+	return releases, nil
+}
+
+// ConvertV2Release converts the Helm 2 release revision into the Helm 3 release revision
+func ConvertV2Release(v2Release *release_v2.Release) (*release.Release, error) {
+	ch, err := convertV2Chart(v2Release.Chart)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := convertV2Config(v2Release.Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse release values: %s", err)
+	}
+
+	rel := &release.Release{
+		Name:      v2Release.Name,
+		Chart:     ch,
+		Config:    config,
+		Manifest:  v2Release.Manifest,
+		Version:   int(v2Release.Version),
+		Namespace: v2Release.Namespace,
+		Info:      &release.Info{Status: release.StatusUnknown},
+	}
+
+	if info := v2Release.Info; info != nil {
+		rel.Info.FirstDeployed = convertV2Timestamp(info.FirstDeployed)
+		rel.Info.LastDeployed = convertV2Timestamp(info.LastDeployed)
+		rel.Info.Deleted = convertV2Timestamp(info.Deleted)
+		rel.Info.Description = info.Description
+
+		if info.Status != nil {
+			if status, ok := v2StatusCodes[info.Status.Code]; ok {
+				rel.Info.Status = status
+			}
+			rel.Info.Notes = info.Status.Notes
+		}
+	}
+
+	for _, v2Hook := range v2Release.Hooks {
+		rel.Hooks = append(rel.Hooks, convertV2Hook(v2Hook))
+	}
+
+	return rel, nil
+}
+
+func convertV2Hook(v2Hook *release_v2.Hook) *release.Hook {
+	hook := &release.Hook{
+		Name:     v2Hook.Name,
+		Kind:     v2Hook.Kind,
+		Path:     v2Hook.Path,
+		Manifest: v2Hook.Manifest,
+		Weight:   int(v2Hook.Weight),
+		LastRun:  release.HookExecution{Phase: release.HookPhaseUnknown},
+	}
+
+	events := map[release.HookEvent]bool{}
+	for _, v2Event := range v2Hook.Events {
+		if event, ok := v2HookEvents[v2Event]; ok && !events[event] {
+			hook.Events = append(hook.Events, event)
+			events[event] = true
+		}
+	}
+
+	for _, v2Policy := range v2Hook.DeletePolicies {
+		if policy, ok := v2HookDeletePolicies[v2Policy]; ok {
+			hook.DeletePolicies = append(hook.DeletePolicies, policy)
+		}
+	}
+
+	if v2Hook.LastRun != nil {
+		hook.LastRun = release.HookExecution{
+			StartedAt:   convertV2Timestamp(v2Hook.LastRun),
+			CompletedAt: convertV2Timestamp(v2Hook.LastRun),
+			Phase:       release.HookPhaseSucceeded,
+		}
+	}
+
+	return hook
+}
+
+func convertV2Chart(v2Chart *chart_v2.Chart) (*chart.Chart, error) {
+	if v2Chart == nil {
+		return nil, nil
+	}
+
+	ch := &chart.Chart{}
+
+	if m := v2Chart.Metadata; m != nil {
+		ch.Metadata = &chart.Metadata{
+			Name:        m.Name,
+			Home:        m.Home,
+			Sources:     m.Sources,
+			Version:     m.Version,
+			Description: m.Description,
+			Keywords:    m.Keywords,
+			Icon:        m.Icon,
+			APIVersion:  m.ApiVersion,
+			Condition:   m.Condition,
+			Tags:        m.Tags,
+			AppVersion:  m.AppVersion,
+			Deprecated:  m.Deprecated,
+			Annotations: m.Annotations,
+			KubeVersion: m.KubeVersion,
+		}
+
+		if ch.Metadata.APIVersion == "" {
+			ch.Metadata.APIVersion = chart.APIVersionV1
+		}
+
+		for _, maintainer := range m.Maintainers {
+			ch.Metadata.Maintainers = append(ch.Metadata.Maintainers, &chart.Maintainer{
+				Name:  maintainer.Name,
+				Email: maintainer.Email,
+				URL:   maintainer.Url,
+			})
+		}
+	}
+
+	for _, t := range v2Chart.Templates {
+		ch.Templates = append(ch.Templates, &chart.File{Name: t.Name, Data: t.Data})
+	}
+
+	for _, f := range v2Chart.Files {
+		ch.Files = append(ch.Files, &chart.File{Name: f.TypeUrl, Data: f.Value})
+	}
+
+	values, err := convertV2Config(v2Chart.Values)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse chart values: %s", err)
+	}
+	ch.Values = values
+
+	for _, v2Dependency := range v2Chart.Dependencies {
+		dependency, err := convertV2Chart(v2Dependency)
+		if err != nil {
+			return nil, err
+		}
+		ch.AddDependency(dependency)
+	}
+
+	return ch, nil
+}
+
+func convertV2Config(config *chart_v2.Config) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if config == nil || config.Raw == "" {
+		return values, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(config.Raw), &values); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func convertV2Timestamp(ts *timestamp.Timestamp) helm_time.Time {
+	if ts == nil {
+		return helm_time.Time{}
+	}
+
+	return helm_time.Time{Time: timeconv.Time(ts)}
+}
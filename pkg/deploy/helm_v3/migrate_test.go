@@ -0,0 +1,136 @@
+package helm_v3
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"helm.sh/helm/v3/pkg/release"
+	chart_v2 "k8s.io/helm/pkg/proto/hapi/chart"
+	release_v2 "k8s.io/helm/pkg/proto/hapi/release"
+	"k8s.io/helm/pkg/timeconv"
+)
+
+func TestConvertV2Release(t *testing.T) {
+	v2Release := &release_v2.Release{
+		Name:      "myproject-production",
+		Namespace: "myproject-production",
+		Version:   3,
+		Manifest:  "---\nkind: Deployment\n",
+		Config:    &chart_v2.Config{Raw: "replicas: 2\n"},
+		Info: &release_v2.Info{
+			Status:        &release_v2.Status{Code: release_v2.Status_DEPLOYED, Notes: "notes"},
+			FirstDeployed: timeconv.Now(),
+			LastDeployed:  timeconv.Now(),
+			Description:   "Upgrade complete",
+		},
+		Chart: &chart_v2.Chart{
+			Metadata:  &chart_v2.Metadata{Name: "myproject", Version: "0.1.0", Engine: "werf"},
+			Templates: []*chart_v2.Template{{Name: "templates/app.yaml", Data: []byte("kind: Deployment")}},
+			Files:     []*any.Any{{TypeUrl: "secret-values.yaml", Value: []byte("encrypted")}},
+			Values:    &chart_v2.Config{Raw: "image:\n  tag: latest\n"},
+			Dependencies: []*chart_v2.Chart{
+				{Metadata: &chart_v2.Metadata{Name: "redis", Version: "1.0.0"}},
+			},
+		},
+		Hooks: []*release_v2.Hook{
+			{
+				Name:           "migrate",
+				Kind:           "Job",
+				Events:         []release_v2.Hook_Event{release_v2.Hook_PRE_UPGRADE, release_v2.Hook_CRD_INSTALL, release_v2.Hook_RELEASE_TEST_SUCCESS, release_v2.Hook_RELEASE_TEST_FAILURE},
+				DeletePolicies: []release_v2.Hook_DeletePolicy{release_v2.Hook_BEFORE_HOOK_CREATION},
+				LastRun:        timeconv.Now(),
+				Weight:         10,
+			},
+		},
+	}
+
+	rel, err := ConvertV2Release(v2Release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rel.Name != "myproject-production" || rel.Namespace != "myproject-production" || rel.Version != 3 || rel.Manifest != v2Release.Manifest {
+		t.Errorf("unexpected release: %s/%s revision %d", rel.Namespace, rel.Name, rel.Version)
+	}
+
+	if rel.Info.Status != release.StatusDeployed || rel.Info.Notes != "notes" || rel.Info.Description != "Upgrade complete" || rel.Info.LastDeployed.IsZero() || !rel.Info.Deleted.IsZero() {
+		t.Errorf("unexpected release info: %+v", rel.Info)
+	}
+
+	if rel.Config["replicas"] != float64(2) {
+		t.Errorf("unexpected release config: %v", rel.Config)
+	}
+
+	if rel.Chart.Metadata.Name != "myproject" || rel.Chart.Metadata.APIVersion != "v1" {
+		t.Errorf("unexpected chart metadata: %+v", rel.Chart.Metadata)
+	}
+
+	if len(rel.Chart.Templates) != 1 || rel.Chart.Templates[0].Name != "templates/app.yaml" {
+		t.Errorf("unexpected chart templates: %v", rel.Chart.Templates)
+	}
+
+	if len(rel.Chart.Files) != 1 || rel.Chart.Files[0].Name != "secret-values.yaml" {
+		t.Errorf("unexpected chart files: %v", rel.Chart.Files)
+	}
+
+	if image, ok := rel.Chart.Values["image"].(map[string]interface{}); !ok || image["tag"] != "latest" {
+		t.Errorf("unexpected chart values: %v", rel.Chart.Values)
+	}
+
+	if deps := rel.Chart.Dependencies(); len(deps) != 1 || deps[0].Name() != "redis" || deps[0].Parent() != rel.Chart {
+		t.Errorf("unexpected chart dependencies: %v", deps)
+	}
+
+	if len(rel.Hooks) != 1 {
+		t.Fatalf("expected 1 hook, got %d", len(rel.Hooks))
+	}
+
+	hook := rel.Hooks[0]
+	if len(hook.Events) != 2 || hook.Events[0] != release.HookPreUpgrade || hook.Events[1] != release.HookTest {
+		t.Errorf("unexpected hook events: %v", hook.Events)
+	}
+
+	if len(hook.DeletePolicies) != 1 || hook.DeletePolicies[0] != release.HookBeforeHookCreation {
+		t.Errorf("unexpected hook delete policies: %v", hook.DeletePolicies)
+	}
+
+	if hook.Weight != 10 || hook.LastRun.Phase != release.HookPhaseSucceeded || hook.LastRun.StartedAt.IsZero() {
+		t.Errorf("unexpected hook: %+v", hook)
+	}
+}
+
+func TestConvertV2ReleaseStatuses(t *testing.T) {
+	for v2Status, status := range map[release_v2.Status_Code]release.Status{
+		release_v2.Status_DELETED:         release.StatusUninstalled,
+		release_v2.Status_SUPERSEDED:      release.StatusSuperseded,
+		release_v2.Status_FAILED:          release.StatusFailed,
+		release_v2.Status_PENDING_UPGRADE: release.StatusPendingUpgrade,
+	} {
+		rel, err := ConvertV2Release(&release_v2.Release{Name: "app", Info: &release_v2.Info{Status: &release_v2.Status{Code: v2Status}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rel.Info.Status != status {
+			t.Errorf("%s: expected %s, got %s", v2Status, status, rel.Info.Status)
+		}
+	}
+}
+
+func TestConvertV2ReleasesDefaultNamespace(t *testing.T) {
+	releases, err := convertV2Releases("app", []*release_v2.Release{
+		{Name: "app", Version: 2},
+		{Name: "app", Version: 1, Namespace: "app-production"},
+	}, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(releases) != 2 || releases[0].Version != 1 || releases[1].Version != 2 {
+		t.Fatalf("unexpected releases order: %v", releases)
+	}
+
+	if releases[0].Namespace != "app-production" || releases[1].Namespace != "default" {
+		t.Errorf("unexpected releases namespaces: %q, %q", releases[0].Namespace, releases[1].Namespace)
+	}
+}
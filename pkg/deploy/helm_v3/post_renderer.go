@@ -0,0 +1,92 @@
+package helm_v3
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"sigs.k8s.io/yaml"
+)
+
+// extraAnnotationsAndLabelsPostRenderer adds werf extra annotations and labels to the rendered release manifests
+type extraAnnotationsAndLabelsPostRenderer struct {
+	ExtraAnnotations map[string]string
+	ExtraLabels      map[string]string
+}
+
+func (pr *extraAnnotationsAndLabelsPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	if len(pr.ExtraAnnotations) == 0 && len(pr.ExtraLabels) == 0 {
+		return renderedManifests, nil
+	}
+
+	splitManifests := releaseutil.SplitManifests(renderedManifests.String())
+
+	var keys []string
+	for key := range splitManifests {
+		keys = append(keys, key)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var res []string
+	for _, key := range keys {
+		manifest := splitManifests[key]
+
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+			return nil, fmt.Errorf("unable to parse manifest: %s\n\n%s", err, manifest)
+		}
+
+		if len(obj) == 0 {
+			continue
+		}
+
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+			obj["metadata"] = metadata
+		}
+
+		setMetadataStringMap(metadata, "annotations", pr.ExtraAnnotations)
+		setMetadataStringMap(metadata, "labels", pr.ExtraLabels)
+
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		// the leading comments such as # Source: are kept to refer the manifest template
+		res = append(res, manifestLeadingComments(manifest)+string(data))
+	}
+
+	return bytes.NewBufferString("---\n" + strings.Join(res, "---\n")), nil
+}
+
+func setMetadataStringMap(metadata map[string]interface{}, field string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+
+	m, _ := metadata[field].(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+		metadata[field] = m
+	}
+
+	for k, v := range values {
+		m[k] = v
+	}
+}
+
+func manifestLeadingComments(manifest string) string {
+	var res string
+	for _, line := range strings.Split(strings.TrimPrefix(manifest, "---\n"), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			break
+		}
+		res += line + "\n"
+	}
+
+	return res
+}
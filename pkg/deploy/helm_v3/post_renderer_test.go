@@ -0,0 +1,35 @@
+package helm_v3
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExtraAnnotationsAndLabelsPostRenderer(t *testing.T) {
+	manifests := `---
+# Source: app/templates/cm.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  labels:
+    app: cm
+`
+
+	pr := &extraAnnotationsAndLabelsPostRenderer{
+		ExtraAnnotations: map[string]string{"project.werf.io/name": "app"},
+		ExtraLabels:      map[string]string{"env": "test"},
+	}
+
+	res, err := pr.Run(bytes.NewBufferString(manifests))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, expected := range []string{"# Source: app/templates/cm.yaml\napiVersion: v1\n", "project.werf.io/name: app", "app: cm", "env: test"} {
+		if !strings.Contains(res.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, res.String())
+		}
+	}
+}
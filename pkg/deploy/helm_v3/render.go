@@ -0,0 +1,74 @@
+package helm_v3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+type RenderOptions struct {
+	ValuesOptions    values.Options
+	SecretValues     []map[string]interface{}
+	LoadChartOptions LoadChartOptions
+	ExtraAnnotations map[string]string
+	ExtraLabels      map[string]string
+	Namespace        string
+}
+
+// Render renders the chart manifests and hooks without connecting to the cluster the same way as helm template does
+func Render(ctx context.Context, out io.Writer, chart, releaseName string, opts RenderOptions) error {
+	envSettings := NewEnvSettings(ctx, opts.Namespace)
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+
+	client := action.NewInstall(cfg)
+	client.DryRun = true
+	client.ClientOnly = true
+	client.Replace = true
+	client.IncludeCRDs = true
+	client.Namespace = opts.Namespace
+	client.ReleaseName = releaseName
+	client.PostRenderer = &extraAnnotationsAndLabelsPostRenderer{ExtraAnnotations: opts.ExtraAnnotations, ExtraLabels: opts.ExtraLabels}
+
+	chartPath, err := client.ChartPathOptions.LocateChart(chart, envSettings)
+	if err != nil {
+		return err
+	}
+
+	vals, err := mergeValues(opts.ValuesOptions, opts.SecretValues, getter.All(envSettings))
+	if err != nil {
+		return err
+	}
+
+	ch, err := LoadChart(chartPath, opts.LoadChartOptions)
+	if err != nil {
+		return err
+	}
+
+	if req := ch.Metadata.Dependencies; req != nil {
+		if err := action.CheckDependencies(ch, req); err != nil {
+			return err
+		}
+	}
+
+	rel, err := client.Run(ch, vals)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(out, strings.TrimSpace(rel.Manifest)); err != nil {
+		return err
+	}
+
+	for _, hook := range rel.Hooks {
+		if _, err := fmt.Fprintf(out, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/werf/kubedog/pkg/tracker"
 	"github.com/werf/kubedog/pkg/trackers/rollout/multitrack"
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/deploy/generic_tracker"
 	helm_kube "helm.sh/helm/v3/pkg/kube"
 	appsv1 "k8s.io/api/apps/v1"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
@@ -19,7 +20,9 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -31,6 +34,9 @@ type ResourcesWaiter struct {
 	LogsFromTime              time.Time
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
+
+	// TrackingErr is the last error of the release resources tracking
+	TrackingErr error
 }
 
 func NewResourcesWaiter(client *helm_kube.Client, logsFromTime time.Time, statusProgressPeriod, hooksStatusProgressPeriod time.Duration) *ResourcesWaiter {
@@ -50,7 +56,10 @@ func extractSpecReplicas(specReplicas *int32) int {
 }
 
 func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resources helm_kube.ResourceList, timeout time.Duration) error {
+	waiter.TrackingErr = nil
+
 	specs := multitrack.MultitrackSpecs{}
+	var genericSpecs []*generic_tracker.Spec
 
 	for _, v := range resources {
 		switch value := asVersioned(v).(type) {
@@ -153,7 +162,26 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 		case *appsv1beta2.ReplicaSet:
 		case *appsv1.ReplicaSet:
 		case *v1.PersistentVolumeClaim:
+			if genericSpec := generic_tracker.MakeSpec(ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		case *extensions.Ingress, *networkingv1beta1.Ingress:
+			if genericSpec := generic_tracker.MakeSpec(ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
 		case *v1.Service:
+			if genericSpec := generic_tracker.MakeSpec(ctx, v, value.Spec.Type == v1.ServiceTypeLoadBalancer); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		case *unstructured.Unstructured:
+			// custom resources
+			if genericSpec := generic_tracker.MakeSpec(ctx, v, true); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
+		default:
+			if genericSpec := generic_tracker.MakeSpec(ctx, v, false); genericSpec != nil {
+				genericSpecs = append(genericSpecs, genericSpec)
+			}
 		}
 	}
 
@@ -161,13 +189,18 @@ func (waiter *ResourcesWaiter) Wait(ctx context.Context, namespace string, resou
 	logboek.Context(ctx).LogOptionalLn()
 	return logboek.Context(ctx).LogProcess("Waiting for release resources to become ready").
 		DoError(func() error {
-			return multitrack.Multitrack(kube.Client, specs, multitrack.MultitrackOptions{
+			err := generic_tracker.Multitrack(ctx, specs, genericSpecs, multitrack.MultitrackOptions{
 				StatusProgressPeriod: waiter.StatusProgressPeriod,
 				Options: tracker.Options{
 					Timeout:      timeout,
 					LogsFromTime: waiter.LogsFromTime,
 				},
 			})
+			if err != nil {
+				waiter.TrackingErr = err
+			}
+
+			return err
 		})
 }
 
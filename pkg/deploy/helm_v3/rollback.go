@@ -27,10 +27,16 @@ type RollbackOptions struct {
 func Rollback(ctx context.Context, releaseName string, opts RollbackOptions) error {
 	return logboek.Context(ctx).Default().LogProcess("Rolling back release %q", releaseName).DoError(func() error {
 		envSettings := NewEnvSettings(ctx, opts.Namespace)
-		cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod})
+		cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod, WaitWaves: opts.Wait, Timeout: opts.Timeout})
 		client := action.NewRollback(cfg)
 		client.Timeout = opts.Timeout
 		client.Version = opts.Version
+		client.DryRun = opts.DryRun
+		client.Recreate = opts.Recreate
+		client.Force = opts.Force
+		client.DisableHooks = opts.DisableHooks
+		client.Wait = opts.Wait
+		client.CleanupOnFail = opts.CleanupOnFail
 
 		return client.Run(releaseName)
 	})
@@ -0,0 +1,39 @@
+package helm_v3
+
+import (
+	"context"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+
+	"github.com/werf/logboek"
+)
+
+type UninstallOptions struct {
+	Namespace    string
+	DisableHooks bool
+	KeepHistory  bool
+	Timeout      time.Duration
+}
+
+func Uninstall(ctx context.Context, releaseName string, opts UninstallOptions) error {
+	return logboek.Context(ctx).Default().LogProcess("Uninstalling release %q", releaseName).DoError(func() error {
+		envSettings := NewEnvSettings(ctx, opts.Namespace)
+		cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{})
+		client := action.NewUninstall(cfg)
+		client.DisableHooks = opts.DisableHooks
+		client.KeepHistory = opts.KeepHistory
+		client.Timeout = opts.Timeout
+
+		res, err := client.Run(releaseName)
+		if err != nil {
+			return err
+		}
+
+		if res != nil && res.Info != "" {
+			logboek.Context(ctx).LogLn(res.Info)
+		}
+
+		return nil
+	})
+}
@@ -12,18 +12,23 @@ import (
 
 	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 type UpgradeOptions struct {
-	ValuesOptions   values.Options
-	Namespace       string
-	CreateNamespace bool
-	Install         bool
-	Atomic          bool
-	Timeout         time.Duration
+	ValuesOptions    values.Options
+	SecretValues     []map[string]interface{}
+	LoadChartOptions LoadChartOptions
+	ExtraAnnotations map[string]string
+	ExtraLabels      map[string]string
+	Namespace        string
+	CreateNamespace  bool
+	Install          bool
+	Atomic           bool
+	Wait             bool
+	AutoRollback     bool
+	Timeout          time.Duration
 
 	StatusProgressPeriod      time.Duration
 	HooksStatusProgressPeriod time.Duration
@@ -33,13 +38,15 @@ func Upgrade(ctx context.Context, chart, releaseName string, opts UpgradeOptions
 	outfmt := output.Table
 
 	envSettings := NewEnvSettings(ctx, opts.Namespace)
-	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod})
+	cfg := NewActionConfig(ctx, envSettings, InitActionConfigOptions{StatusProgressPeriod: opts.StatusProgressPeriod, HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod, WaitWaves: opts.Wait || opts.Atomic, Timeout: opts.Timeout})
 	client := action.NewUpgrade(cfg)
 
 	client.Namespace = opts.Namespace
 	client.Timeout = opts.Timeout
 	client.Atomic = opts.Atomic
 	client.Install = opts.Install
+	client.Wait = opts.Wait
+	client.PostRenderer = &extraAnnotationsAndLabelsPostRenderer{ExtraAnnotations: opts.ExtraAnnotations, ExtraLabels: opts.ExtraLabels}
 
 	// Fixes #7002 - Support reading values from STDIN for `upgrade` command
 	// Must load values AFTER determining if we have to call install so that values loaded from stdin are are not read twice
@@ -51,12 +58,20 @@ func Upgrade(ctx context.Context, chart, releaseName string, opts UpgradeOptions
 			logboek.Context(ctx).Debug().LogF("Release %q does not exist. Installing it now.\n", releaseName)
 
 			return Install(ctx, chart, releaseName, InstallOptions{
-				ValuesOptions:   opts.ValuesOptions,
-				Namespace:       opts.Namespace,
-				CreateNamespace: opts.CreateNamespace,
-				Install:         opts.Install,
-				Atomic:          opts.Atomic,
-				Timeout:         opts.Timeout,
+				ValuesOptions:    opts.ValuesOptions,
+				SecretValues:     opts.SecretValues,
+				LoadChartOptions: opts.LoadChartOptions,
+				ExtraAnnotations: opts.ExtraAnnotations,
+				ExtraLabels:      opts.ExtraLabels,
+				Namespace:        opts.Namespace,
+				CreateNamespace:  opts.CreateNamespace,
+				Install:          opts.Install,
+				Atomic:           opts.Atomic,
+				Wait:             opts.Wait,
+				Timeout:          opts.Timeout,
+
+				StatusProgressPeriod:      opts.StatusProgressPeriod,
+				HooksStatusProgressPeriod: opts.HooksStatusProgressPeriod,
 			})
 		} else if err != nil {
 			return err
@@ -73,13 +88,13 @@ func Upgrade(ctx context.Context, chart, releaseName string, opts UpgradeOptions
 		return err
 	}
 
-	vals, err := opts.ValuesOptions.MergeValues(getter.All(envSettings))
+	vals, err := mergeValues(opts.ValuesOptions, opts.SecretValues, getter.All(envSettings))
 	if err != nil {
 		return err
 	}
 
 	// Check chart dependencies to make sure all are present in /charts
-	ch, err := loader.Load(chartPath)
+	ch, err := LoadChart(chartPath, opts.LoadChartOptions)
 	if err != nil {
 		return err
 	}
@@ -91,7 +106,15 @@ func Upgrade(ctx context.Context, chart, releaseName string, opts UpgradeOptions
 
 	rel, err := client.Run(releaseName, ch, vals)
 	if err != nil {
-		return errors.Wrap(err, "UPGRADE FAILED")
+		err = errors.Wrap(err, "UPGRADE FAILED")
+
+		if opts.AutoRollback && !opts.Atomic {
+			if trackingErr := getTrackingErr(cfg); trackingErr != nil {
+				return autoRollbackRelease(ctx, releaseName, cfg, opts, trackingErr, err)
+			}
+		}
+
+		return err
 	}
 
 	logboek.Context(ctx).Debug().LogF("Release %q has been upgraded\n", releaseName)
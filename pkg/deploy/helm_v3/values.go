@@ -0,0 +1,54 @@
+package helm_v3
+
+import (
+	"github.com/pkg/errors"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/strvals"
+)
+
+// mergeValues merges values the same way as werf does for Helm 2: values files, then secret values, then --set and --set-string values
+func mergeValues(valuesOptions values.Options, secretValues []map[string]interface{}, p getter.Providers) (map[string]interface{}, error) {
+	filesValuesOptions := values.Options{ValueFiles: valuesOptions.ValueFiles}
+	base, err := filesValuesOptions.MergeValues(p)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range secretValues {
+		base = mergeMaps(base, v)
+	}
+
+	for _, value := range valuesOptions.Values {
+		if err := strvals.ParseInto(value, base); err != nil {
+			return nil, errors.Wrap(err, "failed parsing --set data")
+		}
+	}
+
+	for _, value := range valuesOptions.StringValues {
+		if err := strvals.ParseIntoString(value, base); err != nil {
+			return nil, errors.Wrap(err, "failed parsing --set-string data")
+		}
+	}
+
+	return base, nil
+}
+
+func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(a))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		if v, ok := v.(map[string]interface{}); ok {
+			if bv, ok := out[k]; ok {
+				if bv, ok := bv.(map[string]interface{}); ok {
+					out[k] = mergeMaps(bv, v)
+					continue
+				}
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,190 @@
+package helm_v3
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// wavesKubeClient applies release resources in waves grouped by the werf.io/weight annotation:
+// when WaitWaves is set each wave is applied and tracked by the resources waiter until ready before the next one starts.
+// Resources without the annotation have weight 0, waves are processed in ascending order of the weight.
+// All waves and the final wait of the release share the same Timeout.
+type wavesKubeClient struct {
+	*helm_kube.Client
+
+	WaitWaves bool
+	Timeout   time.Duration
+
+	deadline time.Time
+}
+
+type resourcesWave struct {
+	Weight    int
+	Resources helm_kube.ResourceList
+}
+
+func (c *wavesKubeClient) Create(resources helm_kube.ResourceList) (*helm_kube.Result, error) {
+	c.resetDeadline()
+
+	waves, err := splitResourcesIntoWaves(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(waves) <= 1 {
+		return c.Client.Create(resources)
+	}
+
+	result := &helm_kube.Result{}
+	for i, wave := range waves {
+		c.Log("creating resources of wave with weight %d", wave.Weight)
+
+		waveResult, err := c.Client.Create(wave.Resources)
+		if err != nil {
+			return nil, fmt.Errorf("wave with weight %d: %s", wave.Weight, err)
+		}
+		appendResult(result, waveResult)
+
+		if err := c.waitWave(wave, i == len(waves)-1); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Update updates each wave with the original resources of the same wave,
+// original resources that are not present in the target are deleted with the last wave
+func (c *wavesKubeClient) Update(original, target helm_kube.ResourceList, force bool) (*helm_kube.Result, error) {
+	c.resetDeadline()
+
+	waves, err := splitResourcesIntoWaves(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(waves) <= 1 {
+		return c.Client.Update(original, target, force)
+	}
+
+	result := &helm_kube.Result{}
+	for i, wave := range waves {
+		waveOriginal := original.Intersect(wave.Resources)
+		isLastWave := i == len(waves)-1
+		if isLastWave {
+			waveOriginal = append(waveOriginal, original.Difference(target)...)
+		}
+
+		c.Log("updating resources of wave with weight %d", wave.Weight)
+
+		waveResult, err := c.Client.Update(waveOriginal, wave.Resources, force)
+		if err != nil {
+			return nil, fmt.Errorf("wave with weight %d: %s", wave.Weight, err)
+		}
+		appendResult(result, waveResult)
+
+		if err := c.waitWave(wave, isLastWave); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// Wait does not exceed the deadline of the waves
+func (c *wavesKubeClient) Wait(resources helm_kube.ResourceList, timeout time.Duration) error {
+	if !c.deadline.IsZero() {
+		remaining := time.Until(c.deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out waiting for the release resources")
+		} else if remaining < timeout {
+			timeout = remaining
+		}
+	}
+
+	return c.Client.Wait(resources, timeout)
+}
+
+// waitWave waits for the wave resources before the next wave, the last wave is awaited by the release action itself
+func (c *wavesKubeClient) waitWave(wave *resourcesWave, isLastWave bool) error {
+	if !c.WaitWaves || isLastWave {
+		return nil
+	}
+
+	if err := c.Wait(wave.Resources, c.Timeout); err != nil {
+		return fmt.Errorf("wave with weight %d: %s", wave.Weight, err)
+	}
+
+	return nil
+}
+
+func (c *wavesKubeClient) resetDeadline() {
+	if c.WaitWaves && c.Timeout > 0 {
+		c.deadline = time.Now().Add(c.Timeout)
+	}
+}
+
+func appendResult(result, waveResult *helm_kube.Result) {
+	if waveResult == nil {
+		return
+	}
+
+	result.Created = append(result.Created, waveResult.Created...)
+	result.Updated = append(result.Updated, waveResult.Updated...)
+	result.Deleted = append(result.Deleted, waveResult.Deleted...)
+}
+
+func splitResourcesIntoWaves(resources helm_kube.ResourceList) ([]*resourcesWave, error) {
+	wavesByWeight := map[int]*resourcesWave{}
+
+	for _, info := range resources {
+		weight, err := resourceWeight(info)
+		if err != nil {
+			return nil, err
+		}
+
+		wave, ok := wavesByWeight[weight]
+		if !ok {
+			wave = &resourcesWave{Weight: weight}
+			wavesByWeight[weight] = wave
+		}
+		wave.Resources.Append(info)
+	}
+
+	var waves []*resourcesWave
+	for _, wave := range wavesByWeight {
+		waves = append(waves, wave)
+	}
+
+	sort.Slice(waves, func(i, j int) bool {
+		return waves[i].Weight < waves[j].Weight
+	})
+
+	return waves, nil
+}
+
+func resourceWeight(info *resource.Info) (int, error) {
+	objMeta, err := meta.Accessor(info.Object)
+	if err != nil {
+		return 0, nil
+	}
+
+	value, ok := objMeta.GetAnnotations()[WeightAnnoName]
+	if !ok {
+		return 0, nil
+	}
+
+	weight, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%s/%s: invalid value %q for annotation %s: integer expected", strings.ToLower(info.Object.GetObjectKind().GroupVersionKind().Kind), info.Name, value, WeightAnnoName)
+	}
+
+	return weight, nil
+}
@@ -0,0 +1,71 @@
+package helm_v3
+
+import (
+	"testing"
+
+	helm_kube "helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func newTestWaveResource(kind, name string, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetAnnotations(annotations)
+
+	return &resource.Info{Name: name, Object: obj}
+}
+
+func TestSplitResourcesIntoWaves(t *testing.T) {
+	resources := helm_kube.ResourceList{
+		newTestWaveResource("ConfigMap", "config", nil),
+		newTestWaveResource("Deployment", "backend", map[string]string{WeightAnnoName: "10"}),
+		newTestWaveResource("Job", "migrate", map[string]string{WeightAnnoName: "-5"}),
+		newTestWaveResource("Secret", "secret", map[string]string{WeightAnnoName: " 0 "}),
+	}
+
+	waves, err := splitResourcesIntoWaves(resources)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []struct {
+		weight int
+		names  []string
+	}{
+		{-5, []string{"migrate"}},
+		{0, []string{"config", "secret"}},
+		{10, []string{"backend"}},
+	}
+
+	if len(waves) != len(expected) {
+		t.Fatalf("expected %d waves, got %d", len(expected), len(waves))
+	}
+
+	for i, wave := range waves {
+		if wave.Weight != expected[i].weight {
+			t.Errorf("wave %d: expected weight %d, got %d", i, expected[i].weight, wave.Weight)
+		}
+
+		var names []string
+		for _, info := range wave.Resources {
+			names = append(names, info.Name)
+		}
+
+		if len(names) != len(expected[i].names) {
+			t.Fatalf("wave %d: expected resources %v, got %v", i, expected[i].names, names)
+		}
+		for j := range names {
+			if names[j] != expected[i].names[j] {
+				t.Errorf("wave %d: expected resources %v, got %v", i, expected[i].names, names)
+			}
+		}
+	}
+
+	invalid := helm_kube.ResourceList{newTestWaveResource("ConfigMap", "config", map[string]string{WeightAnnoName: "high"})}
+	if _, err := splitResourcesIntoWaves(invalid); err == nil {
+		t.Errorf("expected error for invalid weight")
+	}
+}
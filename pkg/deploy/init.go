@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 )
 
 type InitOptions struct {
@@ -16,6 +17,14 @@ func Init(ctx context.Context, options InitOptions) error {
 		if err := helm.Init(ctx, options.HelmInitOptions); err != nil {
 			return err
 		}
+
+		if err := helm_v3.Init(helm_v3.InitOptions{
+			KubeConfig:       options.HelmInitOptions.KubeConfig,
+			KubeConfigBase64: options.HelmInitOptions.KubeConfigBase64,
+			KubeContext:      options.HelmInitOptions.KubeContext,
+		}); err != nil {
+			return err
+		}
 	}
 
 	return nil
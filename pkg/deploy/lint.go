@@ -1,14 +1,17 @@
 package deploy
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/werf/logboek"
+	"helm.sh/helm/v3/pkg/cli/values"
 
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/images_manager"
 	"github.com/werf/werf/pkg/tag_strategy"
 	"github.com/werf/werf/pkg/util/secretvalues"
@@ -21,9 +24,12 @@ type LintOptions struct {
 	SetString       []string
 	Env             string
 	IgnoreSecretKey bool
+	OutputFormat    string
+	RulesSeverity   map[string]helm.LintSeverity
+	HelmVersion     string
 }
 
-func RunLint(ctx context.Context, projectDir, helmChartDir string, werfConfig *config.WerfConfig, imagesRepository string, images []images_manager.ImageInfoGetter, commonTag string, tagStrategy tag_strategy.TagStrategy, opts LintOptions) error {
+func RunLint(ctx context.Context, out io.Writer, projectDir, helmChartDir string, werfConfig *config.WerfConfig, imagesRepository string, images []images_manager.ImageInfoGetter, commonTag string, tagStrategy tag_strategy.TagStrategy, opts LintOptions) error {
 	logboek.Context(ctx).Debug().LogF("Lint options: %#v\n", opts)
 
 	m, err := GetSafeSecretManager(ctx, projectDir, helmChartDir, opts.SecretValues, opts.IgnoreSecretKey)
@@ -42,21 +48,62 @@ func RunLint(ctx context.Context, projectDir, helmChartDir string, werfConfig *c
 	if err != nil {
 		return err
 	}
+	werfChart.HelmVersion = opts.HelmVersion
 	helm.SetReleaseLogSecretValuesToMask(werfChart.SecretValuesToMask)
 
+	if err := werfChart.ValidateValues(helm.ChartValuesOptions{
+		Set:       opts.Set,
+		SetString: opts.SetString,
+		Values:    opts.Values,
+	}, serviceValuesSchemas()); err != nil {
+		return err
+	}
+
 	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
 	patchLoadChartfile(werfChart.Name)
 
+	var werfImages []string
+	for _, image := range images {
+		werfImages = append(werfImages, image.GetImageName())
+	}
+
+	var renderTemplates func() (string, error)
+	if werfChart.HelmVersion == config.HelmVersion3 {
+		renderTemplates = func() (string, error) {
+			buf := bytes.NewBuffer(nil)
+			err := helm_v3.Render(ctx, buf, werfChart.ChartDir, "RELEASE_NAME", helm_v3.RenderOptions{
+				ValuesOptions: values.Options{
+					ValueFiles:   append(werfChart.Values, opts.Values...),
+					StringValues: append(werfChart.SetString, opts.SetString...),
+					Values:       append(werfChart.Set, opts.Set...),
+				},
+				SecretValues: werfChart.SecretValues,
+				LoadChartOptions: helm_v3.LoadChartOptions{
+					Name:        werfChart.Name,
+					SecretFiles: werfChart.DecodedSecretFilesData,
+				},
+				Namespace: namespace,
+			})
+			return buf.String(), err
+		}
+	}
+
 	if err := helm.Lint(
 		ctx,
-		os.Stdout,
+		out,
 		werfChart.ChartDir,
 		namespace,
 		append(werfChart.Values, opts.Values...),
 		werfChart.SecretValues,
 		append(werfChart.Set, opts.Set...),
 		append(werfChart.SetString, opts.SetString...),
-		helm.LintOptions{Strict: true},
+		helm.LintOptions{
+			Strict:          true,
+			OutputFormat:    opts.OutputFormat,
+			RulesSeverity:   opts.RulesSeverity,
+			WerfImages:      werfImages,
+			RenderTemplates: renderTemplates,
+		},
 	); err != nil {
 		return fmt.Errorf("%s", secretvalues.MaskSecretValuesInString(werfChart.SecretValuesToMask, err.Error()))
 	}
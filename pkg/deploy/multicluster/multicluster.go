@@ -0,0 +1,245 @@
+package multicluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosuri/uitable"
+
+	"github.com/werf/logboek"
+)
+
+// ClusterEnvName is set for the child werf process which deploys the application into one of the target clusters
+const ClusterEnvName = "WERF_DEPLOY_CLUSTER"
+
+type Cluster struct {
+	Name        string
+	KubeContext string
+	KubeConfig  string
+	Namespace   string
+	Values      []string
+}
+
+type Options struct {
+	Parallel      bool
+	StopOnFailure bool
+}
+
+type Result struct {
+	Cluster  *Cluster
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+}
+
+// IsClusterProcess returns true when the current process deploys into the single cluster of the multi-cluster deploy
+func IsClusterProcess() bool {
+	return os.Getenv(ClusterEnvName) != ""
+}
+
+// ParseCluster parses the cluster in the form name=NAME,kube-context=CONTEXT,kube-config=PATH,namespace=NAMESPACE,values=PATH1;PATH2
+func ParseCluster(value string) (*Cluster, error) {
+	cluster := &Cluster{}
+
+	for _, part := range strings.Split(value, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("bad cluster %q: KEY=VALUE pairs separated by comma expected", value)
+		}
+
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "name":
+			cluster.Name = val
+		case "kube-context":
+			cluster.KubeContext = val
+		case "kube-config":
+			cluster.KubeConfig = val
+		case "namespace":
+			cluster.Namespace = val
+		case "values":
+			for _, path := range strings.Split(val, ";") {
+				if path = strings.TrimSpace(path); path != "" {
+					cluster.Values = append(cluster.Values, path)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("bad cluster %q: unknown key %q, name, kube-context, kube-config, namespace or values expected", value, key)
+		}
+	}
+
+	if cluster.Name == "" {
+		return nil, fmt.Errorf("bad cluster %q: name required", value)
+	}
+
+	if cluster.KubeContext == "" && cluster.KubeConfig == "" {
+		return nil, fmt.Errorf("bad cluster %q: kube-context or kube-config required", value)
+	}
+
+	return cluster, nil
+}
+
+// Deploy runs the current werf command for each cluster in the child process with the cluster specific kube context,
+// kube config, namespace and values, images should be built and published before.
+// The release lock is acquired by the child process in the namespace of the target cluster.
+func Deploy(ctx context.Context, clusters []*Cluster, opts Options) error {
+	var results []*Result
+
+	if opts.Parallel {
+		results = deployParallel(ctx, clusters)
+	} else {
+		results = deploySequential(ctx, clusters, opts.StopOnFailure)
+	}
+
+	logboek.Context(ctx).LogOptionalLn()
+	logboek.Context(ctx).Default().LogBlock("Clusters deploy summary").Do(func() {
+		logboek.Context(ctx).LogLn(resultsTable(results))
+	})
+
+	var failed []string
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res.Cluster.Name)
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("deploy failed for clusters: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func deploySequential(ctx context.Context, clusters []*Cluster, stopOnFailure bool) []*Result {
+	var results []*Result
+	var stopped bool
+
+	for _, cluster := range clusters {
+		if stopped {
+			results = append(results, &Result{Cluster: cluster, Skipped: true})
+			continue
+		}
+
+		var res *Result
+		_ = logboek.Context(ctx).Default().LogProcess("Deploying into cluster %s", cluster.Name).DoError(func() error {
+			res = deployCluster(ctx, cluster, os.Stdout, os.Stderr)
+			return res.Err
+		})
+		results = append(results, res)
+
+		if res.Err != nil && stopOnFailure {
+			stopped = true
+		}
+	}
+
+	return results
+}
+
+func deployParallel(ctx context.Context, clusters []*Cluster) []*Result {
+	results := make([]*Result, len(clusters))
+	outputs := make([]*bytes.Buffer, len(clusters))
+
+	logboek.Context(ctx).Default().LogProcess("Deploying into clusters %s in parallel", clusterNames(clusters)).Do(func() {
+		var wg sync.WaitGroup
+		for i, cluster := range clusters {
+			wg.Add(1)
+			go func(i int, cluster *Cluster) {
+				defer wg.Done()
+
+				outputs[i] = &bytes.Buffer{}
+				results[i] = deployCluster(ctx, cluster, outputs[i], outputs[i])
+			}(i, cluster)
+		}
+		wg.Wait()
+	})
+
+	for i, cluster := range clusters {
+		logboek.Context(ctx).Default().LogBlock("Cluster %s output", cluster.Name).Do(func() {
+			logboek.Context(ctx).LogF("%s", outputs[i].String())
+		})
+	}
+
+	return results
+}
+
+func deployCluster(ctx context.Context, cluster *Cluster, stdout, stderr io.Writer) *Result {
+	startedAt := time.Now()
+
+	cmd := exec.CommandContext(ctx, os.Args[0], clusterArgs(os.Args[1:], cluster)...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", ClusterEnvName, cluster.Name))
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("cluster %s: %s", cluster.Name, err)
+	}
+
+	return &Result{Cluster: cluster, Err: err, Duration: time.Since(startedAt)}
+}
+
+// clusterArgs overrides kube options, namespace and adds values of the cluster to the current command args
+func clusterArgs(args []string, cluster *Cluster) []string {
+	res := append([]string{}, args...)
+
+	if cluster.KubeContext != "" {
+		res = append(res, "--kube-context", cluster.KubeContext)
+	}
+
+	if cluster.KubeConfig != "" {
+		res = append(res, "--kube-config", cluster.KubeConfig, "--kube-config-base64", "")
+	}
+
+	if cluster.Namespace != "" {
+		res = append(res, "--namespace", cluster.Namespace)
+	}
+
+	for _, values := range cluster.Values {
+		res = append(res, "--values", values)
+	}
+
+	return res
+}
+
+func resultsTable(results []*Result) string {
+	table := uitable.New()
+	table.MaxColWidth = 80
+	table.AddRow("CLUSTER", "KUBE CONTEXT", "NAMESPACE", "STATUS", "DURATION", "ERROR")
+
+	for _, res := range results {
+		status := "succeeded"
+		var errMsg string
+		switch {
+		case res.Skipped:
+			status = "skipped"
+		case res.Err != nil:
+			status = "failed"
+			errMsg = res.Err.Error()
+		}
+
+		var duration string
+		if !res.Skipped {
+			duration = res.Duration.Round(time.Second).String()
+		}
+
+		table.AddRow(res.Cluster.Name, res.Cluster.KubeContext, res.Cluster.Namespace, status, duration, errMsg)
+	}
+
+	return table.String()
+}
+
+func clusterNames(clusters []*Cluster) string {
+	var names []string
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
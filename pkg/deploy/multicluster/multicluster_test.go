@@ -0,0 +1,42 @@
+package multicluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCluster(t *testing.T) {
+	cluster, err := ParseCluster("name=eu, kube-context=eu-prod,namespace=app,values=.helm/values_eu.yaml;.helm/values_prod.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &Cluster{
+		Name:        "eu",
+		KubeContext: "eu-prod",
+		Namespace:   "app",
+		Values:      []string{".helm/values_eu.yaml", ".helm/values_prod.yaml"},
+	}
+	if !reflect.DeepEqual(cluster, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cluster)
+	}
+
+	for _, value := range []string{"", "name=eu", "kube-context=eu-prod", "name=eu,kube-context=", "name=eu,context=eu-prod"} {
+		if _, err := ParseCluster(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestClusterArgs(t *testing.T) {
+	args := clusterArgs([]string{"converge", "--env", "production"}, &Cluster{
+		Name:       "us",
+		KubeConfig: "/etc/kube/us.yaml",
+		Values:     []string{"values_us.yaml"},
+	})
+
+	expected := []string{"converge", "--env", "production", "--kube-config", "/etc/kube/us.yaml", "--kube-config-base64", "", "--values", "values_us.yaml"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
@@ -1,13 +1,17 @@
 package deploy
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/werf/logboek"
+	"helm.sh/helm/v3/pkg/cli/values"
 
 	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
 	"github.com/werf/werf/pkg/images_manager"
 	"github.com/werf/werf/pkg/tag_strategy"
 )
@@ -24,6 +28,13 @@ type RenderOptions struct {
 	UserExtraAnnotations map[string]string
 	UserExtraLabels      map[string]string
 	IgnoreSecretKey      bool
+	HelmVersion          string
+
+	// RedactSecrets replaces secret values, werf_secret_file content and data of Secret resources with the <redacted> placeholder
+	RedactSecrets bool
+	// OutputDir enables writing of each rendered manifest to the <dir>/[<namespace>_]<kind>-<name>.yaml file instead of the output stream
+	OutputDir     string
+	Kustomization bool
 }
 
 func RunRender(ctx context.Context, out io.Writer, projectDir, helmChartDir string, werfConfig *config.WerfConfig, imagesRepository string, images []images_manager.ImageInfoGetter, commonTag string, tagStrategy tag_strategy.TagStrategy, opts RenderOptions) error {
@@ -43,30 +54,83 @@ func RunRender(ctx context.Context, out io.Writer, projectDir, helmChartDir stri
 	if err != nil {
 		return err
 	}
+	werfChart.HelmVersion = opts.HelmVersion
 
 	werfChart.MergeExtraAnnotations(opts.UserExtraAnnotations)
 	werfChart.MergeExtraLabels(opts.UserExtraLabels)
 	werfChart.LogExtraAnnotations(ctx)
 	werfChart.LogExtraLabels(ctx)
 
-	renderOptions := helm.RenderOptions{
-		ShowNotes: false,
+	if err := werfChart.ValidateValues(helm.ChartValuesOptions{
+		Set:       opts.Set,
+		SetString: opts.SetString,
+		Values:    opts.Values,
+	}, serviceValuesSchemas()); err != nil {
+		return err
+	}
+
+	decodedSecretFilesData := werfChart.DecodedSecretFilesData
+	secretValues := werfChart.SecretValues
+	if opts.RedactSecrets {
+		decodedSecretFilesData = helm.RedactSecretFilesData(decodedSecretFilesData)
+		secretValues = helm.RedactSecretValues(secretValues)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if werfChart.HelmVersion == config.HelmVersion3 {
+		if err := helm_v3.Render(ctx, buf, werfChart.ChartDir, opts.ReleaseName, helm_v3.RenderOptions{
+			ValuesOptions: values.Options{
+				ValueFiles:   append(werfChart.Values, opts.Values...),
+				StringValues: append(werfChart.SetString, opts.SetString...),
+				Values:       append(werfChart.Set, opts.Set...),
+			},
+			SecretValues: secretValues,
+			LoadChartOptions: helm_v3.LoadChartOptions{
+				Name:        werfChart.Name,
+				SecretFiles: decodedSecretFilesData,
+			},
+			ExtraAnnotations: werfChart.ExtraAnnotations,
+			ExtraLabels:      werfChart.ExtraLabels,
+			Namespace:        opts.Namespace,
+		}); err != nil {
+			return err
+		}
+	} else {
+		renderOptions := helm.RenderOptions{
+			ShowNotes: false,
+		}
+
+		helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(decodedSecretFilesData)
+		patchLoadChartfile(werfChart.Name)
+
+		if err := helm.WerfTemplateEngineWithExtraAnnotationsAndLabels(werfChart.ExtraAnnotations, werfChart.ExtraLabels, func() error {
+			return helm.Render(
+				ctx,
+				buf,
+				werfChart.ChartDir,
+				opts.ReleaseName,
+				opts.Namespace,
+				append(werfChart.Values, opts.Values...),
+				secretValues,
+				append(werfChart.Set, opts.Set...),
+				append(werfChart.SetString, opts.SetString...),
+				renderOptions)
+		}); err != nil {
+			return err
+		}
+	}
+
+	manifests := buf.String()
+	if opts.RedactSecrets {
+		if manifests, err = helm.RedactSecretManifests(manifests); err != nil {
+			return fmt.Errorf("unable to redact secrets: %s", err)
+		}
+	}
+
+	if opts.OutputDir != "" {
+		return helm.WriteManifestsToDir(manifests, opts.OutputDir, helm.ManifestsDirOptions{Kustomization: opts.Kustomization})
 	}
 
-	helm.WerfTemplateEngine.InitWerfEngineExtraTemplatesFunctions(werfChart.DecodedSecretFilesData)
-	patchLoadChartfile(werfChart.Name)
-
-	return helm.WerfTemplateEngineWithExtraAnnotationsAndLabels(werfChart.ExtraAnnotations, werfChart.ExtraLabels, func() error {
-		return helm.Render(
-			ctx,
-			out,
-			werfChart.ChartDir,
-			opts.ReleaseName,
-			opts.Namespace,
-			append(werfChart.Values, opts.Values...),
-			werfChart.SecretValues,
-			append(werfChart.Set, opts.Set...),
-			append(werfChart.SetString, opts.SetString...),
-			renderOptions)
-	})
+	_, err = io.WriteString(out, manifests)
+	return err
 }
@@ -0,0 +1,74 @@
+package deploy
+
+import "github.com/werf/werf/pkg/deploy/helm"
+
+// ServiceValuesSchema is the JSON schema of the service values (see GetServiceValues), the schema describes only global.werf,
+// global.namespace and global.env keys and allows any other chart values
+const ServiceValuesSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "werf service values",
+  "type": "object",
+  "properties": {
+    "global": {
+      "type": "object",
+      "properties": {
+        "namespace": {"type": "string"},
+        "env": {"type": "string"},
+        "werf": {"$ref": "#/definitions/werf"}
+      }
+    }
+  },
+  "definitions": {
+    "werf": {
+      "type": "object",
+      "required": ["name", "repo", "ci", "image"],
+      "properties": {
+        "name": {"type": "string"},
+        "repo": {"type": "string"},
+        "docker_tag": {"type": "string"},
+        "is_nameless_image": {"type": "boolean"},
+        "ci": {"$ref": "#/definitions/ci"},
+        "image": {"type": "object"}
+      },
+      "if": {
+        "required": ["is_nameless_image"],
+        "properties": {"is_nameless_image": {"const": true}}
+      },
+      "then": {
+        "properties": {"image": {"$ref": "#/definitions/image"}}
+      },
+      "else": {
+        "properties": {"image": {"additionalProperties": {"$ref": "#/definitions/image"}}}
+      }
+    },
+    "ci": {
+      "type": "object",
+      "required": ["is_tag", "is_branch", "is_custom", "branch", "tag", "ref"],
+      "properties": {
+        "is_tag": {"type": "boolean"},
+        "is_branch": {"type": "boolean"},
+        "is_custom": {"type": "boolean"},
+        "is_custom_tag": {"type": "boolean"},
+        "is_tag_by_stages_signatures": {"type": "boolean"},
+        "branch": {"type": "string"},
+        "tag": {"type": "string"},
+        "ref": {"type": "string"}
+      }
+    },
+    "image": {
+      "type": "object",
+      "required": ["docker_image", "docker_tag"],
+      "properties": {
+        "docker_image": {"type": "string"},
+        "docker_tag": {"type": "string"},
+        "docker_image_id": {"type": "string"},
+        "docker_image_digest": {"type": "string"}
+      }
+    }
+  }
+}
+`
+
+func serviceValuesSchemas() []helm.ValuesSchema {
+	return []helm.ValuesSchema{{Name: "werf service values schema", Data: []byte(ServiceValuesSchema)}}
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/images_manager"
+	"github.com/werf/werf/pkg/tag_strategy"
+)
+
+type serviceValuesTestImage struct {
+	name string
+}
+
+func (i serviceValuesTestImage) IsNameless() bool { return i.name == "" }
+func (i serviceValuesTestImage) GetName() string  { return i.name }
+func (i serviceValuesTestImage) GetImageName() string {
+	return "registry.example.com/project/" + i.name + ":tag"
+}
+func (i serviceValuesTestImage) GetImageID(_ context.Context) (string, error) {
+	return "sha256:id", nil
+}
+func (i serviceValuesTestImage) GetImageDigest(_ context.Context) (string, error) {
+	return "", nil
+}
+func (i serviceValuesTestImage) GetImageTag() string { return "tag" }
+
+func TestServiceValuesSchema(t *testing.T) {
+	chartDir, err := ioutil.TempDir("", "werf-service-values-schema-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(chartDir)
+
+	for _, images := range [][]images_manager.ImageInfoGetter{
+		nil,
+		{serviceValuesTestImage{}},
+		{serviceValuesTestImage{name: "backend"}, serviceValuesTestImage{name: "frontend"}},
+	} {
+		serviceValues, err := GetServiceValues(context.Background(), "project", "registry.example.com/project", "namespace", "tag", tag_strategy.GitBranch, images, ServiceValuesOptions{Env: "production"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		opts := helm.ValidateValuesOptions{
+			SecretValues: []helm.ValuesSource{{Origin: "werf service values", Values: serviceValues}},
+			ExtraSchemas: serviceValuesSchemas(),
+		}
+
+		if err := helm.ValidateValues(chartDir, opts); err != nil {
+			t.Errorf("unexpected error for %d images: %s", len(images), err)
+		}
+
+		opts.Set = []string{"global.werf.ci.is_branch=1"}
+		if err := helm.ValidateValues(chartDir, opts); err == nil {
+			t.Errorf("expected validation error for %d images", len(images))
+		} else if !strings.Contains(err.Error(), "global.werf.ci.is_branch: Invalid type. Expected: boolean, given: integer (--set global.werf.ci.is_branch=1)") {
+			t.Errorf("unexpected error for %d images: %s", len(images), err)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/ghodss/yaml"
 
 	"github.com/werf/logboek"
+	"github.com/werf/werf/pkg/config"
 	"github.com/werf/werf/pkg/deploy/helm"
 	"github.com/werf/werf/pkg/deploy/secret"
 	"github.com/werf/werf/pkg/util"
@@ -37,9 +38,11 @@ type WerfChart struct {
 	SetString        []string
 	ExtraAnnotations map[string]string
 	ExtraLabels      map[string]string
+	HelmVersion      string
 
 	DecodedSecretFilesData map[string]string
 	SecretValuesToMask     []string
+	SecretValuesOrigins    []string
 }
 
 func (chart *WerfChart) SetGlobalAnnotation(name, value string) error {
@@ -49,6 +52,7 @@ func (chart *WerfChart) SetGlobalAnnotation(name, value string) error {
 
 func (chart *WerfChart) SetServiceValues(values map[string]interface{}) error {
 	chart.SecretValues = append(chart.SecretValues, values)
+	chart.SecretValuesOrigins = append(chart.SecretValuesOrigins, "werf service values")
 	return nil
 }
 
@@ -68,29 +72,60 @@ func (chart *WerfChart) SetSecretValuesFile(path string, m secret.Manager) error
 		return fmt.Errorf("cannot unmarshal secret values file %s: %s", path, err)
 	}
 	chart.SecretValues = append(chart.SecretValues, values)
+	chart.SecretValuesOrigins = append(chart.SecretValuesOrigins, path)
 	chart.SecretValuesToMask = secretvalues.ExtractSecretValuesFromMap(values)
 
 	return nil
 }
 
+// ValidateValues validates the merged chart values against the values.schema.json of the chart and the extra schemas
+func (chart *WerfChart) ValidateValues(opts helm.ChartValuesOptions, extraSchemas []helm.ValuesSchema) error {
+	var secretValues []helm.ValuesSource
+	for i, values := range chart.SecretValues {
+		secretValues = append(secretValues, helm.ValuesSource{Origin: chart.SecretValuesOrigins[i], Values: values})
+	}
+
+	if err := helm.ValidateValues(chart.ChartDir, helm.ValidateValuesOptions{
+		Values:       append(chart.Values, opts.Values...),
+		SecretValues: secretValues,
+		Set:          append(chart.Set, opts.Set...),
+		SetString:    append(chart.SetString, opts.SetString...),
+		ExtraSchemas: extraSchemas,
+	}); err != nil {
+		return fmt.Errorf("%s", secretvalues.MaskSecretValuesInString(chart.SecretValuesToMask, err.Error()))
+	}
+
+	return nil
+}
+
+// helmV3Upgrade is replaced in tests
+var helmV3Upgrade = helm_v3.Upgrade
+
 func (chart *WerfChart) Deploy(ctx context.Context, releaseName string, namespace string, opts helm.ChartOptions) error {
-	if os.Getenv("WERF_HELM3") == "1" {
+	if chart.HelmVersion == config.HelmVersion3 {
 		valuesOptions := values.Options{
 			ValueFiles:   append(chart.Values, opts.Values...),
 			StringValues: append(chart.SetString, opts.SetString...),
 			Values:       append(chart.Set, opts.Set...),
-			FileValues:   nil,
 		}
-		secretValues := append(chart.SecretValues, opts.SecretValues...)
-		_ = secretValues
-
-		return helm_v3.Upgrade(ctx, chart.ChartDir, releaseName, helm_v3.UpgradeOptions{
-			ValuesOptions:   valuesOptions,
-			Namespace:       namespace,
-			CreateNamespace: true,
-			Install:         true,
-			Atomic:          false,
-			Timeout:         opts.Timeout,
+
+		// the release resources are always tracked, the auto rollback only decides what to do with the tracking error
+		return helmV3Upgrade(ctx, chart.ChartDir, releaseName, helm_v3.UpgradeOptions{
+			ValuesOptions: valuesOptions,
+			SecretValues:  append(chart.SecretValues, opts.SecretValues...),
+			LoadChartOptions: helm_v3.LoadChartOptions{
+				Name:        chart.Name,
+				SecretFiles: chart.DecodedSecretFilesData,
+			},
+			ExtraAnnotations: chart.ExtraAnnotations,
+			ExtraLabels:      chart.ExtraLabels,
+			Namespace:        namespace,
+			CreateNamespace:  true,
+			Install:          true,
+			Atomic:           false,
+			Wait:             true,
+			AutoRollback:     opts.AutoRollback,
+			Timeout:          opts.Timeout,
 		})
 	} else {
 		opts.SecretValues = append(chart.SecretValues, opts.SecretValues...)
@@ -0,0 +1,38 @@
+package werf_chart
+
+import (
+	"context"
+	"testing"
+
+	"github.com/werf/werf/pkg/config"
+	"github.com/werf/werf/pkg/deploy/helm"
+	"github.com/werf/werf/pkg/deploy/helm_v3"
+)
+
+func TestDeployWithHelmV3TracksResources(t *testing.T) {
+	defer func(upgrade func(context.Context, string, string, helm_v3.UpgradeOptions) error) {
+		helmV3Upgrade = upgrade
+	}(helmV3Upgrade)
+
+	var upgradeOpts helm_v3.UpgradeOptions
+	helmV3Upgrade = func(_ context.Context, _, _ string, opts helm_v3.UpgradeOptions) error {
+		upgradeOpts = opts
+		return nil
+	}
+
+	chart := &WerfChart{Name: "app", ChartDir: ".helm", HelmVersion: config.HelmVersion3}
+
+	for _, autoRollback := range []bool{false, true} {
+		if err := chart.Deploy(context.Background(), "app", "app", helm.ChartOptions{AutoRollback: autoRollback}); err != nil {
+			t.Fatal(err)
+		}
+
+		if !upgradeOpts.Wait {
+			t.Errorf("auto rollback %v: expected release resources to be tracked", autoRollback)
+		}
+
+		if upgradeOpts.AutoRollback != autoRollback {
+			t.Errorf("expected auto rollback %v, got %v", autoRollback, upgradeOpts.AutoRollback)
+		}
+	}
+}
@@ -149,10 +149,7 @@ func (api *api) PushImage(_ context.Context, reference string, opts PushImageOpt
 
 	img := container_registry_extensions.NewManifestOnlyImage(opts.Labels)
 
-	oldDefaultTransport := http.DefaultTransport
-	http.DefaultTransport = api.getHttpTransport()
-	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
-	http.DefaultTransport = oldDefaultTransport
+	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport()))
 
 	if err != nil {
 		return fmt.Errorf("write to the remote %s have failed: %s", ref.String(), err)
@@ -161,19 +158,37 @@ func (api *api) PushImage(_ context.Context, reference string, opts PushImageOpt
 	return nil
 }
 
-func (api *api) image(reference string) (v1.Image, name.Reference, error) {
+// CopyImage writes the manifest of the source image to the destination reference,
+// the layers are mounted from the source repository when both repositories are in the same registry,
+// otherwise the layers are streamed from the source registry to the destination one (see ContextWithBandwidthLimiter)
+func (api *api) CopyImage(ctx context.Context, sourceReference, destinationReference string) error {
+	img, _, err := api.image(sourceReference, remote.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	ref, err := name.ParseReference(destinationReference, api.parseReferenceOptions()...)
+	if err != nil {
+		return fmt.Errorf("parsing reference %q: %v", destinationReference, err)
+	}
+
+	err = remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport()), remote.WithContext(ctx))
+
+	if err != nil {
+		return fmt.Errorf("copy %s to the remote %s have failed: %s", sourceReference, ref.String(), err)
+	}
+
+	return nil
+}
+
+func (api *api) image(reference string, extraOptions ...remote.Option) (v1.Image, name.Reference, error) {
 	ref, err := name.ParseReference(reference, api.parseReferenceOptions()...)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing reference %q: %v", reference, err)
 	}
 
-	// FIXME: Hack for the go-containerregistry library,
-	// FIXME: that uses default transport without options to change transport to custom.
-	// FIXME: Needed for the insecure https registry to work.
-	oldDefaultTransport := http.DefaultTransport
-	http.DefaultTransport = api.getHttpTransport()
-	img, err := remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
-	http.DefaultTransport = oldDefaultTransport
+	options := append([]remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(api.getHttpTransport())}, extraOptions...)
+	img, err := remote.Image(ref, options...)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("reading image %q: %v", ref, err)
@@ -197,25 +212,21 @@ func (api *api) parseReferenceOptions() []name.Option {
 	return options
 }
 
-func (api *api) getHttpTransport() (transport http.RoundTripper) {
-	transport = http.DefaultTransport
+func (api *api) getHttpTransport() http.RoundTripper {
+	transport := baseHttpTransport
 
 	if api.SkipTlsVerifyRegistry {
-		defaultTransport := http.DefaultTransport.(*http.Transport)
-
-		newTransport := &http.Transport{
-			Proxy:                 defaultTransport.Proxy,
-			DialContext:           defaultTransport.DialContext,
-			MaxIdleConns:          defaultTransport.MaxIdleConns,
-			IdleConnTimeout:       defaultTransport.IdleConnTimeout,
-			TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
-			ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
+		transport = &http.Transport{
+			Proxy:                 baseHttpTransport.Proxy,
+			DialContext:           baseHttpTransport.DialContext,
+			MaxIdleConns:          baseHttpTransport.MaxIdleConns,
+			IdleConnTimeout:       baseHttpTransport.IdleConnTimeout,
+			TLSHandshakeTimeout:   baseHttpTransport.TLSHandshakeTimeout,
+			ExpectContinueTimeout: baseHttpTransport.ExpectContinueTimeout,
 			TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
 			TLSNextProto:          make(map[string]func(authority string, c *tls.Conn) http.RoundTripper),
 		}
-
-		transport = newTransport
 	}
 
-	return
+	return newRegistryTransport(transport)
 }
@@ -0,0 +1,74 @@
+package docker_registry
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type bandwidthLimiterContextKey struct{}
+
+// ContextWithBandwidthLimiter makes the registry transport throttle the bodies of the responses (e.g. the layers blobs)
+// to the limiter rate for the requests made with the context
+func ContextWithBandwidthLimiter(ctx context.Context, limiter *BandwidthLimiter) context.Context {
+	return context.WithValue(ctx, bandwidthLimiterContextKey{}, limiter)
+}
+
+func getBandwidthLimiter(ctx context.Context) *BandwidthLimiter {
+	limiter, _ := ctx.Value(bandwidthLimiterContextKey{}).(*BandwidthLimiter)
+	return limiter
+}
+
+// BandwidthLimiter delays the reads so that the average transfer rate since the creation of the limiter does not exceed the limit,
+// the same limiter can be shared by the concurrent transfers
+type BandwidthLimiter struct {
+	BytesPerSecond int64
+
+	startedAt     time.Time
+	reservedBytes int64
+	mux           sync.Mutex
+}
+
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{BytesPerSecond: bytesPerSecond, startedAt: time.Now()}
+}
+
+func (limiter *BandwidthLimiter) Wait(ctx context.Context, bytes int64) error {
+	if limiter.BytesPerSecond <= 0 || bytes <= 0 {
+		return nil
+	}
+
+	limiter.mux.Lock()
+	limiter.reservedBytes += bytes
+	allowedAt := limiter.startedAt.Add(time.Duration(float64(limiter.reservedBytes) / float64(limiter.BytesPerSecond) * float64(time.Second)))
+	limiter.mux.Unlock()
+
+	delay := time.Until(allowedAt)
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// limitedReadCloser waits for the limiter after each read, so the stream is consumed at the limiter rate
+type limitedReadCloser struct {
+	io.ReadCloser
+
+	ctx     context.Context
+	limiter *BandwidthLimiter
+}
+
+func (r *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, int64(n)); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
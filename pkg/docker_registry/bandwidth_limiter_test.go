@@ -0,0 +1,47 @@
+package docker_registry
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistryTransportThrottlesResponseBody(t *testing.T) {
+	blob := bytes.Repeat([]byte("x"), 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(blob)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newRegistryTransport(http.DefaultTransport)}
+
+	limiter := NewBandwidthLimiter(8192)
+	req, err := http.NewRequestWithContext(ContextWithBandwidthLimiter(context.Background(), limiter), http.MethodGet, server.URL+"/v2/repo/blobs/sha256:x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startedAt := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, blob) {
+		t.Fatalf("unexpected body of %d bytes", len(data))
+	}
+
+	if elapsed := time.Since(startedAt); elapsed < 400*time.Millisecond {
+		t.Errorf("expected 4096 bytes to be read at 8192 bytes per second in about 500ms, read in %s", elapsed)
+	}
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/werf/logboek"
 )
 
+var registryApiClient = &http.Client{Transport: newRegistryTransport(baseHttpTransport)}
+
 type apiError struct {
 	error
 }
@@ -44,7 +46,7 @@ func doRequest(ctx context.Context, method, url string, body io.Reader, options
 	}
 
 	logboek.Context(ctx).Debug().LogF("--> %s %s\n", method, url)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := registryApiClient.Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
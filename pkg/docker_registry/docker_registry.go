@@ -28,6 +28,7 @@ type DockerRegistry interface {
 	SelectRepoImageList(ctx context.Context, reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error)
 	DeleteRepoImage(ctx context.Context, repoImageList ...*image.Info) error
 	PushImage(ctx context.Context, reference string, opts PushImageOptions) error
+	CopyImage(ctx context.Context, sourceReference, destinationReference string) error
 
 	ResolveRepoMode(ctx context.Context, registryOrRepositoryAddress, repoMode string) (string, error)
 	String() string
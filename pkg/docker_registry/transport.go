@@ -0,0 +1,285 @@
+package docker_registry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werf/logboek"
+)
+
+const (
+	registryRequestMaxAttempts    = 6
+	registryRequestMinRetryDelay  = time.Second
+	registryRequestMaxRetryDelay  = time.Minute
+	registryRequestMaxRetryAfter  = 5 * time.Minute
+	registryMaxConcurrentRequests = 10
+)
+
+var (
+	// baseHttpTransport is saved before any changes of the http.DefaultTransport
+	baseHttpTransport = http.DefaultTransport.(*http.Transport)
+
+	registryHostsStates    = map[string]*registryHostState{}
+	registryHostsStatesMux sync.Mutex
+)
+
+// registryTransport is used for all requests to the registries: the number of concurrent requests to the registry host is limited
+// (the slot is held until the body of the successful response is read till the end or closed),
+// requests failed with 429 and 5xx codes are retried with exponential backoff and jitter or after the delay from the Retry-After header,
+// rate limit state of the registry from the RateLimit-* headers is reported in the log,
+// response bodies are throttled by the BandwidthLimiter of the request context (see ContextWithBandwidthLimiter).
+type registryTransport struct {
+	base http.RoundTripper
+}
+
+func newRegistryTransport(base http.RoundTripper) *registryTransport {
+	return &registryTransport{base: base}
+}
+
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := getRegistryHostState(req.URL.Host)
+
+	for attempt := 1; ; attempt++ {
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		// the upload streaming the body of another response (e.g. the layer copied between the repositories) does not take the slot:
+		// the streamed response already holds the slot of the source registry, which may be the same host
+		if !isRewindableRequest(req) {
+			resp, err := t.base.RoundTrip(attemptReq)
+			if err == nil {
+				state.observeRateLimit(resp)
+			}
+			return resp, err
+		}
+
+		if err := state.acquire(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			state.release()
+			return nil, err
+		}
+
+		state.observeRateLimit(resp)
+
+		if !isRetryableStatusCode(resp.StatusCode) || attempt >= registryRequestMaxAttempts {
+			if resp.StatusCode >= http.StatusBadRequest || resp.Body == nil {
+				// some callers (e.g. the bearer transport of go-containerregistry) do not close the bodies of failed responses
+				state.release()
+			} else {
+				resp.Body = &slotReleasingReadCloser{ReadCloser: resp.Body, state: state}
+			}
+
+			if limiter := getBandwidthLimiter(req.Context()); limiter != nil && resp.Body != nil {
+				resp.Body = &limitedReadCloser{ReadCloser: resp.Body, ctx: req.Context(), limiter: limiter}
+			}
+			return resp, nil
+		}
+
+		state.release()
+
+		delay, ok := getRetryDelay(resp, attempt, time.Now())
+		if !ok {
+			logboek.Warn().LogF("Registry %s responded %s to %s %s and asked to retry after %s: giving up%s\n", req.URL.Host, resp.Status, req.Method, req.URL.Path, resp.Header.Get("Retry-After"), state.rateLimitDescription())
+			return resp, nil
+		}
+
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		logboek.Warn().LogF("Registry %s responded %s to %s %s, retrying in %s (%d/%d)%s\n", req.URL.Host, resp.Status, req.Method, req.URL.Path, delay.Round(time.Millisecond), attempt, registryRequestMaxAttempts-1, state.rateLimitDescription())
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// slotReleasingReadCloser releases the slot of the registry host once the body is read till the end or closed
+type slotReleasingReadCloser struct {
+	io.ReadCloser
+
+	state       *registryHostState
+	releaseOnce sync.Once
+}
+
+func (r *slotReleasingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		r.release()
+	}
+	return n, err
+}
+
+func (r *slotReleasingReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
+
+func (r *slotReleasingReadCloser) release() {
+	r.releaseOnce.Do(r.state.release)
+}
+
+func isRetryableStatusCode(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRewindableRequest returns false for the requests with the streamed body (e.g. blob uploads), such requests are not retried
+func isRewindableRequest(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	newReq := req.Clone(req.Context())
+	newReq.Body = body
+	return newReq, nil
+}
+
+// getRetryDelay returns the delay from the Retry-After header (seconds or http date) or the exponential backoff with jitter,
+// the request should not be retried when the registry asks to wait too long
+func getRetryDelay(resp *http.Response, attempt int, now time.Time) (time.Duration, bool) {
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		var delay time.Duration
+		var parsed bool
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			delay, parsed = time.Duration(seconds)*time.Second, true
+		} else if date, err := http.ParseTime(retryAfter); err == nil {
+			delay, parsed = date.Sub(now), true
+		}
+
+		if parsed {
+			if delay > registryRequestMaxRetryAfter {
+				return 0, false
+			} else if delay < 0 {
+				delay = 0
+			}
+			return delay, true
+		}
+	}
+
+	backoff := registryRequestMaxRetryDelay
+	if attempt < 32 && registryRequestMinRetryDelay<<uint(attempt-1) < registryRequestMaxRetryDelay {
+		backoff = registryRequestMinRetryDelay << uint(attempt-1)
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+type registryHostState struct {
+	Host string
+
+	semaphore chan struct{}
+
+	rateLimit            int
+	rateLimitRemaining   int
+	lowRateLimitReported bool
+	mux                  sync.Mutex
+}
+
+func getRegistryHostState(host string) *registryHostState {
+	registryHostsStatesMux.Lock()
+	defer registryHostsStatesMux.Unlock()
+
+	state, ok := registryHostsStates[host]
+	if !ok {
+		state = &registryHostState{Host: host, semaphore: make(chan struct{}, registryMaxConcurrentRequests), rateLimit: -1, rateLimitRemaining: -1}
+		registryHostsStates[host] = state
+	}
+
+	return state
+}
+
+func (state *registryHostState) acquire(ctx context.Context) error {
+	select {
+	case state.semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (state *registryHostState) release() {
+	<-state.semaphore
+}
+
+// observeRateLimit saves RateLimit-Limit and RateLimit-Remaining (or X-RateLimit-*) headers, e.g. "100;w=21600" of Docker Hub,
+// and warns once when less than 10% of the limit remains
+func (state *registryHostState) observeRateLimit(resp *http.Response) {
+	limit := parseRateLimitHeader(resp.Header, "RateLimit-Limit")
+	remaining := parseRateLimitHeader(resp.Header, "RateLimit-Remaining")
+	if remaining < 0 {
+		return
+	}
+
+	state.mux.Lock()
+	defer state.mux.Unlock()
+
+	state.rateLimit = limit
+	state.rateLimitRemaining = remaining
+
+	logboek.Debug().LogF("Registry %s rate limit: %d of %d requests remaining\n", state.Host, remaining, limit)
+
+	if limit > 0 && remaining*10 <= limit {
+		if !state.lowRateLimitReported {
+			state.lowRateLimitReported = true
+			logboek.Warn().LogF("Registry %s rate limit is almost exhausted: %d of %d requests remaining\n", state.Host, remaining, limit)
+		}
+	} else {
+		state.lowRateLimitReported = false
+	}
+}
+
+func (state *registryHostState) rateLimitDescription() string {
+	state.mux.Lock()
+	defer state.mux.Unlock()
+
+	if state.rateLimitRemaining < 0 {
+		return ""
+	} else if state.rateLimit < 0 {
+		return fmt.Sprintf(" (rate limit: %d requests remaining)", state.rateLimitRemaining)
+	}
+	return fmt.Sprintf(" (rate limit: %d of %d requests remaining)", state.rateLimitRemaining, state.rateLimit)
+}
+
+func parseRateLimitHeader(header http.Header, name string) int {
+	value := header.Get(name)
+	if value == "" {
+		value = header.Get("X-" + name)
+	}
+
+	value = strings.TrimSpace(strings.SplitN(value, ";", 2)[0])
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return -1
+}
@@ -0,0 +1,103 @@
+package docker_registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetRetryDelay(t *testing.T) {
+	now := time.Now()
+
+	for _, tc := range []struct {
+		retryAfter string
+		attempt    int
+		min, max   time.Duration
+		ok         bool
+	}{
+		{retryAfter: "10", attempt: 1, min: 10 * time.Second, max: 10 * time.Second, ok: true},
+		{retryAfter: now.Add(30 * time.Second).UTC().Format(http.TimeFormat), attempt: 1, min: 29 * time.Second, max: 30 * time.Second, ok: true},
+		{retryAfter: now.Add(-time.Minute).UTC().Format(http.TimeFormat), attempt: 1, min: 0, max: 0, ok: true},
+		{retryAfter: "21600", attempt: 1, ok: false},
+		// exponential backoff with jitter in [backoff/2, backoff] when Retry-After is not set or cannot be parsed
+		{attempt: 1, min: registryRequestMinRetryDelay / 2, max: registryRequestMinRetryDelay, ok: true},
+		{retryAfter: "soon", attempt: 3, min: 2 * time.Second, max: 4 * time.Second, ok: true},
+		{attempt: 10, min: registryRequestMaxRetryDelay / 2, max: registryRequestMaxRetryDelay, ok: true},
+		{attempt: 100, min: registryRequestMaxRetryDelay / 2, max: registryRequestMaxRetryDelay, ok: true},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.retryAfter != "" {
+			resp.Header.Set("Retry-After", tc.retryAfter)
+		}
+
+		for i := 0; i < 10; i++ {
+			delay, ok := getRetryDelay(resp, tc.attempt, now)
+			if ok != tc.ok {
+				t.Fatalf("Retry-After %q, attempt %d: expected ok %v, got %v", tc.retryAfter, tc.attempt, tc.ok, ok)
+			}
+
+			if ok && (delay < tc.min || delay > tc.max) {
+				t.Fatalf("Retry-After %q, attempt %d: expected delay in [%s, %s], got %s", tc.retryAfter, tc.attempt, tc.min, tc.max, delay)
+			}
+		}
+	}
+}
+
+func TestRegistryTransportConcurrentRequestsLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/unauthorized" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		_, _ = w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newRegistryTransport(http.DefaultTransport)}
+
+	// the failed responses release the slot at once, even if the bodies are not closed
+	for i := 0; i < registryMaxConcurrentRequests+1; i++ {
+		if _, err := client.Get(server.URL + "/v2/unauthorized"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var openResponses []*http.Response
+	for i := 0; i < registryMaxConcurrentRequests; i++ {
+		resp, err := client.Get(server.URL + "/v2/blob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		openResponses = append(openResponses, resp)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(server.URL + "/v2/blob")
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("expected request to wait for the slot while %d responses are not closed", registryMaxConcurrentRequests)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	openResponses[0].Body.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected request to get the slot of the closed response")
+	}
+
+	for _, resp := range openResponses[1:] {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,76 @@
+package docker_registry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/werf/werf/pkg/docker_registry"
+)
+
+var _ = Describe("registry transport", func() {
+	var server *httptest.Server
+	var tagsRequests int32
+	var tagsFailures int32
+	var retryAfter string
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&tagsRequests, 0)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/":
+				w.WriteHeader(http.StatusOK)
+			case "/v2/repo/tags/list":
+				w.Header().Set("RateLimit-Limit", "100;w=21600")
+				w.Header().Set("RateLimit-Remaining", "5;w=21600")
+
+				if atomic.AddInt32(&tagsRequests, 1) <= atomic.LoadInt32(&tagsFailures) {
+					w.Header().Set("Retry-After", retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"name":"repo","tags":["tag1","tag2"]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	getTags := func() ([]string, error) {
+		dockerRegistry, err := docker_registry.NewDockerRegistry("", docker_registry.DefaultImplementationName, docker_registry.DockerRegistryOptions{InsecureRegistry: true})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return dockerRegistry.Tags(context.Background(), strings.TrimPrefix(server.URL, "http://")+"/repo")
+	}
+
+	It("retries rate limited requests after Retry-After delay", func() {
+		atomic.StoreInt32(&tagsFailures, 2)
+		retryAfter = "0"
+
+		tags, err := getTags()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(tags).Should(Equal([]string{"tag1", "tag2"}))
+		Ω(atomic.LoadInt32(&tagsRequests)).Should(Equal(int32(3)))
+	})
+
+	It("gives up when registry asks to wait too long", func() {
+		atomic.StoreInt32(&tagsFailures, 1)
+		retryAfter = "21600"
+
+		_, err := getTags()
+		Ω(err).Should(HaveOccurred())
+		Ω(atomic.LoadInt32(&tagsRequests)).Should(Equal(int32(1)))
+	})
+})
@@ -16,6 +16,7 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
@@ -367,6 +368,52 @@ func (repo *Base) remoteBranchesList(repoPath string) ([]string, error) {
 	return res, nil
 }
 
+func (repo *Base) walkCommitFiles(repoPath, commit, dir string, f func(relPath string, content []byte) error) error {
+	repository, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return fmt.Errorf("cannot open repo `%s`: %s", repoPath, err)
+	}
+
+	commitHash, err := newHash(commit)
+	if err != nil {
+		return fmt.Errorf("bad commit hash `%s`: %s", commit, err)
+	}
+
+	commitObj, err := repository.CommitObject(commitHash)
+	if err != nil {
+		return fmt.Errorf("bad commit `%s`: %s", commit, err)
+	}
+
+	tree, err := commitObj.Tree()
+	if err != nil {
+		return fmt.Errorf("cannot get commit `%s` tree: %s", commit, err)
+	}
+
+	dir = strings.Trim(dir, "/")
+	if dir != "" {
+		tree, err = tree.Tree(dir)
+		if err == object.ErrDirectoryNotFound {
+			return fmt.Errorf("directory `%s` not found in commit `%s`", dir, commit)
+		} else if err != nil {
+			return fmt.Errorf("cannot get directory `%s` tree of commit `%s`: %s", dir, commit, err)
+		}
+	}
+
+	return tree.Files().ForEach(func(file *object.File) error {
+		// file.Mode.IsFile() also matches symlinks, content of which is the link target
+		if file.Mode != filemode.Regular && file.Mode != filemode.Executable {
+			return nil
+		}
+
+		content, err := file.Contents()
+		if err != nil {
+			return fmt.Errorf("cannot read file `%s` of commit `%s`: %s", file.Name, commit, err)
+		}
+
+		return f(file.Name, []byte(content))
+	})
+}
+
 func (repo *Base) checksumWithLsTree(ctx context.Context, repoPath, gitDir, workTreeCacheDir string, opts ChecksumOptions) (Checksum, error) {
 	repository, err := git.PlainOpenWithOptions(repoPath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
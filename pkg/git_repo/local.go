@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 
 	"github.com/werf/logboek"
 
@@ -211,6 +212,31 @@ func (repo *Local) IsCommitExists(ctx context.Context, commit string) (bool, err
 	return repo.isCommitExists(ctx, repo.Path, repo.GitDir, commit)
 }
 
+// WithWorkTree runs the function with the work tree of the repo checked out at the specified commit
+func (repo *Local) WithWorkTree(ctx context.Context, commit string, f func(workTreeDir string) error) error {
+	repository, err := git.PlainOpenWithOptions(repo.Path, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		return fmt.Errorf("cannot open repo `%s`: %s", repo.Path, err)
+	}
+
+	commitHash, err := repository.ResolveRevision(plumbing.Revision(commit))
+	if err != nil {
+		return fmt.Errorf("bad commit `%s`: %s", commit, err)
+	}
+
+	commitObj, err := repository.CommitObject(*commitHash)
+	if err != nil {
+		return fmt.Errorf("bad commit `%s`: %s", commit, err)
+	}
+
+	hasSubmodules, err := HasSubmodulesInCommit(commitObj)
+	if err != nil {
+		return err
+	}
+
+	return true_git.WithWorkTree(ctx, repo.GitDir, repo.getRepoWorkTreeCacheDir(), commitHash.String(), true_git.WithWorkTreeOptions{HasSubmodules: hasSubmodules}, f)
+}
+
 func (repo *Local) TagsList(ctx context.Context) ([]string, error) {
 	return repo.tagsList(repo.Path)
 }
@@ -83,11 +83,18 @@ func (repo *Remote) CloneAndFetch(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if isCloned {
-		return nil
+
+	if !isCloned {
+		if err := repo.Fetch(ctx); err != nil {
+			return err
+		}
 	}
 
-	return repo.Fetch(ctx)
+	if err := werf.TouchLastUse(repo.GetClonePath()); err != nil {
+		return fmt.Errorf("unable to record last use of %s: %s", repo.GetClonePath(), err)
+	}
+
+	return nil
 }
 
 func (repo *Remote) isCloneExists() (bool, error) {
@@ -296,10 +303,25 @@ func (repo *Remote) IsCommitExists(ctx context.Context, commit string) (bool, er
 	return repo.isCommitExists(ctx, repo.GetClonePath(), repo.GetClonePath(), commit)
 }
 
+// WalkCommitFiles calls f for each regular file of the commit tree located in the dir (path of the file is relative to the dir)
+func (repo *Remote) WalkCommitFiles(_ context.Context, commit, dir string, f func(relPath string, content []byte) error) error {
+	return repo.walkCommitFiles(repo.GetClonePath(), commit, dir, f)
+}
+
 func (repo *Remote) getWorkTreeCacheDir() string {
 	return filepath.Join(GetWorkTreeCacheDir(), repo.getFilesystemRelativePathByEndpoint())
 }
 
+func RemoteRepoCacheLockName(clonePath string) string {
+	return fmt.Sprintf("remote_git_repo_cache %s", clonePath)
+}
+
+// AcquireCacheLock holds the clone of the remote repo from being removed by the host cleanup until the lock is released
+func (repo *Remote) AcquireCacheLock(ctx context.Context) (lockgate.LockHandle, error) {
+	_, lock, err := werf.AcquireHostLock(ctx, RemoteRepoCacheLockName(repo.GetClonePath()), lockgate.AcquireOptions{Shared: true, Timeout: 600 * time.Second})
+	return lock, err
+}
+
 func (repo *Remote) withRemoteRepoLock(ctx context.Context, f func() error) error {
 	lockName := fmt.Sprintf("remote_git_mapping.%s", repo.Name)
 	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
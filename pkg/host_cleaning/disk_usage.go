@@ -0,0 +1,459 @@
+package host_cleaning
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/go-units"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker"
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/true_git"
+	"github.com/werf/werf/pkg/util"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type volumeUsage struct {
+	// ID is the same for the paths on the same volume
+	ID         string
+	UsedBytes  uint64
+	TotalBytes uint64
+}
+
+func (usage volumeUsage) Percentage() float64 {
+	if usage.TotalBytes == 0 {
+		return 0
+	}
+
+	return float64(usage.UsedBytes) / float64(usage.TotalBytes) * 100
+}
+
+type diskUsageCandidate struct {
+	// Kind is the type of the host object for logging (local stage, git repo clone, git worktree)
+	Kind     string
+	Name     string
+	Size     int64
+	LastUsed time.Time
+	// LockName is held by werf processes using the object, the object is skipped if the lock cannot be acquired exclusively
+	LockName string
+	Remove   func(ctx context.Context) error
+}
+
+type diskUsageVolume struct {
+	// Path is the first measured path of the volume, the usage is re-measured by this path
+	Path       string
+	Usage      volumeUsage
+	Candidates []*diskUsageCandidate
+}
+
+// cleanupByDiskUsage removes the least recently used local stages, remote git repos clones and git worktrees
+// while the usage of the docker storage volume or the werf local cache volume exceeds the allowed percentage.
+// Objects used by running werf processes are skipped.
+func cleanupByDiskUsage(ctx context.Context, allowedPercentage float64, options CommonOptions) error {
+	var volumes []*diskUsageVolume
+	addVolume := func(path string, candidates []*diskUsageCandidate) error {
+		usage, err := getVolumeUsage(path)
+		if err != nil {
+			return err
+		}
+
+		for _, volume := range volumes {
+			if volume.Usage.ID == usage.ID {
+				volume.Candidates = append(volume.Candidates, candidates...)
+				return nil
+			}
+		}
+
+		volumes = append(volumes, &diskUsageVolume{Path: path, Usage: usage, Candidates: candidates})
+		return nil
+	}
+
+	dockerInfo, err := docker.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get docker info: %s", err)
+	}
+
+	if exist, err := util.DirExists(dockerInfo.DockerRootDir); err != nil {
+		return err
+	} else if !exist {
+		// docker server runs on the other host or in the virtual machine
+		logboek.Context(ctx).Warn().LogF("WARNING: Docker storage dir %s is not accessible on the host, local stages will not be removed\n", dockerInfo.DockerRootDir)
+	} else {
+		candidates, err := localStagesCandidates(ctx, options)
+		if err != nil {
+			return err
+		}
+
+		if err := addVolume(dockerInfo.DockerRootDir, candidates); err != nil {
+			return err
+		}
+	}
+
+	if exist, err := util.DirExists(werf.GetLocalCacheDir()); err != nil {
+		return err
+	} else if exist {
+		var candidates []*diskUsageCandidate
+		for _, f := range []func(ctx context.Context) ([]*diskUsageCandidate, error){gitRepoClonesCandidates, gitWorkTreesCandidates} {
+			c, err := f(ctx)
+			if err != nil {
+				return err
+			}
+			candidates = append(candidates, c...)
+		}
+
+		if err := addVolume(werf.GetLocalCacheDir(), candidates); err != nil {
+			return err
+		}
+	}
+
+	for _, volume := range volumes {
+		if err := cleanupVolume(ctx, volume, allowedPercentage, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func cleanupVolume(ctx context.Context, volume *diskUsageVolume, allowedPercentage float64, options CommonOptions) error {
+	if volume.Usage.Percentage() <= allowedPercentage {
+		logboek.Context(ctx).Default().LogFDetails("Volume usage of %s %.2f%% does not exceed allowed %.2f%%\n", volume.Path, volume.Usage.Percentage(), allowedPercentage)
+		return nil
+	}
+
+	sortDiskUsageCandidates(volume.Candidates)
+
+	for _, candidate := range volume.Candidates {
+		if volume.Usage.Percentage() <= allowedPercentage {
+			break
+		}
+
+		reason := fmt.Sprintf("volume usage of %s %.2f%% exceeds allowed %.2f%%", volume.Path, volume.Usage.Percentage(), allowedPercentage)
+		if removed, err := removeDiskUsageCandidate(ctx, candidate, reason, options.DryRun); err != nil {
+			return err
+		} else if !removed {
+			continue
+		}
+
+		if options.DryRun {
+			if uint64(candidate.Size) < volume.Usage.UsedBytes {
+				volume.Usage.UsedBytes -= uint64(candidate.Size)
+			} else {
+				volume.Usage.UsedBytes = 0
+			}
+			continue
+		}
+
+		usage, err := getVolumeUsage(volume.Path)
+		if err != nil {
+			return err
+		}
+		volume.Usage = usage
+	}
+
+	if volume.Usage.Percentage() > allowedPercentage {
+		logboek.Context(ctx).Warn().LogF("WARNING: Volume usage of %s %.2f%% still exceeds allowed %.2f%%: there are no more werf objects that can be removed\n", volume.Path, volume.Usage.Percentage(), allowedPercentage)
+	}
+
+	return nil
+}
+
+// sortDiskUsageCandidates sorts candidates from the least recently used ones
+func sortDiskUsageCandidates(candidates []*diskUsageCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].LastUsed.Before(candidates[j].LastUsed)
+	})
+}
+
+func removeDiskUsageCandidate(ctx context.Context, candidate *diskUsageCandidate, reason string, dryRun bool) (bool, error) {
+	isLocked, lock, err := werf.AcquireHostLock(ctx, candidate.LockName, lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to lock %s %s: %s", candidate.Kind, candidate.Name, err)
+	}
+
+	if !isLocked {
+		logboek.Context(ctx).Default().LogFDetails("Ignore %s %s used by another process\n", candidate.Kind, candidate.Name)
+		return false, nil
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	logboek.Context(ctx).Default().LogFDetails("Removing %s %s (%s, last used %s): %s\n", candidate.Kind, candidate.Name, units.HumanSize(float64(candidate.Size)), candidate.LastUsed.Format(time.RFC3339), reason)
+
+	if dryRun {
+		return true, nil
+	}
+
+	if err := candidate.Remove(ctx); err != nil {
+		logboek.Context(ctx).Warn().LogF("WARNING: Unable to remove %s %s: %s\n", candidate.Kind, candidate.Name, err)
+		return false, nil
+	}
+
+	if err := werf.ForgetLastUse(candidate.Name); err != nil {
+		return false, fmt.Errorf("unable to remove last use record of %s %s: %s", candidate.Kind, candidate.Name, err)
+	}
+
+	return true, nil
+}
+
+func localStagesCandidates(ctx context.Context, options CommonOptions) ([]*diskUsageCandidate, error) {
+	filterSet := filters.NewArgs()
+	filterSet.Add("label", image.WerfLabel)
+	images, err := werfImagesByFilterSet(ctx, filterSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// images of the running containers cannot be removed
+	images, err = processUsedImages(ctx, images, CommonOptions{SkipUsedImages: true, DryRun: options.DryRun})
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*diskUsageCandidate
+	for _, img := range images {
+		candidate, err := localStageCandidate(img)
+		if err != nil {
+			return nil, err
+		} else if candidate == nil {
+			continue
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// localStageCandidate returns the candidate to remove all local stages storage tags of the image at once, because the tags share the image layers.
+// The image tagged outside the local stages storage is skipped, because the space is not freed by removing the stages tags.
+func localStageCandidate(img types.ImageSummary) (*diskUsageCandidate, error) {
+	var repoTags []string
+	for _, repoTag := range img.RepoTags {
+		if strings.HasPrefix(repoTag, storage.LocalStage_ImageRepoPrefix) {
+			repoTags = append(repoTags, repoTag)
+		}
+	}
+
+	if len(repoTags) == 0 || len(repoTags) != len(img.RepoTags) {
+		return nil, nil
+	}
+
+	var stageIDs []image.StageID
+	for _, repoTag := range repoTags {
+		stageID, err := storage.GetStageIDFromLocalStageImageName(repoTag)
+		if err != nil {
+			return nil, err
+		}
+		stageIDs = append(stageIDs, stageID)
+	}
+
+	projectName := img.Labels[image.WerfLabel]
+	candidate := &diskUsageCandidate{
+		Kind:     "local stage",
+		Name:     repoTags[0],
+		Size:     img.Size,
+		LastUsed: time.Unix(img.Created, 0),
+		LockName: storage.GenericStagesAndImagesLockName(projectName),
+		Remove: func(ctx context.Context) error {
+			stagesStorageCache := storage.NewFileStagesStorageCache(werf.GetStagesStorageCacheDir())
+			for _, stageID := range stageIDs {
+				if err := storage.DeleteStageFromStagesStorageCache(ctx, stagesStorageCache, projectName, stageID); err != nil {
+					return fmt.Errorf("unable to delete stages storage cache record (%s): %s", stageID, err)
+				}
+			}
+
+			if err := imageReferencesRemove(ctx, repoTags, CommonOptions{}); err != nil {
+				return err
+			}
+
+			for _, repoTag := range repoTags[1:] {
+				if err := werf.ForgetLastUse(repoTag); err != nil {
+					return fmt.Errorf("unable to remove last use record of local stage %s: %s", repoTag, err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	for _, repoTag := range repoTags {
+		if err := updateCandidateLastUsed(candidate, repoTag); err != nil {
+			return nil, err
+		}
+	}
+
+	return candidate, nil
+}
+
+func gitRepoClonesCandidates(ctx context.Context) ([]*diskUsageCandidate, error) {
+	var candidates []*diskUsageCandidate
+	if err := walkCacheDir(git_repo.GetGitRepoCacheDir(), func(path string) (bool, error) {
+		if strings.HasSuffix(path, ".tmp") {
+			return true, nil
+		}
+
+		if exist, err := util.FileExists(filepath.Join(path, "HEAD")); err != nil || !exist {
+			return false, err
+		}
+
+		candidate := &diskUsageCandidate{
+			Kind:     "git repo clone",
+			Name:     path,
+			LockName: git_repo.RemoteRepoCacheLockName(path),
+			Remove:   removeCacheDir(path),
+		}
+
+		if ok, err := updateDirCandidate(ctx, candidate); err != nil || !ok {
+			return true, err
+		}
+
+		candidates = append(candidates, candidate)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+func gitWorkTreesCandidates(ctx context.Context) ([]*diskUsageCandidate, error) {
+	var candidates []*diskUsageCandidate
+	if err := walkCacheDir(git_repo.GetWorkTreeCacheDir(), func(path string) (bool, error) {
+		gitDirPath := filepath.Join(path, "git_dir")
+		data, err := ioutil.ReadFile(gitDirPath)
+		if os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("error reading %s: %s", gitDirPath, err)
+		}
+
+		candidate := &diskUsageCandidate{
+			Kind:     "git worktree",
+			Name:     path,
+			LockName: true_git.WorkTreeCacheLockName(path),
+			Remove:   removeCacheDir(path),
+		}
+
+		if ok, err := updateDirCandidate(ctx, candidate); err != nil || !ok {
+			return true, err
+		}
+
+		// the worktree of the removed repo is stale and cannot be used anymore
+		if exist, err := util.DirExists(strings.TrimSpace(string(data))); err != nil {
+			return false, err
+		} else if !exist {
+			candidate.LastUsed = time.Time{}
+		}
+
+		candidates = append(candidates, candidate)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// walkCacheDir calls f for each dir until f reports that the dir is handled
+func walkCacheDir(cacheDir string, f func(path string) (bool, error)) error {
+	if exist, err := util.DirExists(cacheDir); err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	if err := filepath.Walk(cacheDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() || path == cacheDir {
+			return nil
+		}
+
+		if handled, err := f(path); err != nil {
+			return err
+		} else if handled {
+			return filepath.SkipDir
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to walk %s: %s", cacheDir, err)
+	}
+
+	return nil
+}
+
+// updateDirCandidate sets the size and the last use time of the dir, the dir that cannot be read is skipped
+// because the size cannot be measured and the dir cannot be removed by werf anyway
+func updateDirCandidate(ctx context.Context, candidate *diskUsageCandidate) (bool, error) {
+	info, err := os.Stat(candidate.Name)
+	if err != nil {
+		return false, err
+	}
+	candidate.LastUsed = info.ModTime()
+
+	if candidate.Size, err = dirSize(candidate.Name); os.IsPermission(err) {
+		logboek.Context(ctx).Warn().LogF("WARNING: Ignore %s %s: %s\n", candidate.Kind, candidate.Name, err)
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to get size of %s: %s", candidate.Name, err)
+	}
+
+	return true, updateCandidateLastUsed(candidate, candidate.Name)
+}
+
+// updateCandidateLastUsed uses the time of the last use recorded by the name if it is later than the current one
+func updateCandidateLastUsed(candidate *diskUsageCandidate, name string) error {
+	lastUsed, exist, err := werf.GetLastUse(name)
+	if err != nil {
+		return fmt.Errorf("unable to get last use of %s %s: %s", candidate.Kind, candidate.Name, err)
+	}
+
+	if exist && lastUsed.After(candidate.LastUsed) {
+		candidate.LastUsed = lastUsed
+	}
+
+	return nil
+}
+
+func removeCacheDir(path string) func(ctx context.Context) error {
+	return func(_ context.Context) error {
+		return os.RemoveAll(path)
+	}
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the files can be removed by the git operations during the walk
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,151 @@
+package host_cleaning
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+
+	"github.com/werf/werf/pkg/git_repo"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestParseAllowedDiskUsagePercentage(t *testing.T) {
+	for value, expected := range map[string]float64{"70%": 70, "70": 70, " 85.5 % ": 85.5, "100%": 100} {
+		if percentage, err := ParseAllowedDiskUsagePercentage(value); err != nil {
+			t.Errorf("unexpected error for %q: %s", value, err)
+		} else if percentage != expected {
+			t.Errorf("expected %v for %q, got %v", expected, value, percentage)
+		}
+	}
+
+	for _, value := range []string{"", "0%", "-10", "101%", "seventy"} {
+		if _, err := ParseAllowedDiskUsagePercentage(value); err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestDiskUsageCleanup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-disk-usage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	repoDir := filepath.Join(dir, "repo")
+	for name, gitDir := range map[string]string{"used": repoDir, "unused": repoDir, "stale": filepath.Join(dir, "removed_repo")} {
+		workTreeCacheDir := filepath.Join(git_repo.GetWorkTreeCacheDir(), "local", name)
+		if err := os.MkdirAll(filepath.Join(workTreeCacheDir, "worktree"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(workTreeCacheDir, "git_dir"), []byte(gitDir+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(workTreeCacheDir, "worktree", "file"), make([]byte, 1000), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.TouchLastUse(filepath.Join(git_repo.GetWorkTreeCacheDir(), "local", "used")); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates, err := gitWorkTreesCandidates(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sortDiskUsageCandidates(candidates)
+
+	var names []string
+	for _, candidate := range candidates {
+		names = append(names, filepath.Base(candidate.Name))
+	}
+
+	if len(names) != 3 || names[0] != "stale" || names[2] != "used" {
+		t.Fatalf("unexpected candidates order: %v", names)
+	}
+
+	if candidates[0].Size < 1000 {
+		t.Errorf("unexpected size of %s: %d", candidates[0].Name, candidates[0].Size)
+	}
+
+	volume := &diskUsageVolume{
+		Path:       dir,
+		Usage:      volumeUsage{UsedBytes: 9000, TotalBytes: 10000},
+		Candidates: candidates,
+	}
+	for _, candidate := range candidates {
+		candidate.Size = 1500
+	}
+
+	if err := cleanupVolume(context.Background(), volume, 70, CommonOptions{DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if volume.Usage.UsedBytes != 6000 {
+		t.Errorf("expected 2 candidates to be removed, used bytes %d left", volume.Usage.UsedBytes)
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate.Name); err != nil || !info.IsDir() {
+			t.Errorf("candidate %s should not be removed in dry run mode", candidate.Name)
+		}
+	}
+}
+
+func TestLocalStageCandidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-disk-usage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	img := types.ImageSummary{
+		ID:       "sha256:1",
+		Size:     1000,
+		Created:  1,
+		Labels:   map[string]string{image.WerfLabel: "project"},
+		RepoTags: []string{"werf-stages-storage/project:sig1-1600000000000", "werf-stages-storage/project:sig2-1600000000001"},
+	}
+
+	if err := werf.TouchLastUse(img.RepoTags[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	candidate, err := localStageCandidate(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if candidate == nil || candidate.Name != img.RepoTags[0] || candidate.Size != 1000 {
+		t.Fatalf("expected single candidate for all image tags, got %#v", candidate)
+	}
+
+	if time.Since(candidate.LastUsed) > time.Minute {
+		t.Errorf("expected last use of any image tag, got %s", candidate.LastUsed)
+	}
+
+	img.RepoTags = append(img.RepoTags, "myimage:latest")
+	if candidate, err := localStageCandidate(img); err != nil || candidate != nil {
+		t.Errorf("expected image tagged outside local stages storage to be skipped, got %#v (%v)", candidate, err)
+	}
+}
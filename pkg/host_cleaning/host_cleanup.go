@@ -3,6 +3,7 @@ package host_cleaning
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,13 +15,17 @@ import (
 
 	"github.com/werf/logboek"
 
+	"github.com/werf/werf/pkg/build_cache"
 	"github.com/werf/werf/pkg/container_runtime"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/tmp_manager"
 )
 
 type HostCleanupOptions struct {
-	DryRun bool
+	BuildCachesSizeLimit int64
+	// AllowedDiskUsagePercentage of the docker storage volume and the werf local cache volume, 0 — no limit
+	AllowedDiskUsagePercentage float64
+	DryRun                     bool
 }
 
 func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
@@ -44,6 +49,20 @@ func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
 			return nil
 		}
 
+		if err := logboek.Context(ctx).LogProcess("Running cleanup for build caches").DoError(func() error {
+			return build_cache.Cleanup(ctx, build_cache.CleanupOptions{SizeLimit: options.BuildCachesSizeLimit, DryRun: options.DryRun})
+		}); err != nil {
+			return err
+		}
+
+		if options.AllowedDiskUsagePercentage > 0 {
+			if err := logboek.Context(ctx).LogProcess("Running cleanup for least recently used local stages and caches by disk usage").DoError(func() error {
+				return cleanupByDiskUsage(ctx, options.AllowedDiskUsagePercentage, commonOptions)
+			}); err != nil {
+				return err
+			}
+		}
+
 		return werf.WithHostLock(ctx, "gc", lockgate.AcquireOptions{}, func() error {
 			if err := tmp_manager.GC(ctx, commonOptions.DryRun); err != nil {
 				return fmt.Errorf("tmp files gc failed: %s", err)
@@ -54,6 +73,39 @@ func HostCleanup(ctx context.Context, options HostCleanupOptions) error {
 	})
 }
 
+// CleanupByDiskUsage removes the least recently used local stages and caches until the disk usage is under the allowed percentage.
+// Cleanup is skipped if another host cleanup is running.
+func CleanupByDiskUsage(ctx context.Context, allowedDiskUsagePercentage float64, dryRun bool) error {
+	isLocked, lock, err := werf.AcquireHostLock(ctx, "host-cleanup", lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		return fmt.Errorf("failed to lock host-cleanup: %s", err)
+	}
+
+	if !isLocked {
+		logboek.Context(ctx).Default().LogFDetails("Ignore cleanup by disk usage: host cleanup is running by another process\n")
+		return nil
+	}
+	defer werf.ReleaseHostLock(lock)
+
+	return logboek.Context(ctx).LogProcess("Running cleanup for least recently used local stages and caches by disk usage").DoError(func() error {
+		return cleanupByDiskUsage(ctx, allowedDiskUsagePercentage, CommonOptions{DryRun: dryRun})
+	})
+}
+
+// ParseAllowedDiskUsagePercentage parses the percentage in the form 70% or 70
+func ParseAllowedDiskUsagePercentage(value string) (float64, error) {
+	percentage, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%")), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q: %s", value, err)
+	}
+
+	if percentage <= 0 || percentage > 100 {
+		return 0, fmt.Errorf("invalid percentage %q: the value should be greater than 0 and not greater than 100", value)
+	}
+
+	return percentage, nil
+}
+
 func safeDanglingImagesCleanup(ctx context.Context, options CommonOptions) error {
 	images, err := werfImagesByFilterSet(ctx, danglingFilterSet())
 	if err != nil {
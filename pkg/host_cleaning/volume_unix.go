@@ -0,0 +1,31 @@
+// +build linux darwin
+
+package host_cleaning
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func getVolumeUsage(path string) (volumeUsage, error) {
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err != nil {
+		return volumeUsage{}, fmt.Errorf("unable to stat %s: %s", path, err)
+	}
+
+	var statfs syscall.Statfs_t
+	if err := syscall.Statfs(path, &statfs); err != nil {
+		return volumeUsage{}, fmt.Errorf("unable to statfs %s: %s", path, err)
+	}
+
+	// the same as df does: the reserved blocks are neither used nor available
+	blockSize := uint64(statfs.Bsize)
+	usedBytes := (uint64(statfs.Blocks) - uint64(statfs.Bfree)) * blockSize
+	availableBytes := uint64(statfs.Bavail) * blockSize
+
+	return volumeUsage{
+		ID:         fmt.Sprintf("%d", stat.Dev),
+		UsedBytes:  usedBytes,
+		TotalBytes: usedBytes + availableBytes,
+	}, nil
+}
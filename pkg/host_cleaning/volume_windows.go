@@ -0,0 +1,42 @@
+// +build windows
+
+package host_cleaning
+
+import (
+	"fmt"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func getVolumeUsage(path string) (volumeUsage, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return volumeUsage{}, err
+	}
+
+	pathPtr, err := syscall.UTF16PtrFromString(absPath)
+	if err != nil {
+		return volumeUsage{}, err
+	}
+
+	var availableBytes, totalBytes, freeBytes uint64
+	if ret, _, err := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&availableBytes)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&freeBytes)),
+	); ret == 0 {
+		return volumeUsage{}, fmt.Errorf("unable to get disk free space of %s: %s", absPath, err)
+	}
+
+	usedBytes := totalBytes - freeBytes
+
+	return volumeUsage{
+		ID:         filepath.VolumeName(absPath),
+		UsedBytes:  usedBytes,
+		TotalBytes: usedBytes + availableBytes,
+	}, nil
+}
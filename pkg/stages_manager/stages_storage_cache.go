@@ -0,0 +1,220 @@
+package stages_manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+type StagesStorageCacheReport struct {
+	// StaleStages are stored in the cache but do not exist in the stages storage, werf resets the cache when such stage is selected
+	StaleStages []image.StageID
+	// OutdatedSignatures have cache records without some of the stages existing in the stages storage, such stages are not reused
+	OutdatedSignatures []string
+	// MissingSignatures have no cache records, records are created on the first use of the signature
+	MissingSignatures []string
+}
+
+// IsConsistent returns false when the cache contains records which do not correspond to the stages storage
+func (report *StagesStorageCacheReport) IsConsistent() bool {
+	return len(report.StaleStages) == 0 && len(report.OutdatedSignatures) == 0
+}
+
+// VerifyStagesStorageCache compares the stages storage cache records of the project with the stages existing in the stages storage
+func (m *StagesManager) VerifyStagesStorageCache(ctx context.Context) (*StagesStorageCacheReport, error) {
+	stagesBySignature, err := m.getStagesStorageStagesBySignature(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StagesStorageCacheReport{}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Verifying stages storage cache %s", m.StagesStorageCache.String()).DoError(func() error {
+		_, cachedStages, err := m.StagesStorageCache.GetAllStages(ctx, m.ProjectName)
+		if err != nil {
+			return fmt.Errorf("error getting project %s stages from stages storage cache: %s", m.ProjectName, err)
+		}
+
+		for _, stageID := range cachedStages {
+			if !containsStageID(stagesBySignature[stageID.Signature], stageID) {
+				report.StaleStages = append(report.StaleStages, stageID)
+			}
+		}
+
+		for _, signature := range sortedSignatures(stagesBySignature) {
+			found, cachedStages, err := m.StagesStorageCache.GetStagesBySignature(ctx, m.ProjectName, signature)
+			if err != nil {
+				return fmt.Errorf("error getting project %s stage %s images from stages storage cache: %s", m.ProjectName, signature, err)
+			}
+
+			if !found {
+				report.MissingSignatures = append(report.MissingSignatures, signature)
+				continue
+			}
+
+			for _, stageID := range stagesBySignature[signature] {
+				if !containsStageID(cachedStages, stageID) {
+					report.OutdatedSignatures = append(report.OutdatedSignatures, signature)
+					break
+				}
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// StagesStorageCacheChange is the change of the signature record made by the stages storage cache rebuild
+type StagesStorageCacheChange struct {
+	Signature string
+	// AddedStages exist in the stages storage but are not cached
+	AddedStages []image.StageID
+	// RemovedStages are cached but do not exist in the stages storage
+	RemovedStages []image.StageID
+}
+
+// RebuildStagesStorageCache replaces the stages storage cache records of the project with the stages existing in the stages storage.
+// The changes of the records are returned, with dryRun the changes are not applied.
+func (m *StagesManager) RebuildStagesStorageCache(ctx context.Context, dryRun bool) ([]StagesStorageCacheChange, error) {
+	stagesBySignature, err := m.getStagesStorageStagesBySignature(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := m.getStagesStorageCacheChanges(ctx, stagesBySignature)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return changes, nil
+	}
+
+	if err := m.ResetStagesStorageCache(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := logboek.Context(ctx).Default().LogProcess("Storing %d signatures into stages storage cache %s", len(stagesBySignature), m.StagesStorageCache.String()).DoError(func() error {
+		for _, signature := range sortedSignatures(stagesBySignature) {
+			if err := m.atomicStoreStagesBySignatureToCacheIfNotExists(ctx, signature, stagesBySignature[signature]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (m *StagesManager) getStagesStorageCacheChanges(ctx context.Context, stagesBySignature map[string][]image.StageID) ([]StagesStorageCacheChange, error) {
+	_, allCachedStages, err := m.StagesStorageCache.GetAllStages(ctx, m.ProjectName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project %s stages from stages storage cache: %s", m.ProjectName, err)
+	}
+
+	signatures := make(map[string][]image.StageID)
+	for signature, stages := range stagesBySignature {
+		signatures[signature] = stages
+	}
+	for _, stageID := range allCachedStages {
+		if _, hasKey := signatures[stageID.Signature]; !hasKey {
+			signatures[stageID.Signature] = nil
+		}
+	}
+
+	var changes []StagesStorageCacheChange
+	for _, signature := range sortedSignatures(signatures) {
+		_, cachedStages, err := m.StagesStorageCache.GetStagesBySignature(ctx, m.ProjectName, signature)
+		if err != nil {
+			return nil, fmt.Errorf("error getting project %s stage %s images from stages storage cache: %s", m.ProjectName, signature, err)
+		}
+
+		change := StagesStorageCacheChange{Signature: signature}
+		for _, stageID := range stagesBySignature[signature] {
+			if !containsStageID(cachedStages, stageID) {
+				change.AddedStages = append(change.AddedStages, stageID)
+			}
+		}
+		for _, stageID := range cachedStages {
+			if !containsStageID(stagesBySignature[signature], stageID) {
+				change.RemovedStages = append(change.RemovedStages, stageID)
+			}
+		}
+
+		if len(change.AddedStages) > 0 || len(change.RemovedStages) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// atomicStoreStagesBySignatureToCacheIfNotExists keeps the record stored by the concurrent werf process after the cache reset
+func (m *StagesManager) atomicStoreStagesBySignatureToCacheIfNotExists(ctx context.Context, signature string, stageIDs []image.StageID) error {
+	if lock, err := m.StorageLockManager.LockStageCache(ctx, m.ProjectName, signature); err != nil {
+		return fmt.Errorf("error locking project %s stage %s cache: %s", m.ProjectName, signature, err)
+	} else {
+		defer m.StorageLockManager.Unlock(ctx, lock)
+	}
+
+	if found, _, err := m.StagesStorageCache.GetStagesBySignature(ctx, m.ProjectName, signature); err != nil {
+		return fmt.Errorf("error getting project %s stage %s images from stages storage cache: %s", m.ProjectName, signature, err)
+	} else if found {
+		return nil
+	}
+
+	if err := m.StagesStorageCache.StoreStagesBySignature(ctx, m.ProjectName, signature, stageIDs); err != nil {
+		return fmt.Errorf("error storing stage images by signature %s into stages storage cache: %s", signature, err)
+	}
+
+	return nil
+}
+
+func (m *StagesManager) getStagesStorageStagesBySignature(ctx context.Context) (map[string][]image.StageID, error) {
+	stagesBySignature := make(map[string][]image.StageID)
+
+	if err := logboek.Context(ctx).Default().LogProcess("Getting all stages from %s", m.StagesStorage.String()).DoError(func() error {
+		stages, err := m.StagesStorage.GetAllStages(ctx, m.ProjectName)
+		if err != nil {
+			return fmt.Errorf("error getting project %s stages from %s: %s", m.ProjectName, m.StagesStorage.String(), err)
+		}
+
+		for _, stageID := range stages {
+			stagesBySignature[stageID.Signature] = append(stagesBySignature[stageID.Signature], stageID)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return stagesBySignature, nil
+}
+
+func containsStageID(stages []image.StageID, stageID image.StageID) bool {
+	for _, s := range stages {
+		if s == stageID {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedSignatures(stagesBySignature map[string][]image.StageID) []string {
+	var res []string
+	for signature := range stagesBySignature {
+		res = append(res, signature)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,139 @@
+package stages_manager
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+	"github.com/werf/werf/pkg/werf"
+)
+
+type fakeStagesStorage struct {
+	storage.StagesStorage
+	stages []image.StageID
+}
+
+func (s *fakeStagesStorage) String() string {
+	return "fake"
+}
+
+func (s *fakeStagesStorage) GetAllStages(_ context.Context, _ string) ([]image.StageID, error) {
+	return s.stages, nil
+}
+
+// newTestStagesStorageCacheManager returns the manager with the cache records:
+// sig1 is consistent, sig2 lacks the existing stage, sig3 is not cached and stale sig4 has no stages in the stages storage
+func newTestStagesStorageCacheManager(t *testing.T) (*StagesManager, func()) {
+	dir, err := ioutil.TempDir("", "werf-stages-storage-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := storage.NewFileStagesStorageCache(filepath.Join(dir, "cache"))
+	m := NewStagesManager("project", storage.NewGenericLockManager(werf.GetHostLocker()), cache)
+	m.StagesStorage = &fakeStagesStorage{stages: []image.StageID{
+		{Signature: "sig1", UniqueID: 1},
+		{Signature: "sig2", UniqueID: 2},
+		{Signature: "sig2", UniqueID: 3},
+		{Signature: "sig3", UniqueID: 4},
+	}}
+
+	ctx := context.Background()
+	for signature, stages := range map[string][]image.StageID{
+		"sig1": {{Signature: "sig1", UniqueID: 1}},
+		"sig2": {{Signature: "sig2", UniqueID: 2}},
+		"sig4": {{Signature: "sig4", UniqueID: 5}},
+	} {
+		if err := cache.StoreStagesBySignature(ctx, "project", signature, stages); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return m, func() { os.RemoveAll(dir) }
+}
+
+func TestVerifyStagesStorageCache(t *testing.T) {
+	m, cleanup := newTestStagesStorageCacheManager(t)
+	defer cleanup()
+
+	report, err := m.VerifyStagesStorageCache(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.IsConsistent() {
+		t.Error("expected cache to be inconsistent")
+	}
+
+	if !reflect.DeepEqual(report.StaleStages, []image.StageID{{Signature: "sig4", UniqueID: 5}}) {
+		t.Errorf("unexpected stale stages %v", report.StaleStages)
+	}
+
+	if !reflect.DeepEqual(report.OutdatedSignatures, []string{"sig2"}) {
+		t.Errorf("unexpected outdated signatures %v", report.OutdatedSignatures)
+	}
+
+	if !reflect.DeepEqual(report.MissingSignatures, []string{"sig3"}) {
+		t.Errorf("unexpected missing signatures %v", report.MissingSignatures)
+	}
+}
+
+func TestRebuildStagesStorageCache(t *testing.T) {
+	m, cleanup := newTestStagesStorageCacheManager(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	expectedChanges := []StagesStorageCacheChange{
+		{Signature: "sig2", AddedStages: []image.StageID{{Signature: "sig2", UniqueID: 3}}},
+		{Signature: "sig3", AddedStages: []image.StageID{{Signature: "sig3", UniqueID: 4}}},
+		{Signature: "sig4", RemovedStages: []image.StageID{{Signature: "sig4", UniqueID: 5}}},
+	}
+
+	changes, err := m.RebuildStagesStorageCache(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("unexpected dry run changes %#v", changes)
+	}
+
+	if report, err := m.VerifyStagesStorageCache(ctx); err != nil {
+		t.Fatal(err)
+	} else if report.IsConsistent() {
+		t.Error("expected dry run not to change the cache")
+	}
+
+	changes, err = m.RebuildStagesStorageCache(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Errorf("unexpected changes %#v", changes)
+	}
+
+	report, err := m.VerifyStagesStorageCache(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.IsConsistent() || len(report.MissingSignatures) != 0 {
+		t.Errorf("expected cache to be consistent after rebuild, got %#v", report)
+	}
+
+	if changes, err := m.RebuildStagesStorageCache(ctx, true); err != nil {
+		t.Fatal(err)
+	} else if len(changes) != 0 {
+		t.Errorf("expected no changes after rebuild, got %#v", changes)
+	}
+}
@@ -3,17 +3,39 @@ package stages_manager
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/werf/logboek"
+
 	"github.com/werf/werf/pkg/container_runtime"
+	"github.com/werf/werf/pkg/docker_registry"
 	"github.com/werf/werf/pkg/image"
 	"github.com/werf/werf/pkg/storage"
 )
 
+const DefaultSyncStagesParallelTasksLimit = 10
+
 type SyncStagesOptions struct {
 	RemoveSource      bool
 	CleanupLocalCache bool
 	WithoutLock       bool
+
+	// StageIDs limits the sync to the specified stages (e.g. the stages of the selected images), all project stages are synced when nil
+	StageIDs []image.StageID
+	// NewerThan limits the sync to the stages created during the specified period
+	NewerThan time.Duration
+
+	// ParallelTasksLimit is the number of stages synced in parallel, DefaultSyncStagesParallelTasksLimit is used by default
+	ParallelTasksLimit int
+	// BandwidthLimit is the average transfer rate of the stages images in bytes per second, the rate is not limited by default.
+	// With the limit the stages are copied between the registries directly and the layers are read at the limited rate,
+	// the transfers made by the docker daemon (when syncing with the local stages storage) cannot be throttled
+	BandwidthLimit int64
+
+	// CheckpointFile records the synced stages so that the interrupted sync can be resumed, the file is removed when all stages are synced
+	CheckpointFile string
+	// SummaryFile is the path to write the JSON summary of the sync
+	SummaryFile string
 }
 
 // SyncStages will make sure, that destination stages storage contains all stages from source stages storage.
@@ -43,6 +65,15 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 	logboek.Context(ctx).Default().LogFDetails("Destination — %s\n", toStagesStorage.String())
 	logboek.Context(ctx).Default().LogOptionalLn()
 
+	summary := newSyncStagesSummary(fromStagesStorage, toStagesStorage)
+	if opts.SummaryFile != "" {
+		defer func() {
+			if err := summary.WriteFile(opts.SummaryFile); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to write sync summary: %s\n", err)
+			}
+		}()
+	}
+
 	var errors []error
 
 	getAllStagesFunc := func(logProcessMsg string, stagesStorage storage.StagesStorage) ([]image.StageID, error) {
@@ -64,7 +95,10 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 	if stages, err := getAllStagesFunc("Getting all repo images list from source stages storage %s", fromStagesStorage); err != nil {
 		return fmt.Errorf("unable to get repo images from source %s: %s", fromStagesStorage.String(), err)
 	} else {
-		existingSourceStages = stages
+		existingSourceStages = selectStagesToSync(stages, opts, time.Now())
+		if len(existingSourceStages) != len(stages) {
+			logboek.Context(ctx).Default().LogFDetails("Selected stages count: %d\n", len(existingSourceStages))
+		}
 	}
 
 	if stages, err := getAllStagesFunc("Getting all repo images list from destination stages storage %s", toStagesStorage); err != nil {
@@ -73,6 +107,12 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 		existingDestinationStages = stages
 	}
 
+	checkpoint, err := openSyncStagesCheckpoint(opts.CheckpointFile, fromStagesStorage.Address(), toStagesStorage.Address())
+	if err != nil {
+		return err
+	}
+	defer checkpoint.Close()
+
 	var stagesToSync []image.StageID
 
 	for _, sourceStageDesc := range existingSourceStages {
@@ -84,28 +124,50 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 			}
 		}
 
-		if !stageExistsInDestination || opts.RemoveSource {
+		switch {
+		case checkpoint.IsSynced(sourceStageDesc):
+			summary.SkippedCheckpointStages++
+		case !stageExistsInDestination || opts.RemoveSource:
 			stagesToSync = append(stagesToSync, sourceStageDesc)
+		default:
+			summary.SkippedExistingStages++
 		}
 	}
 
+	summary.SelectedStages = len(existingSourceStages)
+
+	if summary.SkippedCheckpointStages > 0 {
+		logboek.Context(ctx).Default().LogFDetails("Stages synced by previous run: %d\n", summary.SkippedCheckpointStages)
+	}
 	logboek.Context(ctx).Default().LogFDetails("Stages to sync: %d\n", len(stagesToSync))
 
-	maxWorkers := 10
-	resultsChan := make(chan struct {
-		error
-		image.StageID
-	}, 1000)
+	maxWorkers := opts.ParallelTasksLimit
+	if maxWorkers <= 0 {
+		maxWorkers = DefaultSyncStagesParallelTasksLimit
+	}
+
+	var limiter *docker_registry.BandwidthLimiter
+	if opts.BandwidthLimit > 0 {
+		limiter = docker_registry.NewBandwidthLimiter(opts.BandwidthLimit)
+
+		if !isRegistryToRegistrySync(fromStagesStorage, toStagesStorage) {
+			logboek.Context(ctx).Warn().LogF("WARNING: bandwidth limit is not applied: the stages are transferred by the docker daemon when syncing with the local stages storage\n")
+		}
+	}
+
+	resultsChan := make(chan syncStageResult, 1000)
 	jobsChan := make(chan image.StageID, 1000)
 
 	for w := 0; w < maxWorkers; w++ {
-		go runSyncWorker(ctx, projectName, fromStagesStorage, toStagesStorage, containerRuntime, opts, w, jobsChan, resultsChan)
+		go runSyncWorker(ctx, projectName, fromStagesStorage, toStagesStorage, containerRuntime, limiter, opts, w, jobsChan, resultsChan)
 	}
 
-	for _, stageDesc := range stagesToSync {
-		jobsChan <- stageDesc
-	}
-	close(jobsChan)
+	go func() {
+		for _, stageDesc := range stagesToSync {
+			jobsChan <- stageDesc
+		}
+		close(jobsChan)
+	}()
 
 	failedCounter := 0
 	succeededCounter := 0
@@ -116,12 +178,21 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 			failedCounter++
 			logboek.Context(ctx).Warn().LogF("%5d/%d failed: %s\n", failedCounter, len(stagesToSync), desc.error)
 			errors = append(errors, desc.error)
+			summary.FailedStages = append(summary.FailedStages, SyncStagesFailure{StageID: desc.StageID, Error: desc.error.Error()})
 		} else {
 			succeededCounter++
 			logboek.Context(ctx).Default().LogF("%5d/%d synced\n", succeededCounter, len(stagesToSync))
+			summary.TransferredBytes += desc.TransferredBytes
+
+			if err := checkpoint.Add(desc.StageID); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to record stage %s into checkpoint file: %s\n", desc.StageID.String(), err)
+			}
 		}
 	}
 
+	summary.SyncedStages = succeededCounter
+	summary.finish()
+
 	if len(errors) > 0 {
 		logboek.Context(ctx).Default().LogLn()
 		logboek.Context(ctx).Default().LogFHighlight("synced %d/%d, failed %d/%d\n", succeededCounter, len(stagesToSync), failedCounter, len(stagesToSync))
@@ -133,62 +204,103 @@ func SyncStages(ctx context.Context, projectName string, fromStagesStorage stora
 		return fmt.Errorf("%s", errorMsg)
 	}
 
+	if err := checkpoint.Remove(); err != nil {
+		return err
+	}
+
 	isOk = true
 	return nil
 }
 
-func runSyncWorker(ctx context.Context, projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions, workerId int, jobs chan image.StageID, results chan struct {
+// selectStagesToSync filters source stages by the StageIDs and NewerThan options
+func selectStagesToSync(stages []image.StageID, opts SyncStagesOptions, now time.Time) []image.StageID {
+	if opts.StageIDs == nil && opts.NewerThan == 0 {
+		return stages
+	}
+
+	var res []image.StageID
+	for _, stageID := range stages {
+		if opts.StageIDs != nil && !containsStageID(opts.StageIDs, stageID) {
+			continue
+		}
+
+		// unique id of the stage is the creation timestamp in milliseconds
+		if opts.NewerThan != 0 && time.Unix(0, stageID.UniqueID*int64(time.Millisecond)).Before(now.Add(-opts.NewerThan)) {
+			continue
+		}
+
+		res = append(res, stageID)
+	}
+
+	return res
+}
+
+type syncStageResult struct {
 	error
 	image.StageID
-}) {
+	TransferredBytes int64
+}
+
+func runSyncWorker(ctx context.Context, projectName string, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, limiter *docker_registry.BandwidthLimiter, opts SyncStagesOptions, workerId int, jobs chan image.StageID, results chan syncStageResult) {
 	for stageID := range jobs {
-		results <- struct {
-			error
-			image.StageID
-		}{
-			syncStage(ctx, projectName, stageID, fromStagesStorage, toStagesStorage, containerRuntime, opts),
-			stageID,
-		}
+		transferredBytes, err := syncStage(ctx, projectName, stageID, fromStagesStorage, toStagesStorage, containerRuntime, limiter, opts)
+		results <- syncStageResult{err, stageID, transferredBytes}
 	}
 }
 
-func syncStage(ctx context.Context, projectName string, stageID image.StageID, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, opts SyncStagesOptions) error {
+func syncStage(ctx context.Context, projectName string, stageID image.StageID, fromStagesStorage storage.StagesStorage, toStagesStorage storage.StagesStorage, containerRuntime container_runtime.ContainerRuntime, limiter *docker_registry.BandwidthLimiter, opts SyncStagesOptions) (int64, error) {
 	if fromStagesStorage.Address() == storage.LocalStorageAddress || toStagesStorage.Address() == storage.LocalStorageAddress {
 		opts.CleanupLocalCache = false
 	}
 
 	stageDesc, err := fromStagesStorage.GetStageDescription(ctx, projectName, stageID.Signature, stageID.UniqueID)
 	if err != nil {
-		return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), fromStagesStorage.String(), err)
+		return 0, fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), fromStagesStorage.String(), err)
 	} else if stageDesc == nil {
 		// Bad stage id given: stage does not exists in the source stages storage
-		return nil
+		return 0, nil
 	}
 
+	var transferredBytes int64
+
 	if destStageDesc, err := toStagesStorage.GetStageDescription(ctx, projectName, stageID.Signature, stageID.UniqueID); err != nil {
-		return fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), toStagesStorage.String(), err)
+		return 0, fmt.Errorf("error getting stage %s description from %s: %s", stageID.String(), toStagesStorage.String(), err)
+	} else if destStageDesc == nil && limiter != nil && isRegistryToRegistrySync(fromStagesStorage, toStagesStorage) {
+		newImageName := toStagesStorage.ConstructStageImageName(projectName, stageDesc.StageID.Signature, stageDesc.StageID.UniqueID)
+
+		logboek.Context(ctx).Info().LogF("Copying %s to %s\n", stageDesc.Info.Name, newImageName)
+		if err := toStagesStorage.(*storage.RepoStagesStorage).DockerRegistry.CopyImage(docker_registry.ContextWithBandwidthLimiter(ctx, limiter), stageDesc.Info.Name, newImageName); err != nil {
+			return 0, fmt.Errorf("unable to copy %s to %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
+		}
+
+		transferredBytes = stageDesc.Info.Size
 	} else if destStageDesc == nil {
 		img := container_runtime.NewStageImage(nil, stageDesc.Info.Name, containerRuntime.(*container_runtime.LocalDockerServerRuntime))
 
 		logboek.Context(ctx).Info().LogF("Fetching %s\n", img.Name())
 		if err := fromStagesStorage.FetchImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-			return fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
+			return 0, fmt.Errorf("unable to fetch %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
+		}
+
+		transferredBytes = stageDesc.Info.Size
+		if inspect := img.GetInspect(); inspect != nil {
+			transferredBytes = inspect.Size
 		}
 
 		newImageName := toStagesStorage.ConstructStageImageName(projectName, stageDesc.StageID.Signature, stageDesc.StageID.UniqueID)
 		logboek.Context(ctx).Info().LogF("Renaming image %s to %s\n", img.Name(), newImageName)
 		if err := containerRuntime.RenameImage(ctx, &container_runtime.DockerImage{Image: img}, newImageName, opts.CleanupLocalCache); err != nil {
-			return err
+			return 0, err
 		}
 
 		logboek.Context(ctx).Info().LogF("Storing %s\n", newImageName)
 		if err := toStagesStorage.StoreImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-			return fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
+			return 0, fmt.Errorf("unable to store %s to %s: %s", stageDesc.Info.Name, toStagesStorage.String(), err)
 		}
 
 		if opts.CleanupLocalCache {
 			if err := containerRuntime.RemoveImage(ctx, &container_runtime.DockerImage{Image: img}); err != nil {
-				return err
+				return 0, err
 			}
 		}
 	}
@@ -197,9 +309,16 @@ func syncStage(ctx context.Context, projectName string, stageID image.StageID, f
 		deleteOpts := storage.DeleteImageOptions{RmiForce: true, RmForce: true, RmContainersThatUseImage: true}
 		logboek.Context(ctx).Info().LogF("Removing %s\n", stageDesc.Info.Name)
 		if err := fromStagesStorage.DeleteStages(ctx, deleteOpts, stageDesc); err != nil {
-			return fmt.Errorf("unable to remove %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
+			return 0, fmt.Errorf("unable to remove %s from %s: %s", stageDesc.Info.Name, fromStagesStorage.String(), err)
 		}
 	}
 
-	return nil
+	return transferredBytes, nil
+}
+
+// isRegistryToRegistrySync returns true when the stages can be copied between the registries without the docker daemon
+func isRegistryToRegistrySync(fromStagesStorage, toStagesStorage storage.StagesStorage) bool {
+	_, isFromRepo := fromStagesStorage.(*storage.RepoStagesStorage)
+	_, isToRepo := toStagesStorage.(*storage.RepoStagesStorage)
+	return isFromRepo && isToRepo
 }
@@ -0,0 +1,125 @@
+package stages_manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+// syncStagesCheckpoint is an append-only file with a JSON record per synced stage.
+// Records of the syncs between other stages storages are ignored, so the same file can be used for several syncs.
+type syncStagesCheckpoint struct {
+	Path        string
+	Source      string
+	Destination string
+
+	file   *os.File
+	synced map[image.StageID]bool
+	mux    sync.Mutex
+}
+
+type syncStagesCheckpointRecord struct {
+	Source      string        `json:"source"`
+	Destination string        `json:"destination"`
+	StageID     image.StageID `json:"stageID"`
+}
+
+func openSyncStagesCheckpoint(path, source, destination string) (*syncStagesCheckpoint, error) {
+	checkpoint := &syncStagesCheckpoint{Path: path, Source: source, Destination: destination, synced: make(map[image.StageID]bool)}
+	if path == "" {
+		return checkpoint, nil
+	}
+
+	if err := checkpoint.load(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open checkpoint file %s: %s", path, err)
+	}
+	checkpoint.file = f
+
+	return checkpoint, nil
+}
+
+func (checkpoint *syncStagesCheckpoint) load() error {
+	f, err := os.Open(checkpoint.Path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to open checkpoint file %s: %s", checkpoint.Path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record syncStagesCheckpointRecord
+		// the last record may be truncated by the interrupted sync
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		if record.Source == checkpoint.Source && record.Destination == checkpoint.Destination {
+			checkpoint.synced[record.StageID] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read checkpoint file %s: %s", checkpoint.Path, err)
+	}
+	return nil
+}
+
+func (checkpoint *syncStagesCheckpoint) IsSynced(stageID image.StageID) bool {
+	return checkpoint.synced[stageID]
+}
+
+func (checkpoint *syncStagesCheckpoint) Add(stageID image.StageID) error {
+	checkpoint.mux.Lock()
+	defer checkpoint.mux.Unlock()
+
+	checkpoint.synced[stageID] = true
+	if checkpoint.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(syncStagesCheckpointRecord{Source: checkpoint.Source, Destination: checkpoint.Destination, StageID: stageID})
+	if err != nil {
+		return err
+	}
+
+	if _, err := checkpoint.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return checkpoint.file.Sync()
+}
+
+// Remove deletes the checkpoint file when all stages are synced
+func (checkpoint *syncStagesCheckpoint) Remove() error {
+	if checkpoint.file == nil {
+		return nil
+	}
+
+	checkpoint.Close()
+
+	if err := os.Remove(checkpoint.Path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove checkpoint file %s: %s", checkpoint.Path, err)
+	}
+	return nil
+}
+
+func (checkpoint *syncStagesCheckpoint) Close() {
+	checkpoint.mux.Lock()
+	defer checkpoint.mux.Unlock()
+
+	if checkpoint.file != nil {
+		checkpoint.file.Close()
+		checkpoint.file = nil
+	}
+}
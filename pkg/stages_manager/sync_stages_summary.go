@@ -0,0 +1,58 @@
+package stages_manager
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+type SyncStagesSummary struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+
+	StartedAt       time.Time `json:"startedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+
+	// SelectedStages is the number of source stages matching the options
+	SelectedStages          int `json:"selectedStages"`
+	SkippedExistingStages   int `json:"skippedExistingStages"`
+	SkippedCheckpointStages int `json:"skippedCheckpointStages"`
+	SyncedStages            int `json:"syncedStages"`
+
+	FailedStages     []SyncStagesFailure `json:"failedStages"`
+	TransferredBytes int64               `json:"transferredBytes"`
+}
+
+type SyncStagesFailure struct {
+	StageID image.StageID `json:"stageID"`
+	Error   string        `json:"error"`
+}
+
+func newSyncStagesSummary(fromStagesStorage, toStagesStorage storage.StagesStorage) *SyncStagesSummary {
+	return &SyncStagesSummary{
+		Source:       fromStagesStorage.String(),
+		Destination:  toStagesStorage.String(),
+		StartedAt:    time.Now(),
+		FailedStages: []SyncStagesFailure{},
+	}
+}
+
+func (summary *SyncStagesSummary) finish() {
+	summary.DurationSeconds = time.Since(summary.StartedAt).Seconds()
+}
+
+func (summary *SyncStagesSummary) WriteFile(path string) error {
+	if summary.DurationSeconds == 0 {
+		summary.finish()
+	}
+
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,93 @@
+package stages_manager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+func TestSelectStagesToSync(t *testing.T) {
+	now := time.Now()
+	oldStage := image.StageID{Signature: "old", UniqueID: now.Add(-72*time.Hour).UnixNano() / int64(time.Millisecond)}
+	newStage := image.StageID{Signature: "new", UniqueID: now.Add(-time.Hour).UnixNano() / int64(time.Millisecond)}
+	stages := []image.StageID{oldStage, newStage}
+
+	if res := selectStagesToSync(stages, SyncStagesOptions{}, now); len(res) != 2 {
+		t.Errorf("expected all stages to be selected, got %v", res)
+	}
+
+	if res := selectStagesToSync(stages, SyncStagesOptions{NewerThan: 48 * time.Hour}, now); len(res) != 1 || res[0] != newStage {
+		t.Errorf("expected only new stage to be selected, got %v", res)
+	}
+
+	if res := selectStagesToSync(stages, SyncStagesOptions{StageIDs: []image.StageID{oldStage}}, now); len(res) != 1 || res[0] != oldStage {
+		t.Errorf("expected only old stage to be selected, got %v", res)
+	}
+
+	if res := selectStagesToSync(stages, SyncStagesOptions{StageIDs: []image.StageID{oldStage}, NewerThan: 48 * time.Hour}, now); len(res) != 0 {
+		t.Errorf("expected no stages to be selected, got %v", res)
+	}
+}
+
+func TestSyncStagesCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-sync-stages-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoint")
+	stage1 := image.StageID{Signature: "sig1", UniqueID: 1}
+	stage2 := image.StageID{Signature: "sig2", UniqueID: 2}
+
+	checkpoint, err := openSyncStagesCheckpoint(path, "source", "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoint.Add(stage1); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint.Close()
+
+	otherCheckpoint, err := openSyncStagesCheckpoint(path, "source", "other-destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := otherCheckpoint.Add(stage2); err != nil {
+		t.Fatal(err)
+	}
+	otherCheckpoint.Close()
+
+	// simulate the record truncated by the interrupted sync
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"source":"source","destination":"destin`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	checkpoint, err = openSyncStagesCheckpoint(path, "source", "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !checkpoint.IsSynced(stage1) {
+		t.Error("expected stage1 to be synced")
+	}
+	if checkpoint.IsSynced(stage2) {
+		t.Error("expected stage2 synced to another destination to be ignored")
+	}
+
+	if err := checkpoint.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected checkpoint file to be removed, got %v", err)
+	}
+}
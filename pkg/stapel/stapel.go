@@ -110,6 +110,10 @@ func MkdirBinPath() string {
 	return embeddedBinPath("mkdir")
 }
 
+func LnBinPath() string {
+	return embeddedBinPath("ln")
+}
+
 func BashBinPath() string {
 	return embeddedBinPath("bash")
 }
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+const BoltStagesStorageCacheBucket = "stages_storage_cache"
+
+// BoltStagesStorageCache stores records in the embedded database file: STAGES_STORAGE_CACHE_BUCKET/NAMESPACE/PROJECT/SIGNATURE
+func NewBoltStagesStorageCache(db *bolt.DB, namespace string) *BoltStagesStorageCache {
+	return &BoltStagesStorageCache{DB: db, Namespace: namespace}
+}
+
+type BoltStagesStorageCache struct {
+	DB        *bolt.DB
+	Namespace string
+}
+
+func (cache *BoltStagesStorageCache) String() string {
+	return fmt.Sprintf("%s:%s", cache.DB.Path(), cache.Namespace)
+}
+
+func (cache *BoltStagesStorageCache) GetAllStages(ctx context.Context, projectName string) (bool, []image.StageID, error) {
+	var found bool
+	var res []image.StageID
+
+	err := cache.DB.View(func(tx *bolt.Tx) error {
+		bucket := cache.projectBucket(tx, projectName)
+		if bucket == nil {
+			return nil
+		}
+		found = true
+
+		return bucket.ForEach(func(signature, data []byte) error {
+			if stages, ok := cache.unmarshalRecord(ctx, projectName, string(signature), data); ok {
+				res = append(res, stages...)
+			}
+			return nil
+		})
+	})
+
+	return found, res, err
+}
+
+func (cache *BoltStagesStorageCache) DeleteAllStages(_ context.Context, projectName string) error {
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		if bucket := cache.namespaceBucket(tx); bucket != nil && bucket.Bucket([]byte(projectName)) != nil {
+			return bucket.DeleteBucket([]byte(projectName))
+		}
+		return nil
+	})
+}
+
+func (cache *BoltStagesStorageCache) GetStagesBySignature(ctx context.Context, projectName, signature string) (bool, []image.StageID, error) {
+	var found bool
+	var res []image.StageID
+
+	err := cache.DB.View(func(tx *bolt.Tx) error {
+		bucket := cache.projectBucket(tx, projectName)
+		if bucket == nil {
+			return nil
+		}
+
+		if data := bucket.Get([]byte(signature)); data != nil {
+			res, found = cache.unmarshalRecord(ctx, projectName, signature, data)
+		}
+
+		return nil
+	})
+
+	return found, res, err
+}
+
+func (cache *BoltStagesStorageCache) StoreStagesBySignature(_ context.Context, projectName, signature string, stages []image.StageID) error {
+	data, err := json.Marshal(StagesStorageCacheRecord{Stages: stages})
+	if err != nil {
+		return err
+	}
+
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BoltStagesStorageCacheBucket))
+		for _, name := range []string{cache.Namespace, projectName} {
+			if bucket, err = bucket.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("unable to create bucket %q: %s", name, err)
+			}
+		}
+
+		return bucket.Put([]byte(signature), data)
+	})
+}
+
+func (cache *BoltStagesStorageCache) DeleteStagesBySignature(_ context.Context, projectName, signature string) error {
+	return cache.DB.Update(func(tx *bolt.Tx) error {
+		if bucket := cache.projectBucket(tx, projectName); bucket != nil {
+			return bucket.Delete([]byte(signature))
+		}
+		return nil
+	})
+}
+
+func (cache *BoltStagesStorageCache) namespaceBucket(tx *bolt.Tx) *bolt.Bucket {
+	return tx.Bucket([]byte(BoltStagesStorageCacheBucket)).Bucket([]byte(cache.Namespace))
+}
+
+func (cache *BoltStagesStorageCache) projectBucket(tx *bolt.Tx, projectName string) *bolt.Bucket {
+	if bucket := cache.namespaceBucket(tx); bucket != nil {
+		return bucket.Bucket([]byte(projectName))
+	}
+	return nil
+}
+
+func (cache *BoltStagesStorageCache) unmarshalRecord(ctx context.Context, projectName, signature string, data []byte) ([]image.StageID, bool) {
+	res := &StagesStorageCacheRecord{}
+	if err := json.Unmarshal(data, res); err != nil {
+		logboek.Context(ctx).Error().LogF("Error unmarshalling json of %s/%s record in %s: %s: will ignore cache\n", projectName, signature, cache.String(), err)
+		return nil, false
+	}
+
+	return res.Stages, true
+}
@@ -96,6 +96,36 @@ func (repo *DockerImagesRepo) PublishImage(ctx context.Context, publishImage *co
 	return publishImage.Export(ctx)
 }
 
+// CopyRepoImage copies the repo image from any images repo of the same registry by imageName and tag and verifies the digest of the copy,
+// the existing image with the same digest is not copied again
+func (repo *DockerImagesRepo) CopyRepoImage(ctx context.Context, repoImage *image.Info, imageName, tag string) (*image.Info, error) {
+	reference := repo.ImageRepositoryNameWithTag(imageName, tag)
+
+	if existingRepoImage, err := repo.DockerRegistry.TryGetRepoImage(ctx, reference); err != nil {
+		return nil, err
+	} else if existingRepoImage != nil {
+		if existingRepoImage.RepoDigest != repoImage.RepoDigest {
+			return nil, fmt.Errorf("image %s already exists with digest %s, expected %s", reference, existingRepoImage.RepoDigest, repoImage.RepoDigest)
+		}
+		return existingRepoImage, nil
+	}
+
+	if err := repo.DockerRegistry.CopyImage(ctx, repoImage.Name, reference); err != nil {
+		return nil, err
+	}
+
+	copiedRepoImage, err := repo.DockerRegistry.GetRepoImage(ctx, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if copiedRepoImage.RepoDigest != repoImage.RepoDigest {
+		return nil, fmt.Errorf("digest %s of copied image %s does not match digest %s of source image %s", copiedRepoImage.RepoDigest, reference, repoImage.RepoDigest, repoImage.Name)
+	}
+
+	return copiedRepoImage, nil
+}
+
 func (repo *DockerImagesRepo) ImageRepositoryName(imageName string) string {
 	return repo.imagesRepoManager.ImageRepo(imageName)
 }
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/werf/lockgate"
+)
+
+// FileLocksInspector lists the host file locks by the records the lock manager keeps while the locks are held.
+// File locks have no lease: the lock is released by the OS when the holder process exits, so it cannot become stale,
+// and the records of the exited processes are removed on listing.
+type FileLocksInspector struct {
+	RecordsDir string
+}
+
+func NewFileLocksInspector(recordsDir string) *FileLocksInspector {
+	return &FileLocksInspector{RecordsDir: recordsDir}
+}
+
+type fileLockRecord struct {
+	ProjectName string    `json:"projectName"`
+	LockName    string    `json:"lockName"`
+	Shared      bool      `json:"shared"`
+	PID         int       `json:"pid"`
+	AcquiredAt  time.Time `json:"acquiredAt"`
+}
+
+func (inspector *FileLocksInspector) recordPath(handle lockgate.LockHandle) string {
+	return filepath.Join(inspector.RecordsDir, fmt.Sprintf("%s.json", handle.UUID))
+}
+
+func (inspector *FileLocksInspector) RecordLock(projectName string, handle lockgate.LockHandle, shared bool) error {
+	data, err := json.Marshal(fileLockRecord{
+		ProjectName: projectName,
+		LockName:    handle.LockName,
+		Shared:      shared,
+		PID:         os.Getpid(),
+		AcquiredAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(inspector.RecordsDir, 0755); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", inspector.RecordsDir, err)
+	}
+
+	return ioutil.WriteFile(inspector.recordPath(handle), data, 0644)
+}
+
+func (inspector *FileLocksInspector) ForgetLock(handle lockgate.LockHandle) error {
+	if err := os.Remove(inspector.recordPath(handle)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (inspector *FileLocksInspector) ListLocks(_ context.Context, projectName string) ([]LockInfo, error) {
+	infos, err := ioutil.ReadDir(inspector.RecordsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read dir %s: %s", inspector.RecordsDir, err)
+	}
+
+	locksByName := make(map[string]*LockInfo)
+	ownersByName := make(map[string][]string)
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+
+		path := filepath.Join(inspector.RecordsDir, info.Name())
+
+		data, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read lock record %s: %s", path, err)
+		}
+
+		var record fileLockRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal lock record %s: %s", path, err)
+		}
+
+		if !isProcessAlive(record.PID) {
+			// the lock has been released by the OS with the exit of the holder process
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("unable to remove lock record %s: %s", path, err)
+			}
+			continue
+		}
+
+		if record.ProjectName != projectName {
+			continue
+		}
+
+		lock, hasKey := locksByName[record.LockName]
+		if !hasKey {
+			lock = &LockInfo{Name: record.LockName, Shared: record.Shared, AcquiredAt: record.AcquiredAt}
+			locksByName[record.LockName] = lock
+		}
+
+		lock.Holders++
+		if record.AcquiredAt.Before(lock.AcquiredAt) {
+			lock.AcquiredAt = record.AcquiredAt
+		}
+		ownersByName[record.LockName] = append(ownersByName[record.LockName], fmt.Sprintf("pid %d", record.PID))
+	}
+
+	var res []LockInfo
+	for lockName, lock := range locksByName {
+		sort.Strings(ownersByName[lockName])
+		lock.Owner = strings.Join(ownersByName[lockName], ",")
+		res = append(res, *lock)
+	}
+
+	return res, nil
+}
+
+func (inspector *FileLocksInspector) ForceRelease(_ context.Context, lock LockInfo) error {
+	return fmt.Errorf("host lock %q is released by the OS when the holder process exits: terminate the werf process holding the lock (%s) instead", lock.Name, lock.Owner)
+}
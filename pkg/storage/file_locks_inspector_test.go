@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate/pkg/file_locker"
+)
+
+func TestFileLocksInspector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-file-locks-inspector-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	locker, err := file_locker.NewFileLocker(filepath.Join(dir, "locks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inspector := NewFileLocksInspector(filepath.Join(dir, "records"))
+	manager := NewGenericLockManager(locker)
+	manager.Inspector = inspector
+
+	ctx := context.Background()
+
+	imageLock, err := manager.LockImage(ctx, "project", "registry.example.com/project:tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sharedLocks []LockHandle
+	for i := 0; i < 2; i++ {
+		lock, err := manager.LockStagesAndImages(ctx, "project", LockStagesAndImagesOptions{GetOrCreateImagesOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sharedLocks = append(sharedLocks, lock)
+	}
+
+	if _, err := manager.LockStage(ctx, "other-project", "sig"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the record of the exited process is skipped and removed
+	deadRecordPath := filepath.Join(inspector.RecordsDir, "dead.json")
+	data, _ := json.Marshal(fileLockRecord{ProjectName: "project", LockName: "project.sig", PID: 1 << 30, AcquiredAt: time.Now()})
+	if err := ioutil.WriteFile(deadRecordPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	locks, err := manager.ListLocks(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locksByName := make(map[string]LockInfo)
+	for _, lock := range locks {
+		locksByName[lock.Name] = lock
+	}
+
+	if len(locks) != 2 {
+		t.Errorf("expected 2 locks of the project, got %#v", locks)
+	}
+
+	if lock := locksByName["registry.example.com/project:tag.image"]; lock.Kind != ImageLockKind || lock.Holders != 1 || !strings.HasPrefix(lock.Owner, "pid ") {
+		t.Errorf("unexpected image lock %#v", lock)
+	}
+
+	if lock := locksByName["project.stages_and_images"]; lock.Kind != StagesAndImagesLockKind || !lock.Shared || lock.Holders != 2 {
+		t.Errorf("unexpected stages and images lock %#v", lock)
+	}
+
+	if _, err := os.Stat(deadRecordPath); !os.IsNotExist(err) {
+		t.Errorf("expected record of the exited process to be removed, got %v", err)
+	}
+
+	for _, lock := range append(sharedLocks, imageLock) {
+		if err := manager.Unlock(ctx, lock); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if locks, err := manager.ListLocks(ctx, "project"); err != nil {
+		t.Fatal(err)
+	} else if len(locks) != 0 {
+		t.Errorf("expected no locks after unlock, got %#v", locks)
+	}
+}
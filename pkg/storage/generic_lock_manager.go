@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/werf/lockgate"
 	"github.com/werf/logboek"
@@ -16,25 +17,47 @@ func NewGenericLockManager(locker lockgate.Locker) *GenericLockManager {
 type GenericLockManager struct {
 	// Single Locker for all projects
 	Locker lockgate.Locker
+	// Inspector lists and force releases the locks of the Locker, nil if not supported
+	Inspector GenericLocksInspector
+}
+
+type GenericLocksInspector interface {
+	ListLocks(ctx context.Context, projectName string) ([]LockInfo, error)
+	ForceRelease(ctx context.Context, lock LockInfo) error
+}
+
+// GenericLocksRecorder is the inspector which lists the locks recorded by the lock manager while the locks are held
+type GenericLocksRecorder interface {
+	RecordLock(projectName string, handle lockgate.LockHandle, shared bool) error
+	ForgetLock(handle lockgate.LockHandle) error
 }
 
 func (manager *GenericLockManager) LockStage(ctx context.Context, projectName, signature string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStageLockName(projectName, signature), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(ctx, projectName, genericStageLockName(projectName, signature), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockStageCache(ctx context.Context, projectName, signature string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStageCacheLockName(projectName, signature), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(ctx, projectName, genericStageCacheLockName(projectName, signature), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockImage(ctx context.Context, projectName, imageName string) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericImageLockName(imageName), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{}))
-	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
+	return manager.acquire(ctx, projectName, genericImageLockName(imageName), lockgate.AcquireOptions{})
 }
 
 func (manager *GenericLockManager) LockStagesAndImages(ctx context.Context, projectName string, opts LockStagesAndImagesOptions) (LockHandle, error) {
-	_, lock, err := manager.Locker.Acquire(genericStagesAndImagesLockName(projectName), werf.SetupLockerDefaultOptions(ctx, lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly}))
+	return manager.acquire(ctx, projectName, GenericStagesAndImagesLockName(projectName), lockgate.AcquireOptions{Shared: opts.GetOrCreateImagesOnly})
+}
+
+func (manager *GenericLockManager) acquire(ctx context.Context, projectName, lockName string, opts lockgate.AcquireOptions) (LockHandle, error) {
+	_, lock, err := manager.Locker.Acquire(lockName, werf.SetupLockerDefaultOptions(ctx, opts))
+	if err == nil {
+		if recorder, ok := manager.Inspector.(GenericLocksRecorder); ok {
+			if err := recorder.RecordLock(projectName, lock, opts.Shared); err != nil {
+				logboek.Context(ctx).Warn().LogF("WARNING: unable to record held lock %q: %s\n", lockName, err)
+			}
+		}
+	}
+
 	return LockHandle{LockgateHandle: lock, ProjectName: projectName}, err
 }
 
@@ -42,8 +65,55 @@ func (manager *GenericLockManager) Unlock(ctx context.Context, lock LockHandle)
 	err := manager.Locker.Release(lock.LockgateHandle)
 	if err != nil {
 		logboek.Context(ctx).Error().LogF("ERROR: unable to release lock for %q: %s\n", lock.LockgateHandle.LockName, err)
+		return err
+	}
+
+	if recorder, ok := manager.Inspector.(GenericLocksRecorder); ok {
+		if err := recorder.ForgetLock(lock.LockgateHandle); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: unable to forget released lock %q: %s\n", lock.LockgateHandle.LockName, err)
+		}
+	}
+
+	return nil
+}
+
+func (manager *GenericLockManager) ListLocks(ctx context.Context, projectName string) ([]LockInfo, error) {
+	if manager.Inspector == nil {
+		return nil, fmt.Errorf("listing locks is not supported by the synchronization")
+	}
+
+	locks, err := manager.Inspector.ListLocks(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range locks {
+		locks[i].Kind = genericLockKind(locks[i].Name)
+	}
+
+	return locks, nil
+}
+
+func (manager *GenericLockManager) ForceUnlock(ctx context.Context, _ string, lock LockInfo) error {
+	if manager.Inspector == nil {
+		return fmt.Errorf("force release of locks is not supported by the synchronization")
+	}
+	return manager.Inspector.ForceRelease(ctx, lock)
+}
+
+func genericLockKind(lockName string) LockKind {
+	switch {
+	case strings.HasSuffix(lockName, ".stages_and_images"):
+		return StagesAndImagesLockKind
+	case strings.HasSuffix(lockName, ".cache"):
+		return StageCacheLockKind
+	case strings.HasSuffix(lockName, ".image"):
+		return ImageLockKind
+	case strings.Contains(lockName, "."):
+		return StageLockKind
+	default:
+		return UnknownLockKind
 	}
-	return err
 }
 
 func genericStageLockName(projectName, signature string) string {
@@ -58,6 +128,6 @@ func genericImageLockName(imageName string) string {
 	return fmt.Sprintf("%s.image", imageName)
 }
 
-func genericStagesAndImagesLockName(projectName string) string {
+func GenericStagesAndImagesLockName(projectName string) string {
 	return fmt.Sprintf("%s.stages_and_images", projectName)
 }
@@ -15,6 +15,7 @@ type ImagesRepo interface {
 
 	GetAllImageRepoTags(ctx context.Context, imageName string) ([]string, error)
 	PublishImage(ctx context.Context, publishImage *container_runtime.WerfImage) error
+	CopyRepoImage(ctx context.Context, repoImage *image.Info, imageName, tag string) (*image.Info, error)
 
 	CreateImageRepo(ctx context.Context, imageName string) error
 	DeleteImageRepo(ctx context.Context, imageName string) error
@@ -22,6 +23,7 @@ type ImagesRepo interface {
 	ImageRepositoryName(imageName string) string
 	ImageRepositoryNameWithTag(imageName, tag string) string
 	ImageRepositoryTag(imageName, tag string) string
+	IsMonorepo() bool
 
 	String() string
 }
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/logging"
+)
+
+type MigrateImagesRepoOptions struct {
+	ImageNameList   []string
+	DeleteOldLayout bool
+	DryRun          bool
+}
+
+// MigrateImagesRepo copies the images of the project from the layout of fromImagesRepo to the layout of toImagesRepo (multirepo or monorepo mode of the same images repo).
+// Images metadata by commit in the stages storage does not depend on the images repo mode,
+// but the images with content signatures referenced by the metadata should be found in the new layout before the old layout is deleted.
+func MigrateImagesRepo(ctx context.Context, projectName string, fromImagesRepo, toImagesRepo ImagesRepo, stagesStorage StagesStorage, storageLockManager LockManager, options MigrateImagesRepoOptions) error {
+	if fromImagesRepo.IsMonorepo() == toImagesRepo.IsMonorepo() {
+		return fmt.Errorf("images repo %s is already in %s mode", fromImagesRepo.String(), imagesRepoModeName(toImagesRepo))
+	}
+
+	if lock, err := storageLockManager.LockStagesAndImages(ctx, projectName, LockStagesAndImagesOptions{GetOrCreateImagesOnly: false}); err != nil {
+		return fmt.Errorf("unable to lock stages and images: %s", err)
+	} else {
+		defer storageLockManager.Unlock(ctx, lock)
+	}
+
+	// nameless image is stored in the images repo itself by the same tags in both modes
+	var imageNameList []string
+	for _, imageName := range options.ImageNameList {
+		if imageName == "" {
+			continue
+		}
+		imageNameList = append(imageNameList, imageName)
+	}
+	sort.Strings(imageNameList)
+
+	repoImages, err := selectImagesRepoImagesToMigrate(ctx, fromImagesRepo, options.ImageNameList)
+	if err != nil {
+		return err
+	}
+
+	for _, imageName := range imageNameList {
+		if err := logboek.Context(ctx).Default().LogProcess(logging.ImageLogProcessName(imageName, false)).DoError(func() error {
+			return migrateImage(ctx, projectName, imageName, repoImages[imageName], fromImagesRepo, toImagesRepo, stagesStorage, options.DryRun)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if !options.DeleteOldLayout {
+		logboek.Context(ctx).Default().LogF("Old %s layout of images repo %s is kept, use --images-repo-mode=%s from now on\n", imagesRepoModeName(fromImagesRepo), fromImagesRepo.String(), imagesRepoModeName(toImagesRepo))
+		return nil
+	}
+
+	// deletion by digest removes all tags of the repository with the same digest,
+	// so the digests of the nameless image tags are protected in the monorepo, even if the nameless image is not migrated
+	protectedDigests := map[string]bool{}
+	if fromImagesRepo.IsMonorepo() {
+		namelessRepoImages, err := selectImagesRepoImagesToMigrate(ctx, fromImagesRepo, []string{""})
+		if err != nil {
+			return err
+		}
+
+		for _, repoImage := range namelessRepoImages[""] {
+			protectedDigests[repoImage.RepoDigest] = true
+		}
+	}
+
+	return logboek.Context(ctx).Default().LogProcess("Deleting old %s layout", imagesRepoModeName(fromImagesRepo)).DoError(func() error {
+		for _, imageName := range imageNameList {
+			for _, repoImage := range repoImages[imageName] {
+				if protectedDigests[repoImage.RepoDigest] {
+					logboek.Context(ctx).Warn().LogF("Skip deletion of %s: digest %s is used by the nameless image\n", repoImage.Name, repoImage.RepoDigest)
+					continue
+				}
+
+				if !options.DryRun {
+					if err := fromImagesRepo.DeleteRepoImage(ctx, DeleteImageOptions{}, repoImage); err != nil {
+						if docker_registry.IsManifestUnknownError(err) {
+							continue
+						}
+						return fmt.Errorf("unable to delete %s: %s", repoImage.Name, err)
+					}
+				}
+
+				logboek.Context(ctx).Default().LogFDetails("  deleted: %s\n", repoImage.Name)
+			}
+		}
+
+		return nil
+	})
+}
+
+func migrateImage(ctx context.Context, projectName, imageName string, repoImageList []*image.Info, fromImagesRepo, toImagesRepo ImagesRepo, stagesStorage StagesStorage, dryRun bool) error {
+	for _, repoImage := range repoImageList {
+		tag := getImagesRepoImageTag(fromImagesRepo, imageName, repoImage)
+		reference := toImagesRepo.ImageRepositoryNameWithTag(imageName, tag)
+
+		if !dryRun {
+			if _, err := toImagesRepo.CopyRepoImage(ctx, repoImage, imageName, tag); err != nil {
+				return fmt.Errorf("unable to copy %s to %s: %s", repoImage.Name, reference, err)
+			}
+		}
+
+		logboek.Context(ctx).Default().LogFDetails("  %s -> %s\n", repoImage.Name, reference)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	return checkImageMetadataConsistency(ctx, projectName, imageName, repoImageList, toImagesRepo, stagesStorage)
+}
+
+// checkImageMetadataConsistency checks that each content signature of the image metadata by commit found in the old layout is also found in the new layout
+func checkImageMetadataConsistency(ctx context.Context, projectName, imageName string, oldRepoImageList []*image.Info, toImagesRepo ImagesRepo, stagesStorage StagesStorage) error {
+	newRepoImages, err := selectImagesRepoImagesToMigrate(ctx, toImagesRepo, []string{imageName})
+	if err != nil {
+		return err
+	}
+
+	oldContentSignatures := repoImagesContentSignatures(oldRepoImageList)
+	newContentSignatures := repoImagesContentSignatures(newRepoImages[imageName])
+
+	commits, err := stagesStorage.GetImageCommits(ctx, projectName, imageName)
+	if err != nil {
+		return fmt.Errorf("get image %s commits failed: %s", imageName, err)
+	}
+
+	var recordsWithoutImages int
+	for _, commit := range commits {
+		imageMetadata, err := stagesStorage.GetImageMetadataByCommit(ctx, projectName, imageName, commit)
+		if err != nil {
+			return fmt.Errorf("get image %s metadata by commit %s failed: %s", imageName, commit, err)
+		} else if imageMetadata == nil {
+			continue
+		}
+
+		if !oldContentSignatures[imageMetadata.ContentSignature] {
+			recordsWithoutImages++
+		} else if !newContentSignatures[imageMetadata.ContentSignature] {
+			return fmt.Errorf("image %s with content signature %s referenced by commit %s metadata is not found in %s layout", imageName, imageMetadata.ContentSignature, commit, imagesRepoModeName(toImagesRepo))
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Checked %d metadata by commit records (%d without images)\n", len(commits), recordsWithoutImages)
+
+	return nil
+}
+
+func selectImagesRepoImagesToMigrate(ctx context.Context, imagesRepo ImagesRepo, imageNameList []string) (map[string][]*image.Info, error) {
+	return imagesRepo.SelectRepoImages(ctx, imageNameList, func(reference string, info *image.Info, err error) (bool, error) {
+		if err != nil && docker_registry.IsManifestUnknownError(err) {
+			logboek.Context(ctx).Warn().LogF("Skip image %s: %s\n", reference, err)
+			return false, nil
+		}
+
+		return true, err
+	})
+}
+
+// getImagesRepoImageTag returns the tag of the image without the image name prefix of the monorepo mode
+func getImagesRepoImageTag(imagesRepo ImagesRepo, imageName string, repoImage *image.Info) string {
+	// the label contains the tag of the first publication of the image, the image can be also tagged by other tags
+	if tag, ok := repoImage.Labels[image.WerfImageTagLabel]; ok && imagesRepo.ImageRepositoryTag(imageName, tag) == repoImage.Tag {
+		return tag
+	}
+
+	return strings.TrimPrefix(repoImage.Tag, imagesRepo.ImageRepositoryTag(imageName, ""))
+}
+
+func repoImagesContentSignatures(repoImageList []*image.Info) map[string]bool {
+	res := map[string]bool{}
+	for _, repoImage := range repoImageList {
+		if contentSignature, ok := repoImage.Labels[image.WerfContentSignatureLabel]; ok {
+			res[contentSignature] = true
+		}
+	}
+	return res
+}
+
+func imagesRepoModeName(imagesRepo ImagesRepo) string {
+	if imagesRepo.IsMonorepo() {
+		return docker_registry.MonorepoRepoMode
+	}
+	return docker_registry.MultirepoRepoMode
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/werf/werf/pkg/docker_registry"
+	"github.com/werf/werf/pkg/image"
+)
+
+func TestGetImagesRepoImageTag(t *testing.T) {
+	for _, tc := range []struct {
+		repoMode string
+		tag      string
+		labels   map[string]string
+		expected string
+	}{
+		{repoMode: docker_registry.MultirepoRepoMode, tag: "v1.0", expected: "v1.0"},
+		{repoMode: docker_registry.MonorepoRepoMode, tag: "image-v1.0", expected: "v1.0"},
+		{repoMode: docker_registry.MonorepoRepoMode, tag: "image-image-v1.0", labels: map[string]string{image.WerfImageTagLabel: "image-v1.0"}, expected: "image-v1.0"},
+		// the image tagged by the other tag keeps the label of the first publication
+		{repoMode: docker_registry.MonorepoRepoMode, tag: "image-v2.0", labels: map[string]string{image.WerfImageTagLabel: "v1.0"}, expected: "v2.0"},
+	} {
+		manager, err := newImagesRepoManager("repo", tc.repoMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := &DockerImagesRepo{imagesRepoManager: manager}
+		if tag := getImagesRepoImageTag(repo, "image", &image.Info{Tag: tc.tag, Labels: tc.labels}); tag != tc.expected {
+			t.Errorf("%s %s: expected tag %q, got %q", tc.repoMode, tc.tag, tc.expected, tag)
+		}
+	}
+}
+
+// fakeDockerRegistry keeps the repo images by references, deletion by digest removes all tags of the repository with the same digest as the registry does
+type fakeDockerRegistry struct {
+	docker_registry.DockerRegistry
+
+	images map[string]*image.Info
+	// copiedDigest replaces the digest of the copied images when set
+	copiedDigest string
+}
+
+func newFakeDockerRegistry(repoImages ...*image.Info) *fakeDockerRegistry {
+	registry := &fakeDockerRegistry{images: map[string]*image.Info{}}
+	for _, repoImage := range repoImages {
+		registry.images[repoImage.Name] = repoImage
+	}
+	return registry
+}
+
+func (registry *fakeDockerRegistry) TryGetRepoImage(_ context.Context, reference string) (*image.Info, error) {
+	return registry.images[reference], nil
+}
+
+func (registry *fakeDockerRegistry) GetRepoImage(_ context.Context, reference string) (*image.Info, error) {
+	if repoImage, ok := registry.images[reference]; ok {
+		return repoImage, nil
+	}
+	return nil, fmt.Errorf("image %s not found", reference)
+}
+
+func (registry *fakeDockerRegistry) SelectRepoImageList(_ context.Context, reference string, f func(string, *image.Info, error) (bool, error)) ([]*image.Info, error) {
+	var references []string
+	for ref, repoImage := range registry.images {
+		if repoImage.Repository == reference {
+			references = append(references, ref)
+		}
+	}
+	sort.Strings(references)
+
+	var res []*image.Info
+	for _, ref := range references {
+		if ok, err := f(ref, registry.images[ref], nil); err != nil {
+			return nil, err
+		} else if ok {
+			res = append(res, registry.images[ref])
+		}
+	}
+	return res, nil
+}
+
+func (registry *fakeDockerRegistry) CopyImage(_ context.Context, sourceReference, destinationReference string) error {
+	sourceImage, ok := registry.images[sourceReference]
+	if !ok {
+		return fmt.Errorf("image %s not found", sourceReference)
+	}
+
+	copiedImage := *sourceImage
+	copiedImage.Name = destinationReference
+	copiedImage.Repository = strings.SplitN(destinationReference, ":", 2)[0]
+	copiedImage.Tag = strings.SplitN(destinationReference, ":", 2)[1]
+	if registry.copiedDigest != "" {
+		copiedImage.RepoDigest = registry.copiedDigest
+	}
+	registry.images[destinationReference] = &copiedImage
+
+	return nil
+}
+
+func (registry *fakeDockerRegistry) DeleteRepoImage(_ context.Context, repoImageList ...*image.Info) error {
+	for _, repoImage := range repoImageList {
+		for ref, existingImage := range registry.images {
+			if existingImage.Repository == repoImage.Repository && existingImage.RepoDigest == repoImage.RepoDigest {
+				delete(registry.images, ref)
+			}
+		}
+	}
+	return nil
+}
+
+func (registry *fakeDockerRegistry) references() []string {
+	var res []string
+	for ref := range registry.images {
+		res = append(res, ref)
+	}
+	sort.Strings(res)
+	return res
+}
+
+type fakeImageMetadataStagesStorage struct {
+	StagesStorage
+
+	contentSignatureByCommit map[string]string
+}
+
+func (stagesStorage *fakeImageMetadataStagesStorage) GetImageCommits(_ context.Context, _, _ string) ([]string, error) {
+	var commits []string
+	for commit := range stagesStorage.contentSignatureByCommit {
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+func (stagesStorage *fakeImageMetadataStagesStorage) GetImageMetadataByCommit(_ context.Context, _, _, commit string) (*ImageMetadata, error) {
+	return &ImageMetadata{ContentSignature: stagesStorage.contentSignatureByCommit[commit]}, nil
+}
+
+type fakeLockManager struct {
+	LockManager
+}
+
+func (lockManager *fakeLockManager) LockStagesAndImages(_ context.Context, projectName string, _ LockStagesAndImagesOptions) (LockHandle, error) {
+	return LockHandle{ProjectName: projectName}, nil
+}
+
+func (lockManager *fakeLockManager) Unlock(_ context.Context, _ LockHandle) error {
+	return nil
+}
+
+func newTestRepoImage(repository, imageName, tag, contentSignature, digest string) *image.Info {
+	return &image.Info{
+		Name:       fmt.Sprintf("%s:%s", repository, tag),
+		Repository: repository,
+		Tag:        tag,
+		RepoDigest: digest,
+		Labels: map[string]string{
+			image.WerfImageLabel:            "true",
+			image.WerfImageNameLabel:        imageName,
+			image.WerfImageTagLabel:         strings.TrimPrefix(tag, imageName+monorepoTagPartsSeparator),
+			image.WerfContentSignatureLabel: contentSignature,
+		},
+	}
+}
+
+// newTestMonorepoMigration returns the monorepo with the nameless image and two tags of the app image,
+// one of them has the same digest as the nameless image, and the multirepo of the same registry
+func newTestMonorepoMigration(t *testing.T) (*fakeDockerRegistry, ImagesRepo, ImagesRepo) {
+	registry := newFakeDockerRegistry(
+		newTestRepoImage("repo", "", "latest", "nameless-sig", "sha256:nameless"),
+		newTestRepoImage("repo", "app", "app-v1", "app-sig-1", "sha256:app-1"),
+		newTestRepoImage("repo", "app", "app-v2", "app-sig-2", "sha256:nameless"),
+	)
+
+	var imagesRepos []ImagesRepo
+	for _, repoMode := range []string{docker_registry.MonorepoRepoMode, docker_registry.MultirepoRepoMode} {
+		manager, err := newImagesRepoManager("repo", repoMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imagesRepos = append(imagesRepos, &DockerImagesRepo{DockerRegistry: registry, imagesRepoManager: manager, projectName: "project"})
+	}
+
+	return registry, imagesRepos[0], imagesRepos[1]
+}
+
+func TestMigrateImagesRepo(t *testing.T) {
+	registry, fromImagesRepo, toImagesRepo := newTestMonorepoMigration(t)
+	stagesStorage := &fakeImageMetadataStagesStorage{contentSignatureByCommit: map[string]string{
+		"commit-1": "app-sig-1",
+		"commit-2": "app-sig-2",
+		// the image of the commit has been cleaned up
+		"commit-3": "app-sig-0",
+	}}
+
+	// the nameless image is not migrated, but its digests should be protected
+	if err := MigrateImagesRepo(context.Background(), "project", fromImagesRepo, toImagesRepo, stagesStorage, &fakeLockManager{}, MigrateImagesRepoOptions{ImageNameList: []string{"app"}, DeleteOldLayout: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"repo/app:v1", "repo/app:v2", "repo:app-v2", "repo:latest"}
+	if references := registry.references(); !reflect.DeepEqual(references, expected) {
+		t.Errorf("expected %v, got %v", expected, references)
+	}
+
+	for reference, digest := range map[string]string{"repo/app:v1": "sha256:app-1", "repo/app:v2": "sha256:nameless"} {
+		if repoImage := registry.images[reference]; repoImage != nil && repoImage.RepoDigest != digest {
+			t.Errorf("expected %s digest %s, got %s", reference, digest, repoImage.RepoDigest)
+		}
+	}
+}
+
+func TestMigrateImagesRepoChecksDigestOfCopiedImages(t *testing.T) {
+	registry, fromImagesRepo, toImagesRepo := newTestMonorepoMigration(t)
+	registry.copiedDigest = "sha256:broken"
+
+	err := MigrateImagesRepo(context.Background(), "project", fromImagesRepo, toImagesRepo, &fakeImageMetadataStagesStorage{}, &fakeLockManager{}, MigrateImagesRepoOptions{ImageNameList: []string{"", "app"}, DeleteOldLayout: true})
+	if err == nil || !strings.Contains(err.Error(), "does not match digest") {
+		t.Fatalf("expected digest mismatch error, got %v", err)
+	}
+
+	for _, reference := range []string{"repo:latest", "repo:app-v1", "repo:app-v2"} {
+		if _, ok := registry.images[reference]; !ok {
+			t.Errorf("expected %s of the old layout to be kept", reference)
+		}
+	}
+}
+
+func TestMigrateImagesRepoDryRun(t *testing.T) {
+	registry, fromImagesRepo, toImagesRepo := newTestMonorepoMigration(t)
+	stagesStorage := &fakeImageMetadataStagesStorage{contentSignatureByCommit: map[string]string{"commit-1": "app-sig-1"}}
+
+	if err := MigrateImagesRepo(context.Background(), "project", fromImagesRepo, toImagesRepo, stagesStorage, &fakeLockManager{}, MigrateImagesRepoOptions{ImageNameList: []string{"", "app"}, DeleteOldLayout: true, DryRun: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"repo:app-v1", "repo:app-v2", "repo:latest"}
+	if references := registry.references(); !reflect.DeepEqual(references, expected) {
+		t.Errorf("expected %v, got %v", expected, references)
+	}
+}
@@ -2,11 +2,17 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/werf/lockgate/pkg/distributed_locker"
 	"github.com/werf/werf/pkg/kubeutils"
@@ -114,6 +120,92 @@ func (manager *KuberntesLockManager) Unlock(ctx context.Context, lock LockHandle
 	}
 }
 
+// lockgate stores lock leases in the configmap annotations
+const kubernetesLockLeaseAnnotationPrefix = "lockgate.io/"
+
+func (manager *KuberntesLockManager) ListLocks(ctx context.Context, projectName string) ([]LockInfo, error) {
+	name := manager.GetConfigMapNameFunc(projectName)
+
+	cm, err := manager.KubeClient.CoreV1().ConfigMaps(manager.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get cm/%s in ns/%s: %s", name, manager.Namespace, err)
+	}
+
+	var res []LockInfo
+	for key, value := range cm.Annotations {
+		if !strings.HasPrefix(key, kubernetesLockLeaseAnnotationPrefix) {
+			continue
+		}
+
+		var lease *distributed_locker.LockLeaseRecord
+		if err := json.Unmarshal([]byte(value), &lease); err != nil {
+			logboek.Context(ctx).Warn().LogF("WARNING: unable to parse lock lease annotation %s of cm/%s in ns/%s: %s\n", key, name, manager.Namespace, err)
+			continue
+		}
+
+		res = append(res, LockInfo{
+			Kind:     kubernetesLockKind(lease.LockName),
+			Name:     lease.LockName,
+			Owner:    lease.UUID,
+			Shared:   lease.IsShared,
+			Holders:  lease.SharedHoldersCount,
+			ExpireAt: time.Unix(lease.ExpireAtTimestamp, 0),
+		})
+	}
+
+	return res, nil
+}
+
+// ForceUnlock removes the lease only if it is still held by the same owner
+func (manager *KuberntesLockManager) ForceUnlock(ctx context.Context, projectName string, lock LockInfo) error {
+	name := manager.GetConfigMapNameFunc(projectName)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := manager.KubeClient.CoreV1().ConfigMaps(manager.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to get cm/%s in ns/%s: %s", name, manager.Namespace, err)
+		}
+
+		var leaseKey string
+		for key, value := range cm.Annotations {
+			if !strings.HasPrefix(key, kubernetesLockLeaseAnnotationPrefix) {
+				continue
+			}
+
+			var lease *distributed_locker.LockLeaseRecord
+			if err := json.Unmarshal([]byte(value), &lease); err == nil && lease.LockName == lock.Name && lease.UUID == lock.Owner {
+				leaseKey = key
+				break
+			}
+		}
+
+		if leaseKey == "" {
+			return fmt.Errorf("lock %q with owner %q not found in cm/%s in ns/%s", lock.Name, lock.Owner, name, manager.Namespace)
+		}
+
+		delete(cm.Annotations, leaseKey)
+		_, err = manager.KubeClient.CoreV1().ConfigMaps(manager.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func kubernetesLockKind(lockName string) LockKind {
+	switch {
+	case lockName == kuberntesStagesAndImagesLockName(""):
+		return StagesAndImagesLockKind
+	case strings.HasPrefix(lockName, "stage-cache/"):
+		return StageCacheLockKind
+	case strings.HasPrefix(lockName, "stage/"):
+		return StageLockKind
+	case strings.HasPrefix(lockName, "image/"):
+		return ImageLockKind
+	default:
+		return UnknownLockKind
+	}
+}
+
 // FIXME: v1.2 use the same locks names as generic lock manager (include project name into lock name)
 func kubernetesStageLockName(_, signature string) string {
 	return fmt.Sprintf("stage/%s", signature)
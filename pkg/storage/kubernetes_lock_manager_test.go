@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+func TestKubernetesLockManagerListAndForceUnlock(t *testing.T) {
+	ctx := context.Background()
+
+	annotations := map[string]string{"other": "value"}
+	for key, lease := range map[string]*distributed_locker.LockLeaseRecord{
+		"lockgate.io/active":  distributed_locker.NewLockLeaseRecord("stages_and_images", true),
+		"lockgate.io/expired": distributed_locker.NewLockLeaseRecord("stage/signature", false),
+	} {
+		if key == "lockgate.io/expired" {
+			lease.ExpireAtTimestamp = time.Now().Add(-time.Hour).Unix()
+		}
+
+		data, err := json.Marshal(lease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotations[key] = string(data)
+	}
+
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "werf-project", Namespace: "werf-synchronization", Annotations: annotations}})
+	manager := NewKubernetesLockManager("werf-synchronization", client, nil, func(projectName string) string {
+		return "werf-" + projectName
+	})
+
+	locks, err := manager.ListLocks(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locksByKind := make(map[LockKind]LockInfo)
+	for _, lock := range locks {
+		locksByKind[lock.Kind] = lock
+	}
+
+	if len(locks) != 2 || !locksByKind[StagesAndImagesLockKind].Shared || locksByKind[StagesAndImagesLockKind].IsExpired() || !locksByKind[StageLockKind].IsExpired() {
+		t.Fatalf("unexpected locks: %#v", locks)
+	}
+
+	expiredLock := locksByKind[StageLockKind]
+
+	staleLock := expiredLock
+	staleLock.Owner = "another-owner"
+	if err := manager.ForceUnlock(ctx, "project", staleLock); err == nil {
+		t.Fatal("expected the lease of another owner not to be released")
+	}
+
+	if err := manager.ForceUnlock(ctx, "project", expiredLock); err != nil {
+		t.Fatal(err)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps("werf-synchronization").Get(ctx, "werf-project", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, hasKey := cm.Annotations["lockgate.io/expired"]; hasKey {
+		t.Error("expected the expired lease to be removed")
+	}
+	if _, hasKey := cm.Annotations["lockgate.io/active"]; !hasKey {
+		t.Error("expected the active lease to be kept")
+	}
+	if cm.Annotations["other"] != "value" {
+		t.Error("expected other annotations to be kept")
+	}
+
+	if locks, err := manager.ListLocks(ctx, "unknown-project"); err != nil || len(locks) != 0 {
+		t.Errorf("expected no locks for unknown project, got %v %v", locks, err)
+	}
+}
+
+func TestGenericLockKind(t *testing.T) {
+	for lockName, expected := range map[string]LockKind{
+		GenericStagesAndImagesLockName("project"):   StagesAndImagesLockKind,
+		genericStageLockName("project", "sig"):      StageLockKind,
+		genericStageCacheLockName("project", "sig"): StageCacheLockKind,
+		genericImageLockName("registry/app:tag"):    ImageLockKind,
+	} {
+		if kind := genericLockKind(lockName); kind != expected {
+			t.Errorf("expected %s kind of %q, got %s", expected, lockName, kind)
+		}
+	}
+}
@@ -48,6 +48,21 @@ func getSignatureAndUniqueIDFromLocalStageImageTag(repoStageImageTag string) (st
 	}
 }
 
+// GetStageIDFromLocalStageImageName parses the stage id from the local stage image name werf-stages-storage/PROJECT:SIGNATURE-UNIQUEID
+func GetStageIDFromLocalStageImageName(imageName string) (image.StageID, error) {
+	parts := strings.SplitN(imageName, ":", 2)
+	if len(parts) != 2 || !strings.HasPrefix(imageName, LocalStage_ImageRepoPrefix) || !strings.Contains(parts[1], "-") {
+		return image.StageID{}, fmt.Errorf("unexpected local stage image name %q", imageName)
+	}
+
+	signature, uniqueID, err := getSignatureAndUniqueIDFromLocalStageImageTag(parts[1])
+	if err != nil {
+		return image.StageID{}, err
+	}
+
+	return image.StageID{Signature: signature, UniqueID: uniqueID}, nil
+}
+
 type LocalDockerServerStagesStorage struct {
 	// Local stages storage is compatible only with docker-server backed runtime
 	LocalDockerServerRuntime *container_runtime.LocalDockerServerRuntime
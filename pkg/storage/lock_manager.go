@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/werf/lockgate"
 )
@@ -12,6 +13,11 @@ type LockManager interface {
 	LockImage(ctx context.Context, projectName, imageName string) (LockHandle, error)
 	LockStagesAndImages(ctx context.Context, projectName string, opts LockStagesAndImagesOptions) (LockHandle, error)
 	Unlock(ctx context.Context, lockHandle LockHandle) error
+
+	// ListLocks returns currently held locks of the project
+	ListLocks(ctx context.Context, projectName string) ([]LockInfo, error)
+	// ForceUnlock releases the lock for all holders, holders lose the lock on the next lease renewal
+	ForceUnlock(ctx context.Context, projectName string, lock LockInfo) error
 }
 
 type LockHandle struct {
@@ -22,3 +28,31 @@ type LockHandle struct {
 type LockStagesAndImagesOptions struct {
 	GetOrCreateImagesOnly bool `json:"getOrCreateImagesOnly"`
 }
+
+type LockKind string
+
+const (
+	StageLockKind           LockKind = "stage"
+	StageCacheLockKind      LockKind = "stage-cache"
+	ImageLockKind           LockKind = "image"
+	StagesAndImagesLockKind LockKind = "stages-and-images"
+	UnknownLockKind         LockKind = "unknown"
+)
+
+type LockInfo struct {
+	Kind LockKind `json:"kind"`
+	Name string   `json:"name"`
+	// Owner is the lease id or the client id of the holder, empty when the holder cannot be identified
+	Owner   string `json:"owner"`
+	Shared  bool   `json:"shared"`
+	Holders int64  `json:"holders"`
+	// AcquiredAt is zero when unknown
+	AcquiredAt time.Time `json:"acquiredAt"`
+	// ExpireAt is zero for the locks without lease which are released when the holder process exits
+	ExpireAt time.Time `json:"expireAt"`
+}
+
+// IsExpired lease is not renewed by the holder anymore and can be safely released
+func (lock LockInfo) IsExpired() bool {
+	return !lock.ExpireAt.IsZero() && time.Now().After(lock.ExpireAt)
+}
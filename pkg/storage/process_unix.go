@@ -0,0 +1,10 @@
+// +build linux darwin
+
+package storage
+
+import "syscall"
+
+func isProcessAlive(pid int) bool {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,15 @@
+// +build windows
+
+package storage
+
+import "os"
+
+func isProcessAlive(pid int) bool {
+	// FindProcess opens the process on windows and fails if the process does not exist
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	process.Release()
+	return true
+}
@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+// expired leases are kept for a while, so that the holders could renew the leases after the network failure
+const redisExpiredLeaseRetentionPeriod = time.Hour
+
+// RedisDistributedLockerBackend stores the lease of the lock in the key NAMESPACE:locks:LOCK_NAME.
+// Leases are changed in WATCH/MULTI transactions, the change is retried when the key has been modified by another client.
+func NewRedisDistributedLockerBackend(client *redis.Client, namespace string) *RedisDistributedLockerBackend {
+	return &RedisDistributedLockerBackend{Client: client, Namespace: namespace}
+}
+
+type RedisDistributedLockerBackend struct {
+	Client    *redis.Client
+	Namespace string
+}
+
+func (backend *RedisDistributedLockerBackend) Acquire(lockName string, opts distributed_locker.AcquireOptions) (lockgate.LockHandle, error) {
+	var handle lockgate.LockHandle
+
+	err := backend.changeLease(context.Background(), lockName, func(oldLease *distributed_locker.LockLeaseRecord) (*distributed_locker.LockLeaseRecord, error) {
+		switch {
+		case oldLease == nil || time.Now().After(time.Unix(oldLease.ExpireAtTimestamp, 0)):
+			newLease := distributed_locker.NewLockLeaseRecord(lockName, opts.Shared)
+			handle = newLease.LockHandle
+			return newLease, nil
+		case opts.Shared && oldLease.IsShared:
+			oldLease.SharedHoldersCount++
+			oldLease.ExpireAtTimestamp = time.Now().Unix() + distributed_locker.DistributedLockLeaseTTLSeconds
+			handle = oldLease.LockHandle
+			return oldLease, nil
+		default:
+			return nil, distributed_locker.ErrShouldWait
+		}
+	})
+
+	return handle, err
+}
+
+func (backend *RedisDistributedLockerBackend) RenewLease(handle lockgate.LockHandle) error {
+	return backend.changeHeldLease(handle, func(lease *distributed_locker.LockLeaseRecord) *distributed_locker.LockLeaseRecord {
+		lease.ExpireAtTimestamp = time.Now().Unix() + distributed_locker.DistributedLockLeaseTTLSeconds
+		return lease
+	})
+}
+
+func (backend *RedisDistributedLockerBackend) Release(handle lockgate.LockHandle) error {
+	return backend.changeHeldLease(handle, func(lease *distributed_locker.LockLeaseRecord) *distributed_locker.LockLeaseRecord {
+		lease.SharedHoldersCount--
+		if lease.SharedHoldersCount == 0 {
+			return nil
+		}
+		return lease
+	})
+}
+
+// ListLocks returns the locks of the project, image locks are not listed because their names do not contain the project name
+func (backend *RedisDistributedLockerBackend) ListLocks(ctx context.Context, projectName string) ([]LockInfo, error) {
+	client := backend.Client.WithContext(ctx)
+
+	var res []LockInfo
+	iter := client.Scan(0, backend.lockKey(projectName+".*"), 100).Iterator()
+	for iter.Next() {
+		lease, err := getRedisLease(client, iter.Val())
+		if err != nil {
+			return nil, err
+		} else if lease == nil {
+			continue
+		}
+
+		res = append(res, LockInfo{
+			Name:     lease.LockName,
+			Owner:    lease.UUID,
+			Shared:   lease.IsShared,
+			Holders:  lease.SharedHoldersCount,
+			ExpireAt: time.Unix(lease.ExpireAtTimestamp, 0),
+		})
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("unable to scan %s locks: %s", projectName, err)
+	}
+
+	return res, nil
+}
+
+// ForceRelease removes the lease only if it is still held by the same owner
+func (backend *RedisDistributedLockerBackend) ForceRelease(ctx context.Context, lock LockInfo) error {
+	return backend.changeLease(ctx, lock.Name, func(lease *distributed_locker.LockLeaseRecord) (*distributed_locker.LockLeaseRecord, error) {
+		if lease == nil || lease.UUID != lock.Owner {
+			return nil, fmt.Errorf("lock %q with owner %q not found", lock.Name, lock.Owner)
+		}
+		return nil, nil
+	})
+}
+
+func (backend *RedisDistributedLockerBackend) changeHeldLease(handle lockgate.LockHandle, changeFunc func(lease *distributed_locker.LockLeaseRecord) *distributed_locker.LockLeaseRecord) error {
+	return backend.changeLease(context.Background(), handle.LockName, func(lease *distributed_locker.LockLeaseRecord) (*distributed_locker.LockLeaseRecord, error) {
+		if lease == nil {
+			return nil, distributed_locker.ErrNoExistingLockLeaseFound
+		} else if lease.UUID != handle.UUID {
+			return nil, distributed_locker.ErrLockAlreadyLeased
+		}
+		return changeFunc(lease), nil
+	})
+}
+
+// changeLease stores the lease returned by changeFunc or removes the lease when nil is returned
+func (backend *RedisDistributedLockerBackend) changeLease(ctx context.Context, lockName string, changeFunc func(lease *distributed_locker.LockLeaseRecord) (*distributed_locker.LockLeaseRecord, error)) error {
+	key := backend.lockKey(lockName)
+
+	for {
+		err := backend.Client.WatchContext(ctx, func(tx *redis.Tx) error {
+			lease, err := getRedisLease(tx, key)
+			if err != nil {
+				return err
+			}
+
+			newLease, err := changeFunc(lease)
+			if err != nil {
+				return err
+			}
+
+			var data []byte
+			if newLease != nil {
+				if data, err = json.Marshal(newLease); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+				if newLease == nil {
+					pipe.Del(key)
+				} else {
+					pipe.Set(key, string(data), time.Until(time.Unix(newLease.ExpireAtTimestamp, 0))+redisExpiredLeaseRetentionPeriod)
+				}
+				return nil
+			})
+			return err
+		}, key)
+
+		if err == redis.TxFailedErr {
+			// the lease has been changed by another client since WATCH: retry with the same period as the optimistic locking of lockgate backends
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(distributed_locker.DistributedOptimisticLockingRetryPeriodSeconds * time.Second):
+			}
+			continue
+		}
+		return err
+	}
+}
+
+func (backend *RedisDistributedLockerBackend) lockKey(lockName string) string {
+	return fmt.Sprintf("%s:locks:%s", backend.Namespace, lockName)
+}
+
+func getRedisLease(client redis.Cmdable, key string) (*distributed_locker.LockLeaseRecord, error) {
+	data, err := client.Get(key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get %s: %s", key, err)
+	}
+
+	var lease *distributed_locker.LockLeaseRecord
+	if err := json.Unmarshal([]byte(data), &lease); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal lease %s: %s", strings.TrimSpace(data), err)
+	}
+	return lease, nil
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+// RedisStagesStorageCache stores records of the project in the hash NAMESPACE:stages-storage-cache:PROJECT by signature
+func NewRedisStagesStorageCache(client *redis.Client, namespace string) *RedisStagesStorageCache {
+	return &RedisStagesStorageCache{Client: client, Namespace: namespace}
+}
+
+type RedisStagesStorageCache struct {
+	Client    *redis.Client
+	Namespace string
+}
+
+func (cache *RedisStagesStorageCache) String() string {
+	return fmt.Sprintf("redis://%s/%d:%s", cache.Client.Options().Addr, cache.Client.Options().DB, cache.Namespace)
+}
+
+func (cache *RedisStagesStorageCache) GetAllStages(ctx context.Context, projectName string) (bool, []image.StageID, error) {
+	records, err := cache.Client.WithContext(ctx).HGetAll(cache.projectKey(projectName)).Result()
+	if err != nil {
+		return false, nil, fmt.Errorf("unable to get %s stages from %s: %s", projectName, cache.String(), err)
+	}
+
+	var res []image.StageID
+	for signature, data := range records {
+		if stages, ok := cache.unmarshalRecord(ctx, projectName, signature, data); ok {
+			res = append(res, stages...)
+		}
+	}
+
+	return len(records) > 0, res, nil
+}
+
+func (cache *RedisStagesStorageCache) DeleteAllStages(ctx context.Context, projectName string) error {
+	if err := cache.Client.WithContext(ctx).Del(cache.projectKey(projectName)).Err(); err != nil {
+		return fmt.Errorf("unable to delete %s stages from %s: %s", projectName, cache.String(), err)
+	}
+	return nil
+}
+
+func (cache *RedisStagesStorageCache) GetStagesBySignature(ctx context.Context, projectName, signature string) (bool, []image.StageID, error) {
+	data, err := cache.Client.WithContext(ctx).HGet(cache.projectKey(projectName), signature).Result()
+	if err == redis.Nil {
+		return false, nil, nil
+	} else if err != nil {
+		return false, nil, fmt.Errorf("unable to get %s/%s stages from %s: %s", projectName, signature, cache.String(), err)
+	}
+
+	stages, found := cache.unmarshalRecord(ctx, projectName, signature, data)
+	return found, stages, nil
+}
+
+func (cache *RedisStagesStorageCache) StoreStagesBySignature(ctx context.Context, projectName, signature string, stages []image.StageID) error {
+	data, err := json.Marshal(StagesStorageCacheRecord{Stages: stages})
+	if err != nil {
+		return err
+	}
+
+	if err := cache.Client.WithContext(ctx).HSet(cache.projectKey(projectName), signature, string(data)).Err(); err != nil {
+		return fmt.Errorf("unable to store %s/%s stages into %s: %s", projectName, signature, cache.String(), err)
+	}
+	return nil
+}
+
+func (cache *RedisStagesStorageCache) DeleteStagesBySignature(ctx context.Context, projectName, signature string) error {
+	if err := cache.Client.WithContext(ctx).HDel(cache.projectKey(projectName), signature).Err(); err != nil {
+		return fmt.Errorf("unable to delete %s/%s stages from %s: %s", projectName, signature, cache.String(), err)
+	}
+	return nil
+}
+
+func (cache *RedisStagesStorageCache) projectKey(projectName string) string {
+	return fmt.Sprintf("%s:stages-storage-cache:%s", cache.Namespace, projectName)
+}
+
+func (cache *RedisStagesStorageCache) unmarshalRecord(ctx context.Context, projectName, signature string, data string) ([]image.StageID, bool) {
+	res := &StagesStorageCacheRecord{}
+	if err := json.Unmarshal([]byte(data), res); err != nil {
+		logboek.Context(ctx).Error().LogF("Error unmarshalling json of %s/%s record in %s: %s: will ignore cache\n", projectName, signature, cache.String(), err)
+		return nil, false
+	}
+
+	return res.Stages, true
+}
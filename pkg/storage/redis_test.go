@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v7"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+
+	"github.com/werf/werf/pkg/image"
+)
+
+func newTestRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, redis.NewClient(&redis.Options{Addr: server.Addr()})
+}
+
+func TestRedisDistributedLockerBackend(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedisClient(t)
+	defer server.Close()
+
+	backend := NewRedisDistributedLockerBackend(client, "werf-synchronization")
+
+	handle, err := backend.Acquire("project.stages_and_images", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Acquire("project.stages_and_images", distributed_locker.AcquireOptions{}); err != distributed_locker.ErrShouldWait {
+		t.Errorf("expected ErrShouldWait, got %v", err)
+	}
+	if err := backend.RenewLease(handle); err != nil {
+		t.Error(err)
+	}
+	if err := backend.Release(handle); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Release(handle); err != distributed_locker.ErrNoExistingLockLeaseFound {
+		t.Errorf("expected ErrNoExistingLockLeaseFound, got %v", err)
+	}
+
+	shared1, err := backend.Acquire("project.sig", distributed_locker.AcquireOptions{Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared2, err := backend.Acquire("project.sig", distributed_locker.AcquireOptions{Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared1.UUID != shared2.UUID {
+		t.Errorf("expected shared holders to get the same lease, got %s and %s", shared1.UUID, shared2.UUID)
+	}
+	if _, err := backend.Acquire("project.sig", distributed_locker.AcquireOptions{}); err != distributed_locker.ErrShouldWait {
+		t.Errorf("expected ErrShouldWait, got %v", err)
+	}
+
+	locks, err := backend.ListLocks(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 1 || locks[0].Name != "project.sig" || !locks[0].Shared || locks[0].Holders != 2 || locks[0].Owner != shared1.UUID {
+		t.Fatalf("unexpected locks %#v", locks)
+	}
+
+	if err := backend.ForceRelease(ctx, LockInfo{Name: "project.sig", Owner: "other"}); err == nil {
+		t.Error("expected error on force release of the lock with another owner")
+	}
+	if err := backend.ForceRelease(ctx, locks[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.RenewLease(shared1); err != distributed_locker.ErrNoExistingLockLeaseFound {
+		t.Errorf("expected ErrNoExistingLockLeaseFound, got %v", err)
+	}
+
+	// the expired lease is taken over by the next holder
+	expired, err := backend.Acquire("project.expired", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease, err := getRedisLease(client, backend.lockKey("project.expired"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lease.ExpireAtTimestamp = time.Now().Add(-time.Minute).Unix()
+	if err := backend.changeLease(ctx, "project.expired", func(*distributed_locker.LockLeaseRecord) (*distributed_locker.LockLeaseRecord, error) {
+		return lease, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	newHandle, err := backend.Acquire("project.expired", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newHandle.UUID == expired.UUID {
+		t.Error("expected new lease to be acquired")
+	}
+	if err := backend.RenewLease(expired); err != distributed_locker.ErrLockAlreadyLeased {
+		t.Errorf("expected ErrLockAlreadyLeased, got %v", err)
+	}
+}
+
+func TestRedisStagesStorageCache(t *testing.T) {
+	ctx := context.Background()
+	server, client := newTestRedisClient(t)
+	defer server.Close()
+
+	cache := NewRedisStagesStorageCache(client, "werf-synchronization")
+	stages := []image.StageID{{Signature: "sig", UniqueID: 1}, {Signature: "sig", UniqueID: 2}}
+
+	if found, _, err := cache.GetAllStages(ctx, "project"); err != nil || found {
+		t.Fatalf("expected empty cache, got found=%v err=%v", found, err)
+	}
+
+	if err := cache.StoreStagesBySignature(ctx, "project", "sig", stages); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.StoreStagesBySignature(ctx, "project", "other", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, res, err := cache.GetStagesBySignature(ctx, "project", "sig"); err != nil || !found || len(res) != 2 || res[1] != stages[1] {
+		t.Errorf("unexpected stages %v found=%v err=%v", res, found, err)
+	}
+	if found, res, err := cache.GetAllStages(ctx, "project"); err != nil || !found || len(res) != 2 {
+		t.Errorf("unexpected stages %v found=%v err=%v", res, found, err)
+	}
+
+	server.HSet(cache.projectKey("project"), "broken", "{")
+	if found, _, err := cache.GetStagesBySignature(ctx, "project", "broken"); err != nil || found {
+		t.Errorf("expected broken record to be ignored, got found=%v err=%v", found, err)
+	}
+
+	if err := cache.DeleteStagesBySignature(ctx, "project", "sig"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _, err := cache.GetStagesBySignature(ctx, "project", "sig"); err != nil || found {
+		t.Errorf("expected deleted signature, got found=%v err=%v", found, err)
+	}
+
+	if err := cache.DeleteAllStages(ctx, "project"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _, err := cache.GetAllStages(ctx, "project"); err != nil || found {
+		t.Errorf("expected empty cache, got found=%v err=%v", found, err)
+	}
+}
@@ -15,3 +15,28 @@ type StagesStorageCache interface {
 
 	String() string
 }
+
+// DeleteStageFromStagesStorageCache removes the stage from the cache record of the stage signature, other stages of the signature are kept
+func DeleteStageFromStagesStorageCache(ctx context.Context, cache StagesStorageCache, projectName string, stageID image.StageID) error {
+	exist, stages, err := cache.GetStagesBySignature(ctx, projectName, stageID.Signature)
+	if err != nil {
+		return err
+	} else if !exist {
+		return nil
+	}
+
+	var keptStages []image.StageID
+	for _, id := range stages {
+		if id != stageID {
+			keptStages = append(keptStages, id)
+		}
+	}
+
+	if len(keptStages) == len(stages) {
+		return nil
+	} else if len(keptStages) == 0 {
+		return cache.DeleteStagesBySignature(ctx, projectName, stageID.Signature)
+	}
+
+	return cache.StoreStagesBySignature(ctx, projectName, stageID.Signature, keptStages)
+}
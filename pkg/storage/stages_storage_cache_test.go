@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/werf"
+)
+
+func TestDeleteStageFromStagesStorageCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "werf-stages-storage-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := werf.Init(filepath.Join(dir, "tmp"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	cache := NewFileStagesStorageCache(filepath.Join(dir, "cache"))
+
+	first := image.StageID{Signature: "sig", UniqueID: 1}
+	second := image.StageID{Signature: "sig", UniqueID: 2}
+	if err := cache.StoreStagesBySignature(ctx, "project", "sig", []image.StageID{first, second}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeleteStageFromStagesStorageCache(ctx, cache, "project", first); err != nil {
+		t.Fatal(err)
+	}
+
+	if exist, stages, err := cache.GetStagesBySignature(ctx, "project", "sig"); err != nil {
+		t.Fatal(err)
+	} else if !exist || !reflect.DeepEqual(stages, []image.StageID{second}) {
+		t.Errorf("expected only %s to be kept, got %v (exist %v)", second, stages, exist)
+	}
+
+	if err := DeleteStageFromStagesStorageCache(ctx, cache, "project", second); err != nil {
+		t.Fatal(err)
+	}
+
+	if exist, _, err := cache.GetStagesBySignature(ctx, "project", "sig"); err != nil {
+		t.Fatal(err)
+	} else if exist {
+		t.Errorf("expected record of the signature to be deleted with the last stage")
+	}
+}
+
+func TestGetStageIDFromLocalStageImageName(t *testing.T) {
+	stageID, err := GetStageIDFromLocalStageImageName("werf-stages-storage/project:sig-1600000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stageID.Signature != "sig" || stageID.UniqueID != 1600000000000 {
+		t.Errorf("unexpected stage id %s", stageID)
+	}
+
+	for _, name := range []string{"werf-stages-storage/project", "project:sig-1600000000000", "werf-stages-storage/project:sig"} {
+		if _, err := GetStageIDFromLocalStageImageName(name); err == nil {
+			t.Errorf("expected error for %q", name)
+		}
+	}
+}
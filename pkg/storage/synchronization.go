@@ -2,11 +2,19 @@ package storage
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"os"
 	"strings"
+
+	"github.com/go-redis/redis/v7"
 )
 
+const DefaultRedisSynchronizationNamespace = "werf-synchronization"
+
 var (
 	ErrBadKubernetesSyncrhonizationAddress = errors.New("bad kubernetes synchronization address")
+	ErrBadRedisSynchronizationAddress      = errors.New("bad redis synchronization address")
 )
 
 type KubernetesSynchronizationParams struct {
@@ -50,3 +58,44 @@ func ParseKubernetesSynchronization(address string) (*KubernetesSynchronizationP
 
 	return res, nil
 }
+
+type RedisSynchronizationParams struct {
+	Options *redis.Options
+	// Namespace is the prefix of the keys of all projects
+	Namespace string
+}
+
+// ParseRedisSynchronization parses redis[s]://[[USER]:PASSWORD@]HOST[:PORT][/DB][?namespace=NAMESPACE] address,
+// the password can also be specified with $WERF_SYNCHRONIZATION_REDIS_PASSWORD
+func ParseRedisSynchronization(address string) (*RedisSynchronizationParams, error) {
+	if !strings.HasPrefix(address, "redis://") && !strings.HasPrefix(address, "rediss://") {
+		return nil, ErrBadRedisSynchronizationAddress
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrBadRedisSynchronizationAddress, err)
+	}
+
+	res := &RedisSynchronizationParams{Namespace: DefaultRedisSynchronizationNamespace}
+
+	query := u.Query()
+	if namespace := query.Get("namespace"); namespace != "" {
+		res.Namespace = namespace
+	}
+	query.Del("namespace")
+	if len(query) > 0 {
+		return nil, fmt.Errorf("%s: unsupported params %s", ErrBadRedisSynchronizationAddress, query.Encode())
+	}
+	u.RawQuery = ""
+
+	if res.Options, err = redis.ParseURL(u.String()); err != nil {
+		return nil, fmt.Errorf("%s: %s", ErrBadRedisSynchronizationAddress, err)
+	}
+
+	if res.Options.Password == "" {
+		res.Options.Password = os.Getenv("WERF_SYNCHRONIZATION_REDIS_PASSWORD")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,110 @@
+package synchronization_server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// AccessConfig restricts the access to the synchronization server: each client is authenticated by the bearer token
+// or by the common name of the client certificate and has access only to the projects of the matched rule
+type AccessConfig struct {
+	Rules []AccessRule `json:"rules"`
+}
+
+type AccessRule struct {
+	Token      string `json:"token,omitempty"`
+	CommonName string `json:"commonName,omitempty"`
+	// Projects are the names or the glob patterns of the allowed projects (e.g. "*" for all projects)
+	Projects []string `json:"projects,omitempty"`
+	// Admin allows to list and force release the held locks
+	Admin bool `json:"admin,omitempty"`
+}
+
+func LoadAccessConfig(path string) (*AccessConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read access config %s: %s", path, err)
+	}
+
+	config := &AccessConfig{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("unable to parse access config %s: %s", path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid access config %s: %s", path, err)
+	}
+
+	return config, nil
+}
+
+func (config *AccessConfig) Validate() error {
+	for i, rule := range config.Rules {
+		if rule.Token == "" && rule.CommonName == "" {
+			return fmt.Errorf("rule %d: token or commonName required", i)
+		}
+
+		if len(rule.Projects) == 0 && !rule.Admin {
+			return fmt.Errorf("rule %d: at least one project or admin access required", i)
+		}
+
+		for _, pattern := range rule.Projects {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("rule %d: bad project pattern %q: %s", i, pattern, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Authenticate returns the rule matched by the bearer token or the verified client certificate of the request
+func (config *AccessConfig) Authenticate(r *http.Request) (*AccessRule, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	var commonName string
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		commonName = r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+
+		if rule.Token != "" && subtle.ConstantTimeCompare([]byte(rule.Token), []byte(token)) == 1 {
+			return rule, true
+		}
+
+		if rule.CommonName != "" && rule.CommonName == commonName {
+			return rule, true
+		}
+	}
+
+	return nil, false
+}
+
+// HasAdminRules returns true if any client has the admin access
+func (config *AccessConfig) HasAdminRules() bool {
+	for _, rule := range config.Rules {
+		if rule.Admin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (rule *AccessRule) IsProjectAllowed(projectName string) bool {
+	for _, pattern := range rule.Projects {
+		if matched, _ := path.Match(pattern, projectName); matched {
+			return true
+		}
+	}
+
+	return false
+}
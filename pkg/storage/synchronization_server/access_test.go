@@ -0,0 +1,144 @@
+package synchronization_server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+	"github.com/werf/lockgate/pkg/distributed_locker/optimistic_locking_store"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+func newTestAccessHandler(clientProjectStore ClientProjectStore) *SynchronizationServerHandler {
+	handler := NewSynchronizationServerHandler(context.Background(), func(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+		return distributed_locker.NewOptimisticLockingStorageBasedBackend(optimistic_locking_store.NewInMemoryStore()), nil
+	}, func(clientID string) (storage.StagesStorageCache, error) {
+		return nil, nil
+	})
+	handler.Access = &AccessConfig{Rules: []AccessRule{
+		{Token: "project-token", Projects: []string{"project"}},
+		{Token: "team-token", Projects: []string{"team-*"}},
+	}}
+	handler.ClientProjectStore = clientProjectStore
+
+	return handler
+}
+
+func newTestClientProjectStore(t *testing.T) *FileClientProjectStore {
+	dir, err := ioutil.TempDir("", "werf-client-projects-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return NewFileClientProjectStore(dir)
+}
+
+func TestSynchronizationServerAccess(t *testing.T) {
+	clientProjectStore := newTestClientProjectStore(t)
+
+	server := httptest.NewServer(newTestAccessHandler(clientProjectStore))
+	defer server.Close()
+
+	newClient := func(token, projectName string) *http.Client {
+		client, err := NewHttpClient(ClientOptions{Token: token, ProjectName: projectName})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client
+	}
+
+	for _, tc := range []struct {
+		token, projectName, expectedErr string
+	}{
+		{"", "project", "401 Unauthorized"},
+		{"unknown-token", "project", "401 Unauthorized"},
+		{"project-token", "", "403 Forbidden"},
+		{"project-token", "team-a", "403 Forbidden"},
+	} {
+		if _, err := NewSynchronizationClient(server.URL, newClient(tc.token, tc.projectName)).NewClientID(); err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+			t.Errorf("token %q project %q: expected %q error, got %v", tc.token, tc.projectName, tc.expectedErr, err)
+		}
+	}
+
+	projectClient := newClient("project-token", "project")
+	clientID, err := NewSynchronizationClient(server.URL, projectClient).NewClientID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker := NewHttpLocker(server.URL+"/"+clientID+"/locker", projectClient)
+	if acquired, lock, err := locker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	} else if !acquired {
+		t.Fatal("expected lock to be acquired")
+	} else if err := locker.Release(lock); err != nil {
+		t.Fatal(err)
+	}
+
+	// the client id is bound to the project it has been created for, the project name of the request does not matter
+	for _, projectName := range []string{"team-a", "project"} {
+		teamLocker := NewHttpLocker(server.URL+"/"+clientID+"/locker", newClient("team-token", projectName))
+		if _, _, err := teamLocker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err == nil || !strings.Contains(err.Error(), `belongs to project "project"`) {
+			t.Errorf("project %q: expected access to be denied, got %v", projectName, err)
+		}
+	}
+
+	unknownLocker := NewHttpLocker(server.URL+"/unknown-client-id/locker", projectClient)
+	if _, _, err := unknownLocker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err == nil || !strings.Contains(err.Error(), "unknown clientID") {
+		t.Errorf("expected access to be denied, got %v", err)
+	}
+
+	// the binding survives the server restart
+	restartedServer := httptest.NewServer(newTestAccessHandler(clientProjectStore))
+	defer restartedServer.Close()
+
+	locker = NewHttpLocker(restartedServer.URL+"/"+clientID+"/locker", projectClient)
+	if acquired, lock, err := locker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	} else if !acquired {
+		t.Fatal("expected lock to be acquired")
+	} else if err := locker.Release(lock); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheClientProject(t *testing.T) {
+	handler := newTestAccessHandler(newTestClientProjectStore(t))
+
+	for i := 0; i < maxCachedClientProjects+10; i++ {
+		handler.cacheClientProject(fmt.Sprintf("client-%d", i), "project")
+	}
+
+	if len(handler.projectNameByClientID) != maxCachedClientProjects {
+		t.Errorf("expected %d cached client projects, got %d", maxCachedClientProjects, len(handler.projectNameByClientID))
+	}
+}
+
+func TestParseSynchronizationAddress(t *testing.T) {
+	address, opts, err := ParseSynchronizationAddress("https://sync.example.com:55581/?token=secret&ca-file=/ca.pem&skip-tls-verify=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if address != "https://sync.example.com:55581" {
+		t.Errorf("unexpected address %q", address)
+	}
+
+	if opts.Token != "secret" || opts.CAFile != "/ca.pem" || !opts.SkipTlsVerify {
+		t.Errorf("unexpected options %#v", opts)
+	}
+
+	if _, _, err := ParseSynchronizationAddress("https://sync.example.com?cert-file=/cert.pem"); err == nil {
+		t.Error("expected error for cert-file without key-file")
+	}
+}
@@ -0,0 +1,414 @@
+package synchronization_server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+// HeldLock is the lock lease acquired through the synchronization server
+type HeldLock struct {
+	ClientID    string    `json:"clientID"`
+	ProjectName string    `json:"projectName"`
+	LockName    string    `json:"lockName"`
+	UUID        string    `json:"uuid"`
+	Shared      bool      `json:"shared"`
+	Holders     int64     `json:"holders"`
+	AcquiredAt  time.Time `json:"acquiredAt"`
+	RenewedAt   time.Time `json:"renewedAt"`
+	Age         string    `json:"age"`
+	// Expired lease is not renewed by the holder anymore and can be safely released
+	Expired bool `json:"expired"`
+}
+
+// LocksRegistry tracks the locks held and awaited by the clients of the synchronization server
+type LocksRegistry struct {
+	mux       sync.Mutex
+	heldLocks map[string]*HeldLock
+	waitingAt map[string]time.Time
+}
+
+func NewLocksRegistry() *LocksRegistry {
+	return &LocksRegistry{
+		heldLocks: make(map[string]*HeldLock),
+		waitingAt: make(map[string]time.Time),
+	}
+}
+
+// expiredLocksRetentionPeriod is the time the expired leases are listed to be released, then the registry forgets them
+const expiredLocksRetentionPeriod = time.Hour
+
+func locksRegistryKey(clientID, lockName string) string {
+	return fmt.Sprintf("%s/%s", clientID, lockName)
+}
+
+// List returns the held locks including the expired ones, the oldest first
+func (registry *LocksRegistry) List() []HeldLock {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	now := time.Now()
+	var res []HeldLock
+	for key, lock := range registry.heldLocks {
+		expireAt := lock.RenewedAt.Add(distributed_locker.DistributedLockLeaseTTLSeconds * time.Second)
+		if now.Sub(expireAt) > expiredLocksRetentionPeriod {
+			delete(registry.heldLocks, key)
+			continue
+		}
+
+		heldLock := *lock
+		heldLock.Age = now.Sub(lock.AcquiredAt).Round(time.Second).String()
+		heldLock.Expired = now.After(expireAt)
+		res = append(res, heldLock)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].AcquiredAt.Before(res[j].AcquiredAt) })
+
+	return res
+}
+
+// ActiveLeases returns the number of held locks by project
+func (registry *LocksRegistry) ActiveLeases() map[string]int {
+	res := make(map[string]int)
+	for _, lock := range registry.List() {
+		if !lock.Expired {
+			res[lock.ProjectName]++
+		}
+	}
+	return res
+}
+
+func (registry *LocksRegistry) Get(clientID, lockName string) (HeldLock, bool) {
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	if lock, hasKey := registry.heldLocks[locksRegistryKey(clientID, lockName)]; hasKey {
+		return *lock, true
+	}
+	return HeldLock{}, false
+}
+
+// metricsLockerBackend records the held locks and the lock metrics of the client
+type metricsLockerBackend struct {
+	distributed_locker.DistributedLockerBackend
+	ClientID    string
+	ProjectName string
+	Registry    *LocksRegistry
+	Metrics     *Metrics
+}
+
+func (backend *metricsLockerBackend) Acquire(lockName string, opts distributed_locker.AcquireOptions) (lockgate.LockHandle, error) {
+	startedAt := time.Now()
+	handle, err := backend.DistributedLockerBackend.Acquire(lockName, opts)
+	now := time.Now()
+
+	registry := backend.Registry
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	key := locksRegistryKey(backend.ClientID, lockName)
+
+	switch {
+	case distributed_locker.IsErrShouldWait(err):
+		// the client polls the lock until it is acquired, only the first poll is counted
+		if _, hasKey := registry.waitingAt[key]; !hasKey {
+			backend.Metrics.ObserveLockAcquire(backend.ProjectName, lockAcquireResultWait, now.Sub(startedAt))
+			registry.waitingAt[key] = startedAt
+		}
+	case err != nil:
+		backend.Metrics.ObserveLockAcquire(backend.ProjectName, lockAcquireResultError, now.Sub(startedAt))
+		delete(registry.waitingAt, key)
+	default:
+		backend.Metrics.ObserveLockAcquire(backend.ProjectName, lockAcquireResultAcquired, now.Sub(startedAt))
+		if waitingAt, hasKey := registry.waitingAt[key]; hasKey {
+			backend.Metrics.ObserveLockWait(backend.ProjectName, now.Sub(waitingAt))
+			delete(registry.waitingAt, key)
+		}
+
+		if lock, hasKey := registry.heldLocks[key]; hasKey && lock.UUID == handle.UUID {
+			lock.Holders++
+			lock.RenewedAt = now
+		} else {
+			registry.heldLocks[key] = &HeldLock{
+				ClientID:    backend.ClientID,
+				ProjectName: backend.ProjectName,
+				LockName:    lockName,
+				UUID:        handle.UUID,
+				Shared:      opts.Shared,
+				Holders:     1,
+				AcquiredAt:  now,
+				RenewedAt:   now,
+			}
+		}
+	}
+
+	return handle, err
+}
+
+func (backend *metricsLockerBackend) RenewLease(handle lockgate.LockHandle) error {
+	if err := backend.DistributedLockerBackend.RenewLease(handle); err != nil {
+		return err
+	}
+
+	registry := backend.Registry
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	now := time.Now()
+	key := locksRegistryKey(backend.ClientID, handle.LockName)
+	if lock, hasKey := registry.heldLocks[key]; hasKey && lock.UUID == handle.UUID {
+		lock.RenewedAt = now
+	} else {
+		// the lease has been acquired before the server restart
+		registry.heldLocks[key] = &HeldLock{
+			ClientID:    backend.ClientID,
+			ProjectName: backend.ProjectName,
+			LockName:    handle.LockName,
+			UUID:        handle.UUID,
+			Holders:     1,
+			AcquiredAt:  now,
+			RenewedAt:   now,
+		}
+	}
+
+	return nil
+}
+
+func (backend *metricsLockerBackend) Release(handle lockgate.LockHandle) error {
+	if err := backend.DistributedLockerBackend.Release(handle); err != nil {
+		return err
+	}
+
+	registry := backend.Registry
+	registry.mux.Lock()
+	defer registry.mux.Unlock()
+
+	key := locksRegistryKey(backend.ClientID, handle.LockName)
+	if lock, hasKey := registry.heldLocks[key]; hasKey && lock.UUID == handle.UUID {
+		lock.Holders--
+		if lock.Holders <= 0 {
+			backend.Metrics.ObserveLockHold(backend.ProjectName, time.Since(lock.AcquiredAt))
+			delete(registry.heldLocks, key)
+		}
+	}
+
+	return nil
+}
+
+type AdminReleaseLockRequest struct {
+	ClientID string `json:"clientID"`
+	LockName string `json:"lockName"`
+}
+
+type AdminReleaseLockResponse struct {
+	Err string `json:"err,omitempty"`
+}
+
+func (server *SynchronizationServerHandler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !server.authorizeAdmin(w, r, false) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	server.Metrics.WritePrometheus(w, server.LocksRegistry.ActiveLeases())
+}
+
+func (server *SynchronizationServerHandler) handleAdminLocks(w http.ResponseWriter, r *http.Request) {
+	if !server.authorizeAdmin(w, r, true) {
+		return
+	}
+
+	server.writeLocks(w, "")
+}
+
+// handleClientLocks lists the locks of the client, the client is authorized by the handler of the client id
+func (server *SynchronizationServerHandler) handleClientLocks(w http.ResponseWriter, _ *http.Request, clientID string) {
+	server.writeLocks(w, clientID)
+}
+
+// writeLocks writes the held locks of the client or of all clients if clientID is empty
+func (server *SynchronizationServerHandler) writeLocks(w http.ResponseWriter, clientID string) {
+	locks := []HeldLock{}
+	for _, lock := range server.LocksRegistry.List() {
+		if clientID == "" || lock.ClientID == clientID {
+			locks = append(locks, lock)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(locks)
+}
+
+func (server *SynchronizationServerHandler) handleAdminReleaseLock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !server.authorizeAdmin(w, r, true) {
+		return
+	}
+
+	server.handleReleaseLock(w, r, "")
+}
+
+// handleClientReleaseLock releases the lock of the client, the client is authorized by the handler of the client id
+func (server *SynchronizationServerHandler) handleClientReleaseLock(w http.ResponseWriter, r *http.Request, clientID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "Bad request: application/json content type required", http.StatusBadRequest)
+		return
+	}
+
+	server.handleReleaseLock(w, r, clientID)
+}
+
+// handleReleaseLock releases the lock of the requested client, the client of the request is ignored if clientID is specified
+func (server *SynchronizationServerHandler) handleReleaseLock(w http.ResponseWriter, r *http.Request, clientID string) {
+	var request AdminReleaseLockRequest
+	var response AdminReleaseLockResponse
+
+	if r.Header.Get("Content-Type") == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("Bad request: %s", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// the form of the admin page is submitted by the browser with the client certificate, so the request should be made from the page
+		if subtle.ConstantTimeCompare([]byte(r.FormValue("csrfToken")), []byte(server.csrfToken)) != 1 {
+			http.Error(w, "Forbidden: bad CSRF token", http.StatusForbidden)
+			return
+		}
+
+		request.ClientID, request.LockName = r.FormValue("clientID"), r.FormValue("lockName")
+	}
+
+	if clientID != "" {
+		request.ClientID = clientID
+	}
+
+	status := http.StatusOK
+	if err := server.forceReleaseLock(request.ClientID, request.LockName); err != nil {
+		response.Err = err.Error()
+		status = http.StatusNotFound
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" && response.Err == "" {
+		http.Redirect(w, r, "/admin", http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// forceReleaseLock releases the lock for all holders, holders lose the lock on the next lease renewal
+func (server *SynchronizationServerHandler) forceReleaseLock(clientID, lockName string) error {
+	lock, found := server.LocksRegistry.Get(clientID, lockName)
+	if !found {
+		return fmt.Errorf("lock %q of clientID %q not found", lockName, clientID)
+	}
+
+	server.mux.Lock()
+	clientServer, hasKey := server.SynchronizationServerByClientID[clientID]
+	server.mux.Unlock()
+	if !hasKey {
+		return fmt.Errorf("clientID %q not found", clientID)
+	}
+
+	handle := lockgate.LockHandle{UUID: lock.UUID, LockName: lock.LockName}
+	for i := int64(0); i < lock.Holders; i++ {
+		if err := clientServer.DistributedLockerBackend.Release(handle); distributed_locker.IsErrNoExistingLockLeaseFound(err) || distributed_locker.IsErrLockAlreadyLeased(err) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("unable to release lock %q of clientID %q: %s", lockName, clientID, err)
+		}
+	}
+
+	return nil
+}
+
+var adminPageTemplate = template.Must(template.New("admin").Parse(`<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Werf synchronization server locks</title>
+</head>
+<body>
+<h1>Held locks</h1>
+<table border="1" cellpadding="4">
+  <tr><th>Project</th><th>Lock</th><th>Client ID</th><th>Shared</th><th>Holders</th><th>Age</th><th>Expired</th><th></th></tr>
+  {{- range .Locks }}
+  <tr>
+    <td>{{ .ProjectName }}</td><td>{{ .LockName }}</td><td>{{ .ClientID }}</td><td>{{ .Shared }}</td><td>{{ .Holders }}</td><td>{{ .Age }}</td><td>{{ .Expired }}</td>
+    <td>
+      <form method="post" action="/admin/locks/release">
+        <input type="hidden" name="clientID" value="{{ .ClientID }}">
+        <input type="hidden" name="lockName" value="{{ .LockName }}">
+        <input type="hidden" name="csrfToken" value="{{ $.CSRFToken }}">
+        <input type="submit" value="Force release">
+      </form>
+    </td>
+  </tr>
+  {{- else }}
+  <tr><td colspan="8">No locks held</td></tr>
+  {{- end }}
+</table>
+</body>
+</html>
+`))
+
+func (server *SynchronizationServerHandler) handleAdminPage(w http.ResponseWriter, r *http.Request) {
+	if !server.authorizeAdmin(w, r, true) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	data := struct {
+		Locks     []HeldLock
+		CSRFToken string
+	}{server.LocksRegistry.List(), server.csrfToken}
+
+	if err := adminPageTemplate.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("Internal error: %s", err), http.StatusInternalServerError)
+	}
+}
+
+// authorizeAdmin checks that the client is authenticated and has the admin access if required.
+// Admin endpoints are disabled unless the access config has the rule with the admin access.
+func (server *SynchronizationServerHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request, adminRequired bool) bool {
+	if adminRequired && (server.Access == nil || !server.Access.HasAdminRules()) {
+		http.Error(w, "Not found: admin endpoints are disabled, the access config rule with admin access required", http.StatusNotFound)
+		return false
+	}
+
+	if server.Access == nil {
+		return true
+	}
+
+	rule, ok := server.Access.Authenticate(r)
+	if !ok {
+		http.Error(w, "Unauthorized: valid bearer token or client certificate required", http.StatusUnauthorized)
+		return false
+	}
+
+	if adminRequired && !rule.Admin {
+		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
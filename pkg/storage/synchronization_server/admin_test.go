@@ -0,0 +1,214 @@
+package synchronization_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+	"github.com/werf/lockgate/pkg/distributed_locker/optimistic_locking_store"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+func TestSynchronizationServerAdmin(t *testing.T) {
+	handler := NewSynchronizationServerHandler(context.Background(), func(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+		return distributed_locker.NewOptimisticLockingStorageBasedBackend(optimistic_locking_store.NewInMemoryStore()), nil
+	}, func(clientID string) (storage.StagesStorageCache, error) {
+		return nil, nil
+	})
+	handler.Access = &AccessConfig{Rules: []AccessRule{
+		{Token: "project-token", Projects: []string{"project"}},
+		{Token: "admin-token", Admin: true},
+	}}
+	handler.ClientProjectStore = newTestClientProjectStore(t)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	projectClient, err := NewHttpClient(ClientOptions{Token: "project-token", ProjectName: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminClient, err := NewHttpClient(ClientOptions{Token: "admin-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientID, err := NewSynchronizationClient(server.URL, projectClient).NewClientID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker := NewHttpLocker(server.URL+"/"+clientID+"/locker", projectClient)
+	_, lock, err := locker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(client *http.Client, path string) (int, string) {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(data)
+	}
+
+	if status, _ := get(projectClient, "/admin/locks"); status != http.StatusForbidden {
+		t.Errorf("expected admin access to be denied for the project client, got status %d", status)
+	}
+
+	status, body := get(adminClient, "/admin/locks")
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+
+	var locks []HeldLock
+	if err := json.Unmarshal([]byte(body), &locks); err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 1 || locks[0].ClientID != clientID || locks[0].ProjectName != "project" || locks[0].LockName != "project.stages_and_images" {
+		t.Fatalf("unexpected held locks: %#v", locks)
+	}
+
+	if _, body := get(projectClient, "/metrics"); !strings.Contains(body, `werf_synchronization_active_leases{project="project"} 1`) ||
+		!strings.Contains(body, `werf_synchronization_lock_acquires_total{project="project",result="acquired"} 1`) ||
+		!strings.Contains(body, "werf_synchronization_client_ids_issued_total 1") {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+
+	request, _ := json.Marshal(AdminReleaseLockRequest{ClientID: clientID, LockName: "project.stages_and_images"})
+	resp, err := adminClient.Post(server.URL+"/admin/locks/release", "application/json", bytes.NewReader(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected force release status %d", resp.StatusCode)
+	}
+
+	if _, body := get(adminClient, "/admin/locks"); strings.TrimSpace(body) != "[]" {
+		t.Errorf("expected no held locks after force release, got %s", body)
+	}
+
+	if err := locker.Release(lock); err == nil {
+		t.Error("expected release of the force released lock to fail")
+	}
+
+	// the form of the admin page requires the CSRF token of the page
+	if _, lock, err = locker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"clientID": {clientID}, "lockName": {"project.stages_and_images"}}
+	resp, err = adminClient.PostForm(server.URL+"/admin/locks/release", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected form without CSRF token to be rejected, got status %d", resp.StatusCode)
+	}
+
+	_, page := get(adminClient, "/admin")
+	match := regexp.MustCompile(`name="csrfToken" value="([^"]+)"`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatalf("no CSRF token in admin page:\n%s", page)
+	}
+
+	form.Set("csrfToken", match[1])
+	noRedirectClient := &http.Client{Transport: adminClient.Transport, CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = noRedirectClient.PostForm(server.URL+"/admin/locks/release", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSeeOther {
+		t.Errorf("expected form with CSRF token to release the lock, got status %d", resp.StatusCode)
+	}
+}
+
+func TestSynchronizationServerAdminDisabled(t *testing.T) {
+	handler := NewSynchronizationServerHandler(context.Background(), func(clientID string) (distributed_locker.DistributedLockerBackend, error) {
+		return distributed_locker.NewOptimisticLockingStorageBasedBackend(optimistic_locking_store.NewInMemoryStore()), nil
+	}, func(clientID string) (storage.StagesStorageCache, error) {
+		return nil, nil
+	})
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, path := range []string{"/admin", "/admin/locks"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected admin endpoint to be disabled without access config, got status %d", path, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected metrics to be available, got status %d", resp.StatusCode)
+	}
+}
+
+func TestMetricsLockerBackendCountsWaitOnce(t *testing.T) {
+	backend := &metricsLockerBackend{
+		DistributedLockerBackend: distributed_locker.NewOptimisticLockingStorageBasedBackend(optimistic_locking_store.NewInMemoryStore()),
+		ClientID:                 "client",
+		ProjectName:              "project",
+		Registry:                 NewLocksRegistry(),
+		Metrics:                  NewMetrics(),
+	}
+
+	holder, err := backend.Acquire("lock", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := backend.Acquire("lock", distributed_locker.AcquireOptions{}); !distributed_locker.IsErrShouldWait(err) {
+			t.Fatalf("expected should wait error, got %v", err)
+		}
+	}
+
+	if err := backend.Release(holder); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.Acquire("lock", distributed_locker.AcquireOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	backend.Metrics.WritePrometheus(&buf, nil)
+	for _, expected := range []string{
+		`werf_synchronization_lock_acquires_total{project="project",result="acquired"} 2`,
+		`werf_synchronization_lock_acquires_total{project="project",result="wait"} 1`,
+		`werf_synchronization_lock_wait_duration_seconds_count{project="project"} 1`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in metrics:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package synchronization_server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/werf/lockgate"
+	"github.com/werf/lockgate/pkg/distributed_locker"
+	"github.com/werf/logboek"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+const (
+	BoltLocksBucket = "locks"
+
+	// expired leases are kept for a while after the server restart, so that the clients could renew the leases they still hold
+	boltExpiredLeaseRetentionPeriod = time.Hour
+)
+
+// OpenBoltDatabase opens the database file shared by the lock-manager and the stages-storage-cache.
+// Only one server can hold the database file on the local filesystem, the restarted server waits until the previous one exits.
+// The file lock is not reliable on network filesystems, so the file must not be shared between replicas.
+func OpenBoltDatabase(ctx context.Context, path string) (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("unable to create dir %s: %s", filepath.Dir(path), err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		logboek.Context(ctx).Default().LogF("Waiting for database %s to be released by another synchronization server ...\n", path)
+		db, err = bolt.Open(path, 0600, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database %s: %s", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{BoltLocksBucket, BoltClientProjectsBucket, storage.BoltStagesStorageCacheBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("unable to create bucket %q: %s", name, err)
+			}
+		}
+
+		return purgeExpiredBoltLeases(tx, time.Now().Add(-boltExpiredLeaseRetentionPeriod))
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to init database %s: %s", path, err)
+	}
+
+	return db, nil
+}
+
+func purgeExpiredBoltLeases(tx *bolt.Tx, expiredBefore time.Time) error {
+	return tx.Bucket([]byte(BoltLocksBucket)).ForEach(func(namespace, _ []byte) error {
+		bucket := tx.Bucket([]byte(BoltLocksBucket)).Bucket(namespace)
+		if bucket == nil {
+			return nil
+		}
+
+		var expiredLockNames [][]byte
+		if err := bucket.ForEach(func(lockName, data []byte) error {
+			var lease *distributed_locker.LockLeaseRecord
+			if err := json.Unmarshal(data, &lease); err != nil || time.Unix(lease.ExpireAtTimestamp, 0).Before(expiredBefore) {
+				expiredLockNames = append(expiredLockNames, lockName)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, lockName := range expiredLockNames {
+			if err := bucket.Delete(lockName); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// BoltDistributedLockerBackend stores lock leases in the database file: LOCKS_BUCKET/NAMESPACE/LOCK_NAME
+func NewBoltDistributedLockerBackend(db *bolt.DB, namespace string) *BoltDistributedLockerBackend {
+	return &BoltDistributedLockerBackend{DB: db, Namespace: namespace}
+}
+
+type BoltDistributedLockerBackend struct {
+	DB        *bolt.DB
+	Namespace string
+}
+
+func (backend *BoltDistributedLockerBackend) Acquire(lockName string, opts distributed_locker.AcquireOptions) (lockgate.LockHandle, error) {
+	var handle lockgate.LockHandle
+
+	err := backend.DB.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket([]byte(BoltLocksBucket)).CreateBucketIfNotExists([]byte(backend.Namespace))
+		if err != nil {
+			return fmt.Errorf("unable to create bucket %q: %s", backend.Namespace, err)
+		}
+
+		oldLease, err := getBoltLease(bucket, lockName)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case oldLease == nil || time.Now().After(time.Unix(oldLease.ExpireAtTimestamp, 0)):
+			newLease := distributed_locker.NewLockLeaseRecord(lockName, opts.Shared)
+			handle = newLease.LockHandle
+			return putBoltLease(bucket, newLease)
+		case opts.Shared && oldLease.IsShared:
+			oldLease.SharedHoldersCount++
+			oldLease.ExpireAtTimestamp = time.Now().Unix() + distributed_locker.DistributedLockLeaseTTLSeconds
+			handle = oldLease.LockHandle
+			return putBoltLease(bucket, oldLease)
+		default:
+			return distributed_locker.ErrShouldWait
+		}
+	})
+
+	return handle, err
+}
+
+func (backend *BoltDistributedLockerBackend) RenewLease(handle lockgate.LockHandle) error {
+	return backend.changeLease(handle, func(bucket *bolt.Bucket, lease *distributed_locker.LockLeaseRecord) error {
+		lease.ExpireAtTimestamp = time.Now().Unix() + distributed_locker.DistributedLockLeaseTTLSeconds
+		return putBoltLease(bucket, lease)
+	})
+}
+
+func (backend *BoltDistributedLockerBackend) Release(handle lockgate.LockHandle) error {
+	return backend.changeLease(handle, func(bucket *bolt.Bucket, lease *distributed_locker.LockLeaseRecord) error {
+		lease.SharedHoldersCount--
+		if lease.SharedHoldersCount == 0 {
+			return bucket.Delete([]byte(lease.LockName))
+		}
+		return putBoltLease(bucket, lease)
+	})
+}
+
+func (backend *BoltDistributedLockerBackend) changeLease(handle lockgate.LockHandle, changeFunc func(bucket *bolt.Bucket, lease *distributed_locker.LockLeaseRecord) error) error {
+	return backend.DB.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(BoltLocksBucket)).Bucket([]byte(backend.Namespace))
+		if bucket == nil {
+			return distributed_locker.ErrNoExistingLockLeaseFound
+		}
+
+		if lease, err := getBoltLease(bucket, handle.LockName); err != nil {
+			return err
+		} else if lease == nil {
+			return distributed_locker.ErrNoExistingLockLeaseFound
+		} else if lease.UUID != handle.UUID {
+			return distributed_locker.ErrLockAlreadyLeased
+		} else {
+			return changeFunc(bucket, lease)
+		}
+	})
+}
+
+func getBoltLease(bucket *bolt.Bucket, lockName string) (*distributed_locker.LockLeaseRecord, error) {
+	data := bucket.Get([]byte(lockName))
+	if data == nil {
+		return nil, nil
+	}
+
+	var lease *distributed_locker.LockLeaseRecord
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal lease of lock %q: %s", lockName, err)
+	}
+	return lease, nil
+}
+
+func putBoltLease(bucket *bolt.Bucket, lease *distributed_locker.LockLeaseRecord) error {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(lease.LockName), data)
+}
@@ -0,0 +1,105 @@
+package synchronization_server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+func TestBoltBackendSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "werf-bolt-backend-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "synchronization.db")
+
+	db, err := OpenBoltDatabase(ctx, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker := NewBoltDistributedLockerBackend(db, "client")
+	exclusive, err := locker.Acquire("exclusive", distributed_locker.AcquireOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := locker.Acquire("shared", distributed_locker.AcquireOptions{Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handle, err := locker.Acquire("shared", distributed_locker.AcquireOptions{Shared: true}); err != nil {
+		t.Fatal(err)
+	} else if handle != shared {
+		t.Fatalf("expected shared lease %v, got %v", shared, handle)
+	}
+
+	stages := []image.StageID{{Signature: "signature", UniqueID: 1}}
+	if err := storage.NewBoltStagesStorageCache(db, "client").StoreStagesBySignature(ctx, "project", "signature", stages); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = OpenBoltDatabase(ctx, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	locker = NewBoltDistributedLockerBackend(db, "client")
+	if _, err := locker.Acquire("exclusive", distributed_locker.AcquireOptions{}); !distributed_locker.IsErrShouldWait(err) {
+		t.Fatalf("expected the lease to be kept after restart, got %v", err)
+	}
+	if err := locker.RenewLease(exclusive); err != nil {
+		t.Fatal(err)
+	}
+	if err := locker.Release(exclusive); err != nil {
+		t.Fatal(err)
+	}
+	if err := locker.Release(exclusive); !distributed_locker.IsErrNoExistingLockLeaseFound(err) {
+		t.Fatalf("expected no lease after release, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := locker.Release(shared); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := locker.Acquire("shared", distributed_locker.AcquireOptions{}); err != nil {
+		t.Fatalf("expected the shared lease to be released by all holders, got %v", err)
+	}
+
+	cache := storage.NewBoltStagesStorageCache(db, "client")
+	if found, res, err := cache.GetStagesBySignature(ctx, "project", "signature"); err != nil {
+		t.Fatal(err)
+	} else if !found || len(res) != 1 || res[0] != stages[0] {
+		t.Fatalf("unexpected cache record after restart: %v %v", found, res)
+	}
+
+	if found, _, err := storage.NewBoltStagesStorageCache(db, "other-client").GetAllStages(ctx, "project"); err != nil {
+		t.Fatal(err)
+	} else if found {
+		t.Fatal("expected cache records to be isolated by client id")
+	}
+
+	if err := cache.DeleteAllStages(ctx, "project"); err != nil {
+		t.Fatal(err)
+	}
+	if found, _, err := cache.GetAllStages(ctx, "project"); err != nil || found {
+		t.Fatalf("expected no cache records, got %v %v", found, err)
+	}
+}
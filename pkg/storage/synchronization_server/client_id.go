@@ -9,40 +9,67 @@ import (
 	"github.com/werf/werf/pkg/storage"
 )
 
+// GetOrCreateClientID selects the oldest client id of the project known to the synchronization server,
+// the new client id is requested and stored if there are no such client ids
 func GetOrCreateClientID(ctx context.Context, projectName string, synchronizationClient *SynchronizationClient, stagesStorage storage.StagesStorage) (string, error) {
-	if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
+	clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName)
+	if err != nil {
 		return "", err
-	} else if len(clientIDRecords) > 0 {
+	}
+
+	unknownClientIDs := map[string]bool{}
+	for len(clientIDRecords) > 0 {
 		res := selectOldestClientIDRecord(clientIDRecords)
-		logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
-		return res.ClientID, nil
-	} else {
-		newClientID, err := synchronizationClient.NewClientID()
-		if err != nil {
+
+		if isKnown, err := synchronizationClient.IsClientIDKnown(res.ClientID); err != nil {
 			return "", err
+		} else if isKnown {
+			logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
+			return res.ClientID, nil
 		}
 
-		now := time.Now()
-		timestampMillisec := now.Unix()*1000 + now.UnixNano()/1000_000
-		rec := &storage.ClientIDRecord{ClientID: newClientID, TimestampMillisec: timestampMillisec}
+		logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s skipped clientID unknown to the server: %s\n", projectName, res.String())
+		unknownClientIDs[res.ClientID] = true
+		clientIDRecords = excludeClientIDRecords(clientIDRecords, unknownClientIDs)
+	}
 
-		if err := stagesStorage.PostClientIDRecord(ctx, projectName, rec); err != nil {
-			return "", err
-		}
+	newClientID, err := synchronizationClient.NewClientID()
+	if err != nil {
+		return "", err
+	}
 
-		// wait between posting new id and getting current id to lower probability of collision with another process posting new client-id
-		time.Sleep(2 * time.Second)
+	now := time.Now()
+	timestampMillisec := now.Unix()*1000 + now.UnixNano()/1000_000
+	rec := &storage.ClientIDRecord{ClientID: newClientID, TimestampMillisec: timestampMillisec}
 
-		if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
-			return "", err
-		} else if len(clientIDRecords) > 0 {
-			res := selectOldestClientIDRecord(clientIDRecords)
-			logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
-			return res.ClientID, nil
-		} else {
-			return "", fmt.Errorf("could not find clientID in stages storage %s after successful creation", stagesStorage.String())
+	if err := stagesStorage.PostClientIDRecord(ctx, projectName, rec); err != nil {
+		return "", err
+	}
+
+	// wait between posting new id and getting current id to lower probability of collision with another process posting new client-id
+	time.Sleep(clientIDCollisionWaitPeriod)
+
+	if clientIDRecords, err := stagesStorage.GetClientIDRecords(ctx, projectName); err != nil {
+		return "", err
+	} else if clientIDRecords = excludeClientIDRecords(clientIDRecords, unknownClientIDs); len(clientIDRecords) > 0 {
+		res := selectOldestClientIDRecord(clientIDRecords)
+		logboek.Context(ctx).Debug().LogF("GetOrCreateClientID %s selected clientID: %s\n", projectName, res.String())
+		return res.ClientID, nil
+	} else {
+		return "", fmt.Errorf("could not find clientID in stages storage %s after successful creation", stagesStorage.String())
+	}
+}
+
+var clientIDCollisionWaitPeriod = 2 * time.Second
+
+func excludeClientIDRecords(records []*storage.ClientIDRecord, clientIDs map[string]bool) []*storage.ClientIDRecord {
+	var res []*storage.ClientIDRecord
+	for _, rec := range records {
+		if !clientIDs[rec.ClientID] {
+			res = append(res, rec)
 		}
 	}
+	return res
 }
 
 func selectOldestClientIDRecord(records []*storage.ClientIDRecord) *storage.ClientIDRecord {
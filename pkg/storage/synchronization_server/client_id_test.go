@@ -0,0 +1,98 @@
+package synchronization_server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+type fakeClientIDStagesStorage struct {
+	storage.StagesStorage
+	records []*storage.ClientIDRecord
+}
+
+func (s *fakeClientIDStagesStorage) GetClientIDRecords(_ context.Context, _ string) ([]*storage.ClientIDRecord, error) {
+	return s.records, nil
+}
+
+func (s *fakeClientIDStagesStorage) PostClientIDRecord(_ context.Context, _ string, rec *storage.ClientIDRecord) error {
+	s.records = append(s.records, rec)
+	return nil
+}
+
+func (s *fakeClientIDStagesStorage) String() string {
+	return "fake"
+}
+
+func TestGetOrCreateClientIDReplacesClientIDUnknownToServer(t *testing.T) {
+	defer func(period time.Duration) { clientIDCollisionWaitPeriod = period }(clientIDCollisionWaitPeriod)
+	clientIDCollisionWaitPeriod = 0
+
+	server := httptest.NewServer(newTestAccessHandler(newTestClientProjectStore(t)))
+	defer server.Close()
+
+	httpClient, err := NewHttpClient(ClientOptions{Token: "project-token", ProjectName: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	synchronizationClient := NewSynchronizationClient(server.URL, httpClient)
+
+	// the client id has been stored before the access has been restricted
+	stagesStorage := &fakeClientIDStagesStorage{records: []*storage.ClientIDRecord{{ClientID: "old-client-id", TimestampMillisec: 1}}}
+
+	clientID, err := GetOrCreateClientID(context.Background(), "project", synchronizationClient, stagesStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clientID == "old-client-id" {
+		t.Fatal("expected new client id to be issued")
+	}
+
+	if len(stagesStorage.records) != 2 {
+		t.Fatalf("expected new client id to be stored, got %d records", len(stagesStorage.records))
+	}
+
+	locker := NewHttpLocker(server.URL+"/"+clientID+"/locker", httpClient)
+	if acquired, lock, err := locker.Acquire("project.stages_and_images", lockgate.AcquireOptions{NonBlocking: true}); err != nil {
+		t.Fatal(err)
+	} else if !acquired {
+		t.Fatal("expected lock to be acquired")
+	} else if err := locker.Release(lock); err != nil {
+		t.Fatal(err)
+	}
+
+	// the stored new client id is used by the following runs
+	if nextClientID, err := GetOrCreateClientID(context.Background(), "project", synchronizationClient, stagesStorage); err != nil {
+		t.Fatal(err)
+	} else if nextClientID != clientID {
+		t.Errorf("expected client id %q, got %q", clientID, nextClientID)
+	} else if len(stagesStorage.records) != 2 {
+		t.Errorf("expected no new client ids to be stored, got %d records", len(stagesStorage.records))
+	}
+}
+
+func TestGetOrCreateClientIDKeepsClientIDWithoutAccessRestrictions(t *testing.T) {
+	handler := newTestAccessHandler(nil)
+	handler.Access = nil
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	httpClient, err := NewHttpClient(ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stagesStorage := &fakeClientIDStagesStorage{records: []*storage.ClientIDRecord{{ClientID: "old-client-id", TimestampMillisec: 1}}}
+	if clientID, err := GetOrCreateClientID(context.Background(), "project", NewSynchronizationClient(server.URL, httpClient), stagesStorage); err != nil {
+		t.Fatal(err)
+	} else if clientID != "old-client-id" {
+		t.Errorf("expected stored client id, got %q", clientID)
+	}
+}
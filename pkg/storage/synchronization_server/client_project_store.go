@@ -0,0 +1,136 @@
+package synchronization_server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/werf/werf/pkg/kubeutils"
+)
+
+const (
+	BoltClientProjectsBucket = "client_projects"
+
+	kubernetesClientProjectAnnotation = "werf.io/project-name"
+)
+
+// ClientProjectStore persists the projects the client ids have been issued for,
+// so that the clients keep access to the own project only after the server restart
+type ClientProjectStore interface {
+	// GetProjectName returns empty string if the client id has not been issued
+	GetProjectName(clientID string) (string, error)
+	StoreProjectName(clientID, projectName string) error
+}
+
+// FileClientProjectStore stores the project of the client id in the file: BASE_DIR/CLIENT_ID
+func NewFileClientProjectStore(baseDir string) *FileClientProjectStore {
+	return &FileClientProjectStore{BaseDir: baseDir}
+}
+
+type FileClientProjectStore struct {
+	BaseDir string
+}
+
+func (store *FileClientProjectStore) GetProjectName(clientID string) (string, error) {
+	// the client id from the url path is not used in the file path unless it is issued by the server
+	if _, err := uuid.Parse(clientID); err != nil {
+		return "", nil
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(store.BaseDir, clientID))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to read project of clientID %q: %s", clientID, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (store *FileClientProjectStore) StoreProjectName(clientID, projectName string) error {
+	if err := os.MkdirAll(store.BaseDir, 0755); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", store.BaseDir, err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(store.BaseDir, clientID), []byte(projectName+"\n"), 0644); err != nil {
+		return fmt.Errorf("unable to write project of clientID %q: %s", clientID, err)
+	}
+
+	return nil
+}
+
+// BoltClientProjectStore stores the project of the client id in the database file: CLIENT_PROJECTS_BUCKET/CLIENT_ID
+func NewBoltClientProjectStore(db *bolt.DB) *BoltClientProjectStore {
+	return &BoltClientProjectStore{DB: db}
+}
+
+type BoltClientProjectStore struct {
+	DB *bolt.DB
+}
+
+func (store *BoltClientProjectStore) GetProjectName(clientID string) (string, error) {
+	var projectName string
+	err := store.DB.View(func(tx *bolt.Tx) error {
+		projectName = string(tx.Bucket([]byte(BoltClientProjectsBucket)).Get([]byte(clientID)))
+		return nil
+	})
+	return projectName, err
+}
+
+func (store *BoltClientProjectStore) StoreProjectName(clientID, projectName string) error {
+	return store.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(BoltClientProjectsBucket)).Put([]byte(clientID), []byte(projectName))
+	})
+}
+
+// KubernetesClientProjectStore stores the project of the client id in the annotation of the client id configmap: NAMESPACE/cm/werf-CLIENT_ID
+func NewKubernetesClientProjectStore(client kubernetes.Interface, namespace string) *KubernetesClientProjectStore {
+	return &KubernetesClientProjectStore{KubeClient: client, Namespace: namespace}
+}
+
+type KubernetesClientProjectStore struct {
+	KubeClient kubernetes.Interface
+	Namespace  string
+}
+
+func (store *KubernetesClientProjectStore) GetProjectName(clientID string) (string, error) {
+	configMapName := fmt.Sprintf("werf-%s", clientID)
+
+	obj, err := store.KubeClient.CoreV1().ConfigMaps(store.Namespace).Get(context.Background(), configMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("get ConfigMap %s error: %s", configMapName, err)
+	}
+
+	return obj.Annotations[kubernetesClientProjectAnnotation], nil
+}
+
+func (store *KubernetesClientProjectStore) StoreProjectName(clientID, projectName string) error {
+	configMapName := fmt.Sprintf("werf-%s", clientID)
+
+	obj, err := kubeutils.GetOrCreateConfigMapWithNamespaceIfNotExists(store.KubeClient, store.Namespace, configMapName)
+	if err != nil {
+		return err
+	}
+
+	if obj.Annotations == nil {
+		obj.Annotations = make(map[string]string)
+	}
+	obj.Annotations[kubernetesClientProjectAnnotation] = projectName
+
+	if _, err := store.KubeClient.CoreV1().ConfigMaps(store.Namespace).Update(context.Background(), obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update ConfigMap %s error: %s", configMapName, err)
+	}
+
+	return nil
+}
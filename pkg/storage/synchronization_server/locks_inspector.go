@@ -0,0 +1,94 @@
+package synchronization_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+
+	"github.com/werf/werf/pkg/storage"
+)
+
+// HttpLocksInspector lists and force releases locks of the client using the locks api of the client id on the synchronization server
+type HttpLocksInspector struct {
+	URL        string
+	ClientID   string
+	HttpClient *http.Client
+}
+
+func NewHttpLocksInspector(url, clientID string, httpClient *http.Client) *HttpLocksInspector {
+	return &HttpLocksInspector{URL: url, ClientID: clientID, HttpClient: httpClient}
+}
+
+func (inspector *HttpLocksInspector) ListLocks(ctx context.Context, _ string) ([]storage.LockInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s/locks", inspector.URL, inspector.ClientID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var heldLocks []HeldLock
+	if err := inspector.do(req, &heldLocks); err != nil {
+		return nil, err
+	}
+
+	var res []storage.LockInfo
+	for _, lock := range heldLocks {
+		res = append(res, storage.LockInfo{
+			Name:       lock.LockName,
+			Owner:      lock.ClientID,
+			Shared:     lock.Shared,
+			Holders:    lock.Holders,
+			AcquiredAt: lock.AcquiredAt,
+			ExpireAt:   lock.RenewedAt.Add(distributed_locker.DistributedLockLeaseTTLSeconds * time.Second),
+		})
+	}
+
+	return res, nil
+}
+
+func (inspector *HttpLocksInspector) ForceRelease(ctx context.Context, lock storage.LockInfo) error {
+	data, err := json.Marshal(AdminReleaseLockRequest{ClientID: inspector.ClientID, LockName: lock.Name})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/%s/locks/release", inspector.URL, inspector.ClientID), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var response AdminReleaseLockResponse
+	if err := inspector.do(req, &response); err != nil {
+		return err
+	}
+
+	if response.Err != "" {
+		return fmt.Errorf("%s", response.Err)
+	}
+	return nil
+}
+
+func (inspector *HttpLocksInspector) do(req *http.Request, response interface{}) error {
+	resp, err := inspector.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request %s %s failed: %s", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s: %s", req.Method, req.URL, err)
+	}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("request %s %s failed: %s: %s", req.Method, req.URL, resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package synchronization_server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/werf/lockgate"
+)
+
+func TestHttpLocksInspector(t *testing.T) {
+	handler := newTestAccessHandler(newTestClientProjectStore(t))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	projectClient, err := NewHttpClient(ClientOptions{Token: "project-token", ProjectName: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientID, err := NewSynchronizationClient(server.URL, projectClient).NewClientID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locker := NewHttpLocker(server.URL+"/"+clientID+"/locker", projectClient)
+	for _, lockName := range []string{"project.stages_and_images", "project.sig"} {
+		if _, _, err := locker.Acquire(lockName, lockgate.AcquireOptions{NonBlocking: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the holder of the lock has been killed and does not renew the lease anymore
+	handler.LocksRegistry.mux.Lock()
+	handler.LocksRegistry.heldLocks[locksRegistryKey(clientID, "project.sig")].RenewedAt = time.Now().Add(-10 * time.Minute)
+	handler.LocksRegistry.mux.Unlock()
+
+	// the project token is enough to list and release the locks of the own client id
+	inspector := NewHttpLocksInspector(server.URL, clientID, projectClient)
+	locks, err := inspector.ListLocks(context.Background(), "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(locks) != 2 {
+		t.Fatalf("expected 2 locks, got %#v", locks)
+	}
+
+	for _, lock := range locks {
+		if expired := lock.Name == "project.sig"; lock.IsExpired() != expired {
+			t.Errorf("lock %q: expected expired %v, got %#v", lock.Name, expired, lock)
+		}
+
+		if lock.IsExpired() {
+			if err := inspector.ForceRelease(context.Background(), lock); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if locks, err := inspector.ListLocks(context.Background(), "project"); err != nil {
+		t.Fatal(err)
+	} else if len(locks) != 1 || locks[0].Name != "project.stages_and_images" {
+		t.Errorf("expected only the active lock to be kept, got %#v", locks)
+	}
+
+	// the locks of the client id are not available with the token of another project
+	teamClient, err := NewHttpClient(ClientOptions{Token: "team-token", ProjectName: "team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewHttpLocksInspector(server.URL, clientID, teamClient).ListLocks(context.Background(), "project"); err == nil {
+		t.Error("expected listing of the locks of another project to be denied")
+	}
+}
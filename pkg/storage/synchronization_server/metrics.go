@@ -0,0 +1,202 @@
+package synchronization_server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/werf/werf/pkg/image"
+	"github.com/werf/werf/pkg/storage"
+)
+
+const (
+	lockAcquireResultAcquired = "acquired"
+	lockAcquireResultWait     = "wait"
+	lockAcquireResultError    = "error"
+
+	cacheResultHit  = "hit"
+	cacheResultMiss = "miss"
+)
+
+// Metrics are exposed by the synchronization server in the Prometheus text format
+type Metrics struct {
+	mux sync.Mutex
+
+	clientIDsIssued     int64
+	lockAcquires        map[metricLabels]int64
+	lockAcquireDuration map[metricLabels]*durationSummary
+	lockWaitDuration    map[metricLabels]*durationSummary
+	lockHoldDuration    map[metricLabels]*durationSummary
+	cacheRequests       map[metricLabels]int64
+}
+
+type metricLabels struct {
+	ProjectName string
+	Result      string
+}
+
+type durationSummary struct {
+	Sum   float64
+	Count int64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		lockAcquires:        make(map[metricLabels]int64),
+		lockAcquireDuration: make(map[metricLabels]*durationSummary),
+		lockWaitDuration:    make(map[metricLabels]*durationSummary),
+		lockHoldDuration:    make(map[metricLabels]*durationSummary),
+		cacheRequests:       make(map[metricLabels]int64),
+	}
+}
+
+func (metrics *Metrics) IncClientIDsIssued() {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	metrics.clientIDsIssued++
+}
+
+func (metrics *Metrics) ObserveLockAcquire(projectName, result string, duration time.Duration) {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	metrics.lockAcquires[metricLabels{ProjectName: projectName, Result: result}]++
+	observeDuration(metrics.lockAcquireDuration, projectName, duration)
+}
+
+func (metrics *Metrics) ObserveLockWait(projectName string, duration time.Duration) {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	observeDuration(metrics.lockWaitDuration, projectName, duration)
+}
+
+func (metrics *Metrics) ObserveLockHold(projectName string, duration time.Duration) {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	observeDuration(metrics.lockHoldDuration, projectName, duration)
+}
+
+func (metrics *Metrics) ObserveCacheRequest(projectName string, found bool) {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	result := cacheResultMiss
+	if found {
+		result = cacheResultHit
+	}
+	metrics.cacheRequests[metricLabels{ProjectName: projectName, Result: result}]++
+}
+
+func observeDuration(summaries map[metricLabels]*durationSummary, projectName string, duration time.Duration) {
+	labels := metricLabels{ProjectName: projectName}
+	if _, hasKey := summaries[labels]; !hasKey {
+		summaries[labels] = &durationSummary{}
+	}
+	summaries[labels].Sum += duration.Seconds()
+	summaries[labels].Count++
+}
+
+// WritePrometheus writes metrics in the Prometheus text format, activeLeases are the number of held locks by project
+func (metrics *Metrics) WritePrometheus(w io.Writer, activeLeases map[string]int) {
+	metrics.mux.Lock()
+	defer metrics.mux.Unlock()
+
+	writeMetricHeader(w, "werf_synchronization_client_ids_issued_total", "counter", "Number of issued client ids.")
+	fmt.Fprintf(w, "werf_synchronization_client_ids_issued_total %d\n", metrics.clientIDsIssued)
+
+	writeMetricHeader(w, "werf_synchronization_lock_acquires_total", "counter", "Number of lock acquires by result: acquired, wait (counted once until the awaited lock is acquired) or error.")
+	for _, labels := range sortedMetricLabels(metrics.lockAcquires) {
+		fmt.Fprintf(w, "werf_synchronization_lock_acquires_total{project=%s,result=%s} %d\n", quoteLabelValue(labels.ProjectName), quoteLabelValue(labels.Result), metrics.lockAcquires[labels])
+	}
+
+	writeDurationSummary(w, "werf_synchronization_lock_acquire_duration_seconds", "Duration of lock acquire requests.", metrics.lockAcquireDuration)
+	writeDurationSummary(w, "werf_synchronization_lock_wait_duration_seconds", "Duration from the first rejected acquire request until the lock is acquired.", metrics.lockWaitDuration)
+	writeDurationSummary(w, "werf_synchronization_lock_hold_duration_seconds", "Duration from the lock acquire until the lock release.", metrics.lockHoldDuration)
+
+	writeMetricHeader(w, "werf_synchronization_active_leases", "gauge", "Number of currently held locks.")
+	var projectNames []string
+	for projectName := range activeLeases {
+		projectNames = append(projectNames, projectName)
+	}
+	sort.Strings(projectNames)
+	for _, projectName := range projectNames {
+		fmt.Fprintf(w, "werf_synchronization_active_leases{project=%s} %d\n", quoteLabelValue(projectName), activeLeases[projectName])
+	}
+
+	writeMetricHeader(w, "werf_synchronization_stages_storage_cache_requests_total", "counter", "Number of stages storage cache get requests by result: hit or miss.")
+	for _, labels := range sortedMetricLabels(metrics.cacheRequests) {
+		fmt.Fprintf(w, "werf_synchronization_stages_storage_cache_requests_total{project=%s,result=%s} %d\n", quoteLabelValue(labels.ProjectName), quoteLabelValue(labels.Result), metrics.cacheRequests[labels])
+	}
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func writeDurationSummary(w io.Writer, name, help string, summaries map[metricLabels]*durationSummary) {
+	writeMetricHeader(w, name, "summary", help)
+
+	var labelsList []metricLabels
+	for labels := range summaries {
+		labelsList = append(labelsList, labels)
+	}
+	sortMetricLabels(labelsList)
+
+	for _, labels := range labelsList {
+		fmt.Fprintf(w, "%s_sum{project=%s} %g\n", name, quoteLabelValue(labels.ProjectName), summaries[labels].Sum)
+		fmt.Fprintf(w, "%s_count{project=%s} %d\n", name, quoteLabelValue(labels.ProjectName), summaries[labels].Count)
+	}
+}
+
+func sortedMetricLabels(counters map[metricLabels]int64) []metricLabels {
+	var res []metricLabels
+	for labels := range counters {
+		res = append(res, labels)
+	}
+	sortMetricLabels(res)
+	return res
+}
+
+func sortMetricLabels(labelsList []metricLabels) {
+	sort.Slice(labelsList, func(i, j int) bool {
+		if labelsList[i].ProjectName != labelsList[j].ProjectName {
+			return labelsList[i].ProjectName < labelsList[j].ProjectName
+		}
+		return labelsList[i].Result < labelsList[j].Result
+	})
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabelValue(value string) string {
+	return fmt.Sprintf(`"%s"`, labelValueReplacer.Replace(value))
+}
+
+// metricsStagesStorageCache counts hits and misses of the stages storage cache
+type metricsStagesStorageCache struct {
+	storage.StagesStorageCache
+	Metrics *Metrics
+}
+
+func (cache *metricsStagesStorageCache) GetAllStages(ctx context.Context, projectName string) (bool, []image.StageID, error) {
+	found, stages, err := cache.StagesStorageCache.GetAllStages(ctx, projectName)
+	if err == nil {
+		cache.Metrics.ObserveCacheRequest(projectName, found)
+	}
+	return found, stages, err
+}
+
+func (cache *metricsStagesStorageCache) GetStagesBySignature(ctx context.Context, projectName, signature string) (bool, []image.StageID, error) {
+	found, stages, err := cache.StagesStorageCache.GetStagesBySignature(ctx, projectName, signature)
+	if err == nil {
+		cache.Metrics.ObserveCacheRequest(projectName, found)
+	}
+	return found, stages, err
+}
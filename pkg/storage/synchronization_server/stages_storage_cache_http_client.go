@@ -8,10 +8,10 @@ import (
 	"github.com/werf/werf/pkg/image"
 )
 
-func NewStagesStorageCacheHttpClient(url string) *StagesStorageCacheHttpClient {
+func NewStagesStorageCacheHttpClient(url string, httpClient *http.Client) *StagesStorageCacheHttpClient {
 	return &StagesStorageCacheHttpClient{
 		URL:        url,
-		HttpClient: &http.Client{},
+		HttpClient: httpClient,
 	}
 }
 
@@ -1,19 +1,162 @@
 package synchronization_server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/werf/lockgate/pkg/distributed_locker"
+)
+
+const (
+	// ProjectNameHeader is the project of the request, the server checks that the authenticated client has access to the project
+	ProjectNameHeader = "X-Werf-Project-Name"
+
+	clientOptionToken         = "token"
+	clientOptionTokenFile     = "token-file"
+	clientOptionCAFile        = "ca-file"
+	clientOptionCertFile      = "cert-file"
+	clientOptionKeyFile       = "key-file"
+	clientOptionSkipTlsVerify = "skip-tls-verify"
 )
 
+// ClientOptions configure authentication and TLS of the synchronization server client
+type ClientOptions struct {
+	ProjectName string
+
+	// Token is sent as the bearer token
+	Token string
+	// CAFile verifies the server certificate instead of the system CA
+	CAFile string
+	// CertFile and KeyFile are the client certificate for mTLS authentication
+	CertFile      string
+	KeyFile       string
+	SkipTlsVerify bool
+}
+
+// ParseSynchronizationAddress separates the client options from the address of the synchronization server.
+// Options are specified with the query params of the address and $WERF_SYNCHRONIZATION_* env vars:
+// token ($WERF_SYNCHRONIZATION_TOKEN), token-file ($WERF_SYNCHRONIZATION_TOKEN_FILE), ca-file ($WERF_SYNCHRONIZATION_CA_FILE),
+// cert-file ($WERF_SYNCHRONIZATION_CERT_FILE), key-file ($WERF_SYNCHRONIZATION_KEY_FILE) and skip-tls-verify ($WERF_SYNCHRONIZATION_SKIP_TLS_VERIFY).
+func ParseSynchronizationAddress(address string) (string, ClientOptions, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", ClientOptions{}, fmt.Errorf("bad url: %s", err)
+	}
+
+	query := u.Query()
+	getOption := func(name string) string {
+		if value := query.Get(name); value != "" {
+			return value
+		}
+		return os.Getenv(fmt.Sprintf("WERF_SYNCHRONIZATION_%s", strings.ToUpper(strings.Replace(name, "-", "_", -1))))
+	}
+
+	opts := ClientOptions{
+		Token:    getOption(clientOptionToken),
+		CAFile:   getOption(clientOptionCAFile),
+		CertFile: getOption(clientOptionCertFile),
+		KeyFile:  getOption(clientOptionKeyFile),
+	}
+
+	if tokenFile := getOption(clientOptionTokenFile); tokenFile != "" && opts.Token == "" {
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return "", ClientOptions{}, fmt.Errorf("unable to read token file %s: %s", tokenFile, err)
+		}
+		opts.Token = strings.TrimSpace(string(data))
+	}
+
+	if value := getOption(clientOptionSkipTlsVerify); value != "" {
+		if opts.SkipTlsVerify, err = strconv.ParseBool(value); err != nil {
+			return "", ClientOptions{}, fmt.Errorf("bad %s value %q: %s", clientOptionSkipTlsVerify, value, err)
+		}
+	}
+
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return "", ClientOptions{}, fmt.Errorf("both %s and %s should be specified", clientOptionCertFile, clientOptionKeyFile)
+	}
+
+	for _, name := range []string{clientOptionToken, clientOptionTokenFile, clientOptionCAFile, clientOptionCertFile, clientOptionKeyFile, clientOptionSkipTlsVerify} {
+		query.Del(name)
+	}
+	u.RawQuery = query.Encode()
+
+	return strings.TrimSuffix(u.String(), "/"), opts, nil
+}
+
+// NewHttpClient creates the client sending the token and the project name with each request
+func NewHttpClient(opts ClientOptions) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: opts.SkipTlsVerify}
+
+	if opts.CAFile != "" {
+		data, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA file %s: %s", opts.CAFile, err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts.CAFile)
+		}
+	}
+
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s: %s", opts.CertFile, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: &clientTransport{opts: opts, base: transport}}, nil
+}
+
+type clientTransport struct {
+	opts ClientOptions
+	base http.RoundTripper
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	if t.opts.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t.opts.Token))
+	}
+
+	if t.opts.ProjectName != "" {
+		req.Header.Set(ProjectNameHeader, t.opts.ProjectName)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// NewHttpLocker creates the locker using the client of the synchronization server
+func NewHttpLocker(url string, httpClient *http.Client) *distributed_locker.DistributedLocker {
+	return distributed_locker.NewDistributedLocker(&distributed_locker.HttpBackend{
+		URLEndpoint: url,
+		HttpClient:  httpClient,
+	})
+}
+
 type SynchronizationClient struct {
 	HttpClient *http.Client
 	URL        string
 }
 
-func NewSynchronizationClient(url string) *SynchronizationClient {
+func NewSynchronizationClient(url string, httpClient *http.Client) *SynchronizationClient {
 	return &SynchronizationClient{
 		URL:        url,
-		HttpClient: &http.Client{},
+		HttpClient: httpClient,
 	}
 }
 
@@ -25,3 +168,22 @@ func (client *SynchronizationClient) NewClientID() (string, error) {
 	}
 	return response.ClientID, response.Err.Error
 }
+
+// IsClientIDKnown checks that the server accepts the client id, the client ids issued before the access has been restricted are unknown to the server
+func (client *SynchronizationClient) IsClientIDKnown(clientID string) (bool, error) {
+	url := fmt.Sprintf("%s/%s/check-client-id", client.URL, clientID)
+
+	resp, err := client.HttpClient.Get(url)
+	if err != nil {
+		return false, fmt.Errorf("error requesting url %q: %s", url, err)
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("error reading response of %q request: %s", url, err)
+	}
+
+	// other errors are not related to the client id and are reported by the following requests
+	return !(resp.StatusCode == http.StatusForbidden && strings.Contains(string(body), unknownClientIDError)), nil
+}
@@ -2,7 +2,10 @@ package synchronization_server
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strings"
 	"sync"
@@ -17,9 +20,52 @@ import (
 	"github.com/werf/werf/pkg/storage"
 )
 
-func RunSynchronizationServer(ctx context.Context, ip, port string, distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error), stagesStorageCacheFactoryFunc func(clientID string) (storage.StagesStorageCache, error)) error {
+type SynchronizationServerOptions struct {
+	// TLSCertFile and TLSKeyFile enable https
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile enables mTLS: clients should present the certificate signed by the CA
+	TLSClientCAFile string
+	// Access restricts the access to the projects for the authenticated clients, nil — no restrictions
+	Access *AccessConfig
+	// ClientProjectStore persists the projects of the issued client ids, required when the access is restricted
+	ClientProjectStore ClientProjectStore
+}
+
+func RunSynchronizationServer(ctx context.Context, ip, port string, distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error), stagesStorageCacheFactoryFunc func(clientID string) (storage.StagesStorageCache, error), opts SynchronizationServerOptions) error {
 	handler := NewSynchronizationServerHandler(ctx, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc)
-	return http.ListenAndServe(fmt.Sprintf("%s:%s", ip, port), handler)
+	handler.Access = opts.Access
+	handler.ClientProjectStore = opts.ClientProjectStore
+
+	if opts.Access != nil && opts.ClientProjectStore == nil {
+		return fmt.Errorf("client project store required when access is restricted")
+	}
+
+	server := &http.Server{Addr: fmt.Sprintf("%s:%s", ip, port), Handler: handler}
+
+	if opts.TLSClientCAFile != "" {
+		if opts.TLSCertFile == "" {
+			return fmt.Errorf("client CA file requires server TLS certificate")
+		}
+
+		data, err := ioutil.ReadFile(opts.TLSClientCAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read client CA file %s: %s", opts.TLSClientCAFile, err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in client CA file %s", opts.TLSClientCAFile)
+		}
+
+		server.TLSConfig = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+	}
+
+	if opts.TLSCertFile != "" {
+		return server.ListenAndServeTLS(opts.TLSCertFile, opts.TLSKeyFile)
+	}
+
+	return server.ListenAndServe()
 }
 
 type SynchronizationServerHandler struct {
@@ -28,9 +74,22 @@ type SynchronizationServerHandler struct {
 	DistributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error)
 	StagesStorageCacheFactoryFunc       func(clientID string) (storage.StagesStorageCache, error)
 
+	// Access restricts the access to the projects for the authenticated clients, nil — no restrictions
+	Access *AccessConfig
+	// ClientProjectStore persists the projects of the issued client ids when the access is restricted
+	ClientProjectStore ClientProjectStore
+
 	ctx                             context.Context
 	mux                             sync.Mutex
 	SynchronizationServerByClientID map[string]*SynchronizationServerHandlerByClientID
+	// projectNameByClientID caches the projects of the client ids from the ClientProjectStore
+	projectNameByClientID map[string]string
+
+	Metrics       *Metrics
+	LocksRegistry *LocksRegistry
+
+	// csrfToken protects the form of the admin page
+	csrfToken string
 }
 
 func newSynchronizationServerHandler(ctx context.Context, distributedLockerBackendFactoryFunc func(clientID string) (distributed_locker.DistributedLockerBackend, error), stagesStorageCacheFactoryFunc func(requestID string) (storage.StagesStorageCache, error)) *SynchronizationServerHandler {
@@ -39,6 +98,11 @@ func newSynchronizationServerHandler(ctx context.Context, distributedLockerBacke
 		DistributedLockerBackendFactoryFunc: distributedLockerBackendFactoryFunc,
 		StagesStorageCacheFactoryFunc:       stagesStorageCacheFactoryFunc,
 		SynchronizationServerByClientID:     make(map[string]*SynchronizationServerHandlerByClientID),
+		projectNameByClientID:               make(map[string]string),
+		Metrics:                             NewMetrics(),
+		LocksRegistry:                       NewLocksRegistry(),
+		csrfToken:                           uuid.New().String(),
+		ctx:                                 ctx,
 	}
 }
 
@@ -46,6 +110,10 @@ func NewSynchronizationServerHandler(ctx context.Context, distributedLockerBacke
 	srv := newSynchronizationServerHandler(ctx, distributedLockerBackendFactoryFunc, stagesStorageCacheFactoryFunc)
 	srv.HandleFunc("/health", srv.handleHealth)
 	srv.HandleFunc("/new-client-id", srv.handleNewClientID)
+	srv.HandleFunc("/metrics", srv.handleMetrics)
+	srv.HandleFunc("/admin", srv.handleAdminPage)
+	srv.HandleFunc("/admin/locks", srv.handleAdminLocks)
+	srv.HandleFunc("/admin/locks/release", srv.handleAdminReleaseLock)
 	srv.HandleFunc("/", srv.handleRequestByClientID)
 
 	return srv
@@ -78,15 +146,129 @@ type NewClientIDResponse struct {
 }
 
 func (server *SynchronizationServerHandler) handleNewClientID(w http.ResponseWriter, r *http.Request) {
+	clientID := uuid.New().String()
+	if !server.authorizeNewClientID(w, r, clientID) {
+		return
+	}
+
 	var request NewClientIDRequest
 	var response NewClientIDResponse
 	HandleRequest(w, r, &request, &response, func() {
 		logboek.Context(server.ctx).Debug().LogF("SynchronizationServerHandler -- NewClientID request %#v\n", request)
-		response.ClientID = uuid.New().String()
+		response.ClientID = clientID
+		server.Metrics.IncClientIDsIssued()
 		logboek.Context(server.ctx).Debug().LogF("SynchronizationServerHandler -- NewClientID response %#v\n", response)
 	})
 }
 
+// maxCachedClientProjects limits the number of the cached client id projects, the evicted ones are read from the ClientProjectStore again
+const maxCachedClientProjects = 10000
+
+// authorizeNewClientID checks that the client has access to the project of the request and binds the new client id to the project.
+// The error response is written if the access is denied.
+func (server *SynchronizationServerHandler) authorizeNewClientID(w http.ResponseWriter, r *http.Request, clientID string) bool {
+	if server.Access == nil {
+		return true
+	}
+
+	rule, ok := server.Access.Authenticate(r)
+	if !ok {
+		http.Error(w, "Unauthorized: valid bearer token or client certificate required", http.StatusUnauthorized)
+		return false
+	}
+
+	projectName := r.Header.Get(ProjectNameHeader)
+	if projectName == "" {
+		http.Error(w, fmt.Sprintf("Forbidden: %s header required", ProjectNameHeader), http.StatusForbidden)
+		return false
+	}
+
+	if !rule.IsProjectAllowed(projectName) {
+		http.Error(w, fmt.Sprintf("Forbidden: no access to project %q", projectName), http.StatusForbidden)
+		return false
+	}
+
+	if err := server.ClientProjectStore.StoreProjectName(clientID, projectName); err != nil {
+		http.Error(w, fmt.Sprintf("Internal error: %s", err), http.StatusInternalServerError)
+		return false
+	}
+
+	server.cacheClientProject(clientID, projectName)
+
+	return true
+}
+
+// unknownClientIDError is returned for the client ids which have not been issued with the restricted access,
+// e.g. the ones issued before the access has been configured
+const unknownClientIDError = "unknown clientID"
+
+// authorize checks that the client has access to the project the client id has been issued for.
+// The error response is written if the access is denied.
+func (server *SynchronizationServerHandler) authorize(w http.ResponseWriter, r *http.Request, clientID string) bool {
+	if server.Access == nil {
+		return true
+	}
+
+	rule, ok := server.Access.Authenticate(r)
+	if !ok {
+		http.Error(w, "Unauthorized: valid bearer token or client certificate required", http.StatusUnauthorized)
+		return false
+	}
+
+	projectName, err := server.getClientProject(clientID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Internal error: %s", err), http.StatusInternalServerError)
+		return false
+	}
+
+	if projectName == "" {
+		http.Error(w, fmt.Sprintf("Forbidden: %s %q", unknownClientIDError, clientID), http.StatusForbidden)
+		return false
+	}
+
+	if !rule.IsProjectAllowed(projectName) {
+		http.Error(w, fmt.Sprintf("Forbidden: clientID %q belongs to project %q", clientID, projectName), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (server *SynchronizationServerHandler) getClientProject(clientID string) (string, error) {
+	server.mux.Lock()
+	projectName, hasKey := server.projectNameByClientID[clientID]
+	server.mux.Unlock()
+
+	if hasKey {
+		return projectName, nil
+	}
+
+	projectName, err := server.ClientProjectStore.GetProjectName(clientID)
+	if err != nil {
+		return "", fmt.Errorf("unable to get project of clientID %q: %s", clientID, err)
+	}
+
+	if projectName != "" {
+		server.cacheClientProject(clientID, projectName)
+	}
+
+	return projectName, nil
+}
+
+func (server *SynchronizationServerHandler) cacheClientProject(clientID, projectName string) {
+	server.mux.Lock()
+	defer server.mux.Unlock()
+
+	if len(server.projectNameByClientID) >= maxCachedClientProjects {
+		for key := range server.projectNameByClientID {
+			delete(server.projectNameByClientID, key)
+			break
+		}
+	}
+
+	server.projectNameByClientID[clientID] = projectName
+}
+
 func (server *SynchronizationServerHandler) handleLanding(w http.ResponseWriter, r *http.Request) {
 	rawPage := ` <!doctype html>
 <html>
@@ -101,6 +283,8 @@ func (server *SynchronizationServerHandler) handleLanding(w http.ResponseWriter,
 
 <p>Use "werf synchronization" command to run own synchronization http server. You can also configure werf to use local or kubernetes based synchronization backend.</p>
 
+<p>Server metrics in the Prometheus format are available at <a href="/metrics">/metrics</a>, currently held locks are listed at <a href="/admin">/admin</a> when the admin access is configured.</p>
+
 <p>More info about synchronization in werf: <a href="https://werf.io/documentation/reference/stages_and_images.html#synchronization-locks-and-stages-storage-cache">https://werf.io/documentation/reference/stages_and_images.html#synchronization-locks-and-stages-storage-cache</a></p>
 </body>
 </html>
@@ -124,15 +308,34 @@ func (server *SynchronizationServerHandler) handleRequestByClientID(w http.Respo
 		return
 	}
 
-	if clientServer, err := server.getOrCreateHandlerByClientID(server.ctx, clientID); err != nil {
+	if !server.authorize(w, r, clientID) {
+		return
+	}
+
+	projectName := r.Header.Get(ProjectNameHeader)
+	if server.Access != nil {
+		projectName, _ = server.getClientProject(clientID)
+	}
+
+	clientServer, err := server.getOrCreateHandlerByClientID(server.ctx, clientID, projectName)
+	if err != nil {
 		http.Error(w, fmt.Sprintf("Internal error: %s", err), http.StatusInternalServerError)
 		return
-	} else {
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/%s", clientID)) {
+	case "/locks":
+		server.handleClientLocks(w, r, clientID)
+	case "/locks/release":
+		server.handleClientReleaseLock(w, r, clientID)
+	case "/check-client-id":
+		fmt.Fprintf(w, "OK")
+	default:
 		http.StripPrefix(fmt.Sprintf("/%s", clientID), clientServer).ServeHTTP(w, r)
 	}
 }
 
-func (server *SynchronizationServerHandler) getOrCreateHandlerByClientID(ctx context.Context, clientID string) (*SynchronizationServerHandlerByClientID, error) {
+func (server *SynchronizationServerHandler) getOrCreateHandlerByClientID(ctx context.Context, clientID, projectName string) (*SynchronizationServerHandlerByClientID, error) {
 	server.mux.Lock()
 	defer server.mux.Unlock()
 
@@ -149,6 +352,15 @@ func (server *SynchronizationServerHandler) getOrCreateHandlerByClientID(ctx con
 			return nil, fmt.Errorf("unable to create stages storage cache for clientID %q: %s", clientID, err)
 		}
 
+		distributedLockerBackend = &metricsLockerBackend{
+			DistributedLockerBackend: distributedLockerBackend,
+			ClientID:                 clientID,
+			ProjectName:              projectName,
+			Registry:                 server.LocksRegistry,
+			Metrics:                  server.Metrics,
+		}
+		stagesStorageCache = &metricsStagesStorageCache{StagesStorageCache: stagesStorageCache, Metrics: server.Metrics}
+
 		handler := NewSynchronizationServerHandlerByClientID(ctx, clientID, distributedLockerBackend, stagesStorageCache)
 		server.SynchronizationServerByClientID[clientID] = handler
 
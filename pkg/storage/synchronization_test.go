@@ -50,3 +50,25 @@ func checkKubernetesSyncrhonization(t *testing.T, address string, expected *Kube
 		}
 	}
 }
+
+func TestParseRedisSynchronization(t *testing.T) {
+	if params, err := ParseRedisSynchronization("kubernetes://allo"); err != ErrBadRedisSynchronizationAddress {
+		t.Errorf("unexpected parse response: params=%v err=%v", params, err)
+	}
+
+	if params, err := ParseRedisSynchronization("redis://localhost:6379/0?other=value"); err == nil {
+		t.Errorf("expected error on unsupported params, got %#v", params)
+	}
+
+	if params, err := ParseRedisSynchronization("redis://localhost:6379"); err != nil {
+		t.Error(err)
+	} else if params.Namespace != DefaultRedisSynchronizationNamespace || params.Options.Addr != "localhost:6379" || params.Options.DB != 0 {
+		t.Errorf("unexpected params %#v, options %#v", params, params.Options)
+	}
+
+	if params, err := ParseRedisSynchronization("rediss://:secret@redis.example.com:6380/2?namespace=myns"); err != nil {
+		t.Error(err)
+	} else if params.Namespace != "myns" || params.Options.Addr != "redis.example.com:6380" || params.Options.DB != 2 || params.Options.Password != "secret" || params.Options.TLSConfig == nil {
+		t.Errorf("unexpected params %#v, options %#v", params, params.Options)
+	}
+}
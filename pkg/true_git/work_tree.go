@@ -52,9 +52,18 @@ func WithWorkTree(ctx context.Context, gitDir, workTreeCacheDir string, commit s
 	})
 }
 
+func WorkTreeCacheLockName(workTreeCacheDir string) string {
+	return fmt.Sprintf("git_work_tree_cache %s", workTreeCacheDir)
+}
+
 func withWorkTreeCacheLock(ctx context.Context, workTreeCacheDir string, f func() error) error {
-	lockName := fmt.Sprintf("git_work_tree_cache %s", workTreeCacheDir)
-	return werf.WithHostLock(ctx, lockName, lockgate.AcquireOptions{Timeout: 600 * time.Second}, f)
+	return werf.WithHostLock(ctx, WorkTreeCacheLockName(workTreeCacheDir), lockgate.AcquireOptions{Timeout: 600 * time.Second}, func() error {
+		if err := werf.TouchLastUse(workTreeCacheDir); err != nil {
+			return fmt.Errorf("unable to record last use of %s: %s", workTreeCacheDir, err)
+		}
+
+		return f()
+	})
 }
 
 func prepareWorkTree(ctx context.Context, repoDir, workTreeCacheDir string, commit string, withSubmodules bool) (string, error) {
@@ -37,15 +37,7 @@ func ExtractSecretValuesFromMap(data map[string]interface{}) []string {
 			}
 		default:
 			elemStr := fmt.Sprintf("%v", elemI)
-			if len(elemStr) >= 4 {
-				maskedValues = append(maskedValues, elemStr)
-			}
-			for _, line := range strings.Split(elemStr, "\n") {
-				trimmedLine := strings.TrimSpace(line)
-				if len(trimmedLine) >= 4 {
-					maskedValues = append(maskedValues, trimmedLine)
-				}
-			}
+			maskedValues = append(maskedValues, ExtractSecretValuesFromString(elemStr)...)
 
 			dataMap := map[string]interface{}{}
 			if err := json.Unmarshal([]byte(elemStr), &dataMap); err == nil {
@@ -65,3 +57,20 @@ func ExtractSecretValuesFromMap(data map[string]interface{}) []string {
 
 	return maskedValues
 }
+
+// ExtractSecretValuesFromString returns the value and its lines, short values are skipped to avoid masking of the arbitrary output
+func ExtractSecretValuesFromString(value string) []string {
+	maskedValues := []string{}
+
+	if len(value) >= 4 {
+		maskedValues = append(maskedValues, value)
+	}
+	for _, line := range strings.Split(value, "\n") {
+		trimmedLine := strings.TrimSpace(line)
+		if len(trimmedLine) >= 4 {
+			maskedValues = append(maskedValues, trimmedLine)
+		}
+	}
+
+	return maskedValues
+}
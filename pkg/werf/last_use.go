@@ -0,0 +1,62 @@
+package werf
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const LastUseVersion = "1"
+
+func GetLastUseDir() string {
+	return filepath.Join(GetLocalCacheDir(), "last_use", LastUseVersion)
+}
+
+// TouchLastUse records the time of use of the host object (local stage image, git repo cache dir, etc.),
+// the time is used by the host cleanup to remove the least recently used objects
+func TouchLastUse(name string) error {
+	path := lastUsePath(name)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("unable to create dir %s: %s", filepath.Dir(path), err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); os.IsNotExist(err) {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create %s: %s", path, err)
+		}
+		return f.Close()
+	} else if err != nil {
+		return fmt.Errorf("unable to touch %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// GetLastUse returns the time of the last use of the host object recorded by TouchLastUse
+func GetLastUse(name string) (time.Time, bool, error) {
+	info, err := os.Stat(lastUsePath(name))
+	if os.IsNotExist(err) {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return info.ModTime(), true, nil
+}
+
+func ForgetLastUse(name string) error {
+	if err := os.Remove(lastUsePath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func lastUsePath(name string) string {
+	return filepath.Join(GetLastUseDir(), fmt.Sprintf("%x", sha256.Sum256([]byte(name))))
+}